@@ -0,0 +1,64 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"time"
+)
+
+const (
+	minAcceptDelay = 5 * time.Millisecond
+	maxAcceptDelay = time.Second
+)
+
+// SetMaxAcceptErrors sets the maximum number of consecutive temporary
+// Accept errors, such as EMFILE or ECONNABORTED, after which Serve returns.
+// The Server sleeps with an exponential backoff between the retries.
+// Zero, the default, means retrying forever.
+func (m *Rum) SetMaxAcceptErrors(n int) {
+	m.maxAcceptErrors = n
+}
+
+// accept accepts an admitted connection, retrying with backoff on temporary errors.
+func (m *Rum) accept(l net.Listener) (net.Conn, error) {
+	var delay time.Duration
+	var errs int
+	for {
+		conn, err := l.Accept()
+		if err == nil {
+			if !m.admit(conn) {
+				continue
+			}
+			return conn, nil
+		}
+		if !isTemporary(err) {
+			return nil, err
+		}
+		errs++
+		if m.maxAcceptErrors > 0 && errs >= m.maxAcceptErrors {
+			return nil, err
+		}
+		if delay == 0 {
+			delay = minAcceptDelay
+		} else {
+			delay *= 2
+		}
+		if delay > maxAcceptDelay {
+			delay = maxAcceptDelay
+		}
+		time.Sleep(delay)
+	}
+}
+
+func isTemporary(err error) bool {
+	if errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr interface{ Temporary() bool }
+	return errors.As(err, &netErr) && netErr.Temporary()
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+type testErrorListener struct {
+	net.Listener
+	errs []error
+}
+
+func (l *testErrorListener) Accept() (net.Conn, error) {
+	if len(l.errs) > 0 {
+		err := l.errs[0]
+		l.errs = l.errs[1:]
+		return nil, err
+	}
+	return nil, errors.New("closed")
+}
+
+func TestAccept(t *testing.T) {
+	emfile := &net.OpError{Op: "accept", Err: os.NewSyscallError("accept", syscall.EMFILE)}
+	m := New()
+	l := &testErrorListener{errs: []error{emfile, syscall.ECONNABORTED, emfile}}
+	if _, err := m.accept(l); err == nil || err.Error() != "closed" {
+		t.Error(err)
+	}
+	if len(l.errs) != 0 {
+		t.Error(len(l.errs))
+	}
+	m.SetMaxAcceptErrors(2)
+	l = &testErrorListener{errs: []error{emfile, emfile, emfile}}
+	if _, err := m.accept(l); err != emfile {
+		t.Error(err)
+	}
+	if len(l.errs) != 1 {
+		t.Error(len(l.errs))
+	}
+	if isTemporary(errors.New("foo")) {
+		t.Error()
+	}
+}
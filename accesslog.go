@@ -0,0 +1,196 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrLogLevel is returned when parsing an unknown log level.
+var ErrLogLevel = errors.New("Unknown Log Level")
+
+// LogLevel is the level of an AccessLog.
+type LogLevel int32
+
+const (
+	// LogAll logs all of the requests, sampling the successful ones.
+	LogAll LogLevel = iota
+	// LogErrors logs the 4xx and the 5xx responses.
+	LogErrors
+	// LogServerErrors logs the 5xx responses.
+	LogServerErrors
+	// LogOff logs nothing.
+	LogOff
+)
+
+var logLevels = []string{"all", "errors", "server-errors", "off"}
+
+// String returns the name of the level.
+func (l LogLevel) String() string {
+	if l >= 0 && int(l) < len(logLevels) {
+		return logLevels[l]
+	}
+	return "unknown"
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (l LogLevel) MarshalText() ([]byte, error) {
+	if l.String() == "unknown" {
+		return nil, ErrLogLevel
+	}
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (l *LogLevel) UnmarshalText(text []byte) error {
+	for i, name := range logLevels {
+		if string(text) == name {
+			*l = LogLevel(i)
+			return nil
+		}
+	}
+	return ErrLogLevel
+}
+
+// AccessLog is an access logging middleware whose level, sampling and
+// filters can be adjusted at runtime, such as by the admin API, so the
+// logging overhead is controlled without restarts.
+type AccessLog struct {
+	level   int32
+	sample  uint32
+	seq     uint32
+	exclude atomic.Value
+	logger  *log.Logger
+}
+
+// NewAccessLog returns a new AccessLog at LogAll without sampling,
+// writing to the logger, or to the standard logger if nil.
+func NewAccessLog(logger *log.Logger) *AccessLog {
+	if logger == nil {
+		logger = log.New(log.Writer(), "", log.LstdFlags)
+	}
+	l := &AccessLog{logger: logger, sample: 1}
+	l.exclude.Store([]string(nil))
+	return l
+}
+
+// SetLevel sets the level.
+func (l *AccessLog) SetLevel(level LogLevel) {
+	atomic.StoreInt32(&l.level, int32(level))
+}
+
+// Level returns the level.
+func (l *AccessLog) Level() LogLevel {
+	return LogLevel(atomic.LoadInt32(&l.level))
+}
+
+// SetSample logs 1 of n of the 1xx, 2xx and 3xx responses, while the 4xx and
+// the 5xx responses are all logged as allowed by the level. Zero or one logs all.
+func (l *AccessLog) SetSample(n int) {
+	if n < 1 {
+		n = 1
+	}
+	atomic.StoreUint32(&l.sample, uint32(n))
+}
+
+// Sample returns the sampling of the successful responses.
+func (l *AccessLog) Sample() int {
+	return int(atomic.LoadUint32(&l.sample))
+}
+
+// SetExclude excludes the requests whose path begins with one of the prefixes.
+func (l *AccessLog) SetExclude(prefixes ...string) {
+	l.exclude.Store(append([]string(nil), prefixes...))
+}
+
+// Exclude returns the excluded path prefixes.
+func (l *AccessLog) Exclude() []string {
+	return append([]string(nil), l.exclude.Load().([]string)...)
+}
+
+// Handler returns a handler logging the requests served by the handler.
+func (l *AccessLog) Handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.serve(handler, w, r)
+	})
+}
+
+func (l *AccessLog) serve(handler http.Handler, w http.ResponseWriter, r *http.Request) {
+	if l.Level() == LogOff || l.excluded(r.URL.Path) {
+		handler.ServeHTTP(w, r)
+		return
+	}
+	start := time.Now()
+	aw := &accessResponseWriter{forwarder: forwarder{w}}
+	handler.ServeHTTP(expose(aw, w), r)
+	if aw.code == 0 {
+		aw.code = http.StatusOK
+	}
+	if l.logs(aw.code) {
+		l.logger.Printf("%s %s %s %d %d %s", r.RemoteAddr, r.Method, r.URL.RequestURI(), aw.code, aw.size, time.Since(start))
+	}
+}
+
+func (l *AccessLog) excluded(path string) bool {
+	for _, prefix := range l.exclude.Load().([]string) {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *AccessLog) logs(code int) bool {
+	switch level := l.Level(); {
+	case code >= 500:
+		return level < LogOff
+	case code >= 400:
+		return level <= LogErrors
+	case level != LogAll:
+		return false
+	}
+	sample := atomic.LoadUint32(&l.sample)
+	return sample <= 1 || atomic.AddUint32(&l.seq, 1)%sample == 0
+}
+
+// SetAccessLog logs the requests served by the Server with the AccessLog,
+// which the admin API adjusts. A nil AccessLog disables the logging. It
+// should be called before serving.
+func (m *Rum) SetAccessLog(l *AccessLog) {
+	m.accessLog = l
+}
+
+type accessResponseWriter struct {
+	forwarder
+	code int
+	size int64
+	// body records the body up to the limit, if not nil.
+	body  *bytes.Buffer
+	limit int
+}
+
+func (w *accessResponseWriter) WriteHeader(code int) {
+	if w.code == 0 && !informational(code) {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *accessResponseWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	if w.body != nil && w.size <= int64(w.limit) {
+		w.body.Write(p[:n])
+	}
+	w.size += int64(n)
+	return n, err
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) lines() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Count(b.buf.String(), "\n")
+}
+
+func TestAccessLog(t *testing.T) {
+	buf := &syncBuffer{}
+	l := NewAccessLog(log.New(buf, "", 0))
+	h := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	serve := func(target string, n int) {
+		for i := 0; i < n; i++ {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+		}
+	}
+	serve("/", 1)
+	if s := buf.buf.String(); !strings.HasPrefix(s, "192.0.2.1:1234 GET / 200 2 ") {
+		t.Error(s)
+	}
+	l.SetSample(10)
+	serve("/", 20)
+	serve("/missing", 3)
+	if n := buf.lines(); n != 1+2+3 {
+		t.Error(n)
+	}
+	l.SetLevel(LogErrors)
+	serve("/", 20)
+	serve("/missing", 1)
+	if n := buf.lines(); n != 7 {
+		t.Error(n)
+	}
+	l.SetLevel(LogServerErrors)
+	serve("/missing", 1)
+	serve("/error", 1)
+	if n := buf.lines(); n != 8 {
+		t.Error(n)
+	}
+	l.SetExclude("/err")
+	serve("/error", 1)
+	l.SetExclude()
+	l.SetLevel(LogOff)
+	serve("/error", 1)
+	if n := buf.lines(); n != 8 {
+		t.Error(n)
+	}
+	var level LogLevel
+	if err := level.UnmarshalText([]byte("server-errors")); err != nil || level != LogServerErrors {
+		t.Error(level, err)
+	}
+	if err := level.UnmarshalText([]byte("debug")); err != ErrLogLevel {
+		t.Error(err)
+	}
+	if _, err := LogLevel(9).MarshalText(); err != ErrLogLevel {
+		t.Error(err)
+	}
+}
+
+func TestAccessLogAdmin(t *testing.T) {
+	addr := ":8080"
+	buf := &syncBuffer{}
+	m := New()
+	m.SetAccessLog(NewAccessLog(log.New(buf, "", 0)))
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	})
+	m.Admin("/admin", AdminToken("secret"))
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	req, _ := http.NewRequest("POST", "http://"+addr+"/admin/logging", strings.NewReader(`{"level":"errors","exclude":["/health"]}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	var config struct {
+		Level   LogLevel `json:"level"`
+		Sample  int      `json:"sample"`
+		Exclude []string `json:"exclude"`
+	}
+	if err := json.Unmarshal(body, &config); err != nil || resp.StatusCode != http.StatusOK ||
+		config.Level != LogErrors || config.Sample != 1 || len(config.Exclude) != 1 {
+		t.Error(resp.StatusCode, string(body), err)
+	}
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	if n := buf.lines(); n != 1 {
+		t.Error(n)
+	}
+	m.Close()
+	<-done
+}
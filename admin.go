@@ -0,0 +1,238 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Route describes a registered route.
+type Route struct {
+	Pattern string   `json:"pattern"`
+	Methods []string `json:"methods,omitempty"`
+}
+
+// Routes returns the routes registered to the Mux and its groups, sorted by pattern.
+func (m *Mux) Routes() []Route {
+	m.mut.RLock()
+	routes := m.routes()
+	m.mut.RUnlock()
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Pattern < routes[j].Pattern })
+	return routes
+}
+
+func (m *Mux) routes() []Route {
+	routes := m.entries()
+	for _, groupMux := range m.groups {
+		groupMux.mut.RLock()
+		routes = append(routes, groupMux.routes()...)
+		groupMux.mut.RUnlock()
+	}
+	return routes
+}
+
+// entries returns the routes registered to the Mux, excluding its groups.
+func (m *Mux) entries() []Route {
+	var routes []Route
+	for _, p := range m.prefixes {
+		for _, entry := range p.m {
+			methods := entry.methods()
+			if entry.aliased != nil {
+				methods = entry.aliased.entry.methods()
+			}
+			routes = append(routes, Route{Pattern: p.prefix + entry.pattern(), Methods: methods})
+		}
+	}
+	for pattern, entry := range m.subtrees {
+		routes = append(routes, Route{Pattern: pattern, Methods: entry.methods()})
+	}
+	return routes
+}
+
+// pattern returns the params part of the pattern of the entry.
+func (entry *Entry) pattern() string {
+	if entry.key == "" {
+		return ""
+	}
+	segments := strings.Split(entry.key, "/")
+	for i := range segments {
+		if segments[i] == ":" && i < len(entry.match) {
+			segments[i] = ":" + entry.match[i]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func (entry *Entry) methods() []string {
+	var allowed []string
+	for i, method := range methods {
+		if entry.handlers[i] != nil {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+// AdminToken returns a function authenticating the requests with the bearer token.
+func AdminToken(token string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") {
+			return false
+		}
+		return subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(token)) == 1
+	}
+}
+
+// Admin mounts the admin API under the prefix, authenticated by the auth
+// function such as AdminToken. The prefix is kept live in the maintenance
+// mode, and is not injected with the faults of the Chaos. It exposes the
+// JSON endpoints:
+//
+//	GET  prefix/routes       lists the routes
+//	GET  prefix/stats        returns the connection statistics
+//	GET  prefix/inflight     lists the requests being served, if enabled by SetInflight
+//	POST prefix/maintenance  toggles the maintenance mode: {"enabled": true}
+//	POST prefix/ratelimits   sets a rate limit: {"class": "api", "rate": 10, "burst": 20}
+//	POST prefix/drain        drains the Server
+//	POST prefix/logging      adjusts the AccessLog: {"level": "errors", "sample": 100, "exclude": ["/health"]}
+//	POST prefix/chaos        sets the faults of the Chaos: {"faults": [{"route": "/users/:id", "percent": 10, "latency": "100ms", "status": 503}]}
+func (m *Rum) Admin(prefix string, auth func(r *http.Request) bool) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	m.SetMaintenanceAllow(append(append([]string{}, m.loadMaintenance().allow...), prefix+"/")...)
+	m.SetChaosAllow(append(append([]string{}, m.Mux.chaosAllow...), prefix+"/")...)
+	admin := func(method string, handler func(w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if auth == nil || !auth(r) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeAdminJSON(w, http.StatusUnauthorized, map[string]string{"error": http.StatusText(http.StatusUnauthorized)})
+				return
+			}
+			if r.Method != method {
+				w.Header().Set("Allow", method)
+				writeAdminJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": http.StatusText(http.StatusMethodNotAllowed)})
+				return
+			}
+			handler(w, r)
+		}
+	}
+	m.Group(prefix, func(g *Mux) {
+		g.HandleFunc("/routes", admin("GET", func(w http.ResponseWriter, r *http.Request) {
+			writeAdminJSON(w, http.StatusOK, m.Mux.Routes())
+		}))
+		g.HandleFunc("/stats", admin("GET", func(w http.ResponseWriter, r *http.Request) {
+			stats := map[string]interface{}{
+				"conns":       m.Conns(),
+				"shedded":     m.Shedded(),
+				"writeErrors": m.WriteErrors(),
+				"slowClients": m.SlowClients(),
+				"writeStall":  m.WriteStall(),
+				"overloaded":  m.Overloaded(),
+				"maintenance": m.Maintenance(),
+				"draining":    m.Draining(),
+				"requests":    m.Requests(),
+				"badRequests": m.BadRequests(),
+				"alloc":       m.AllocStats(),
+				"leaks":       m.LeakStats(),
+			}
+			if overload, ok := m.Mux.OverloadStats(); ok {
+				stats["overload"] = overload
+			}
+			writeAdminJSON(w, http.StatusOK, stats)
+		}))
+		g.HandleFunc("/inflight", admin("GET", func(w http.ResponseWriter, r *http.Request) {
+			if m.inflight == nil {
+				writeAdminJSON(w, http.StatusNotFound, map[string]string{"error": "inflight tracking disabled"})
+				return
+			}
+			writeAdminJSON(w, http.StatusOK, m.Inflight())
+		}))
+		g.HandleFunc("/maintenance", admin("POST", func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Enabled bool `json:"enabled"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeAdminJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			m.SetMaintenance(body.Enabled, m.loadMaintenance().handler)
+			writeAdminJSON(w, http.StatusOK, map[string]bool{"maintenance": m.Maintenance()})
+		}))
+		g.HandleFunc("/ratelimits", admin("POST", func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Class string  `json:"class"`
+				Rate  float64 `json:"rate"`
+				Burst int     `json:"burst"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeAdminJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			limit := RateLimit{Rate: body.Rate, Burst: body.Burst}
+			m.Mux.mut.RLock()
+			if l := m.Mux.limiters[body.Class]; l != nil {
+				limit.Key = l.limit.Key
+			}
+			m.Mux.mut.RUnlock()
+			m.SetRateLimit(body.Class, limit)
+			writeAdminJSON(w, http.StatusOK, body)
+		}))
+		g.HandleFunc("/drain", admin("POST", func(w http.ResponseWriter, r *http.Request) {
+			m.Drain()
+			writeAdminJSON(w, http.StatusAccepted, map[string]interface{}{"draining": true, "conns": m.Conns()})
+		}))
+		g.HandleFunc("/logging", admin("POST", func(w http.ResponseWriter, r *http.Request) {
+			l := m.accessLog
+			if l == nil {
+				writeAdminJSON(w, http.StatusNotFound, map[string]string{"error": "no access log"})
+				return
+			}
+			var body struct {
+				Level   *LogLevel `json:"level"`
+				Sample  *int      `json:"sample"`
+				Exclude []string  `json:"exclude"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeAdminJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			if body.Level != nil {
+				l.SetLevel(*body.Level)
+			}
+			if body.Sample != nil {
+				l.SetSample(*body.Sample)
+			}
+			if body.Exclude != nil {
+				l.SetExclude(body.Exclude...)
+			}
+			writeAdminJSON(w, http.StatusOK, map[string]interface{}{"level": l.Level(), "sample": l.Sample(), "exclude": l.Exclude()})
+		}))
+		g.HandleFunc("/chaos", admin("POST", func(w http.ResponseWriter, r *http.Request) {
+			c := m.Mux.Chaos()
+			if c == nil {
+				writeAdminJSON(w, http.StatusNotFound, map[string]string{"error": "no chaos"})
+				return
+			}
+			var body struct {
+				Faults []Fault `json:"faults"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeAdminJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			c.SetFaults(body.Faults...)
+			writeAdminJSON(w, http.StatusOK, map[string]interface{}{"faults": c.Faults()})
+		}))
+	})
+}
+
+func writeAdminJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoutes(t *testing.T) {
+	m := NewMux()
+	m.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {}).GET().POST()
+	m.HandleFunc("/users/:id/posts/:post", func(w http.ResponseWriter, r *http.Request) {}).GET()
+	m.Group("/api", func(m *Mux) {
+		m.HandleFunc("/items/:id", func(w http.ResponseWriter, r *http.Request) {})
+	})
+	routes := m.Routes()
+	if len(routes) != 3 {
+		t.Fatalf("routes %v", routes)
+	}
+	if routes[0].Pattern != "/api/items/:id" || len(routes[0].Methods) != 0 {
+		t.Error(routes[0])
+	}
+	if routes[1].Pattern != "/hello" || strings.Join(routes[1].Methods, ",") != "GET,POST" {
+		t.Error(routes[1])
+	}
+	if routes[2].Pattern != "/users/:id/posts/:post" || strings.Join(routes[2].Methods, ",") != "GET" {
+		t.Error(routes[2])
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	}).RateLimit("api")
+	m.Admin("/admin", AdminToken("secret"))
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	do := func(method, path, token, body string, status int) []byte {
+		req, _ := http.NewRequest(method, "http://"+addr+path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != status {
+			t.Errorf("%s %s: status %d != %d", method, path, resp.StatusCode, status)
+		}
+		if resp.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("%s %s: Content-Type %s", method, path, resp.Header.Get("Content-Type"))
+		}
+		return b
+	}
+	do("GET", "/admin/routes", "", "", http.StatusUnauthorized)
+	do("GET", "/admin/routes", "wrong", "", http.StatusUnauthorized)
+	do("GET", "/admin/maintenance", "secret", "", http.StatusMethodNotAllowed)
+	var routes []Route
+	if err := json.Unmarshal(do("GET", "/admin/routes", "secret", "", http.StatusOK), &routes); err != nil {
+		t.Error(err)
+	} else if len(routes) != 9 {
+		t.Errorf("routes %v", routes)
+	}
+	do("POST", "/admin/ratelimits", "secret", `{"class":"api","rate":1,"burst":1}`, http.StatusOK)
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	testHTTP("GET", "http://"+addr+"/", http.StatusTooManyRequests, "429 Too Many Requests\n", t)
+	do("POST", "/admin/ratelimits", "secret", `{"class":"api"}`, http.StatusOK)
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	do("POST", "/admin/maintenance", "secret", `{"enabled":true}`, http.StatusOK)
+	testHTTP("GET", "http://"+addr+"/", http.StatusServiceUnavailable, "503 Service Unavailable : Maintenance\n", t)
+	var stats map[string]interface{}
+	if err := json.Unmarshal(do("GET", "/admin/stats", "secret", "", http.StatusOK), &stats); err != nil {
+		t.Error(err)
+	} else if stats["maintenance"] != true || stats["draining"] != false {
+		t.Errorf("stats %v", stats)
+	}
+	do("POST", "/admin/maintenance", "secret", `{"enabled":false}`, http.StatusOK)
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	do("POST", "/admin/drain", "secret", "", http.StatusAccepted)
+	<-done
+	if !m.Draining() {
+		t.Error("should be draining")
+	}
+	m.Close()
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type aliasMode int
+
+const (
+	aliasDispatch aliasMode = iota
+	aliasLink
+	aliasRedirect
+)
+
+// alias dispatches the requests of an alias pattern to the entry.
+type alias struct {
+	entry *Entry
+	mux   *Mux
+	route string
+	mode  aliasMode
+}
+
+// Alias registers the patterns as the aliases of the entry, dispatching to
+// the entry with its methods, its handlers and its options, so the legacy
+// URLs do not require duplicate handler registration. The params of the
+// aliases are named as the params of the entry.
+func (entry *Entry) Alias(patterns ...string) *Entry {
+	return entry.alias(aliasDispatch, patterns)
+}
+
+// LinkAlias is like Alias but adds a Link header of the canonical URL
+// of the entry with rel="canonical" to the responses of the aliases.
+func (entry *Entry) LinkAlias(patterns ...string) *Entry {
+	return entry.alias(aliasLink, patterns)
+}
+
+// RedirectAlias is like Alias but redirects the requests of the aliases
+// to the canonical URL of the entry, with a 301 Moved Permanently for GET
+// and HEAD, or a 308 Permanent Redirect keeping the method and the body.
+func (entry *Entry) RedirectAlias(patterns ...string) *Entry {
+	return entry.alias(aliasRedirect, patterns)
+}
+
+func (entry *Entry) alias(mode aliasMode, patterns []string) *Entry {
+	m := entry.mux
+	m.mut.RLock()
+	route := m.route(entry)
+	m.mut.RUnlock()
+	for _, pattern := range patterns {
+		aliasEntry := m.Handle(pattern, entry.handler)
+		aliasEntry.aliased = &alias{entry: entry, mux: m, route: route, mode: mode}
+	}
+	return entry
+}
+
+// route returns the registered pattern of the entry.
+func (entry *Entry) route() string {
+	if entry.subtree {
+		return entry.key
+	}
+	m := entry.mux
+	if m == nil {
+		return ""
+	}
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+	return m.route(entry)
+}
+
+// route returns the registered pattern of the entry in the Mux.
+func (m *Mux) route(entry *Entry) string {
+	for _, p := range m.prefixes {
+		for _, e := range p.m {
+			if e == entry {
+				return p.prefix + entry.pattern()
+			}
+			for _, variant := range e.variants {
+				if variant == entry {
+					return p.prefix + entry.pattern()
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// serve replies to the request of the alias with a redirect, or adds the
+// Link header. It reports whether the request should be served by the entry.
+func (a *alias) serve(w http.ResponseWriter, r *http.Request) bool {
+	if a.mode == aliasDispatch {
+		return true
+	}
+	location, ok := a.canonical(r)
+	if !ok {
+		return true
+	}
+	if a.mode == aliasLink {
+		w.Header().Add("Link", "<"+location+`>; rel="canonical"`)
+		return true
+	}
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+	code := http.StatusMovedPermanently
+	if r.Method != "GET" && r.Method != "HEAD" {
+		code = http.StatusPermanentRedirect
+	}
+	http.Redirect(w, r, location, code)
+	return false
+}
+
+// canonical returns the path of the entry with the params of the alias.
+func (a *alias) canonical(r *http.Request) (string, bool) {
+	if a.route == "" {
+		return "", false
+	}
+	segments := strings.Split(a.route, "/")
+	var params map[string]string
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+		if params == nil {
+			params = a.mux.Params(r)
+		}
+		value, ok := params[segment[1:]]
+		if !ok {
+			return "", false
+		}
+		segments[i] = url.PathEscape(value)
+	}
+	return strings.Join(segments, "/"), true
+}
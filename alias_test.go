@@ -0,0 +1,65 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAlias(t *testing.T) {
+	m := NewMux()
+	users := m.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " user " + m.Params(r)["id"]))
+	}).GET().PUT()
+	users.Alias("/user/:id", "/profiles/:id/show")
+	users.LinkAlias("/u/:id")
+	users.RedirectAlias("/people/:id")
+	users.RedirectAlias("/person/:name")
+	m.Group("/api", func(m *Mux) {
+		m.HandleFunc("/items/:id", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("item " + m.Params(r)["id"]))
+		}).GET().RedirectAlias("/item/:id")
+	})
+	tests := []struct {
+		method, target string
+		code           int
+		body           string
+		link, location string
+	}{
+		{"GET", "/users/8", http.StatusOK, "GET user 8", "", ""},
+		{"GET", "/user/8", http.StatusOK, "GET user 8", "", ""},
+		{"PUT", "/profiles/8/show", http.StatusOK, "PUT user 8", "", ""},
+		{"POST", "/user/8", http.StatusMethodNotAllowed, "", "", ""},
+		{"GET", "/u/a%20b", http.StatusOK, "GET user a b", `</users/a%20b>; rel="canonical"`, ""},
+		{"GET", "/people/8?x=1", http.StatusMovedPermanently, "", "", "/users/8?x=1"},
+		{"PUT", "/people/8", http.StatusPermanentRedirect, "", "", "/users/8"},
+		{"GET", "/person/8", http.StatusOK, "GET user ", "", ""},
+		{"GET", "/api/item/3", http.StatusMovedPermanently, "", "", "/api/items/3"},
+	}
+	m.Problems()
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+		if w.Code != test.code {
+			t.Error(test.method, test.target, w.Code)
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Error(test.method, test.target, w.Body.String())
+		}
+		if w.Header().Get("Link") != test.link || w.Header().Get("Location") != test.location {
+			t.Error(test.method, test.target, w.Header())
+		}
+	}
+	var routes []string
+	for _, route := range m.Routes() {
+		routes = append(routes, route.Pattern+" "+strings.Join(route.Methods, ","))
+	}
+	if strings.Join(routes, ";") != "/api/item/:id GET;/api/items/:id GET;/people/:id GET,PUT;/person/:name GET,PUT;"+
+		"/profiles/:id/show GET,PUT;/u/:id GET,PUT;/user/:id GET,PUT;/users/:id GET,PUT" {
+		t.Error(routes)
+	}
+}
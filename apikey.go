@@ -0,0 +1,166 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrAPIKeyNotFound is the error returned by an APIKeyStore when the key
+// does not exist.
+var ErrAPIKeyNotFound = errors.New("API Key Not Found")
+
+// APIKeyContextKey is a context key. The associated value is the *APIKey
+// of the request validated by the APIKeys.
+var APIKeyContextKey = &contextKey{"api-key"}
+
+// APIKey is an API key of the clients.
+type APIKey struct {
+	// ID identifies the key in the metrics and the logs, so that the
+	// secret key is never exposed.
+	ID string `json:"id"`
+	// Key is the secret key sent by the clients.
+	Key string `json:"key"`
+	// Class is the rate-limit class of the key in the APIKeys.
+	Class string `json:"class,omitempty"`
+	// Disabled rejects the key.
+	Disabled bool `json:"disabled,omitempty"`
+	// Expires rejects the key after the time, unless it is zero.
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// GetAPIKey returns the APIKey of the request validated by the APIKeys, or nil.
+func GetAPIKey(r *http.Request) *APIKey {
+	key, _ := r.Context().Value(APIKeyContextKey).(*APIKey)
+	return key
+}
+
+// APIKeyStore looks up the API keys.
+//
+// It is implemented by *MemoryAPIKeyStore, *FileAPIKeyStore and
+// *SQLAPIKeyStore.
+type APIKeyStore interface {
+	// Lookup returns the APIKey of the secret key, or ErrAPIKeyNotFound.
+	Lookup(ctx context.Context, key string) (*APIKey, error)
+}
+
+// APIKeys is a middleware validating the API keys of the requests against
+// a Store. The requests without a key or with an unknown key are replied
+// with 401 Unauthorized, with a disabled or expired key with 403 Forbidden,
+// and those exceeding the rate limit of the class of the key with 429 Too
+// Many Requests. A failure of the Store replies 503 Service Unavailable.
+type APIKeys struct {
+	// Store looks up the keys.
+	Store APIKeyStore
+	// Header is the header carrying the key. If empty, "X-API-Key" is used.
+	Header string
+	// Query is the query parameter carrying the key if the header is
+	// missing, such as "api_key". If empty, the query is not read.
+	Query string
+	// Limits is the rate limit of the classes of the keys. The budget of
+	// a class is partitioned by the key. The keys of the other classes are
+	// not limited.
+	Limits map[string]RateLimit
+	// Metrics optionally exports the usage of the keys: the "apikey.requests"
+	// counter tagged with the key ID, the class and the status, and the
+	// "apikey.rejected" counter tagged with the reason.
+	Metrics Metrics
+
+	once     sync.Once
+	limiters map[string]*rateLimiter
+	usage    sync.Map
+}
+
+// Handler returns a handler validating the API key before calling the handler.
+func (a *APIKeys) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.once.Do(a.init)
+		key := r.Header.Get(a.header())
+		if key == "" && a.Query != "" {
+			key = r.URL.Query().Get(a.Query)
+		}
+		if key == "" {
+			a.reject(w, "missing", http.StatusUnauthorized)
+			return
+		}
+		apiKey, err := a.Store.Lookup(r.Context(), key)
+		if err == ErrAPIKeyNotFound || err == nil && apiKey == nil {
+			a.reject(w, "invalid", http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			a.reject(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		now := time.Now()
+		if apiKey.Disabled || !apiKey.Expires.IsZero() && now.After(apiKey.Expires) {
+			a.reject(w, "disabled", http.StatusForbidden)
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), APIKeyContextKey, apiKey))
+		if l := a.limiters[apiKey.Class]; l != nil && !l.allow(r, now) {
+			w.Header().Set("Retry-After", l.retryAfter())
+			a.reject(w, "limited", http.StatusTooManyRequests)
+			return
+		}
+		a.count(apiKey.ID)
+		if a.Metrics == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+		aw := &accessResponseWriter{forwarder: forwarder{w}}
+		h.ServeHTTP(expose(aw, w), r)
+		if aw.code == 0 {
+			aw.code = http.StatusOK
+		}
+		a.Metrics.Count("apikey.requests", 1, "key:"+apiKey.ID, "class:"+apiKey.Class, "status:"+strconv.Itoa(aw.code))
+	})
+}
+
+// Usage returns the number of the requests served by the ID of the keys.
+func (a *APIKeys) Usage() map[string]int64 {
+	usage := make(map[string]int64)
+	a.usage.Range(func(id, count interface{}) bool {
+		usage[id.(string)] = atomic.LoadInt64(count.(*int64))
+		return true
+	})
+	return usage
+}
+
+func (a *APIKeys) init() {
+	a.limiters = make(map[string]*rateLimiter)
+	for class, limit := range a.Limits {
+		if limit.Key == nil {
+			limit.Key = func(r *http.Request) string { return GetAPIKey(r).ID }
+		}
+		a.limiters[class] = newRateLimiter(limit)
+	}
+}
+
+func (a *APIKeys) header() string {
+	if a.Header == "" {
+		return "X-API-Key"
+	}
+	return a.Header
+}
+
+func (a *APIKeys) count(id string) {
+	count, ok := a.usage.Load(id)
+	if !ok {
+		count, _ = a.usage.LoadOrStore(id, new(int64))
+	}
+	atomic.AddInt64(count.(*int64), 1)
+}
+
+func (a *APIKeys) reject(w http.ResponseWriter, reason string, code int) {
+	if a.Metrics != nil {
+		a.Metrics.Count("apikey.rejected", 1, "reason:"+reason)
+	}
+	http.Error(w, strconv.Itoa(code)+" "+StatusText(code), code)
+}
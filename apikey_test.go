@@ -0,0 +1,81 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type failingAPIKeyStore struct{}
+
+func (failingAPIKeyStore) Lookup(ctx context.Context, key string) (*APIKey, error) {
+	return nil, errors.New("database is down")
+}
+
+func TestAPIKeys(t *testing.T) {
+	metrics := &testMetrics{}
+	keys := &APIKeys{
+		Store: NewMemoryAPIKeyStore(
+			&APIKey{ID: "free", Key: "k1", Class: "free"},
+			&APIKey{ID: "paid", Key: "k2", Class: "paid"},
+			&APIKey{ID: "off", Key: "k3", Disabled: true},
+			&APIKey{ID: "old", Key: "k4", Expires: time.Now().Add(-time.Hour)},
+		),
+		Query:   "api_key",
+		Limits:  map[string]RateLimit{"free": {Rate: 0.001, Burst: 1}},
+		Metrics: metrics,
+	}
+	h := keys.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(GetAPIKey(r).ID))
+	}))
+	tests := []struct {
+		header string
+		query  string
+		status int
+		body   string
+	}{
+		{"", "", http.StatusUnauthorized, "401 Unauthorized\n"},
+		{"unknown", "", http.StatusUnauthorized, "401 Unauthorized\n"},
+		{"k1", "", http.StatusOK, "free"},
+		{"k1", "", http.StatusTooManyRequests, "429 Too Many Requests\n"},
+		{"", "k2", http.StatusOK, "paid"},
+		{"k2", "", http.StatusOK, "paid"},
+		{"k3", "", http.StatusForbidden, "403 Forbidden\n"},
+		{"k4", "", http.StatusForbidden, "403 Forbidden\n"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/?api_key="+test.query, nil)
+		if test.header != "" {
+			r.Header.Set("X-API-Key", test.header)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != test.status || w.Body.String() != test.body {
+			t.Error(test.header, test.query, w.Code, w.Body.String())
+		}
+	}
+	if usage := keys.Usage(); len(usage) != 2 || usage["free"] != 1 || usage["paid"] != 2 {
+		t.Error(usage)
+	}
+	metrics.mu.Lock()
+	counts := metrics.counts
+	metrics.mu.Unlock()
+	if len(counts) != len(tests) || counts[2] != "apikey.requests key:free,class:free,status:200" ||
+		counts[3] != "apikey.rejected reason:limited" {
+		t.Error(counts)
+	}
+	keys = &APIKeys{Store: failingAPIKeyStore{}, Header: "Authorization"}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "k1")
+	w := httptest.NewRecorder()
+	keys.Handler(h).ServeHTTP(w, r)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Error(w.Code)
+	}
+}
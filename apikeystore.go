@@ -0,0 +1,143 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// MemoryAPIKeyStore is an APIKeyStore in memory.
+type MemoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]*APIKey
+}
+
+// NewMemoryAPIKeyStore returns a new MemoryAPIKeyStore with the keys.
+func NewMemoryAPIKeyStore(keys ...*APIKey) *MemoryAPIKeyStore {
+	s := &MemoryAPIKeyStore{keys: make(map[string]*APIKey)}
+	for _, key := range keys {
+		s.keys[key.Key] = key
+	}
+	return s
+}
+
+// Add adds or replaces the key.
+func (s *MemoryAPIKeyStore) Add(key *APIKey) {
+	s.mu.Lock()
+	s.keys[key.Key] = key
+	s.mu.Unlock()
+}
+
+// Remove removes the key.
+func (s *MemoryAPIKeyStore) Remove(key string) {
+	s.mu.Lock()
+	keys := make(map[string]*APIKey, len(s.keys))
+	for k, v := range s.keys {
+		if k != key {
+			keys[k] = v
+		}
+	}
+	s.keys = keys
+	s.mu.Unlock()
+}
+
+// Lookup implements the APIKeyStore interface.
+func (s *MemoryAPIKeyStore) Lookup(ctx context.Context, key string) (*APIKey, error) {
+	s.mu.RLock()
+	apiKey, ok := s.keys[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	return apiKey, nil
+}
+
+// FileAPIKeyStore is an APIKeyStore reading a JSON array of the APIKeys
+// from a file, which is reloaded when modified. A failed reload keeps the
+// previous keys.
+type FileAPIKeyStore struct {
+	// Name is the name of the file.
+	Name string
+	// CheckInterval is the interval of checking the modification of the
+	// file. If zero, one second is used.
+	CheckInterval time.Duration
+
+	mu      sync.Mutex
+	keys    *MemoryAPIKeyStore
+	modTime time.Time
+	checked time.Time
+}
+
+// Lookup implements the APIKeyStore interface.
+func (s *FileAPIKeyStore) Lookup(ctx context.Context, key string) (*APIKey, error) {
+	keys, err := s.load()
+	if keys == nil {
+		return nil, err
+	}
+	return keys.Lookup(ctx, key)
+}
+
+func (s *FileAPIKeyStore) load() (*MemoryAPIKeyStore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	interval := s.CheckInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	now := time.Now()
+	if s.keys != nil && now.Sub(s.checked) < interval {
+		return s.keys, nil
+	}
+	s.checked = now
+	info, err := os.Stat(s.Name)
+	if err != nil {
+		return s.keys, err
+	}
+	if s.keys != nil && !info.ModTime().After(s.modTime) {
+		return s.keys, nil
+	}
+	data, err := ioutil.ReadFile(s.Name)
+	if err != nil {
+		return s.keys, err
+	}
+	var list []*APIKey
+	if err := json.Unmarshal(data, &list); err != nil {
+		return s.keys, err
+	}
+	s.keys = NewMemoryAPIKeyStore(list...)
+	s.modTime = info.ModTime()
+	return s.keys, nil
+}
+
+// SQLAPIKeyStore is an APIKeyStore querying a database. The Query selects
+// the ID, the class, the disabled flag and the nullable expiration of the
+// key given as the single argument, for example:
+//
+//	SELECT id, class, disabled, expires FROM api_keys WHERE key = ?
+type SQLAPIKeyStore struct {
+	DB    *sql.DB
+	Query string
+}
+
+// Lookup implements the APIKeyStore interface.
+func (s *SQLAPIKeyStore) Lookup(ctx context.Context, key string) (*APIKey, error) {
+	apiKey := &APIKey{Key: key}
+	var class sql.NullString
+	var expires sql.NullTime
+	err := s.DB.QueryRowContext(ctx, s.Query, key).Scan(&apiKey.ID, &class, &apiKey.Disabled, &expires)
+	if err == sql.ErrNoRows {
+		return nil, ErrAPIKeyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	apiKey.Class = class.String
+	apiKey.Expires = expires.Time
+	return apiKey, nil
+}
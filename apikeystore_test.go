@@ -0,0 +1,116 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMemoryAPIKeyStore(t *testing.T) {
+	s := NewMemoryAPIKeyStore(&APIKey{ID: "a", Key: "k1"})
+	s.Add(&APIKey{ID: "b", Key: "k2"})
+	if key, err := s.Lookup(context.Background(), "k2"); err != nil || key.ID != "b" {
+		t.Error(key, err)
+	}
+	s.Remove("k1")
+	if _, err := s.Lookup(context.Background(), "k1"); err != ErrAPIKeyNotFound {
+		t.Error(err)
+	}
+}
+
+func TestFileAPIKeyStore(t *testing.T) {
+	f, err := ioutil.TempFile("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Write([]byte(`[{"id": "a", "key": "k1", "class": "free"}]`))
+	f.Close()
+	s := &FileAPIKeyStore{Name: f.Name(), CheckInterval: time.Millisecond}
+	if key, err := s.Lookup(context.Background(), "k1"); err != nil || key.ID != "a" || key.Class != "free" {
+		t.Error(key, err)
+	}
+	ioutil.WriteFile(f.Name(), []byte(`[{"id": "b", "key": "k2"}]`), 0600)
+	modTime := time.Now().Add(time.Second)
+	os.Chtimes(f.Name(), modTime, modTime)
+	time.Sleep(time.Millisecond * 2)
+	if _, err := s.Lookup(context.Background(), "k1"); err != ErrAPIKeyNotFound {
+		t.Error(err)
+	}
+	if key, err := s.Lookup(context.Background(), "k2"); err != nil || key.ID != "b" {
+		t.Error(key, err)
+	}
+	ioutil.WriteFile(f.Name(), []byte(`invalid`), 0600)
+	modTime = modTime.Add(time.Second)
+	os.Chtimes(f.Name(), modTime, modTime)
+	time.Sleep(time.Millisecond * 2)
+	if key, err := s.Lookup(context.Background(), "k2"); err != nil || key.ID != "b" {
+		t.Error("the previous keys should be kept", key, err)
+	}
+	if _, err := (&FileAPIKeyStore{Name: f.Name() + ".missing"}).Lookup(context.Background(), "k2"); err == nil {
+		t.Error("the lookup should fail")
+	}
+}
+
+type testKeyDriver struct{}
+
+func (testKeyDriver) Open(name string) (driver.Conn, error) { return testKeyConn{}, nil }
+
+type testKeyConn struct{}
+
+func (testKeyConn) Prepare(query string) (driver.Stmt, error) { return testKeyStmt{}, nil }
+func (testKeyConn) Close() error                              { return nil }
+func (testKeyConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+type testKeyStmt struct{}
+
+func (testKeyStmt) Close() error                                    { return nil }
+func (testKeyStmt) NumInput() int                                   { return 1 }
+func (testKeyStmt) Exec(args []driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (testKeyStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows := &testKeyRows{}
+	if args[0] == "k1" {
+		rows.values = [][]driver.Value{{"a", "paid", false, time.Unix(2000000000, 0)}}
+	}
+	return rows, nil
+}
+
+type testKeyRows struct {
+	values [][]driver.Value
+}
+
+func (r *testKeyRows) Columns() []string { return []string{"id", "class", "disabled", "expires"} }
+func (r *testKeyRows) Close() error      { return nil }
+func (r *testKeyRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestSQLAPIKeyStore(t *testing.T) {
+	sql.Register("rumtestkeys", testKeyDriver{})
+	db, err := sql.Open("rumtestkeys", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	s := &SQLAPIKeyStore{DB: db, Query: "SELECT id, class, disabled, expires FROM api_keys WHERE key = ?"}
+	key, err := s.Lookup(context.Background(), "k1")
+	if err != nil || key.ID != "a" || key.Class != "paid" || key.Disabled || key.Expires.Unix() != 2000000000 {
+		t.Error(key, err)
+	}
+	if _, err := s.Lookup(context.Background(), "k2"); err != ErrAPIKeyNotFound {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultAuditTimeout = 5 * time.Second
+
+// principalContextKey is the context key of the principal of an audited request.
+var principalContextKey = &contextKey{"principal"}
+
+type principal struct {
+	name string
+}
+
+// SetPrincipal sets the principal of the audited request, such as the
+// user authenticated by an auth middleware, for the audit log. It does
+// nothing if the request is not audited.
+func SetPrincipal(r *http.Request, name string) {
+	if p, ok := r.Context().Value(principalContextKey).(*principal); ok {
+		p.name = name
+	}
+}
+
+// AuditRecord records who did what and when.
+type AuditRecord struct {
+	Time       time.Time         `json:"time"`
+	Principal  string            `json:"principal,omitempty"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Route      string            `json:"route"`
+	Params     map[string]string `json:"params,omitempty"`
+	Status     int               `json:"status"`
+	RemoteAddr string            `json:"remoteAddr,omitempty"`
+	Duration   time.Duration     `json:"duration"`
+}
+
+// AuditSink writes the audit records, such as to a file or a webhook.
+type AuditSink interface {
+	WriteAudit(record *AuditRecord) error
+}
+
+// AuditSinkFunc is an adapter to use a function as an AuditSink.
+type AuditSinkFunc func(record *AuditRecord) error
+
+// WriteAudit calls f(record).
+func (f AuditSinkFunc) WriteAudit(record *AuditRecord) error {
+	return f(record)
+}
+
+type writerAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterAuditSink returns an AuditSink writing the records as JSON
+// lines to the writer, such as an append-only file.
+func NewWriterAuditSink(w io.Writer) AuditSink {
+	return &writerAuditSink{w: w}
+}
+
+func (s *writerAuditSink) WriteAudit(record *AuditRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(b, '\n'))
+	return err
+}
+
+// WebhookAuditSink posts each record as JSON to the URL.
+type WebhookAuditSink struct {
+	// URL is the webhook.
+	URL string
+	// Client posts the records. If nil, a client with a 5 seconds timeout is used.
+	Client *http.Client
+}
+
+var defaultAuditClient = &http.Client{Timeout: defaultAuditTimeout}
+
+// WriteAudit posts the record, failing on a non-2xx status.
+func (s *WebhookAuditSink) WriteAudit(record *AuditRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	client := s.Client
+	if client == nil {
+		client = defaultAuditClient
+	}
+	resp, err := client.Post(s.URL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("audit webhook status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Audit records the mutating requests to a sink, for the compliance
+// sensitive deployments. The records are written after the responses,
+// so the sinks should be fast or buffered.
+type Audit struct {
+	// Sink writes the records.
+	Sink AuditSink
+	// Methods are the audited methods. If empty, POST, PUT, PATCH and
+	// DELETE are audited.
+	Methods []string
+	// Principal returns the principal of the request. If nil, the
+	// principal set by SetPrincipal or the basic auth user is used.
+	Principal func(r *http.Request) string
+	// ErrorLog logs the errors of the sink. If nil, the standard logger is used.
+	ErrorLog *log.Logger
+}
+
+// SetAudit sets the audit log of the requests routed by the Mux and its
+// groups. A nil Audit disables the audit log.
+func (m *Mux) SetAudit(a *Audit) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.audit = a
+}
+
+func (a *Audit) audits(method string) bool {
+	if len(a.Methods) == 0 {
+		return method == "POST" || method == "PUT" || method == "PATCH" || method == "DELETE"
+	}
+	return strSliceContains(a.Methods, method)
+}
+
+func (a *Audit) principal(r *http.Request) string {
+	if a.Principal != nil {
+		return a.Principal(r)
+	}
+	if p, ok := r.Context().Value(principalContextKey).(*principal); ok && p.name != "" {
+		return p.name
+	}
+	user, _, _ := r.BasicAuth()
+	return user
+}
+
+// begin returns the request carrying the principal to be set by SetPrincipal.
+func (a *Audit) begin(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalContextKey, &principal{}))
+}
+
+// record writes the record of the request served by the entry.
+func (a *Audit) record(entry *Entry, r *http.Request, status int, start time.Time) {
+	record := &AuditRecord{
+		Time:       start,
+		Principal:  a.principal(r),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Status:     status,
+		RemoteAddr: r.RemoteAddr,
+		Duration:   time.Since(start),
+	}
+	record.Route = entry.route()
+	if m := entry.mux; m != nil && len(entry.match) > 0 {
+		record.Params = m.Params(r)
+	}
+	if err := a.Sink.WriteAudit(record); err != nil {
+		if a.ErrorLog != nil {
+			a.ErrorLog.Printf("rum: audit %s %s: %v", r.Method, r.URL.Path, err)
+		} else {
+			log.Printf("rum: audit %s %s: %v", r.Method, r.URL.Path, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	var records []*AuditRecord
+	m := NewMux()
+	m.SetAudit(&Audit{Sink: AuditSinkFunc(func(record *AuditRecord) error {
+		records = append(records, record)
+		return nil
+	})})
+	m.Use(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.Header.Get("X-Token"); token != "" {
+			SetPrincipal(r, "user-"+token)
+		}
+	})
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+	m.HandleFunc("/users/:id", handler).GET().PUT().DELETE()
+	m.Group("/api", func(m *Mux) {
+		m.HandleFunc("/items", handler).POST()
+	})
+	serve := func(method, target string, header func(r *http.Request)) {
+		r := httptest.NewRequest(method, target, nil)
+		if header != nil {
+			header(r)
+		}
+		m.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	serve("GET", "/users/8", nil)
+	serve("PUT", "/users/8", func(r *http.Request) { r.Header.Set("X-Token", "1") })
+	serve("DELETE", "/users/9", func(r *http.Request) { r.SetBasicAuth("admin", "secret") })
+	serve("POST", "/api/items", nil)
+	if len(records) != 3 {
+		t.Fatal(len(records))
+	}
+	if r := records[0]; r.Principal != "user-1" || r.Method != "PUT" || r.Path != "/users/8" ||
+		r.Route != "/users/:id" || r.Params["id"] != "8" || r.Status != http.StatusOK || r.Time.IsZero() {
+		t.Error(r)
+	}
+	if r := records[1]; r.Principal != "admin" || r.Status != http.StatusNoContent || r.Params["id"] != "9" {
+		t.Error(r)
+	}
+	if r := records[2]; r.Principal != "" || r.Route != "/api/items" || r.Params != nil {
+		t.Error(r)
+	}
+	buf := bytes.NewBuffer(nil)
+	m.SetAudit(&Audit{
+		Sink:      AuditSinkFunc(func(record *AuditRecord) error { return errors.New("sink") }),
+		Methods:   []string{"GET"},
+		Principal: func(r *http.Request) string { return "fixed" },
+		ErrorLog:  log.New(buf, "", 0),
+	})
+	serve("GET", "/users/8", nil)
+	serve("PUT", "/users/8", nil)
+	if buf.String() != "rum: audit GET /users/8: sink\n" {
+		t.Error(buf.String())
+	}
+	SetPrincipal(httptest.NewRequest("GET", "/", nil), "ignored")
+}
+
+func TestAuditSinks(t *testing.T) {
+	record := &AuditRecord{Principal: "admin", Method: "POST", Path: "/items", Route: "/items", Status: 201}
+	buf := bytes.NewBuffer(nil)
+	sink := NewWriterAuditSink(buf)
+	sink.WriteAudit(record)
+	sink.WriteAudit(record)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var decoded AuditRecord
+	if len(lines) != 2 || json.Unmarshal([]byte(lines[0]), &decoded) != nil || decoded.Principal != "admin" || decoded.Status != 201 {
+		t.Error(buf.String())
+	}
+	var received AuditRecord
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(b, &received)
+		if received.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer webhook.Close()
+	ws := &WebhookAuditSink{URL: webhook.URL}
+	if err := ws.WriteAudit(record); err != nil || received.Route != "/items" {
+		t.Error(err, received)
+	}
+	if err := ws.WriteAudit(&AuditRecord{Path: "/fail"}); err == nil {
+		t.Error()
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrUnauthorized is the error of the credentials rejected by an AuthScheme.
+var ErrUnauthorized = errors.New("Unauthorized")
+
+// ErrInvalidRequest is the Bearer error of a malformed request, replied
+// with 400 Bad Request.
+var ErrInvalidRequest = &AuthError{Code: "invalid_request", Status: http.StatusBadRequest}
+
+// ErrInvalidToken is the Bearer error of an expired, revoked or malformed
+// token, replied with 401 Unauthorized.
+var ErrInvalidToken = &AuthError{Code: "invalid_token"}
+
+// ErrInsufficientScope is the Bearer error of a token without the scope
+// required by the route, replied with 403 Forbidden.
+var ErrInsufficientScope = &AuthError{Code: "insufficient_scope", Status: http.StatusForbidden}
+
+// PrincipalContextKey is a context key. The associated value is the
+// *Principal authenticated by an AuthScheme of the route.
+var PrincipalContextKey = &contextKey{"principal"}
+
+// Principal is the identity authenticated by an AuthScheme.
+type Principal struct {
+	// Scheme is the lowercase name of the AuthScheme, such as "basic".
+	Scheme string
+	// Name is the user name or the subject of the token.
+	Name string
+}
+
+// GetPrincipal returns the Principal authenticated for the request, or nil.
+func GetPrincipal(r *http.Request) *Principal {
+	p, _ := r.Context().Value(PrincipalContextKey).(*Principal)
+	return p
+}
+
+// AuthError is an error of the authentication with the error code of the
+// challenge (RFC 6750, section 3.1) and the status code of the response.
+type AuthError struct {
+	// Code is the error code, such as "invalid_token".
+	Code string
+	// Description is the human readable error_description.
+	Description string
+	// Scope is the scope required by the route.
+	Scope string
+	// Status is the status code of the response. Zero means 401 Unauthorized.
+	Status int
+}
+
+// Error returns the error code and the description.
+func (e *AuthError) Error() string {
+	if e.Description != "" {
+		return e.Code + ": " + e.Description
+	}
+	return e.Code
+}
+
+// WithDescription returns a copy of the error with the description.
+func (e *AuthError) WithDescription(description string) *AuthError {
+	err := *e
+	err.Description = description
+	return &err
+}
+
+// WithScope returns a copy of the error with the scope required by the route.
+func (e *AuthError) WithScope(scope string) *AuthError {
+	err := *e
+	err.Scope = scope
+	return &err
+}
+
+// AuthScheme is an HTTP authentication scheme of the routes.
+type AuthScheme interface {
+	// Authenticate verifies the credentials of the Authorization header of
+	// the scheme, returning the Principal or an error.
+	Authenticate(r *http.Request, credentials string) (*Principal, error)
+	// Challenge returns the WWW-Authenticate challenge of the scheme in the
+	// realm, given the error of the authentication or nil.
+	Challenge(realm string, err error) string
+}
+
+// BasicAuth is the Basic AuthScheme (RFC 7617).
+type BasicAuth struct {
+	// Verify reports whether the password of the user is valid.
+	Verify func(username, password string) bool
+}
+
+// Authenticate implements the AuthScheme interface.
+func (a *BasicAuth) Authenticate(r *http.Request, credentials string) (*Principal, error) {
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+	i := strings.IndexByte(string(decoded), ':')
+	if i < 0 || a.Verify == nil || !a.Verify(string(decoded[:i]), string(decoded[i+1:])) {
+		return nil, ErrUnauthorized
+	}
+	return &Principal{Name: string(decoded[:i])}, nil
+}
+
+// Challenge implements the AuthScheme interface.
+func (a *BasicAuth) Challenge(realm string, err error) string {
+	return `Basic realm=` + quoteAuthParam(realm) + `, charset="UTF-8"`
+}
+
+// BearerAuth is the Bearer AuthScheme (RFC 6750).
+type BearerAuth struct {
+	// Verify verifies the token, returning the subject of the token, or
+	// an error such as ErrInvalidToken or ErrInsufficientScope.
+	Verify func(r *http.Request, token string) (string, error)
+}
+
+// Authenticate implements the AuthScheme interface.
+func (a *BearerAuth) Authenticate(r *http.Request, credentials string) (*Principal, error) {
+	if credentials == "" || strings.ContainsAny(credentials, " \t") {
+		return nil, ErrInvalidRequest
+	}
+	if a.Verify == nil {
+		return nil, ErrInvalidToken
+	}
+	name, err := a.Verify(r, credentials)
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{Name: name}, nil
+}
+
+// Challenge implements the AuthScheme interface.
+func (a *BearerAuth) Challenge(realm string, err error) string {
+	challenge := `Bearer realm=` + quoteAuthParam(realm)
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		challenge += `, error=` + quoteAuthParam(authErr.Code)
+		if authErr.Description != "" {
+			challenge += `, error_description=` + quoteAuthParam(authErr.Description)
+		}
+		if authErr.Scope != "" {
+			challenge += `, scope=` + quoteAuthParam(authErr.Scope)
+		}
+	} else if err != nil {
+		challenge += `, error="invalid_token"`
+	}
+	return challenge
+}
+
+type routeAuth struct {
+	realm   string
+	schemes []string
+}
+
+// AuthScheme registers the AuthScheme with the case-insensitive name, such
+// as "Basic" or "Bearer", to the Mux, so that the routes can require it by
+// Auth. The schemes are looked up by the Mux serving the requests.
+func (m *Mux) AuthScheme(name string, scheme AuthScheme) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	if m.authSchemes == nil {
+		m.authSchemes = make(map[string]AuthScheme)
+	}
+	m.authSchemes[strings.ToLower(name)] = scheme
+}
+
+// Auth requires the entries registered afterwards to the Mux, typically
+// in a Group, to authenticate the requests by one of the schemes.
+func (m *Mux) Auth(realm string, schemes ...string) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.auth = newRouteAuth(realm, schemes)
+}
+
+// Auth requires the entry to authenticate the requests by one of the
+// schemes registered by AuthScheme, in the realm. The requests without
+// the credentials of the schemes are replied with 401 Unauthorized and a
+// WWW-Authenticate challenge of each scheme. The rejected credentials are
+// replied with the status code of the AuthError, 401 Unauthorized by
+// default, and the challenge of the scheme carrying the error. The
+// authenticated Principal is stored in the context of the request. No
+// schemes remove the authentication of the entry.
+//
+//	m.AuthScheme("Basic", &rum.BasicAuth{Verify: verify})
+//	m.AuthScheme("Bearer", &rum.BearerAuth{Verify: verifyToken})
+//	m.HandleFunc("/api/users", users).Auth("api", "Bearer", "Basic")
+func (entry *Entry) Auth(realm string, schemes ...string) *Entry {
+	entry.auth = newRouteAuth(realm, schemes)
+	return entry
+}
+
+func newRouteAuth(realm string, schemes []string) *routeAuth {
+	if len(schemes) == 0 {
+		return nil
+	}
+	a := &routeAuth{realm: realm}
+	for _, scheme := range schemes {
+		a.schemes = append(a.schemes, strings.ToLower(scheme))
+	}
+	return a
+}
+
+// authenticate authenticates the request of the entry, returning the
+// request with the Principal, or false if the request is replied.
+func (m *Mux) authenticate(entry *Entry, w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	a := entry.auth
+	name, credentials := r.Header.Get("Authorization"), ""
+	if i := strings.IndexByte(name, ' '); i > 0 {
+		name, credentials = name[:i], strings.TrimLeft(name[i+1:], " ")
+	}
+	name = strings.ToLower(name)
+	m.mut.RLock()
+	schemes := m.authSchemes
+	m.mut.RUnlock()
+	var err error
+	if name != "" && strSliceContains(a.schemes, name) {
+		if scheme := schemes[name]; scheme != nil {
+			var p *Principal
+			if p, err = scheme.Authenticate(r, credentials); err == nil {
+				if p == nil {
+					p = &Principal{}
+				}
+				if p.Scheme == "" {
+					p.Scheme = name
+				}
+				return r.WithContext(context.WithValue(r.Context(), PrincipalContextKey, p)), true
+			}
+		}
+	}
+	status := http.StatusUnauthorized
+	var authErr *AuthError
+	if errors.As(err, &authErr) && authErr.Status != 0 {
+		status = authErr.Status
+	}
+	for _, s := range a.schemes {
+		scheme := schemes[s]
+		if scheme == nil {
+			continue
+		}
+		if s == name {
+			w.Header().Add("WWW-Authenticate", scheme.Challenge(a.realm, err))
+		} else if status == http.StatusUnauthorized {
+			w.Header().Add("WWW-Authenticate", scheme.Challenge(a.realm, nil))
+		}
+	}
+	if m.context.problems {
+		ProblemJSON(w, status, "", r.URL.Path)
+	} else {
+		http.Error(w, strconv.Itoa(status)+" "+StatusText(status), status)
+	}
+	return r, false
+}
+
+// quoteAuthParam returns the quoted-string of the auth-param value.
+func quoteAuthParam(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuth(t *testing.T) {
+	m := New()
+	m.AuthScheme("Basic", &BasicAuth{Verify: func(username, password string) bool {
+		return username == "alice" && password == "secret"
+	}})
+	m.AuthScheme("bearer", &BearerAuth{Verify: func(r *http.Request, token string) (string, error) {
+		switch token {
+		case "admin":
+			return "admin", nil
+		case "reader":
+			return "", ErrInsufficientScope.WithScope("write")
+		}
+		return "", ErrInvalidToken.WithDescription(`token "expired"`)
+	}})
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		p := GetPrincipal(r)
+		if p == nil {
+			w.Write([]byte("anonymous"))
+			return
+		}
+		w.Write([]byte(p.Scheme + ":" + p.Name))
+	}
+	m.HandleFunc("/both", handler).Auth("api", "Bearer", "Basic")
+	m.HandleFunc("/public", handler)
+	m.Group("/admin", func(m *Mux) {
+		m.Auth(`admin "zone"`, "Bearer")
+		m.HandleFunc("/users", handler)
+	})
+	tests := []struct {
+		path          string
+		authorization string
+		status        int
+		body          string
+		challenges    []string
+	}{
+		{"/public", "", http.StatusOK, "anonymous", nil},
+		{"/both", "", http.StatusUnauthorized, "401 Unauthorized\n",
+			[]string{`Bearer realm="api"`, `Basic realm="api", charset="UTF-8"`}},
+		{"/both", "Basic YWxpY2U6c2VjcmV0", http.StatusOK, "basic:alice", nil},
+		{"/both", "basic YWxpY2U6d3Jvbmc=", http.StatusUnauthorized, "401 Unauthorized\n",
+			[]string{`Bearer realm="api"`, `Basic realm="api", charset="UTF-8"`}},
+		{"/both", "Bearer admin", http.StatusOK, "bearer:admin", nil},
+		{"/both", "Bearer old", http.StatusUnauthorized, "401 Unauthorized\n",
+			[]string{`Bearer realm="api", error="invalid_token", error_description="token \"expired\""`, `Basic realm="api", charset="UTF-8"`}},
+		{"/both", "Bearer reader", http.StatusForbidden, "403 Forbidden\n",
+			[]string{`Bearer realm="api", error="insufficient_scope", scope="write"`}},
+		{"/both", "Bearer a b", http.StatusBadRequest, "400 Bad Request\n",
+			[]string{`Bearer realm="api", error="invalid_request"`}},
+		{"/both", "Digest x", http.StatusUnauthorized, "401 Unauthorized\n",
+			[]string{`Bearer realm="api"`, `Basic realm="api", charset="UTF-8"`}},
+		{"/admin/users", "Basic YWxpY2U6c2VjcmV0", http.StatusUnauthorized, "401 Unauthorized\n",
+			[]string{`Bearer realm="admin \"zone\""`}},
+		{"/admin/users", "Bearer admin", http.StatusOK, "bearer:admin", nil},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", test.path, nil)
+		if test.authorization != "" {
+			r.Header.Set("Authorization", test.authorization)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		if w.Code != test.status || w.Body.String() != test.body {
+			t.Error(test.path, test.authorization, w.Code, w.Body.String())
+		}
+		challenges := w.Header()["Www-Authenticate"]
+		if len(challenges) != len(test.challenges) {
+			t.Error(test.authorization, challenges)
+			continue
+		}
+		for i := range challenges {
+			if challenges[i] != test.challenges[i] {
+				t.Error(test.authorization, challenges[i])
+			}
+		}
+	}
+	m.HandleFunc("/both", handler).Auth("api")
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/both", nil))
+	if w.Code != http.StatusOK {
+		t.Error(w.Code)
+	}
+}
+
+func TestAuthProblems(t *testing.T) {
+	m := New()
+	m.Problems()
+	m.AuthScheme("Bearer", &BearerAuth{})
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {}).Auth("api", "Bearer")
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusUnauthorized || w.Header().Get("Content-Type") != "application/problem+json" ||
+		w.Header().Get("WWW-Authenticate") != `Bearer realm="api"` {
+		t.Error(w.Code, w.Header())
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ACMEChallengePath is the path prefix of the ACME HTTP-01 challenge.
+const ACMEChallengePath = "/.well-known/acme-challenge/"
+
+// ErrAutoCertNil is the error returned by RunAutoTLS when the AutoCertManager is not set.
+var ErrAutoCertNil = errors.New("AutoCert manager must be not nil")
+
+// ErrHostNotAllowed is the error returned by the automatic certificate
+// lookup when the server name is not one of the domains.
+var ErrHostNotAllowed = errors.New("Host not allowed")
+
+// AutoCertManager obtains certificates automatically from an ACME CA.
+//
+// It is satisfied by *autocert.Manager of the golang.org/x/crypto/acme/autocert
+// package. rum only calls these two methods, so the ACME account, the host
+// policy and the certificate cache are configured on the manager, for example:
+//
+//	m.SetAutoCert(&autocert.Manager{
+//		Prompt: autocert.AcceptTOS,
+//		Cache:  autocert.DirCache("certs"),
+//	})
+//	m.RunAutoTLS("example.com", "www.example.com")
+type AutoCertManager interface {
+	// GetCertificate returns a certificate for the ClientHello.
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+	// HTTPHandler responds to the HTTP-01 challenges and calls
+	// fallback for the other requests.
+	HTTPHandler(fallback http.Handler) http.Handler
+}
+
+// SetAutoCert sets the ACME certificate manager used by RunAutoTLS and
+// mounts the HTTP-01 challenge handler on the Mux.
+func (m *Rum) SetAutoCert(manager AutoCertManager) {
+	m.mut.Lock()
+	m.autoCert = manager
+	m.mut.Unlock()
+	if manager != nil {
+		m.Handle(ACMEChallengePath+":token", manager.HTTPHandler(nil))
+	}
+}
+
+// SetAutoTLSAddrs sets the TCP network addresses of RunAutoTLS, tlsAddr
+// serving the certificates and challengeAddr answering the HTTP-01
+// challenges. An empty address keeps the default, :443 and :80.
+// It must be called before serving.
+func (m *Rum) SetAutoTLSAddrs(tlsAddr, challengeAddr string) {
+	m.mut.Lock()
+	m.autoTLSAddr = tlsAddr
+	m.autoChallengeAddr = challengeAddr
+	m.mut.Unlock()
+}
+
+// RunAutoTLS listens on the TCP network address :443 serving certificates
+// obtained by the AutoCertManager for the domains, and on :80 to answer
+// the HTTP-01 challenges and redirect the other requests to HTTPS.
+// The addresses can be changed by SetAutoTLSAddrs.
+// If no domain is given, the manager's host policy decides.
+//
+// RunAutoTLS always returns a non-nil error. It returns the error of
+// listening on either address before serving any of them.
+func (m *Rum) RunAutoTLS(domains ...string) error {
+	config, err := m.autoTLSConfig(domains)
+	if err != nil {
+		return err
+	}
+	m.mut.Lock()
+	tlsAddr, challengeAddr := m.autoTLSAddr, m.autoChallengeAddr
+	m.mut.Unlock()
+	if tlsAddr == "" {
+		tlsAddr = ":443"
+	}
+	if challengeAddr == "" {
+		challengeAddr = ":80"
+	}
+	ln, err := net.Listen("tcp", tlsAddr)
+	if err != nil {
+		return err
+	}
+	challengeLn, err := net.Listen("tcp", challengeAddr)
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	challenge := New()
+	challenge.Handler = m.autoCert.HTTPHandler(nil)
+	challenge.SetErrorLog(m.errorLog)
+	m.mut.Lock()
+	m.servers = append(m.servers, challenge)
+	m.mut.Unlock()
+	go func() {
+		if err := challenge.Serve(challengeLn); !errors.Is(err, net.ErrClosed) {
+			m.logf("rum: ACME challenge server: %v", err)
+		}
+	}()
+	return m.serve(ln, config, 0)
+}
+
+func (m *Rum) autoTLSConfig(domains []string) (*tls.Config, error) {
+	m.mut.Lock()
+	manager := m.autoCert
+	m.mut.Unlock()
+	if manager == nil {
+		return nil, ErrAutoCertNil
+	}
+	config := m.tlsConfig()
+	domains = append([]string(nil), domains...)
+	for i := range domains {
+		domains[i] = strings.ToLower(domains[i])
+	}
+	config.GetCertificate = m.getCertificate(func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if len(domains) > 0 && !strSliceContains(domains, strings.ToLower(hello.ServerName)) {
+			return nil, ErrHostNotAllowed
+		}
+		return manager.GetCertificate(hello)
+	})
+	if !strSliceContains(config.NextProtos, "acme-tls/1") {
+		config.NextProtos = append(config.NextProtos, "acme-tls/1")
+	}
+	return config, nil
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testAutoCert struct {
+	cert tls.Certificate
+}
+
+func (c *testAutoCert) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return &c.cert, nil
+}
+
+func (c *testAutoCert) HTTPHandler(fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("challenge"))
+	})
+}
+
+func TestAutoTLS(t *testing.T) {
+	cert, err := tls.X509KeyPair(testCertPEM, testKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New()
+	if err := m.RunAutoTLS("localhost"); err != ErrAutoCertNil {
+		t.Error(err)
+	}
+	m.SetAutoCert(&testAutoCert{cert: cert})
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	})
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", ACMEChallengePath+"token", nil))
+	if w.Body.String() != "challenge" {
+		t.Error(w.Body.String())
+	}
+	domains := []string{"LocalHost"}
+	config, err := m.autoTLSConfig(domains)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if domains[0] != "LocalHost" {
+		t.Error(domains)
+	}
+	if !strSliceContains(config.NextProtos, "acme-tls/1") {
+		t.Error(config.NextProtos)
+	}
+	if _, err := config.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.com"}); err != ErrHostNotAllowed {
+		t.Error(err)
+	}
+	addr := ":8080"
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		m.serve(ln, config, 0)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	testHTTPTLS("GET", "https://localhost"+addr+"/", http.StatusOK, "Hello World", t)
+	m.Close()
+	<-done
+}
+
+func TestAutoTLSAddrs(t *testing.T) {
+	cert, err := tls.X509KeyPair(testCertPEM, testKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New()
+	m.SetAutoCert(&testAutoCert{cert: cert})
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	})
+	busy, err := net.Listen("tcp", ":8081")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetAutoTLSAddrs(":8080", ":8081")
+	if err := m.RunAutoTLS("localhost"); err == nil {
+		t.Error("expected an error when the challenge address is in use")
+	}
+	busy.Close()
+	done := make(chan struct{})
+	go func() {
+		m.RunAutoTLS("localhost")
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	testHTTPTLS("GET", "https://localhost:8080/", http.StatusOK, "Hello World", t)
+	testHTTP("GET", "http://localhost:8081/", http.StatusOK, "challenge", t)
+	m.Close()
+	<-done
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// shutdownPollInterval is the maximum interval of polling the active
+// requests by Shutdown.
+const shutdownPollInterval = time.Millisecond * 500
+
+// Go runs the fn in a background goroutine tracked by the DefaultServer.
+func Go(ctx context.Context, fn func(ctx context.Context)) {
+	DefaultServer.Go(ctx, fn)
+}
+
+// Go runs the fn in a background goroutine tracked by the Server, so the
+// fire-and-forget work of the handlers is not killed by a deploy: Shutdown
+// waits for the background goroutines to finish. The context of the fn
+// keeps the values of the ctx, usually the request context, but is not
+// canceled with it. It is canceled when the deadline of Shutdown expires.
+// A panic of the fn is recovered and logged to the error log. Once
+// Shutdown has finished waiting for the active requests, the fn is not
+// run any more, and the rejection is logged to the error log.
+func (m *Rum) Go(ctx context.Context, fn func(ctx context.Context)) {
+	m.backgroundOnce.Do(func() {
+		m.background, m.cancelBackground = context.WithCancel(context.Background())
+	})
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	m.taskMu.Lock()
+	if m.tasksClosed {
+		m.taskMu.Unlock()
+		m.logf("rum: background task rejected: server is shutting down")
+		return
+	}
+	atomic.AddInt64(&m.tasks, 1)
+	m.taskGroup.Add(1)
+	m.taskMu.Unlock()
+	go func() {
+		defer m.taskGroup.Done()
+		defer atomic.AddInt64(&m.tasks, -1)
+		defer func() {
+			if err := recover(); err != nil {
+				m.logf("rum: background task panic: %v", err)
+			}
+		}()
+		fn(&detachedContext{Context: m.background, values: ctx})
+	}()
+}
+
+// Tasks returns the number of the background goroutines started by Go
+// and not finished yet.
+func (m *Rum) Tasks() int64 {
+	return atomic.LoadInt64(&m.tasks)
+}
+
+// Shutdown gracefully shuts down the Server. It drains the Server, waits
+// for the active requests to finish, then stops accepting new background
+// goroutines and waits for those started by Go to finish. Finally it
+// closes the Server and runs the OnStop hooks. If the ctx is done first,
+// the contexts of the background goroutines are canceled, the Server is
+// closed, and the error of the ctx is returned. Otherwise the first error
+// of the OnStop hooks is returned.
+func (m *Rum) Shutdown(ctx context.Context) error {
+	m.Drain()
+	err := m.waitRequests(ctx)
+	m.taskMu.Lock()
+	m.tasksClosed = true
+	m.taskMu.Unlock()
+	if err == nil {
+		err = m.waitTasks(ctx)
+	}
+	if err != nil {
+		m.backgroundOnce.Do(func() {})
+		if m.cancelBackground != nil {
+			m.cancelBackground()
+		}
+	}
+	m.Close()
+	if stopErr := m.stop(ctx); err == nil {
+		err = stopErr
+	}
+	return err
+}
+
+// waitRequests waits for the active requests to finish, polling them with
+// an exponential backoff.
+func (m *Rum) waitRequests(ctx context.Context) error {
+	interval := time.Millisecond
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for atomic.LoadInt64(&m.active) > 0 {
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if interval *= 2; interval > shutdownPollInterval {
+			interval = shutdownPollInterval
+		}
+		timer.Reset(interval)
+	}
+	return nil
+}
+
+// waitTasks waits for the background goroutines to finish.
+func (m *Rum) waitTasks(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.taskGroup.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// detachedContext is canceled with the Context but has the values of the values.
+type detachedContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c *detachedContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
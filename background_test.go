@@ -0,0 +1,111 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type backgroundKey struct{}
+
+func TestGo(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	m.SetErrorLog(log.New(ioutil.Discard, "", 0))
+	var finished int32
+	var value interface{}
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), backgroundKey{}, "value")
+		m.Go(ctx, func(ctx context.Context) {
+			time.Sleep(time.Millisecond * 50)
+			if ctx.Err() == nil {
+				value = ctx.Value(backgroundKey{})
+				atomic.AddInt32(&finished, 1)
+			}
+		})
+		m.Go(ctx, func(ctx context.Context) {
+			panic("foo")
+		})
+		w.Write([]byte("Hello World"))
+	})
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	if m.Tasks() != 1 {
+		t.Error(m.Tasks())
+	}
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+	<-done
+	if atomic.LoadInt32(&finished) != 1 || value != "value" || m.Tasks() != 0 {
+		t.Error(finished, value, m.Tasks())
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	m := New()
+	canceled := make(chan struct{})
+	m.Go(nil, func(ctx context.Context) {
+		<-ctx.Done()
+		close(canceled)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Error(err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("not canceled")
+	}
+}
+
+func TestShutdownWaitsRequests(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	var finished int32
+	started := make(chan struct{})
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(time.Millisecond * 50)
+		m.Go(r.Context(), func(ctx context.Context) {
+			time.Sleep(time.Millisecond * 10)
+			atomic.AddInt32(&finished, 1)
+		})
+		w.Write([]byte("Hello World"))
+	})
+	go m.Run(addr)
+	time.Sleep(time.Millisecond * 10)
+	served := make(chan struct{})
+	go func() {
+		testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+		close(served)
+	}()
+	<-started
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+	<-served
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Error(finished)
+	}
+	m.Go(nil, func(ctx context.Context) {
+		t.Error("accepted after shutdown")
+	})
+	if m.Tasks() != 0 {
+		t.Error(m.Tasks())
+	}
+}
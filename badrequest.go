@@ -0,0 +1,48 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+var badRequestResponse = []byte("HTTP/1.1 400 Bad Request\r\nContent-Type: text/plain; charset=utf-8\r\nConnection: close\r\nContent-Length: 16\r\n\r\n400 Bad Request\n")
+
+// BadRequests returns the number of the malformed requests replied with
+// 400 Bad Request or 431 Request Header Fields Too Large.
+func (m *Rum) BadRequests() uint64 {
+	return atomic.LoadUint64(&m.badRequests)
+}
+
+// malformed reports whether the error of reading a request is caused by
+// a malformed request rather than by the connection.
+func malformed(err error) bool {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return false
+	}
+	var netErr net.Error
+	var recordErr tls.RecordHeaderError
+	return !errors.As(err, &netErr) && !errors.As(err, &recordErr)
+}
+
+// badRequest replies 400 Bad Request, or 431 Request Header Fields Too
+// Large for ErrHeaderFieldsTooLarge, before the connection is closed, if
+// the request failed to be read because it is malformed.
+func (m *Rum) badRequest(conn net.Conn, err error) {
+	if !malformed(err) {
+		return
+	}
+	atomic.AddUint64(&m.badRequests, 1)
+	conn.SetWriteDeadline(time.Now().Add(time.Second))
+	if err == ErrHeaderFieldsTooLarge {
+		conn.Write(headerFieldsTooLargeResponse)
+		return
+	}
+	conn.Write(badRequestResponse)
+}
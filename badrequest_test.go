@@ -0,0 +1,56 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBadRequest(t *testing.T) {
+	for _, fast := range []bool{false, true} {
+		addr := ":8080"
+		m := New()
+		m.SetFast(fast)
+		m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("Hello World"))
+		})
+		done := make(chan struct{})
+		go func() {
+			m.Run(addr)
+			close(done)
+		}()
+		time.Sleep(time.Millisecond * 10)
+		conn, err := net.Dial("tcp", "127.0.0.1"+addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write([]byte("GARBAGE\r\n\r\n"))
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusBadRequest || string(body) != "400 Bad Request\n" || !resp.Close {
+			t.Error(resp.StatusCode, string(body), resp.Close)
+		}
+		conn.Close()
+		conn, err = net.Dial("tcp", "127.0.0.1"+addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+		testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+		time.Sleep(time.Millisecond * 10)
+		if m.BadRequests() != 1 {
+			t.Error(m.BadRequests())
+		}
+		m.Close()
+		<-done
+	}
+}
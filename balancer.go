@@ -0,0 +1,162 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"hash/fnv"
+	"net/http"
+	"sync/atomic"
+)
+
+// Balancer selects the upstream of a proxied request.
+type Balancer interface {
+	// Pick returns one of the candidate upstreams, which are healthy and
+	// have not been tried for the request yet, or nil to fail the request.
+	Pick(r *http.Request, candidates []*Upstream) *Upstream
+}
+
+// RoundRobin returns a Balancer picking the upstreams in turn.
+func RoundRobin() Balancer {
+	return &roundRobin{}
+}
+
+type roundRobin struct {
+	next uint64
+}
+
+func (b *roundRobin) Pick(r *http.Request, candidates []*Upstream) *Upstream {
+	if len(candidates) == 0 {
+		return nil
+	}
+	n := atomic.AddUint64(&b.next, 1) - 1
+	return candidates[n%uint64(len(candidates))]
+}
+
+// LeastConn returns a Balancer picking the upstream with the fewest active
+// requests, in turn among the equal ones.
+func LeastConn() Balancer {
+	return &leastConn{}
+}
+
+type leastConn struct {
+	roundRobin
+}
+
+func (b *leastConn) Pick(r *http.Request, candidates []*Upstream) *Upstream {
+	if len(candidates) == 0 {
+		return nil
+	}
+	least := make([]*Upstream, 0, len(candidates))
+	for _, u := range candidates {
+		if len(least) > 0 && u.Conns() < least[0].Conns() {
+			least = least[:0]
+		}
+		if len(least) == 0 || u.Conns() == least[0].Conns() {
+			least = append(least, u)
+		}
+	}
+	return b.roundRobin.Pick(r, least)
+}
+
+// Binder is implemented by the Balancers binding the clients to the picked
+// upstreams, such as Sticky. The Proxy calls Bind before writing the
+// response of the upstream.
+type Binder interface {
+	Bind(w http.ResponseWriter, r *http.Request, u *Upstream)
+}
+
+// Sticky returns a Balancer binding the clients to the upstreams with the
+// named cookie, for the stateful upstreams. The clients without a valid
+// cookie, or whose upstream is not available, are balanced by the fallback.
+// If the fallback is nil, RoundRobin is used.
+func Sticky(cookie string, fallback Balancer) Balancer {
+	if fallback == nil {
+		fallback = RoundRobin()
+	}
+	return &sticky{cookie: cookie, fallback: fallback}
+}
+
+type sticky struct {
+	cookie   string
+	fallback Balancer
+}
+
+func (b *sticky) Pick(r *http.Request, candidates []*Upstream) *Upstream {
+	if c, err := r.Cookie(b.cookie); err == nil {
+		for _, u := range candidates {
+			if u.ID() == c.Value {
+				return u
+			}
+		}
+	}
+	return b.fallback.Pick(r, candidates)
+}
+
+func (b *sticky) Bind(w http.ResponseWriter, r *http.Request, u *Upstream) {
+	if c, err := r.Cookie(b.cookie); err == nil && c.Value == u.ID() {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: b.cookie, Value: u.ID(), Path: "/", HttpOnly: true})
+}
+
+// ConsistentHash returns a Balancer picking the upstreams by the rendezvous
+// hashing of the key of the requests, such as HashHeader("X-User") or
+// HashQuery("id"), so that the requests with the same key go to the same
+// upstream and only the keys of a removed upstream move. The requests with
+// an empty key are balanced by the fallback. If the fallback is nil,
+// RoundRobin is used.
+func ConsistentHash(key func(r *http.Request) string, fallback Balancer) Balancer {
+	if fallback == nil {
+		fallback = RoundRobin()
+	}
+	return &consistentHash{key: key, fallback: fallback}
+}
+
+// HashHeader returns a function returning the value of the named header.
+func HashHeader(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// HashQuery returns a function returning the named query value.
+func HashQuery(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.URL.Query().Get(name)
+	}
+}
+
+type consistentHash struct {
+	key      func(r *http.Request) string
+	fallback Balancer
+}
+
+func (b *consistentHash) Pick(r *http.Request, candidates []*Upstream) *Upstream {
+	key := b.key(r)
+	if key == "" {
+		return b.fallback.Pick(r, candidates)
+	}
+	var picked *Upstream
+	var max uint64
+	for _, u := range candidates {
+		h := fnv.New64a()
+		h.Write([]byte(u.ID()))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		if score := mix64(h.Sum64()); picked == nil || score > max {
+			picked, max = u, score
+		}
+	}
+	return picked
+}
+
+// mix64 is the finalizer of MurmurHash3, spreading the FNV hashes over all the bits.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
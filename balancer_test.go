@@ -0,0 +1,123 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRoundRobin(t *testing.T) {
+	upstreams := []*Upstream{{}, {}, {}}
+	b := RoundRobin()
+	r := httptest.NewRequest("GET", "/", nil)
+	for i := 0; i < 6; i++ {
+		if u := b.Pick(r, upstreams); u != upstreams[i%3] {
+			t.Error(i)
+		}
+	}
+	if b.Pick(r, nil) != nil {
+		t.Error("should be nil")
+	}
+}
+
+func TestLeastConn(t *testing.T) {
+	upstreams := []*Upstream{{}, {}, {}}
+	atomic.StoreInt64(&upstreams[0].conns, 2)
+	atomic.StoreInt64(&upstreams[1].conns, 1)
+	atomic.StoreInt64(&upstreams[2].conns, 1)
+	b := LeastConn()
+	r := httptest.NewRequest("GET", "/", nil)
+	picked := map[*Upstream]int{}
+	for i := 0; i < 4; i++ {
+		picked[b.Pick(r, upstreams)]++
+	}
+	if picked[upstreams[0]] != 0 || picked[upstreams[1]] != 2 || picked[upstreams[2]] != 2 {
+		t.Error(picked)
+	}
+	if b.Pick(r, nil) != nil {
+		t.Error("should be nil")
+	}
+}
+
+func TestSticky(t *testing.T) {
+	p, _ := NewProxy("http://127.0.0.1:9001", "http://127.0.0.1:9002", "http://127.0.0.1:9003")
+	upstreams := p.Upstreams()
+	b := Sticky("upstream", nil)
+	r := httptest.NewRequest("GET", "/", nil)
+	u := b.Pick(r, upstreams)
+	w := httptest.NewRecorder()
+	b.(Binder).Bind(w, r, u)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != u.ID() {
+		t.Fatal(cookies)
+	}
+	r.AddCookie(cookies[0])
+	for i := 0; i < 3; i++ {
+		if b.Pick(r, upstreams) != u {
+			t.Error(i)
+		}
+	}
+	w = httptest.NewRecorder()
+	b.(Binder).Bind(w, r, u)
+	if len(w.Result().Cookies()) != 0 {
+		t.Error(w.Result().Cookies())
+	}
+	var others []*Upstream
+	for _, o := range upstreams {
+		if o != u {
+			others = append(others, o)
+		}
+	}
+	if o := b.Pick(r, others); o == u || o == nil {
+		t.Error(o)
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	p, _ := NewProxy("http://127.0.0.1:9001", "http://127.0.0.1:9002", "http://127.0.0.1:9003")
+	upstreams := p.Upstreams()
+	b := ConsistentHash(HashHeader("X-User"), nil)
+	picked := map[string]*Upstream{}
+	counts := map[*Upstream]int{}
+	for i := 0; i < 300; i++ {
+		r := httptest.NewRequest("GET", "/", nil)
+		user := "user" + strconv.Itoa(i)
+		r.Header.Set("X-User", user)
+		u := b.Pick(r, upstreams)
+		if b.Pick(r, upstreams) != u {
+			t.Error(user)
+		}
+		picked[user] = u
+		counts[u]++
+	}
+	for _, u := range upstreams {
+		if counts[u] < 50 {
+			t.Error(counts)
+		}
+	}
+	moved := 0
+	for user, u := range picked {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-User", user)
+		if v := b.Pick(r, upstreams[:2]); v != u {
+			if u != upstreams[2] {
+				moved++
+			}
+		}
+	}
+	if moved != 0 {
+		t.Error(moved)
+	}
+	b = ConsistentHash(HashQuery("id"), nil)
+	r := httptest.NewRequest("GET", "/?id=1", nil)
+	if b.Pick(r, upstreams) != b.Pick(r, upstreams) {
+		t.Error("should be consistent")
+	}
+	if b.Pick(httptest.NewRequest("GET", "/", nil), nil) != nil {
+		t.Error("should be nil")
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+// Package benchmarks compares the rum server modes against net/http.
+//
+// Run the suite with
+//
+//	go test -bench=. -benchmem ./benchmarks
+//
+// and use cmd/rumbench to load test a running server.
+package benchmarks
+
+import (
+	"github.com/hslam/rum"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// LargeBodySize is the size of the body written by the large body route.
+const LargeBodySize = 64 * 1024
+
+var largeBody = []byte(strings.Repeat("a", LargeBodySize))
+
+// Mode is a server mode.
+type Mode int
+
+const (
+	// Std is the net/http server with http.ServeMux.
+	Std Mode = iota
+	// Normal is the rum server with the net/http request parser.
+	Normal
+	// Fast is the rum server with the fast request parser.
+	Fast
+	// Poll is the rum server with netpoll and the fast request parser.
+	Poll
+)
+
+// String returns the name of the mode.
+func (m Mode) String() string {
+	switch m {
+	case Std:
+		return "Std"
+	case Normal:
+		return "Normal"
+	case Fast:
+		return "Fast"
+	case Poll:
+		return "Poll"
+	}
+	return "Unknown"
+}
+
+// Modes lists all server modes.
+var Modes = []Mode{Std, Normal, Fast, Poll}
+
+// Server is a running benchmark server.
+type Server struct {
+	// Addr is the listening address.
+	Addr  string
+	close func() error
+}
+
+// Close closes the server.
+func (s *Server) Close() error {
+	return s.close()
+}
+
+// NewServer starts a server in the mode with the routes
+// "/", "/hello/:name" and "/large".
+func NewServer(mode Mode) (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{Addr: ln.Addr().String()}
+	if mode == Std {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/", hello)
+		mux.HandleFunc("/hello/", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("Hello " + strings.TrimPrefix(r.URL.Path, "/hello/")))
+		})
+		mux.HandleFunc("/large", large)
+		server := &http.Server{Handler: mux}
+		go server.Serve(ln)
+		s.close = server.Close
+		return s, nil
+	}
+	m := rum.New()
+	m.SetFast(mode == Fast || mode == Poll)
+	m.SetPoll(mode == Poll)
+	m.HandleFunc("/", hello)
+	m.HandleFunc("/hello/:name", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello " + m.Params(r)["name"]))
+	})
+	m.HandleFunc("/large", large)
+	go m.Serve(ln)
+	s.close = m.Close
+	return s, nil
+}
+
+func hello(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("Hello World"))
+}
+
+func large(w http.ResponseWriter, r *http.Request) {
+	w.Write(largeBody)
+}
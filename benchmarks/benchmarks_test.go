@@ -0,0 +1,87 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package benchmarks
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func benchmarkMode(b *testing.B, mode Mode, path string, size int) {
+	s, err := NewServer(mode)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer s.Close()
+	client := &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: 64}}
+	defer client.CloseIdleConnections()
+	url := "http://" + s.Addr + path
+	b.SetBytes(int64(size))
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			resp, err := client.Get(url)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			n, _ := io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+			if n != int64(size) {
+				b.Errorf("read %d bytes, expected %d", n, size)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkStatic(b *testing.B) {
+	for _, mode := range Modes {
+		b.Run(mode.String(), func(b *testing.B) {
+			benchmarkMode(b, mode, "/", len("Hello World"))
+		})
+	}
+}
+
+func BenchmarkParams(b *testing.B) {
+	for _, mode := range Modes {
+		b.Run(mode.String(), func(b *testing.B) {
+			benchmarkMode(b, mode, "/hello/rum", len("Hello rum"))
+		})
+	}
+}
+
+func BenchmarkLargeBody(b *testing.B) {
+	for _, mode := range Modes {
+		b.Run(mode.String(), func(b *testing.B) {
+			benchmarkMode(b, mode, "/large", LargeBodySize)
+		})
+	}
+}
+
+func TestModes(t *testing.T) {
+	for _, mode := range Modes {
+		s, err := NewServer(mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.Get("http://" + s.Addr + "/hello/rum")
+		if err != nil {
+			t.Error(mode, err)
+		} else {
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != "Hello rum" {
+				t.Error(mode, string(body))
+			}
+		}
+		s.Close()
+	}
+	if Mode(-1).String() != "Unknown" {
+		t.Error(Mode(-1).String())
+	}
+}
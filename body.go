@@ -0,0 +1,150 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+)
+
+// maxBodyDrain is the maximum number of the remaining body bytes discarded
+// to keep the connection usable after the body is too large.
+const maxBodyDrain = 256 << 10
+
+// ErrBodyTooLarge is returned by the request body reads when the body
+// exceeds the maximum body size.
+var ErrBodyTooLarge = errors.New("Request Body Too Large")
+
+// SetMaxBodySize sets the maximum size in bytes of the request bodies.
+// Zero means no limit.
+//
+// A request with a larger Content-Length is replied by the body too large
+// handler without calling the handler. Otherwise the body reads return
+// ErrBodyTooLarge once the limit is exceeded, and the body too large handler
+// replies if the handler has not written the response. The connection is
+// kept alive only if the remaining body can be drained.
+func (m *Rum) SetMaxBodySize(n int64) {
+	m.maxBodySize = n
+}
+
+// SetBodyTooLarge sets the handler replying to the requests whose bodies
+// exceed the maximum body size. If nil, the BodyTooLarge is used.
+func (m *Rum) SetBodyTooLarge(handler ErrorHandler) {
+	m.bodyTooLarge = handler
+}
+
+// BodyTooLarge replies with a 413 status code and a problem details JSON body.
+func BodyTooLarge(w http.ResponseWriter, r *http.Request, err error) {
+	ProblemJSON(w, http.StatusRequestEntityTooLarge, "", err.Error())
+}
+
+type maxBodyReader struct {
+	io.ReadCloser
+	remaining int64
+	limit     int64
+	exceeded  bool
+}
+
+func (r *maxBodyReader) Read(p []byte) (n int, err error) {
+	if r.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err = r.ReadCloser.Read(p)
+	if int64(n) <= r.remaining {
+		r.remaining -= int64(n)
+		return n, err
+	}
+	n = int(r.remaining)
+	r.remaining = 0
+	r.exceeded = true
+	return n, ErrBodyTooLarge
+}
+
+// drain discards the remaining body and reports whether the body is fully read.
+func (r *maxBodyReader) drain() bool {
+	_, err := io.CopyN(ioutil.Discard, r.ReadCloser, maxBodyDrain+1)
+	return err == io.EOF
+}
+
+type bodyLimitResponseWriter struct {
+	forwarder
+	wroteHeader bool
+}
+
+func (w *bodyLimitResponseWriter) WriteHeader(code int) {
+	w.wroteHeader = w.wroteHeader || !informational(code)
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *bodyLimitResponseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements the http.Flusher interface.
+func (w *bodyLimitResponseWriter) Flush() {
+	w.wroteHeader = true
+	w.forwarder.Flush()
+}
+
+// FlushError implements the error-returning Flush.
+func (w *bodyLimitResponseWriter) FlushError() error {
+	w.wroteHeader = true
+	return Flush(w.ResponseWriter)
+}
+
+// Hijack implements the http.Hijacker interface.
+func (w *bodyLimitResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.wroteHeader = true
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// limitBody limits the request body. The body is marked as exceeded
+// without being read if the Content-Length is larger than the limit.
+func (m *Rum) limitBody(w http.ResponseWriter, req *http.Request) (*maxBodyReader, *bodyLimitResponseWriter) {
+	if m.maxBodySize <= 0 || req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body := &maxBodyReader{ReadCloser: req.Body, remaining: m.maxBodySize, limit: m.maxBodySize}
+	if req.ContentLength > m.maxBodySize {
+		body.exceeded = true
+		return body, nil
+	}
+	req.Body = body
+	return body, &bodyLimitResponseWriter{forwarder: forwarder{w}}
+}
+
+func (m *Rum) replyBodyTooLarge(w http.ResponseWriter, req *http.Request, body *maxBodyReader) {
+	handler := m.bodyTooLarge
+	if handler == nil {
+		handler = BodyTooLarge
+	}
+	handler(w, req, fmt.Errorf("%w : limit %d bytes", ErrBodyTooLarge, body.limit))
+}
+
+// finishBody replies to the request whose body is too large if the handler
+// has not written the response, and reports whether the connection is still usable.
+func (m *Rum) finishBody(w http.ResponseWriter, req *http.Request, body *maxBodyReader, lw *bodyLimitResponseWriter) bool {
+	if !body.exceeded {
+		return true
+	}
+	if lw != nil && !lw.wroteHeader {
+		m.replyBodyTooLarge(w, req, body)
+	}
+	if req.ContentLength > maxBodyDrain {
+		return false
+	}
+	return body.drain()
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxBodySize(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	m.SetMaxBodySize(10)
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	}).All()
+	m.HandleFunc("/read", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != ErrBodyTooLarge {
+			t.Error(err)
+		}
+	}).POST()
+	m.HandleFunc("/reply", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}).POST()
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(conn)
+	body := strings.Repeat("a", 20)
+	conn.Write([]byte("POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 20\r\n\r\n" + body))
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Error(resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/problem+json" {
+		t.Error(ct)
+	}
+	var problem struct {
+		Status int    `json:"status"`
+		Detail string `json:"detail"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+		t.Error(err)
+	} else if problem.Status != http.StatusRequestEntityTooLarge || !strings.Contains(problem.Detail, "limit 10 bytes") {
+		t.Error(problem)
+	}
+	resp.Body.Close()
+	chunked := "POST /read HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n14\r\n" + body + "\r\n0\r\n\r\n"
+	conn.Write([]byte(chunked))
+	if resp, err = http.ReadResponse(reader, nil); err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Error(resp.StatusCode)
+	}
+	conn.Write([]byte(strings.Replace(chunked, "/read", "/reply", 1)))
+	if resp, err = http.ReadResponse(reader, nil); err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Error(resp.StatusCode)
+	}
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	if resp, err = http.ReadResponse(reader, nil); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || string(b) != "Hello World" {
+		t.Error(resp.StatusCode, string(b))
+	}
+	resp.Body.Close()
+	conn.Write([]byte("POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 1048576\r\n\r\n"))
+	if resp, err = http.ReadResponse(reader, nil); err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Error(resp.StatusCode)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Error(err)
+	}
+	conn.Close()
+	m.Close()
+	<-done
+}
+
+func TestSetBodyTooLarge(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	m.SetMaxBodySize(1)
+	m.SetBodyTooLarge(func(w http.ResponseWriter, r *http.Request, err error) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	})
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	}).All()
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Post("http://"+addr+"/", "text/plain", strings.NewReader("Hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge || !strings.HasPrefix(string(b), ErrBodyTooLarge.Error()) {
+		t.Error(resp.StatusCode, string(b))
+	}
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	m.Close()
+	<-done
+}
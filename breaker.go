@@ -0,0 +1,229 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all of the requests through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all of the requests with the fallback handler.
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe requests through.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker stops calling a failing handler, typically a proxy to a
+// downstream service, and replies with a fallback handler until the handler
+// recovers. A request fails if it replies with a 5xx status code, panics or
+// takes longer than the Latency. The zero value is ready to use.
+type CircuitBreaker struct {
+	// ErrorRate is the ratio of the failed requests in a window above which
+	// the circuit opens. Zero means 0.5.
+	ErrorRate float64
+	// Latency is the duration above which a request fails. Zero means no limit.
+	Latency time.Duration
+	// MinRequests is the number of requests in a window below which the
+	// circuit stays closed. Zero means 10.
+	MinRequests int
+	// Window is the duration of the counting windows. Zero means 10 seconds.
+	Window time.Duration
+	// OpenTimeout is the duration the circuit stays open before probing
+	// the handler. Zero means 5 seconds.
+	OpenTimeout time.Duration
+	// Probes is the number of concurrent probe requests in the half-open
+	// state. Zero means 1.
+	Probes int
+	// Fallback replies to the rejected requests. If nil, the requests are
+	// replied with a 503 status code and the Retry-After header.
+	Fallback http.Handler
+
+	mu          sync.Mutex
+	state       CircuitState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+}
+
+// State returns the state of the circuit breaker.
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == CircuitOpen && time.Since(cb.openedAt) >= cb.openTimeout() {
+		return CircuitHalfOpen
+	}
+	return cb.state
+}
+
+// Handler returns a handler that calls the handler through the circuit breaker.
+func (cb *CircuitBreaker) Handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bw, ok := cb.begin(w, r)
+		if !ok {
+			return
+		}
+		defer bw.end()
+		handler.ServeHTTP(expose(bw, w), r)
+	})
+}
+
+// CircuitBreaker sets the circuit breaker of the entry.
+func (entry *Entry) CircuitBreaker(cb *CircuitBreaker) *Entry {
+	entry.breaker = cb
+	return entry
+}
+
+func (cb *CircuitBreaker) openTimeout() time.Duration {
+	if cb.OpenTimeout > 0 {
+		return cb.OpenTimeout
+	}
+	return time.Second * 5
+}
+
+// begin reports whether the request is let through, or replies with the fallback handler.
+func (cb *CircuitBreaker) begin(w http.ResponseWriter, r *http.Request) (*breakerResponseWriter, bool) {
+	now := time.Now()
+	cb.mu.Lock()
+	probe := false
+	switch cb.state {
+	case CircuitOpen:
+		if now.Sub(cb.openedAt) < cb.openTimeout() {
+			retryAfter := cb.openTimeout() - now.Sub(cb.openedAt)
+			cb.mu.Unlock()
+			cb.reject(w, r, retryAfter)
+			return nil, false
+		}
+		cb.state = CircuitHalfOpen
+		cb.probes = 0
+		fallthrough
+	case CircuitHalfOpen:
+		probes := cb.Probes
+		if probes < 1 {
+			probes = 1
+		}
+		if cb.probes >= probes {
+			cb.mu.Unlock()
+			cb.reject(w, r, cb.openTimeout())
+			return nil, false
+		}
+		cb.probes++
+		probe = true
+	}
+	cb.mu.Unlock()
+	return &breakerResponseWriter{forwarder: forwarder{w}, cb: cb, start: now, probe: probe}, true
+}
+
+func (cb *CircuitBreaker) reject(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	if cb.Fallback != nil {
+		cb.Fallback.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	http.Error(w, "503 Service Unavailable", http.StatusServiceUnavailable)
+}
+
+// done records the result of a request let through.
+func (cb *CircuitBreaker) done(probe, failed bool, now time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if probe {
+		if cb.state != CircuitHalfOpen {
+			return
+		}
+		if failed {
+			cb.state = CircuitOpen
+			cb.openedAt = now
+		} else {
+			cb.state = CircuitClosed
+			cb.windowStart = now
+			cb.requests, cb.failures = 0, 0
+		}
+		return
+	}
+	if cb.state != CircuitClosed {
+		return
+	}
+	window := cb.Window
+	if window <= 0 {
+		window = time.Second * 10
+	}
+	if now.Sub(cb.windowStart) >= window {
+		cb.windowStart = now
+		cb.requests, cb.failures = 0, 0
+	}
+	cb.requests++
+	if failed {
+		cb.failures++
+	}
+	minRequests := cb.MinRequests
+	if minRequests <= 0 {
+		minRequests = 10
+	}
+	errorRate := cb.ErrorRate
+	if errorRate <= 0 {
+		errorRate = 0.5
+	}
+	if cb.requests >= minRequests && float64(cb.failures) >= errorRate*float64(cb.requests) {
+		cb.state = CircuitOpen
+		cb.openedAt = now
+	}
+}
+
+// breakerResponseWriter records the status code of a request let through.
+type breakerResponseWriter struct {
+	forwarder
+	cb    *CircuitBreaker
+	start time.Time
+	probe bool
+	code  int
+}
+
+func (w *breakerResponseWriter) WriteHeader(code int) {
+	if w.code == 0 && !informational(code) {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *breakerResponseWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// end records the result of the request. It must be deferred directly,
+// so that a panic of the handler is recorded as a failure.
+func (w *breakerResponseWriter) end() {
+	now := time.Now()
+	if e := recover(); e != nil {
+		w.cb.done(w.probe, true, now)
+		panic(e)
+	}
+	failed := w.code >= 500 || w.cb.Latency > 0 && now.Sub(w.start) > w.cb.Latency
+	w.cb.done(w.probe, failed, now)
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	m := NewMux()
+	failing := true
+	cb := &CircuitBreaker{MinRequests: 2, OpenTimeout: time.Millisecond * 50}
+	m.HandleFunc("/downstream", func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			http.Error(w, "502 Bad Gateway", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("Hello World"))
+	}).GET().CircuitBreaker(cb)
+	test := func(status int) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", "/downstream", nil))
+		if w.Code != status {
+			t.Error(w.Code, status)
+		}
+		return w
+	}
+	test(http.StatusBadGateway)
+	if cb.State() != CircuitClosed {
+		t.Error(cb.State())
+	}
+	test(http.StatusBadGateway)
+	if cb.State() != CircuitOpen {
+		t.Error(cb.State())
+	}
+	if w := test(http.StatusServiceUnavailable); w.Header().Get("Retry-After") != "1" {
+		t.Error(w.Header().Get("Retry-After"))
+	}
+	time.Sleep(time.Millisecond * 60)
+	if cb.State() != CircuitHalfOpen {
+		t.Error(cb.State())
+	}
+	test(http.StatusBadGateway)
+	if cb.State() != CircuitOpen {
+		t.Error(cb.State())
+	}
+	failing = false
+	time.Sleep(time.Millisecond * 60)
+	test(http.StatusOK)
+	if cb.State() != CircuitClosed {
+		t.Error(cb.State())
+	}
+	test(http.StatusOK)
+}
+
+func TestCircuitBreakerHandler(t *testing.T) {
+	cb := &CircuitBreaker{
+		MinRequests: 1,
+		Latency:     time.Millisecond * 10,
+		Fallback: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("fallback"))
+		}),
+	}
+	handler := cb.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/panic" {
+			panic("panic test")
+		}
+		time.Sleep(time.Millisecond * 20)
+		w.Write([]byte("slow"))
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Body.String() != "slow" {
+		t.Error(w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Body.String() != "fallback" || cb.State().String() != "open" {
+		t.Error(w.Body.String(), cb.State())
+	}
+	cb = &CircuitBreaker{MinRequests: 1}
+	handler = cb.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("panic test")
+	}))
+	func() {
+		defer func() {
+			if e := recover(); e == nil {
+				t.Error("should panic")
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+	if cb.State() != CircuitOpen {
+		t.Error(cb.State())
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrBodyBudget is returned by the request body reads when the in-flight
+// request body bytes exceed the budget.
+var ErrBodyBudget = errors.New("Body Budget Exceeded")
+
+// SetBodyBudget sets the budget of the total in-flight request body bytes
+// of the Server, so that many simultaneous large uploads can not exhaust
+// the memory. Zero means no budget.
+//
+// A request whose Content-Length does not fit in the remaining budget is
+// replied with 503 Service Unavailable and a Retry-After header without
+// calling the handler, and the connection is closed. The bytes of a body
+// without a Content-Length are counted as they are read, and the reads
+// return ErrBodyBudget once the budget is exceeded. The bytes are released
+// when the request is finished.
+func (m *Rum) SetBodyBudget(n int64) {
+	m.bodyBudget = n
+}
+
+// BodyBytes returns the in-flight request body bytes counted by the budget.
+func (m *Rum) BodyBytes() int64 {
+	return atomic.LoadInt64(&m.bodyBytes)
+}
+
+// reserveBodyBytes reserves n bytes of the budget and reports whether
+// they fit.
+func (m *Rum) reserveBodyBytes(n int64) bool {
+	for {
+		bytes := atomic.LoadInt64(&m.bodyBytes)
+		if bytes+n > m.bodyBudget {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&m.bodyBytes, bytes, bytes+n) {
+			return true
+		}
+	}
+}
+
+type budgetReader struct {
+	io.ReadCloser
+	m        *Rum
+	reserved int64
+	exceeded bool
+}
+
+func (r *budgetReader) Read(p []byte) (n int, err error) {
+	if r.exceeded {
+		return 0, ErrBodyBudget
+	}
+	if r.ReadCloser == nil {
+		return 0, io.EOF
+	}
+	n, err = r.ReadCloser.Read(p)
+	if n > 0 {
+		if !r.m.reserveBodyBytes(int64(n)) {
+			r.exceeded = true
+			return 0, ErrBodyBudget
+		}
+		r.reserved += int64(n)
+	}
+	return n, err
+}
+
+// release releases the reserved bytes.
+func (r *budgetReader) release() {
+	atomic.AddInt64(&r.m.bodyBytes, -r.reserved)
+	r.reserved = 0
+}
+
+// budgetBody reserves the budget of the request body. The returned reader
+// is exceeded without reading the body if the Content-Length does not fit.
+func (m *Rum) budgetBody(req *http.Request, body *maxBodyReader) *budgetReader {
+	if m.bodyBudget <= 0 || req.Body == nil || req.Body == http.NoBody || body != nil && body.exceeded {
+		return nil
+	}
+	r := &budgetReader{m: m}
+	if req.ContentLength > 0 {
+		if !m.reserveBodyBytes(req.ContentLength) {
+			r.exceeded = true
+			return r
+		}
+		r.reserved = req.ContentLength
+		return r
+	}
+	r.ReadCloser = req.Body
+	req.Body = r
+	return r
+}
+
+// replyBodyBudget replies to the request whose body does not fit in the budget.
+func replyBodyBudget(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, "503 Service Unavailable", http.StatusServiceUnavailable)
+}
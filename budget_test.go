@@ -0,0 +1,87 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBodyBudget(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	m.SetBodyBudget(10)
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if n := m.BodyBytes(); n != 5 {
+			t.Error(n)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}).POST()
+	m.HandleFunc("/chunked", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != ErrBodyBudget {
+			t.Error(err)
+		}
+	}).POST()
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	if resp, err := client.Post("http://"+addr+"/", "text/plain", strings.NewReader("hello")); err != nil {
+		t.Error(err)
+	} else {
+		if body, _ := ioutil.ReadAll(resp.Body); string(body) != "hello" {
+			t.Error(string(body))
+		}
+		resp.Body.Close()
+	}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(conn)
+	body := strings.Repeat("a", 20)
+	conn.Write([]byte("POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 20\r\n\r\n" + body))
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Error(resp.StatusCode)
+	}
+	if retry := resp.Header.Get("Retry-After"); retry != "1" {
+		t.Error(retry)
+	}
+	if !resp.Close {
+		t.Error("expected the connection to be closed")
+	}
+	conn.Close()
+	if conn, err = net.Dial("tcp", addr); err != nil {
+		t.Fatal(err)
+	}
+	reader = bufio.NewReader(conn)
+	conn.Write([]byte("POST /chunked HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n14\r\n" + body + "\r\n0\r\n\r\n"))
+	if resp, err = http.ReadResponse(reader, nil); err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	conn.Close()
+	if n := m.BodyBytes(); n != 0 {
+		t.Error(n)
+	}
+	m.Close()
+	<-done
+}
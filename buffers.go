@@ -0,0 +1,156 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// bufferTiers are the sizes of the pooled buffers of the connections.
+var bufferTiers = [...]int{512, 4 << 10, 64 << 10}
+
+var (
+	readerPools [len(bufferTiers)]sync.Pool
+	writerPools [len(bufferTiers)]sync.Pool
+)
+
+// SetAdaptiveBuffers enables the adaptive sizing of the buffers of the
+// connections. The buffers are taken from the pools of 512 B, 4 KB and
+// 64 KB, and are resized between the requests to fit the typical sizes
+// of the requests and the responses of each connection, which reduces the
+// memory of the many idle keep-alive connections while keeping the
+// throughput of the large transfers. The buffers of the connections
+// in the fast mode are not smaller than 4 KB.
+func (m *Rum) SetAdaptiveBuffers(enabled bool) {
+	m.adaptiveBuffers = enabled
+}
+
+// tier returns the smallest tier not smaller than the size.
+func tier(size float64, min int) int {
+	for i := min; i < len(bufferTiers); i++ {
+		if size <= float64(bufferTiers[i]) {
+			return i
+		}
+	}
+	return len(bufferTiers) - 1
+}
+
+func getReader(t int, conn net.Conn) *bufio.Reader {
+	if v := readerPools[t].Get(); v != nil {
+		r := v.(*bufio.Reader)
+		r.Reset(conn)
+		return r
+	}
+	return bufio.NewReaderSize(conn, bufferTiers[t])
+}
+
+func getWriter(t int, conn net.Conn) *bufio.Writer {
+	if v := writerPools[t].Get(); v != nil {
+		w := v.(*bufio.Writer)
+		w.Reset(conn)
+		return w
+	}
+	return bufio.NewWriterSize(conn, bufferTiers[t])
+}
+
+func putReader(t int, r *bufio.Reader) {
+	r.Reset(nil)
+	readerPools[t].Put(r)
+}
+
+func putWriter(t int, w *bufio.Writer) {
+	w.Reset(nil)
+	writerPools[t].Put(w)
+}
+
+// countConn counts the bytes read from and written to the connection.
+type countConn struct {
+	net.Conn
+	read    int64
+	written int64
+}
+
+func (c *countConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	atomic.AddInt64(&c.read, int64(n))
+	return n, err
+}
+
+func (c *countConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	atomic.AddInt64(&c.written, int64(n))
+	return n, err
+}
+
+// connBuffers sizes the buffers of a connection by the moving averages
+// of the bytes read and written per request.
+type connBuffers struct {
+	conn        net.Conn
+	counter     *countConn
+	rw          *bufio.ReadWriter
+	min         int
+	readerTier  int
+	writerTier  int
+	read        float64
+	written     float64
+	lastRead    int64
+	lastWritten int64
+	hijacked    bool
+}
+
+// newConnBuffers returns the buffers of the conn, starting at 4 KB.
+// The counter counts the bytes of the conn.
+func newConnBuffers(conn net.Conn, counter *countConn, fast bool) *connBuffers {
+	b := &connBuffers{conn: conn, counter: counter, readerTier: 1, writerTier: 1}
+	if fast {
+		b.min = 1
+	}
+	b.read, b.written = float64(bufferTiers[1]), float64(bufferTiers[1])
+	b.rw = bufio.NewReadWriter(getReader(b.readerTier, b.conn), getWriter(b.writerTier, b.conn))
+	return b
+}
+
+// adapt resizes the buffers after a request when they hold no data. The
+// buffers of a hijacked connection are left to the handler.
+func (b *connBuffers) adapt(hijacked bool) {
+	if b.hijacked = b.hijacked || hijacked; b.hijacked {
+		return
+	}
+	read, written := atomic.LoadInt64(&b.counter.read), atomic.LoadInt64(&b.counter.written)
+	b.read = b.read*0.75 + float64(read-b.lastRead)*0.25
+	b.written = b.written*0.75 + float64(written-b.lastWritten)*0.25
+	b.lastRead, b.lastWritten = read, written
+	if t := tier(b.read, b.min); t != b.readerTier && b.rw.Reader.Buffered() == 0 {
+		putReader(b.readerTier, b.rw.Reader)
+		b.rw.Reader, b.readerTier = getReader(t, b.conn), t
+	}
+	if t := tier(b.written, b.min); t != b.writerTier && b.rw.Writer.Buffered() == 0 {
+		putWriter(b.writerTier, b.rw.Writer)
+		b.rw.Writer, b.writerTier = getWriter(t, b.conn), t
+	}
+}
+
+// free returns the buffers to the pools.
+func (b *connBuffers) free() {
+	if b.hijacked {
+		return
+	}
+	putReader(b.readerTier, b.rw.Reader)
+	putWriter(b.writerTier, b.rw.Writer)
+}
+
+// newConn tracks the conn and returns its buffers, which are adaptive if enabled.
+func (m *Rum) newConn(conn net.Conn) (net.Conn, *bufio.ReadWriter, *connBuffers) {
+	if !m.adaptiveBuffers {
+		conn = m.trackConn(conn)
+		return conn, bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)), nil
+	}
+	counter := &countConn{Conn: conn}
+	conn = m.trackConn(counter)
+	buffers := newConnBuffers(conn, counter, m.fast)
+	return conn, buffers.rw, buffers
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConnBuffers(t *testing.T) {
+	conn, _ := net.Pipe()
+	counter := &countConn{Conn: conn}
+	b := newConnBuffers(counter, counter, false)
+	if b.rw.Reader.Size() != 4<<10 || b.rw.Writer.Size() != 4<<10 {
+		t.Error(b.rw.Reader.Size(), b.rw.Writer.Size())
+	}
+	for i := 0; i < 16; i++ {
+		counter.read += 100
+		counter.written += 200
+		b.adapt(false)
+	}
+	if b.rw.Reader.Size() != 512 || b.rw.Writer.Size() != 512 {
+		t.Error(b.rw.Reader.Size(), b.rw.Writer.Size())
+	}
+	for i := 0; i < 16; i++ {
+		counter.read += 100
+		counter.written += 64 << 10
+		b.adapt(false)
+	}
+	if b.rw.Reader.Size() != 512 || b.rw.Writer.Size() != 64<<10 {
+		t.Error(b.rw.Reader.Size(), b.rw.Writer.Size())
+	}
+	b.adapt(true)
+	counter.written = 0
+	b.adapt(false)
+	if b.rw.Writer.Size() != 64<<10 {
+		t.Error("should not resize the buffers of a hijacked connection")
+	}
+	b.free()
+	fast := newConnBuffers(counter, counter, true)
+	for i := 0; i < 16; i++ {
+		fast.adapt(false)
+	}
+	if fast.rw.Reader.Size() != 4<<10 {
+		t.Error(fast.rw.Reader.Size())
+	}
+	fast.free()
+}
+
+func TestAdaptiveBuffers(t *testing.T) {
+	large := strings.Repeat("a", 100<<10)
+	for _, fast := range []bool{false, true} {
+		addr := ":8080"
+		m := New()
+		m.SetFast(fast)
+		m.SetAdaptiveBuffers(true)
+		m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("Hello World"))
+		})
+		m.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(large))
+		})
+		done := make(chan struct{})
+		go func() {
+			m.Run(addr)
+			close(done)
+		}()
+		time.Sleep(time.Millisecond * 10)
+		client := &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: 1}}
+		for i := 0; i < 16; i++ {
+			path, body := "/", "Hello World"
+			if i%4 == 3 {
+				path, body = "/large", large
+			}
+			resp, err := client.Get("http://" + addr + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || string(result) != body {
+				t.Error(path, resp.StatusCode, len(result))
+			}
+		}
+		client.CloseIdleConnections()
+		m.Close()
+		<-done
+	}
+}
@@ -0,0 +1,435 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCacheMiss is the error returned by a CacheStore when the key is not found.
+var ErrCacheMiss = errors.New("Cache Miss")
+
+// CacheStore stores the responses of a ResponseCache out of the process,
+// so that the replicas of a service share their caches.
+type CacheStore interface {
+	// Get returns the value of the key, or ErrCacheMiss.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores the value of the key for the ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete deletes the key.
+	Delete(ctx context.Context, key string) error
+}
+
+const (
+	defaultCacheMaxSize      = 64 << 20
+	defaultCacheMaxEntrySize = 1 << 20
+)
+
+// ResponseCache caches the responses of the GET requests in memory, by
+// the freshness lifetime of their Cache-Control. A stale response is
+// served within the stale-while-revalidate window while being refreshed
+// in the background, and within the stale-if-error window when the
+// handler fails with a 500, 502, 503 or 504 status code.
+//
+//	c := &rum.ResponseCache{TTL: time.Minute, StaleWhileRevalidate: time.Minute, StaleIfError: time.Hour}
+//	m.Handle("/articles/:id", c.Handler(articles))
+//
+// The responses with Cache-Control no-store, no-cache or private, with a
+// Set-Cookie or with Vary: * are not cached. The cached responses carry
+// an Age and an X-Cache header of HIT, STALE or MISS. The concurrent
+// misses of a key are filled by a single call of the handler.
+type ResponseCache struct {
+	// TTL is the freshness lifetime of the responses without a max-age or
+	// an s-maxage. If zero, these responses are not cached.
+	TTL time.Duration
+	// StaleWhileRevalidate is the duration after the freshness lifetime
+	// during which a stale response is served while it is refreshed in
+	// the background, unless overridden by the stale-while-revalidate of
+	// the response.
+	StaleWhileRevalidate time.Duration
+	// StaleIfError is the duration after the freshness lifetime during
+	// which a stale response is served if the handler fails, unless
+	// overridden by the stale-if-error of the response.
+	StaleIfError time.Duration
+	// MaxSize is the maximum size in bytes of the cached bodies. If zero,
+	// 64 MB is used.
+	MaxSize int64
+	// MaxEntrySize is the maximum size in bytes of a cached body. If zero,
+	// 1 MB is used.
+	MaxEntrySize int64
+	// Key returns the key of a request, or an empty string if it is not
+	// cached. If nil, the RequestKey stored by a CacheKey or a RequestHash,
+	// or else the CoalesceKey, is used. The requests with an Authorization
+	// are never cached.
+	Key func(r *http.Request) string
+	// Store stores the responses instead of the memory, such as a
+	// RedisStore shared by the replicas. If nil, the responses are cached
+	// in memory up to the MaxSize.
+	Store CacheStore
+
+	mu         sync.Mutex
+	entries    sync.Map
+	lru        *list.List
+	size       int64
+	flights    sync.Map
+	refreshing sync.Map
+	now        func() time.Time
+}
+
+// cachedResponse is a response cached by a ResponseCache.
+type cachedResponse struct {
+	key                  string
+	code                 int
+	header               http.Header
+	body                 []byte
+	vary                 map[string]string
+	stored               time.Time
+	lifetime             time.Duration
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+	element              *list.Element
+}
+
+// cacheFlight is a fill of a key by the handler.
+type cacheFlight struct {
+	wg     sync.WaitGroup
+	cached *cachedResponse
+}
+
+// Handler returns a handler caching the responses of the handler.
+func (c *ResponseCache) Handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := c.key(r)
+		if key == "" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		cached := c.load(r.Context(), key)
+		if cached != nil && !cached.matches(r) {
+			cached = nil
+		}
+		var age time.Duration
+		if cached != nil {
+			age = c.clock().Sub(cached.stored)
+			if !requestNoCache(r) {
+				switch {
+				case age < cached.lifetime:
+					c.serve(w, r, cached, age, "HIT")
+					return
+				case age < cached.lifetime+cached.staleWhileRevalidate:
+					c.refresh(handler, r, key)
+					c.serve(w, r, cached, age, "STALE")
+					return
+				}
+			}
+		}
+		if cached == nil && r.Method == "GET" {
+			f := &cacheFlight{}
+			f.wg.Add(1)
+			if v, loaded := c.flights.LoadOrStore(key, f); loaded {
+				f = v.(*cacheFlight)
+				f.wg.Wait()
+				if f.cached != nil && f.cached.matches(r) {
+					c.serve(w, r, f.cached, 0, "HIT")
+					return
+				}
+			} else {
+				defer func() {
+					c.flights.Delete(key)
+					f.wg.Done()
+				}()
+				var rec *recordResponseWriter
+				rec, f.cached = c.fetch(handler, r, key)
+				c.write(w, rec)
+				return
+			}
+		}
+		rec, _ := c.fetch(handler, r, key)
+		if cached != nil && serverError(rec.code) && age < cached.lifetime+cached.staleIfError {
+			c.serve(w, r, cached, age, "STALE")
+			return
+		}
+		c.write(w, rec)
+	})
+}
+
+// write writes the recorded response of a miss.
+func (c *ResponseCache) write(w http.ResponseWriter, rec *recordResponseWriter) {
+	header := w.Header()
+	for k, v := range rec.header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set("X-Cache", "MISS")
+	w.WriteHeader(rec.code)
+	w.Write(rec.body.Bytes())
+}
+
+func (c *ResponseCache) key(r *http.Request) string {
+	if r.Method != "GET" && r.Method != "HEAD" || r.Header.Get("Authorization") != "" {
+		return ""
+	}
+	if c.Key != nil {
+		return c.Key(r)
+	}
+	if key := RequestKey(r); key != "" {
+		return key
+	}
+	return CoalesceKey(r)
+}
+
+func (c *ResponseCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// fetch serves the request with the handler, recording and caching the
+// response. It returns the recorded response, and the cached one or nil.
+func (c *ResponseCache) fetch(handler http.Handler, r *http.Request, key string) (*recordResponseWriter, *cachedResponse) {
+	rec := &recordResponseWriter{header: make(http.Header)}
+	handler.ServeHTTP(rec, r)
+	if rec.code == 0 {
+		rec.code = http.StatusOK
+	}
+	if r.Method != "GET" {
+		return rec, nil
+	}
+	cached := c.cacheable(r, rec)
+	if cached != nil {
+		cached.key = key
+		c.store(r.Context(), cached)
+	}
+	return rec, cached
+}
+
+// refresh refreshes the cached response in the background, once at a time.
+func (c *ResponseCache) refresh(handler http.Handler, r *http.Request, key string) {
+	if _, loaded := c.refreshing.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	req := r.Clone(&detachedContext{Context: context.Background(), values: r.Context()})
+	req.Body = http.NoBody
+	go func() {
+		defer c.refreshing.Delete(key)
+		defer func() { recover() }()
+		c.fetch(handler, req, key)
+	}()
+}
+
+func (c *ResponseCache) serve(w http.ResponseWriter, r *http.Request, cached *cachedResponse, age time.Duration, status string) {
+	header := w.Header()
+	for k, v := range cached.header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+	header.Set("X-Cache", status)
+	if etag := cached.header.Get("ETag"); etag != "" && cached.code == http.StatusOK && etagMatch(r.Header.Get("If-None-Match"), etag) {
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(cached.code)
+	if r.Method != "HEAD" {
+		w.Write(cached.body)
+	}
+}
+
+// cacheable returns the cached response of the recorded response, or nil
+// if it is not cacheable.
+func (c *ResponseCache) cacheable(r *http.Request, rec *recordResponseWriter) *cachedResponse {
+	switch rec.code {
+	case 200, 203, 204, 300, 301, 308, 404, 405, 410, 414, 501:
+	default:
+		return nil
+	}
+	maxEntrySize := c.MaxEntrySize
+	if maxEntrySize <= 0 {
+		maxEntrySize = defaultCacheMaxEntrySize
+	}
+	if int64(rec.body.Len()) > maxEntrySize || rec.header.Get("Set-Cookie") != "" {
+		return nil
+	}
+	cached := &cachedResponse{
+		code:                 rec.code,
+		header:               rec.header,
+		lifetime:             c.TTL,
+		staleWhileRevalidate: c.StaleWhileRevalidate,
+		staleIfError:         c.StaleIfError,
+	}
+	var maxAge, sMaxAge bool
+	for _, directive := range strings.Split(strings.Join(rec.header.Values("Cache-Control"), ","), ",") {
+		name, value := strings.TrimSpace(directive), ""
+		if i := strings.IndexByte(name, '='); i >= 0 {
+			name, value = strings.TrimSpace(name[:i]), strings.Trim(strings.TrimSpace(name[i+1:]), `"`)
+		}
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		d := time.Duration(seconds) * time.Second
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache", "private":
+			return nil
+		case "s-maxage":
+			if err == nil {
+				cached.lifetime, sMaxAge = d, true
+			}
+		case "max-age":
+			if err == nil && !sMaxAge {
+				cached.lifetime, maxAge = d, true
+			}
+		case "stale-while-revalidate":
+			if err == nil {
+				cached.staleWhileRevalidate = d
+			}
+		case "stale-if-error":
+			if err == nil {
+				cached.staleIfError = d
+			}
+		}
+	}
+	if !maxAge && !sMaxAge && c.TTL <= 0 ||
+		cached.lifetime+cached.staleWhileRevalidate+cached.staleIfError <= 0 {
+		return nil
+	}
+	var ok bool
+	if cached.vary, ok = varyValues(rec.header, r); !ok {
+		return nil
+	}
+	cached.body = append([]byte(nil), rec.body.Bytes()...)
+	cached.stored = c.clock()
+	return cached
+}
+
+// matches reports whether the request matches the Vary of the response.
+func (cached *cachedResponse) matches(r *http.Request) bool {
+	return varyMatches(cached.vary, r)
+}
+
+// varyValues returns the values of the request for the fields of the Vary
+// of the response header, or false for Vary: *.
+func varyValues(header http.Header, r *http.Request) (vary map[string]string, ok bool) {
+	for _, field := range strings.Split(strings.Join(header.Values("Vary"), ","), ",") {
+		field = http.CanonicalHeaderKey(strings.TrimSpace(field))
+		if field == "*" {
+			return nil, false
+		}
+		if field != "" {
+			if vary == nil {
+				vary = make(map[string]string)
+			}
+			vary[field] = strings.Join(r.Header.Values(field), ",")
+		}
+	}
+	return vary, true
+}
+
+// varyMatches reports whether the request has the values of the Vary.
+func varyMatches(vary map[string]string, r *http.Request) bool {
+	for field, value := range vary {
+		if strings.Join(r.Header.Values(field), ",") != value {
+			return false
+		}
+	}
+	return true
+}
+
+// load returns the cached response of the key from the Store or the memory.
+func (c *ResponseCache) load(ctx context.Context, key string) *cachedResponse {
+	if c.Store == nil {
+		return c.get(key)
+	}
+	data, err := c.Store.Get(ctx, key)
+	if err != nil {
+		return nil
+	}
+	var s savedResponse
+	if json.Unmarshal(data, &s) != nil || s.Key != key || s.Checksum != s.checksum() {
+		return nil
+	}
+	return s.cachedResponse()
+}
+
+// store caches the response to the Store or the memory.
+func (c *ResponseCache) store(ctx context.Context, cached *cachedResponse) {
+	if c.Store == nil {
+		c.put(cached)
+		return
+	}
+	data, err := json.Marshal(newSavedResponse(cached))
+	if err != nil {
+		return
+	}
+	c.Store.Set(ctx, cached.key, data, cached.lifetime+cached.staleWhileRevalidate+cached.staleIfError)
+}
+
+// get returns the cached response of the key in memory, marking it
+// recently used.
+func (c *ResponseCache) get(key string) *cachedResponse {
+	v, ok := c.entries.Load(key)
+	if !ok {
+		return nil
+	}
+	cached := v.(*cachedResponse)
+	c.mu.Lock()
+	if cached.element != nil {
+		c.lru.MoveToFront(cached.element)
+	}
+	c.mu.Unlock()
+	return cached
+}
+
+// put caches the response, evicting the least recently used responses
+// beyond the maximum size.
+func (c *ResponseCache) put(cached *cachedResponse) {
+	maxSize := c.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultCacheMaxSize
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lru == nil {
+		c.lru = list.New()
+	}
+	if v, ok := c.entries.Load(cached.key); ok {
+		c.evict(v.(*cachedResponse))
+	}
+	cached.element = c.lru.PushFront(cached)
+	c.entries.Store(cached.key, cached)
+	c.size += int64(len(cached.body))
+	for c.size > maxSize {
+		c.evict(c.lru.Back().Value.(*cachedResponse))
+	}
+}
+
+func (c *ResponseCache) evict(cached *cachedResponse) {
+	if cached.element == nil {
+		return
+	}
+	c.lru.Remove(cached.element)
+	cached.element = nil
+	c.entries.Delete(cached.key)
+	c.size -= int64(len(cached.body))
+}
+
+// requestNoCache reports whether the request asks for a fresh response.
+func requestNoCache(r *http.Request) bool {
+	cacheControl := strings.ToLower(r.Header.Get("Cache-Control"))
+	return strings.Contains(cacheControl, "no-cache") || strings.Contains(cacheControl, "max-age=0") ||
+		r.Header.Get("Pragma") == "no-cache"
+}
+
+func serverError(code int) bool {
+	switch code {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
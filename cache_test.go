@@ -0,0 +1,129 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testCache(h http.Handler, header http.Header, t *testing.T) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/a", nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestResponseCacheStale(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Unix(1000, 0)
+	clock := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+	var calls, status int32 = 0, http.StatusOK
+	c := &ResponseCache{TTL: time.Minute, StaleWhileRevalidate: time.Minute, StaleIfError: time.Hour}
+	c.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+		w.Write([]byte(strconv.Itoa(int(n))))
+	}))
+	if w := testCache(h, nil, t); w.Body.String() != "1" || w.Header().Get("X-Cache") != "MISS" {
+		t.Error(w.Body.String(), w.Header())
+	}
+	clock(time.Second * 30)
+	if w := testCache(h, nil, t); w.Body.String() != "1" || w.Header().Get("X-Cache") != "HIT" || w.Header().Get("Age") != "30" {
+		t.Error(w.Body.String(), w.Header())
+	}
+	clock(time.Second * 60)
+	if w := testCache(h, nil, t); w.Body.String() != "1" || w.Header().Get("X-Cache") != "STALE" {
+		t.Error(w.Body.String(), w.Header())
+	}
+	for i := 0; i < 100 && atomic.LoadInt32(&calls) < 2; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(time.Millisecond * 10)
+	if w := testCache(h, nil, t); w.Body.String() != "2" || w.Header().Get("X-Cache") != "HIT" {
+		t.Error(w.Body.String(), w.Header())
+	}
+	atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+	clock(time.Minute * 5)
+	if w := testCache(h, nil, t); w.Code != http.StatusOK || w.Body.String() != "2" || w.Header().Get("X-Cache") != "STALE" {
+		t.Error(w.Code, w.Body.String(), w.Header())
+	}
+	clock(time.Hour)
+	if w := testCache(h, nil, t); w.Code != http.StatusServiceUnavailable || w.Header().Get("X-Cache") != "MISS" {
+		t.Error(w.Code, w.Header())
+	}
+}
+
+func TestResponseCacheBypass(t *testing.T) {
+	var calls int32
+	c := &ResponseCache{TTL: time.Minute}
+	cacheControl := ""
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		w.Header().Set("Vary", "Accept-Language")
+		w.Header().Set("ETag", `"v"`)
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	})
+	h := c.Handler(handler)
+	en := http.Header{"Accept-Language": {"en"}}
+	testCache(h, en, t)
+	if w := testCache(h, en, t); w.Body.String() != "en" || atomic.LoadInt32(&calls) != 1 {
+		t.Error(w.Body.String(), calls)
+	}
+	if w := testCache(h, http.Header{"Accept-Language": {"fr"}}, t); w.Body.String() != "fr" || atomic.LoadInt32(&calls) != 2 {
+		t.Error(w.Body.String(), calls)
+	}
+	if w := testCache(h, http.Header{"Accept-Language": {"fr"}, "If-None-Match": {`"v"`}}, t); w.Code != http.StatusNotModified {
+		t.Error(w.Code)
+	}
+	testCache(h, http.Header{"Accept-Language": {"fr"}, "Authorization": {"Basic x"}}, t)
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Error(calls)
+	}
+	testCache(h, http.Header{"Accept-Language": {"fr"}, "Cache-Control": {"no-cache"}}, t)
+	if atomic.LoadInt32(&calls) != 4 {
+		t.Error(calls)
+	}
+	cacheControl = "no-store"
+	c = &ResponseCache{TTL: time.Minute}
+	h2 := c.Handler(handler)
+	testCache(h2, en, t)
+	testCache(h2, en, t)
+	if atomic.LoadInt32(&calls) != 6 {
+		t.Error(calls)
+	}
+}
+
+func TestResponseCacheEvict(t *testing.T) {
+	c := &ResponseCache{TTL: time.Minute, MaxSize: 10}
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("123456"))
+	}))
+	for _, path := range []string{"/a", "/b"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	if _, ok := c.entries.Load(CoalesceKey(httptest.NewRequest("GET", "/a", nil))); ok || c.size != 6 {
+		t.Error("/a should be evicted", c.size)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrCacheFile is the error returned by Load when the data is not saved
+// by a ResponseCache of this version.
+var ErrCacheFile = errors.New("Invalid Cache File")
+
+const cacheFileVersion = "rum-cache/1"
+
+type cacheFileHeader struct {
+	Version string    `json:"version"`
+	Saved   time.Time `json:"saved"`
+}
+
+// savedResponse is the saved form of a cachedResponse.
+type savedResponse struct {
+	Key                  string            `json:"key"`
+	Code                 int               `json:"code"`
+	Header               http.Header       `json:"header"`
+	Body                 []byte            `json:"body"`
+	Vary                 map[string]string `json:"vary,omitempty"`
+	Stored               time.Time         `json:"stored"`
+	Lifetime             time.Duration     `json:"lifetime"`
+	StaleWhileRevalidate time.Duration     `json:"stale_while_revalidate"`
+	StaleIfError         time.Duration     `json:"stale_if_error"`
+	Checksum             uint32            `json:"checksum"`
+}
+
+func newSavedResponse(cached *cachedResponse) *savedResponse {
+	s := &savedResponse{
+		Key:                  cached.key,
+		Code:                 cached.code,
+		Header:               cached.header,
+		Body:                 cached.body,
+		Vary:                 cached.vary,
+		Stored:               cached.stored,
+		Lifetime:             cached.lifetime,
+		StaleWhileRevalidate: cached.staleWhileRevalidate,
+		StaleIfError:         cached.staleIfError,
+	}
+	s.Checksum = s.checksum()
+	return s
+}
+
+func (s *savedResponse) cachedResponse() *cachedResponse {
+	return &cachedResponse{
+		key:                  s.Key,
+		code:                 s.Code,
+		header:               s.Header,
+		body:                 s.Body,
+		vary:                 s.Vary,
+		stored:               s.Stored,
+		lifetime:             s.Lifetime,
+		staleWhileRevalidate: s.StaleWhileRevalidate,
+		staleIfError:         s.StaleIfError,
+	}
+}
+
+func (s *savedResponse) checksum() uint32 {
+	h := crc32.NewIEEE()
+	io.WriteString(h, s.Key)
+	h.Write(s.Body)
+	return h.Sum32()
+}
+
+// Persist loads the responses saved to the file when the Server starts,
+// and saves the cached responses to the file when the Server shuts down,
+// avoiding a cold cache after a deploy.
+func (c *ResponseCache) Persist(m *Rum, name string) {
+	m.OnStart(func() error {
+		return c.LoadFile(name)
+	})
+	m.OnStop(func(ctx context.Context) error {
+		return c.SaveFile(name)
+	})
+}
+
+// SaveFile saves the cached responses to the named file, replaced
+// atomically.
+func (c *ResponseCache) SaveFile(name string) error {
+	f, err := ioutil.TempFile(filepath.Dir(name), filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+	err = c.Save(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// LoadFile loads the responses saved to the named file. A missing file is
+// not an error.
+func (c *ResponseCache) LoadFile(name string) error {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.Load(f)
+}
+
+// Save writes the responses cached in memory to w, the most recently used
+// first. The responses of a Store are not saved.
+func (c *ResponseCache) Save(w io.Writer) error {
+	c.mu.Lock()
+	var entries []*cachedResponse
+	if c.lru != nil {
+		entries = make([]*cachedResponse, 0, c.lru.Len())
+		for e := c.lru.Front(); e != nil; e = e.Next() {
+			entries = append(entries, e.Value.(*cachedResponse))
+		}
+	}
+	c.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(&cacheFileHeader{Version: cacheFileVersion, Saved: c.clock()}); err != nil {
+		return err
+	}
+	for _, cached := range entries {
+		if err := enc.Encode(newSavedResponse(cached)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Load reads the responses written by Save into the cache. The responses
+// that are corrupted, too large or no longer usable, even stale, are
+// skipped. A truncated data keeps the responses read before.
+func (c *ResponseCache) Load(r io.Reader) error {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	var header cacheFileHeader
+	if err := dec.Decode(&header); err != nil || header.Version != cacheFileVersion {
+		return ErrCacheFile
+	}
+	maxEntrySize := c.MaxEntrySize
+	if maxEntrySize <= 0 {
+		maxEntrySize = defaultCacheMaxEntrySize
+	}
+	now := c.clock()
+	var entries []*cachedResponse
+	for {
+		var s savedResponse
+		if err := dec.Decode(&s); err != nil {
+			break
+		}
+		if s.Key == "" || s.Checksum != s.checksum() || s.Code < 100 || s.Code > 599 ||
+			int64(len(s.Body)) > maxEntrySize || s.Header == nil {
+			continue
+		}
+		age := now.Sub(s.Stored)
+		if age < 0 || age >= s.Lifetime+s.StaleWhileRevalidate+s.StaleIfError {
+			continue
+		}
+		entries = append(entries, s.cachedResponse())
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if _, ok := c.entries.Load(entries[i].key); !ok {
+			c.put(entries[i])
+		}
+	}
+	return nil
+}
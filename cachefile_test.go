@@ -0,0 +1,99 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResponseCachePersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "cache.json")
+	var calls int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(r.URL.Path))
+	})
+	m := New()
+	c := &ResponseCache{TTL: time.Minute}
+	c.Persist(m, name)
+	if err := m.start(); err != nil {
+		t.Fatal(err)
+	}
+	h := c.Handler(handler)
+	for _, path := range []string{"/a", "/b"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	if err := m.stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	m = New()
+	c = &ResponseCache{TTL: time.Minute}
+	c.Persist(m, name)
+	if err := m.start(); err != nil {
+		t.Fatal(err)
+	}
+	h = c.Handler(handler)
+	for _, path := range []string{"/a", "/b"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Body.String() != path || w.Header().Get("X-Cache") != "HIT" || w.Header().Get("Content-Type") != "text/plain" {
+			t.Error(w.Body.String(), w.Header())
+		}
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Error(calls)
+	}
+
+	c = &ResponseCache{}
+	c.now = func() time.Time { return time.Now().Add(time.Hour) }
+	if err := c.LoadFile(name); err != nil || c.size != 0 {
+		t.Error("the expired responses should be skipped", err, c.size)
+	}
+	if err := c.LoadFile(filepath.Join(dir, "missing")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestResponseCacheLoadInvalid(t *testing.T) {
+	c := &ResponseCache{TTL: time.Minute}
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	for _, path := range []string{"/a", "/b"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	var buf bytes.Buffer
+	if err := c.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	lines[1] = strings.Replace(lines[1], `"body":"L2`, `"body":"L3`, 1)
+	c = &ResponseCache{}
+	if err := c.Load(strings.NewReader(strings.Join(lines, "\n"))); err != nil {
+		t.Fatal(err)
+	}
+	if c.size != 2 {
+		t.Error("the corrupted response should be skipped", c.size)
+	}
+	if err := (&ResponseCache{}).Load(strings.NewReader("{}\n")); err != ErrCacheFile {
+		t.Error(err)
+	}
+}
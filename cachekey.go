@@ -0,0 +1,128 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CacheKey builds the normalized keys of a ResponseCache, so that the
+// requests differing only by irrelevant attributes, such as the order of
+// the query parameters or a tracking cookie, share a cached response.
+//
+//	k := &rum.CacheKey{IgnoreQuery: []string{"utm_*"}, Headers: []string{"Accept-Language"}, Cookies: []string{"lang"}}
+//	c := &rum.ResponseCache{TTL: time.Minute}
+//	m.Handle("/articles/:id", k.Handler(c.Handler(articles)))
+//
+// The Handler strips the attributes not in the key from the requests, so
+// that the responses cannot depend on them, which would poison the cache.
+type CacheKey struct {
+	// Query holds the names of the query parameters of the key. If nil,
+	// all of the parameters but the ignored ones are.
+	Query []string
+	// IgnoreQuery holds the names of the ignored query parameters, a
+	// trailing "*" matching a prefix, such as "utm_*".
+	IgnoreQuery []string
+	// Headers holds the names of the headers of the key, such as
+	// Accept-Language.
+	Headers []string
+	// Cookies holds the names of the cookies of the key. The other cookies
+	// are stripped by the Handler.
+	Cookies []string
+}
+
+// Key returns the key of the request: the method, the lowercase host, the
+// path, the query parameters sorted by name, then the selected headers
+// and cookies.
+func (k *CacheKey) Key(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.Method)
+	b.WriteByte(' ')
+	b.WriteString(strings.ToLower(r.Host))
+	b.WriteString(r.URL.EscapedPath())
+	if query := k.query(r.URL.RawQuery); query != "" {
+		b.WriteByte('?')
+		b.WriteString(query)
+	}
+	for _, name := range k.Headers {
+		b.WriteString("\n")
+		b.WriteString(http.CanonicalHeaderKey(name))
+		b.WriteString(": ")
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	for _, name := range k.Cookies {
+		b.WriteString("\nCookie ")
+		b.WriteString(name)
+		if c, err := r.Cookie(name); err == nil {
+			b.WriteByte('=')
+			b.WriteString(c.Value)
+		}
+	}
+	return b.String()
+}
+
+// Handler returns a handler that strips the query parameters and the
+// cookies not in the key from the request, and stores the key in the
+// request context, where a ResponseCache finds it by RequestKey.
+func (k *CacheKey) Handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.Clone(r.Context())
+		r.URL.RawQuery = k.query(r.URL.RawQuery)
+		r.RequestURI = r.URL.RequestURI()
+		cookies := r.Cookies()
+		r.Header.Del("Cookie")
+		for _, c := range cookies {
+			if k.keeps(c.Name) {
+				r.AddCookie(c)
+			}
+		}
+		ctx := context.WithValue(r.Context(), RequestKeyContextKey, k.Key(r))
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// query returns the normalized query of the key.
+func (k *CacheKey) query(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, _ := url.ParseQuery(rawQuery)
+	for name := range values {
+		if !k.includes(name) {
+			values.Del(name)
+		}
+	}
+	return values.Encode()
+}
+
+// includes reports whether the query parameter is in the key.
+func (k *CacheKey) includes(name string) bool {
+	for _, ignored := range k.IgnoreQuery {
+		if ignored == name || strings.HasSuffix(ignored, "*") && strings.HasPrefix(name, ignored[:len(ignored)-1]) {
+			return false
+		}
+	}
+	if k.Query == nil {
+		return true
+	}
+	for _, q := range k.Query {
+		if q == name {
+			return true
+		}
+	}
+	return false
+}
+
+// keeps reports whether the cookie is in the key.
+func (k *CacheKey) keeps(name string) bool {
+	for _, c := range k.Cookies {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheKey(t *testing.T) {
+	k := &CacheKey{IgnoreQuery: []string{"utm_*", "fbclid"}, Headers: []string{"Accept-Language"}, Cookies: []string{"lang"}}
+	r := httptest.NewRequest("GET", "http://Example.com/a?b=2&a=1&utm_source=x&fbclid=y", nil)
+	r.Header.Set("Accept-Language", "en")
+	r.Header.Set("Cookie", "session=1; lang=fr")
+	if key := k.Key(r); key != "GET example.com/a?a=1&b=2\nAccept-Language: en\nCookie lang=fr" {
+		t.Errorf("%q", key)
+	}
+	r2 := httptest.NewRequest("GET", "http://example.com/a?a=1&b=2", nil)
+	r2.Header.Set("Accept-Language", "en")
+	r2.Header.Set("Cookie", "lang=fr")
+	if k.Key(r) != k.Key(r2) {
+		t.Error(k.Key(r2))
+	}
+	k = &CacheKey{Query: []string{"id"}}
+	if key := k.Key(httptest.NewRequest("GET", "http://example.com/a?x=1&id=2", nil)); key != "GET example.com/a?id=2" {
+		t.Errorf("%q", key)
+	}
+}
+
+func TestCacheKeyHandler(t *testing.T) {
+	var calls int32
+	k := &CacheKey{IgnoreQuery: []string{"utm_*"}, Cookies: []string{"lang"}}
+	c := &ResponseCache{TTL: time.Minute}
+	h := k.Handler(c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if _, err := r.Cookie("session"); err == nil {
+			t.Error("the session cookie should be stripped")
+		}
+		if r.URL.Query().Get("utm_source") != "" || r.RequestURI != "/a?b=2" {
+			t.Error(r.RequestURI)
+		}
+		lang, _ := r.Cookie("lang")
+		w.Write([]byte(lang.Value))
+	})))
+	for _, session := range []string{"1", "2"} {
+		r := httptest.NewRequest("GET", "/a?b=2&utm_source="+session, nil)
+		r.Header.Set("Cookie", "session="+session+"; lang=fr")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Body.String() != "fr" {
+			t.Error(w.Body.String())
+		}
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Error(calls)
+	}
+	r := httptest.NewRequest("GET", "/a?b=2", nil)
+	r.Header.Set("Cookie", "lang=en")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Body.String() != "en" || atomic.LoadInt32(&calls) != 2 {
+		t.Error(w.Body.String(), calls)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRedisReply is the error returned by a RedisStore when a reply of the
+// Redis server is malformed.
+var ErrRedisReply = errors.New("Invalid Redis Reply")
+
+const defaultRedisMaxIdle = 8
+
+// RedisStore is a CacheStore of a Redis server, speaking the RESP protocol
+// over a pool of connections.
+//
+//	c := &rum.ResponseCache{TTL: time.Minute, Store: &rum.RedisStore{Addr: "127.0.0.1:6379", Prefix: "rum:"}}
+type RedisStore struct {
+	// Addr is the address of the Redis server.
+	Addr string
+	// Password authenticates the connections if not empty.
+	Password string
+	// DB is the selected database.
+	DB int
+	// Prefix is prepended to the keys.
+	Prefix string
+	// Timeout is the maximum duration of a dial or a command without a
+	// deadline of the context. If zero, one second is used.
+	Timeout time.Duration
+	// MaxIdle is the maximum number of the idle connections. If zero, 8 is used.
+	MaxIdle int
+
+	mu   sync.Mutex
+	idle []*redisConn
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// Get returns the value of the key, or ErrCacheMiss.
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.do(ctx, "GET", s.Prefix+key)
+	if err == nil && value == nil {
+		err = ErrCacheMiss
+	}
+	return value, err
+}
+
+// Set stores the value of the key for the ttl.
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", s.Prefix + key, string(value)}
+	if ms := int64(ttl / time.Millisecond); ms > 0 {
+		args = append(args, "PX", strconv.FormatInt(ms, 10))
+	}
+	_, err := s.do(ctx, args...)
+	return err
+}
+
+// Add stores the value of the key for the ttl unless the key exists,
+// reporting whether it is stored.
+func (s *RedisStore) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	args := []string{"SET", s.Prefix + key, string(value)}
+	if ms := int64(ttl / time.Millisecond); ms > 0 {
+		args = append(args, "PX", strconv.FormatInt(ms, 10))
+	}
+	reply, err := s.do(ctx, append(args, "NX")...)
+	return reply != nil, err
+}
+
+// Delete deletes the key.
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, "DEL", s.Prefix+key)
+	return err
+}
+
+// Close closes the idle connections.
+func (s *RedisStore) Close() error {
+	s.mu.Lock()
+	idle := s.idle
+	s.idle = nil
+	s.mu.Unlock()
+	for _, c := range idle {
+		c.conn.Close()
+	}
+	return nil
+}
+
+func (s *RedisStore) timeout() time.Duration {
+	if s.Timeout > 0 {
+		return s.Timeout
+	}
+	return time.Second
+}
+
+// do sends the command and returns the bulk string or the status of the
+// reply, or nil for the other replies.
+func (s *RedisStore) do(ctx context.Context, args ...string) ([]byte, error) {
+	c, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(s.timeout())
+	}
+	c.conn.SetDeadline(deadline)
+	value, err := c.command(args...)
+	if err != nil {
+		if _, ok := err.(redisError); !ok {
+			c.conn.Close()
+			return nil, err
+		}
+	}
+	s.put(c)
+	return value, err
+}
+
+func (s *RedisStore) get(ctx context.Context) (*redisConn, error) {
+	s.mu.Lock()
+	if n := len(s.idle); n > 0 {
+		c := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mu.Unlock()
+		return c, nil
+	}
+	s.mu.Unlock()
+	dialer := &net.Dialer{Timeout: s.timeout()}
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	conn.SetDeadline(time.Now().Add(s.timeout()))
+	if s.Password != "" {
+		if _, err = c.command("AUTH", s.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.DB != 0 {
+		if _, err = c.command("SELECT", strconv.Itoa(s.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (s *RedisStore) put(c *redisConn) {
+	maxIdle := s.MaxIdle
+	if maxIdle <= 0 {
+		maxIdle = defaultRedisMaxIdle
+	}
+	s.mu.Lock()
+	if len(s.idle) < maxIdle {
+		s.idle = append(s.idle, c)
+		c = nil
+	}
+	s.mu.Unlock()
+	if c != nil {
+		c.conn.Close()
+	}
+}
+
+// redisError is an error reply of the Redis server.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// command writes the command as an array of bulk strings and reads the reply.
+func (c *redisConn) command(args ...string) ([]byte, error) {
+	c.w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		c.w.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n")
+		c.w.WriteString(arg)
+		c.w.WriteString("\r\n")
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	line, err := c.r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, ErrRedisReply
+	}
+	line = line[:len(line)-2]
+	switch line[0] {
+	case '+':
+		return append([]byte{}, line[1:]...), nil
+	case ':':
+		return nil, nil
+	case '-':
+		return nil, redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n < -1 {
+			return nil, ErrRedisReply
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		value := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, value); err != nil {
+			return nil, err
+		}
+		return value[:n], nil
+	}
+	return nil, ErrRedisReply
+}
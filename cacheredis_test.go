@@ -0,0 +1,156 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testRedis serves GET, SET, SET NX, DEL and AUTH of the RESP protocol from a map.
+func testRedis(t *testing.T) (addr string, close func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data sync.Map
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+					args := make([]string, n)
+					for i := range args {
+						line, _ = r.ReadString('\n')
+						size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+						buf := make([]byte, size+2)
+						io.ReadFull(r, buf)
+						args[i] = string(buf[:size])
+					}
+					switch strings.ToUpper(args[0]) {
+					case "AUTH":
+						if args[1] == "secret" {
+							io.WriteString(conn, "+OK\r\n")
+						} else {
+							io.WriteString(conn, "-ERR invalid password\r\n")
+						}
+					case "SET":
+						if strings.ToUpper(args[len(args)-1]) == "NX" {
+							if _, loaded := data.LoadOrStore(args[1], args[2]); loaded {
+								io.WriteString(conn, "$-1\r\n")
+								break
+							}
+						} else {
+							data.Store(args[1], args[2])
+						}
+						io.WriteString(conn, "+OK\r\n")
+					case "GET":
+						if v, ok := data.Load(args[1]); ok {
+							io.WriteString(conn, "$"+strconv.Itoa(len(v.(string)))+"\r\n"+v.(string)+"\r\n")
+						} else {
+							io.WriteString(conn, "$-1\r\n")
+						}
+					case "DEL":
+						data.Delete(args[1])
+						io.WriteString(conn, ":1\r\n")
+					default:
+						io.WriteString(conn, "-ERR unknown command\r\n")
+					}
+				}
+			}(conn)
+		}
+	}()
+	return lis.Addr().String(), func() { lis.Close() }
+}
+
+func TestRedisStore(t *testing.T) {
+	addr, close := testRedis(t)
+	defer close()
+	ctx := context.Background()
+	s := &RedisStore{Addr: addr, Password: "secret", Prefix: "rum:"}
+	defer s.Close()
+	if _, err := s.Get(ctx, "a"); err != ErrCacheMiss {
+		t.Error(err)
+	}
+	if err := s.Set(ctx, "a", []byte("hello\r\nworld"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.Get(ctx, "a"); err != nil || string(v) != "hello\r\nworld" {
+		t.Error(string(v), err)
+	}
+	if added, err := s.Add(ctx, "a", []byte("again"), time.Minute); err != nil || added {
+		t.Error(added, err)
+	}
+	if err := s.Delete(ctx, "a"); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Get(ctx, "a"); err != ErrCacheMiss {
+		t.Error(err)
+	}
+	if added, err := s.Add(ctx, "a", []byte("added"), time.Minute); err != nil || !added {
+		t.Error(added, err)
+	}
+	bad := &RedisStore{Addr: addr, Password: "wrong"}
+	if _, err := bad.Get(ctx, "a"); err == nil || err.Error() != "ERR invalid password" {
+		t.Error(err)
+	}
+}
+
+func TestResponseCacheStore(t *testing.T) {
+	addr, close := testRedis(t)
+	defer close()
+	var calls int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(time.Millisecond * 20)
+		w.Write([]byte("hello"))
+	})
+	store := &RedisStore{Addr: addr}
+	defer store.Close()
+	replicas := []http.Handler{
+		(&ResponseCache{TTL: time.Minute, Store: store}).Handler(handler),
+		(&ResponseCache{TTL: time.Minute, Store: store}).Handler(handler),
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			replicas[0].ServeHTTP(w, httptest.NewRequest("GET", "/a", nil))
+			if w.Body.String() != "hello" {
+				t.Error(w.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Error("the misses should be filled once", calls)
+	}
+	w := httptest.NewRecorder()
+	replicas[1].ServeHTTP(w, httptest.NewRequest("GET", "/a", nil))
+	if w.Body.String() != "hello" || w.Header().Get("X-Cache") != "HIT" || atomic.LoadInt32(&calls) != 1 {
+		t.Error(w.Body.String(), w.Header(), calls)
+	}
+}
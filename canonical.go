@@ -0,0 +1,153 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Canonicalization configures the canonicalization of the request paths
+// applied by the Mux before routing. By default the "." and ".." segments
+// are removed, the repeated slashes are collapsed and an encoded slash
+// "%2F" is kept within its segment, so a param may contain a slash, and
+// even "..", which the handlers must not trust as a file path.
+type Canonicalization struct {
+	// RejectEncodedSlash replies 400 Bad Request to the paths with an
+	// encoded slash, which could otherwise address another route.
+	RejectEncodedSlash bool
+	// KeepDotSegments disables the removal of the "." and ".." segments.
+	KeepDotSegments bool
+	// KeepSlashes disables collapsing the repeated slashes.
+	KeepSlashes bool
+}
+
+// SetCanonicalization sets the canonicalization of the request paths. The
+// handlers see the canonical path in the URL of the request. It may be
+// called while serving.
+func (m *Mux) SetCanonicalization(c Canonicalization) {
+	m.canonicalization.Store(c)
+}
+
+// canonical returns the canonical path of the request, or false if the
+// path is rejected. The canonical path is decoded but the slashes and the
+// percent signs within the segments, which are encoded.
+func (m *Mux) canonical(r *http.Request) (string, bool) {
+	c, _ := m.canonicalization.Load().(Canonicalization)
+	path := r.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	if raw := r.URL.RawPath; hasEncodedSlash(raw) {
+		if c.RejectEncodedSlash {
+			return path, false
+		}
+		path = decodeSegments(raw)
+	} else if strings.Contains(path, "%") {
+		path = strings.ReplaceAll(path, "%", "%25")
+	}
+	if !c.KeepDotSegments && strings.HasPrefix(path, "/") && strings.Contains(path, "/.") {
+		path = removeDotSegments(path)
+	}
+	if !c.KeepSlashes {
+		path = m.replace(path)
+	}
+	return path, true
+}
+
+// canonicalRequest returns the request with the canonical path in its URL.
+func (m *Mux) canonicalRequest(r *http.Request) (string, *http.Request, bool) {
+	path, ok := m.canonical(r)
+	if !ok || unescapeSegment(path) == r.URL.Path {
+		return path, r, ok
+	}
+	u := *r.URL
+	u.Path = unescapeSegment(path)
+	u.RawPath = ""
+	if hasEncodedSlash(path) {
+		u.RawPath = escapeSegments(path)
+	}
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = &u
+	return path, r2, true
+}
+
+func hasEncodedSlash(path string) bool {
+	return strings.Contains(path, "%2F") || strings.Contains(path, "%2f")
+}
+
+// decodeSegments decodes the raw path but the encoded slashes, and encodes
+// the percent signs, so that only the slashes separate the segments.
+func decodeSegments(raw string) string {
+	parts := strings.Split(strings.ReplaceAll(raw, "%2f", "%2F"), "%2F")
+	for i, part := range parts {
+		if decoded, err := url.PathUnescape(part); err == nil {
+			part = decoded
+		}
+		parts[i] = strings.ReplaceAll(part, "%", "%25")
+	}
+	return strings.Join(parts, "%2F")
+}
+
+var segmentUnescaper = strings.NewReplacer("%2F", "/", "%25", "%")
+
+// unescapeSegment decodes the encoded slashes and percent signs of the
+// segment of a canonical path.
+func unescapeSegment(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	return segmentUnescaper.Replace(s)
+}
+
+// escapeSegments returns the escaped form of the canonical path.
+func escapeSegments(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(unescapeSegment(s))
+	}
+	return strings.Join(segments, "/")
+}
+
+// escapedPath returns the escaped form of the canonical path of the request.
+func escapedPath(r *http.Request, path string) string {
+	if unescapeSegment(path) == r.URL.Path {
+		return r.URL.EscapedPath()
+	}
+	return escapeSegments(path)
+}
+
+// removeDotSegments removes the "." and ".." segments of the absolute path,
+// as described in RFC 3986 section 5.2.4. The ".." segments never climb
+// above the root.
+func removeDotSegments(path string) string {
+	segments := strings.Split(path, "/")
+	out := segments[:0]
+	for i, s := range segments {
+		switch s {
+		case ".":
+		case "..":
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+		default:
+			out = append(out, s)
+			continue
+		}
+		if i == len(segments)-1 {
+			out = append(out, "")
+		}
+	}
+	return strings.Join(out, "/")
+}
+
+func (m *Mux) badRequest(w http.ResponseWriter, r *http.Request) {
+	if m.context.problems {
+		ProblemJSON(w, http.StatusBadRequest, "", r.URL.String())
+		return
+	}
+	http.Error(w, "400 Bad Request : "+r.URL.String(), http.StatusBadRequest)
+}
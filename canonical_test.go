@@ -0,0 +1,100 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRemoveDotSegments(t *testing.T) {
+	for path, want := range map[string]string{
+		"/a/./b":     "/a/b",
+		"/a/../b":    "/b",
+		"/a/b/..":    "/a/",
+		"/a/b/.":     "/a/b/",
+		"/../../etc": "/etc",
+		"/..":        "/",
+		"/a/.b/c..":  "/a/.b/c..",
+		"/a//../b":   "/a/b",
+	} {
+		if got := removeDotSegments(path); got != want {
+			t.Errorf("%s: %s != %s", path, got, want)
+		}
+	}
+}
+
+func TestCanonicalization(t *testing.T) {
+	m := New()
+	m.HandleFunc("/files/:name", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + " " + m.Params(r)["name"]))
+	})
+	m.HandleFunc("/etc/passwd", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("passwd"))
+	})
+	serve := func(target string, status int, result string) {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != status || result != "" && w.Body.String() != result {
+			t.Error(target, w.Code, w.Body.String())
+		}
+	}
+	serve("/files/a", http.StatusOK, "/files/a a")
+	serve("/files/../files/./a", http.StatusOK, "/files/a a")
+	serve("//files//a", http.StatusOK, "/files/a a")
+	serve("/files/../../etc/passwd", http.StatusOK, "passwd")
+	serve("/files/a%2Fb", http.StatusOK, "/files/a/b a/b")
+	serve("/files/..%2F..%2Fetc%2Fpasswd", http.StatusOK, "/files/../../etc/passwd ../../etc/passwd")
+	m.SetCanonicalization(Canonicalization{RejectEncodedSlash: true})
+	serve("/files/..%2F..%2Fetc%2Fpasswd", http.StatusBadRequest, "")
+	serve("/files/a%20b", http.StatusOK, "/files/a b a b")
+	m.SetCanonicalization(Canonicalization{KeepDotSegments: true, KeepSlashes: true})
+	serve("/files/../files/a", http.StatusNotFound, "")
+	serve("//files//a", http.StatusNotFound, "")
+}
+
+func TestParamsEncoding(t *testing.T) {
+	m := New()
+	m.HandleFunc("/users/:name/posts/:title", func(w http.ResponseWriter, r *http.Request) {
+		params, raw := m.Params(r), m.RawParams(r)
+		w.Write([]byte(params["name"] + "|" + params["title"] + "|" + raw["name"] + "|" + raw["title"]))
+	})
+	for target, want := range map[string]string{
+		"/users/bob/posts/hello":                  "bob|hello|bob|hello",
+		"/users/a%20b/posts/c%2Fd":                "a b|c/d|a%20b|c%2Fd",
+		"/users/%E4%B8%AD%E6%96%87/posts/%C3%A9":  "中文|é|%E4%B8%AD%E6%96%87|%C3%A9",
+		"/users/100%25/posts/50%25%2F50%25":       "100%|50%/50%|100%25|50%25%2F50%25",
+		"/users/./bob/../alice/posts/a%2Fb":       "alice|a/b|alice|a%2Fb",
+		"/users/%E4%B8%AD/posts/x%2F..%2Fy":       "中|x/../y|%E4%B8%AD|x%2F..%2Fy",
+		"//users//bob//posts//c%2fd":              "bob|c/d|bob|c%2Fd",
+		"/users/" + "%F0%9F%98%80" + "/posts/%3A": "😀|:|%F0%9F%98%80|%3A",
+	} {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Error(target, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestCanonicalizationWhileServing(t *testing.T) {
+	m := NewMux()
+	m.HandleFunc("/files/:name", func(w http.ResponseWriter, r *http.Request) {})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			m.SetCanonicalization(Canonicalization{RejectEncodedSlash: i%2 == 0})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", "/files/a%2Fb", nil))
+		if w.Code != http.StatusOK && w.Code != http.StatusBadRequest {
+			t.Error(w.Code)
+		}
+	}
+	<-done
+}
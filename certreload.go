@@ -0,0 +1,156 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"time"
+)
+
+// ocspTimeout is the timeout of fetching an OCSP response.
+const ocspTimeout = time.Second * 30
+
+// ocspRefresh is the refresh interval of the OCSP responses without a next update.
+const ocspRefresh = time.Hour
+
+// OCSPResponder fetches the OCSP responses stapled to the certificates.
+//
+// It is implemented by *OCSPClient.
+type OCSPResponder interface {
+	// Staple returns the DER encoded OCSP response of the leaf certificate
+	// issued by the issuer, and the time of its next update.
+	Staple(ctx context.Context, leaf, issuer *x509.Certificate) ([]byte, time.Time, error)
+}
+
+// SetCertRefresh enables the Server to check the cert files and the OCSP
+// staples of the certificates at the interval while serving TLS. A cert
+// file or a key file modified, for example renewed by certbot, is reloaded
+// without restarting the Server, and an OCSP response is fetched again
+// halfway to its next update. A failed reload keeps the previous
+// certificate. Zero disables the refresh.
+func (m *Rum) SetCertRefresh(interval time.Duration) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.certRefreshInterval = interval
+	if m.certRefresh != nil {
+		close(m.certRefresh)
+		m.certRefresh = nil
+	}
+	if interval > 0 && len(m.tlsConfigs) > 0 {
+		m.startCertRefresh()
+	}
+}
+
+// SetOCSPStapling sets the OCSPResponder fetching the OCSP responses stapled
+// to the certificates added by AddCert, AddCertFile and ServeTLS, which
+// have an issuer in their chain. The responses are fetched by the refresh
+// of SetCertRefresh. Nil disables the stapling.
+func (m *Rum) SetOCSPStapling(responder OCSPResponder) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.ocsp = responder
+}
+
+// startCertRefresh must be called with the lock held.
+func (m *Rum) startCertRefresh() {
+	done := make(chan struct{})
+	m.certRefresh = done
+	go func(interval time.Duration) {
+		m.refreshCerts()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.refreshCerts()
+			case <-done:
+				return
+			}
+		}
+	}(m.certRefreshInterval)
+}
+
+// refreshCerts reloads the modified cert files and fetches the OCSP
+// responses due for a refresh.
+func (m *Rum) refreshCerts() {
+	m.certRefreshing.Lock()
+	defer m.certRefreshing.Unlock()
+	m.mut.Lock()
+	entries := append([]*certEntry{}, m.defaultCerts...)
+	responder := m.ocsp
+	m.mut.Unlock()
+	certs, _ := m.certs.Load().(certificates)
+	for _, list := range certs {
+		entries = append(entries, list...)
+	}
+	for _, e := range entries {
+		if e.certFile != "" {
+			if modTime := certModTime(e.certFile, e.keyFile); modTime.After(e.modTime) {
+				cert, err := tls.LoadX509KeyPair(e.certFile, e.keyFile)
+				if err != nil {
+					m.logf("rum: reloading the certificate %s: %v", e.certFile, err)
+				} else {
+					e.cert.Store(&cert)
+					e.modTime = modTime
+					e.refresh = time.Time{}
+				}
+			}
+		}
+		if responder != nil && !time.Now().Before(e.refresh) {
+			if err := m.staple(responder, e); err != nil {
+				m.logf("rum: stapling the OCSP response: %v", err)
+			}
+		}
+	}
+}
+
+// staple fetches the OCSP response of the certificate of the entry.
+func (m *Rum) staple(responder OCSPResponder, e *certEntry) error {
+	cert := e.load()
+	if len(cert.Certificate) < 2 {
+		return nil
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		var err error
+		if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return err
+		}
+	}
+	issuer, err := x509.ParseCertificate(cert.Certificate[1])
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ocspTimeout)
+	defer cancel()
+	staple, nextUpdate, err := responder.Staple(ctx, leaf, issuer)
+	if err != nil {
+		return err
+	}
+	stapled := *cert
+	stapled.OCSPStaple = staple
+	e.cert.Store(&stapled)
+	now := time.Now()
+	if nextUpdate.After(now) {
+		e.refresh = now.Add(nextUpdate.Sub(now) / 2)
+	} else {
+		e.refresh = now.Add(ocspRefresh)
+	}
+	return nil
+}
+
+// certModTime returns the latest modification time of the cert file and
+// the key file.
+func certModTime(certFile, keyFile string) time.Time {
+	var modTime time.Time
+	for _, name := range []string{certFile, keyFile} {
+		if info, err := os.Stat(name); err == nil && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCertRefresh(t *testing.T) {
+	ocspServer := newTestOCSPServer(t, false)
+	defer ocspServer.Close()
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	write := func(modTime time.Time) {
+		certPEM, keyPEM := newTestChain(t, "localhost", ocspServer.URL)
+		ioutil.WriteFile(certFile, certPEM, 0600)
+		ioutil.WriteFile(keyFile, keyPEM, 0600)
+		os.Chtimes(certFile, modTime, modTime)
+		os.Chtimes(keyFile, modTime, modTime)
+	}
+	dial := func() (*big.Int, []byte) {
+		conn, err := tls.Dial("tcp", "127.0.0.1:8080", &tls.Config{ServerName: "localhost", InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		state := conn.ConnectionState()
+		return state.PeerCertificates[0].SerialNumber, state.OCSPResponse
+	}
+	write(time.Now().Add(-time.Hour))
+	m := New()
+	m.SetCertRefresh(time.Millisecond * 10)
+	m.SetOCSPStapling(&OCSPClient{})
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	})
+	done := make(chan struct{})
+	go func() {
+		m.RunTLS(":8080", certFile, keyFile)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 50)
+	serial, staple := dial()
+	if len(staple) == 0 {
+		t.Error("the OCSP response should be stapled")
+	}
+	write(time.Now())
+	time.Sleep(time.Millisecond * 50)
+	if reloaded, staple := dial(); reloaded.Cmp(serial) == 0 || len(staple) == 0 {
+		t.Error("the certificate should be reloaded", reloaded, len(staple))
+	}
+	ioutil.WriteFile(certFile, []byte("invalid"), 0600)
+	time.Sleep(time.Millisecond * 50)
+	if kept, _ := dial(); kept.Cmp(serial) == 0 {
+		t.Error("the previous certificate should be kept")
+	}
+	m.Close()
+	<-done
+	if m.certRefresh != nil {
+		t.Error()
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"crypto/tls"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type certificates map[string][]*certEntry
+
+type certEntry struct {
+	cert     atomic.Value
+	certFile string
+	keyFile  string
+	modTime  time.Time
+	refresh  time.Time
+}
+
+func (e *certEntry) load() *tls.Certificate {
+	return e.cert.Load().(*tls.Certificate)
+}
+
+// AddCert adds the certificate served to the TLS clients requesting the
+// host by the SNI extension. A host beginning with "*." matches a single
+// label of the subdomains, such as "*.example.com". The certificates of
+// a host are tried in the order they are added, so that an ECDSA and an
+// RSA certificate can be served to the clients supporting them. The
+// clients without a matching host are served by the TLSConfig, or by the
+// AutoCertManager.
+//
+// It can be called while serving. With the certificates added, RunTLS and
+// ServeTLS accept an empty cert file and key file.
+func (m *Rum) AddCert(host string, cert *tls.Certificate) {
+	e := &certEntry{}
+	e.cert.Store(cert)
+	m.addCertEntry(host, e)
+}
+
+// AddCertFile is like AddCert but with a cert file and a key file, which
+// are reloaded when modified if SetCertRefresh is enabled.
+func (m *Rum) AddCertFile(host string, certFile, keyFile string) error {
+	e, err := loadCertEntry(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	m.addCertEntry(host, e)
+	return nil
+}
+
+// defaultCertFile wraps the GetCertificate callback of a listener to serve
+// the certificate of the cert file and key file of ServeTLS to the clients
+// matching no host, so that it is reloaded as well.
+func (m *Rum) defaultCertFile(config *tls.Config, certFile, keyFile string) error {
+	e, err := loadCertEntry(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	m.mut.Lock()
+	m.defaultCerts = append(m.defaultCerts, e)
+	m.mut.Unlock()
+	getCertificate := config.GetCertificate
+	config.Certificates = nil
+	config.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if cert, err := getCertificate(hello); cert != nil || err != nil {
+			return cert, err
+		}
+		return e.load(), nil
+	}
+	return nil
+}
+
+func loadCertEntry(certFile, keyFile string) (*certEntry, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	e := &certEntry{certFile: certFile, keyFile: keyFile, modTime: certModTime(certFile, keyFile)}
+	e.cert.Store(&cert)
+	return e, nil
+}
+
+func (m *Rum) addCertEntry(host string, e *certEntry) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	old, _ := m.certs.Load().(certificates)
+	certs := make(certificates, len(old)+1)
+	for k, v := range old {
+		certs[k] = v
+	}
+	certs[host] = append(certs[host][:len(certs[host]):len(certs[host])], e)
+	m.certs.Store(certs)
+}
+
+// hasCerts reports whether a certificate has been added.
+func (m *Rum) hasCerts() bool {
+	certs, _ := m.certs.Load().(certificates)
+	return len(certs) > 0
+}
+
+// lookupCert returns the added certificate of the server name of the
+// ClientHello, or nil.
+func (m *Rum) lookupCert(hello *tls.ClientHelloInfo) *tls.Certificate {
+	certs, _ := m.certs.Load().(certificates)
+	if len(certs) == 0 || hello.ServerName == "" {
+		return nil
+	}
+	name := strings.TrimSuffix(strings.ToLower(hello.ServerName), ".")
+	list, ok := certs[name]
+	if !ok {
+		if i := strings.IndexByte(name, '.'); i > 0 {
+			list = certs["*"+name[i:]]
+		}
+	}
+	return selectCert(hello, list)
+}
+
+// selectCert returns the first certificate supported by the client, or the
+// first certificate.
+func selectCert(hello *tls.ClientHelloInfo, list []*certEntry) *tls.Certificate {
+	for _, e := range list {
+		if cert := e.load(); hello.SupportsCertificate(cert) == nil {
+			return cert
+		}
+	}
+	if len(list) > 0 {
+		return list[0].load()
+	}
+	return nil
+}
+
+// getCertificate returns the GetCertificate callback selecting the added
+// certificates by the SNI, and calling the fallback for the other clients.
+func (m *Rum) getCertificate(fallback func(*tls.ClientHelloInfo) (*tls.Certificate, error)) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if cert := m.lookupCert(hello); cert != nil {
+			return cert, nil
+		}
+		if fallback != nil {
+			return fallback(hello)
+		}
+		return nil, nil
+	}
+}
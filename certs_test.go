@@ -0,0 +1,81 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAddCert(t *testing.T) {
+	m := New()
+	example, err := tls.X509KeyPair(testCertPEM, testKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wildcard := example
+	fallback := example
+	m.AddCert("Example.com.", &example)
+	m.AddCert("*.example.org", &wildcard)
+	m.TLSConfig = &tls.Config{GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return &fallback, nil
+	}}
+	config := m.tlsConfig()
+	for _, test := range []struct {
+		name string
+		cert *tls.Certificate
+	}{
+		{"example.com", &example},
+		{"EXAMPLE.COM.", &example},
+		{"www.example.org", &wildcard},
+		{"example.org", &fallback},
+		{"a.www.example.org", &fallback},
+		{"", &fallback},
+	} {
+		cert, err := config.GetCertificate(&tls.ClientHelloInfo{ServerName: test.name})
+		if err != nil || cert != test.cert {
+			t.Error(test.name, err)
+		}
+	}
+	if err := m.AddCertFile("example.net", "", ""); err == nil {
+		t.Error("the loading should fail")
+	}
+}
+
+func TestAddCertServe(t *testing.T) {
+	cf, _ := ioutil.TempFile("", "cert")
+	cf.Write(testCertPEM)
+	cf.Close()
+	defer os.Remove(cf.Name())
+	kf, _ := ioutil.TempFile("", "key")
+	kf.Write(testKeyPEM)
+	kf.Close()
+	defer os.Remove(kf.Name())
+	m := New()
+	if err := m.AddCertFile("localhost", cf.Name(), kf.Name()); err != nil {
+		t.Fatal(err)
+	}
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	})
+	addr := ":8080"
+	done := make(chan struct{})
+	go func() {
+		m.RunTLS(addr, "", "")
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	testHTTPTLS("GET", "https://localhost"+addr+"/", http.StatusOK, "Hello World", t)
+	conn, err := tls.Dial("tcp", "127.0.0.1"+addr, &tls.Config{ServerName: "example.com", InsecureSkipVerify: true})
+	if err == nil {
+		conn.Close()
+		t.Error("the handshake of an unknown host should fail")
+	}
+	m.Close()
+	<-done
+}
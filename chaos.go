@@ -0,0 +1,191 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Fault is a fault injected into a percentage of the requests, for the
+// resilience testing of the clients and the downstreams.
+type Fault struct {
+	// Route is the route pattern of the requests. Empty matches all routes.
+	Route string
+	// Class is the rate-limit class of the entries. Empty matches all classes.
+	Class string
+	// Percent is the percentage of the matched requests injected, in [0, 100].
+	Percent float64
+	// Latency delays the requests before they are served.
+	Latency time.Duration
+	// Status replies to the requests with the error status code, such as
+	// 503, instead of serving them.
+	Status int
+	// Reset resets the connections of the requests instead of serving them.
+	Reset bool
+}
+
+type faultJSON struct {
+	Route   string  `json:"route,omitempty"`
+	Class   string  `json:"class,omitempty"`
+	Percent float64 `json:"percent"`
+	Latency string  `json:"latency,omitempty"`
+	Status  int     `json:"status,omitempty"`
+	Reset   bool    `json:"reset,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface, with the latency
+// as a duration string such as "100ms".
+func (f Fault) MarshalJSON() ([]byte, error) {
+	v := faultJSON{Route: f.Route, Class: f.Class, Percent: f.Percent, Status: f.Status, Reset: f.Reset}
+	if f.Latency > 0 {
+		v.Latency = f.Latency.String()
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (f *Fault) UnmarshalJSON(data []byte) error {
+	var v faultJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*f = Fault{Route: v.Route, Class: v.Class, Percent: v.Percent, Status: v.Status, Reset: v.Reset}
+	if v.Latency != "" {
+		d, err := time.ParseDuration(v.Latency)
+		if err != nil {
+			return err
+		}
+		f.Latency = d
+	}
+	return nil
+}
+
+// Chaos injects the faults into the requests of a Mux. The faults can be
+// replaced at runtime, such as by the admin API.
+type Chaos struct {
+	faults atomic.Value
+	random func() float64
+}
+
+// NewChaos returns a new Chaos injecting the faults.
+func NewChaos(faults ...Fault) *Chaos {
+	c := &Chaos{random: rand.Float64}
+	c.SetFaults(faults...)
+	return c
+}
+
+// SetFaults replaces the faults. The first fault matching a request is
+// injected. No faults disables the injection.
+func (c *Chaos) SetFaults(faults ...Fault) {
+	c.faults.Store(append([]Fault{}, faults...))
+}
+
+// Faults returns the faults.
+func (c *Chaos) Faults() []Fault {
+	return append([]Fault{}, c.faults.Load().([]Fault)...)
+}
+
+// SetChaos sets the fault injection of the requests routed by the Mux and
+// its groups. A nil Chaos disables the injection. The faults can be replaced
+// at runtime by SetFaults.
+//
+// SetChaos must be called before serving.
+func (m *Mux) SetChaos(c *Chaos) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.chaos = c
+}
+
+// Chaos returns the Chaos set by SetChaos, or nil.
+func (m *Mux) Chaos() *Chaos {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+	return m.chaos
+}
+
+// SetChaosAllow sets the paths never injected with the faults, such as the
+// admin paths, so that the Chaos can always be turned off. A path ending in
+// a slash allows all of the paths beginning with it.
+//
+// SetChaosAllow must be called before serving.
+func (m *Mux) SetChaosAllow(paths ...string) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.chaosAllow = paths
+}
+
+// inject injects the fault matching the request, and reports whether the
+// request has been replied or reset.
+func (c *Chaos) inject(entry *Entry, w http.ResponseWriter, r *http.Request) bool {
+	faults := c.faults.Load().([]Fault)
+	if len(faults) == 0 {
+		return false
+	}
+	var route string
+	for _, f := range faults {
+		if f.Class != "" && f.Class != entry.class {
+			continue
+		}
+		if f.Route != "" {
+			if route == "" {
+				route = entry.route()
+			}
+			if f.Route != route {
+				continue
+			}
+		}
+		if f.Percent <= 0 || c.random()*100 >= f.Percent {
+			return false
+		}
+		return f.inject(w, r)
+	}
+	return false
+}
+
+func (f *Fault) inject(w http.ResponseWriter, r *http.Request) bool {
+	if f.Latency > 0 {
+		timer := time.NewTimer(f.Latency)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+		}
+	}
+	if f.Reset {
+		if h, ok := w.(http.Hijacker); ok {
+			if conn, _, err := h.Hijack(); err == nil {
+				reset(conn)
+				return true
+			}
+		}
+	}
+	code := f.Status
+	if code == 0 && f.Reset {
+		// The connection can not be hijacked.
+		code = http.StatusServiceUnavailable
+	}
+	if code > 0 {
+		http.Error(w, strconv.Itoa(code)+" "+StatusText(code), code)
+		return true
+	}
+	return false
+}
+
+// reset closes the conn with a TCP RST instead of a FIN, if possible.
+func reset(conn net.Conn) {
+	c := conn
+	if wc, ok := c.(*writeConn); ok {
+		c = wc.Conn
+	}
+	if tcpConn, ok := c.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	conn.Close()
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChaos(t *testing.T) {
+	c := NewChaos()
+	roll := 0.5
+	c.random = func() float64 { return roll }
+	m := NewMux()
+	m.SetChaos(c)
+	m.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	}).GET().RateLimit("api")
+	m.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}).GET()
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+	if w := serve("/users/1"); w.Body.String() != "Hello World" {
+		t.Error(w.Body.String())
+	}
+	c.SetFaults(Fault{Route: "/users/:id", Percent: 60, Status: http.StatusServiceUnavailable})
+	if w := serve("/users/1"); w.Code != http.StatusServiceUnavailable || w.Body.String() != "503 Service Unavailable\n" {
+		t.Error(w.Code, w.Body.String())
+	}
+	if w := serve("/health"); w.Code != http.StatusOK {
+		t.Error(w.Code)
+	}
+	roll = 0.7
+	if w := serve("/users/1"); w.Code != http.StatusOK {
+		t.Error(w.Code)
+	}
+	c.SetFaults(Fault{Class: "api", Percent: 100, Latency: 20 * time.Millisecond}, Fault{Percent: 100, Status: 500})
+	start := time.Now()
+	if w := serve("/users/1"); w.Code != http.StatusOK || time.Since(start) < 20*time.Millisecond {
+		t.Error(w.Code, time.Since(start))
+	}
+	if w := serve("/health"); w.Code != http.StatusInternalServerError {
+		t.Error(w.Code)
+	}
+	c.SetFaults(Fault{Percent: 100, Reset: true})
+	if w := serve("/health"); w.Code != http.StatusServiceUnavailable {
+		t.Error(w.Code)
+	}
+	c.SetFaults(Fault{Percent: 100})
+	if w := serve("/health"); w.Code != http.StatusOK {
+		t.Error(w.Code)
+	}
+	if faults := c.Faults(); len(faults) != 1 {
+		t.Error(faults)
+	}
+}
+
+func TestChaosReset(t *testing.T) {
+	m := New()
+	m.SetChaos(NewChaos())
+	m.Admin("/admin", AdminToken("secret"))
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	}).GET()
+	addr := ":8080"
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	admin := func(body string) (int, string) {
+		req, _ := http.NewRequest("POST", "http://"+addr+"/admin/chaos", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+	code, body := admin(`{"faults":[{"route":"/","percent":100,"latency":"1ms","reset":true}]}`)
+	var result struct {
+		Faults []Fault `json:"faults"`
+	}
+	if code != http.StatusOK || json.Unmarshal([]byte(body), &result) != nil || len(result.Faults) != 1 ||
+		result.Faults[0].Latency != time.Millisecond || !result.Faults[0].Reset {
+		t.Error(code, body)
+	}
+	if _, err := client.Get("http://" + addr + "/"); err == nil {
+		t.Error("expected reset")
+	}
+	if code, _ := admin(`{"faults":[{"latency":"x"}]}`); code != http.StatusBadRequest {
+		t.Error(code)
+	}
+	if code, _ := admin(`{"faults":[]}`); code != http.StatusOK {
+		t.Error(code)
+	}
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	m.Mux.SetChaos(nil)
+	if code, _ := admin(`{"faults":[]}`); code != http.StatusNotFound {
+		t.Error(code)
+	}
+	m.Close()
+	<-done
+}
+
+func TestChaosAdmin(t *testing.T) {
+	c := NewChaos(Fault{Percent: 100, Status: http.StatusServiceUnavailable})
+	m := New()
+	m.SetChaos(c)
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	})
+	m.Admin("/admin", AdminToken("secret"))
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		return w
+	}
+	if w := serve("GET", "/", ""); w.Code != http.StatusServiceUnavailable {
+		t.Error(w.Code)
+	}
+	if w := serve("POST", "/admin/chaos", `{"faults":[]}`); w.Code != http.StatusOK {
+		t.Error(w.Code, w.Body.String())
+	}
+	if w := serve("GET", "/", ""); w.Code != http.StatusOK {
+		t.Error(w.Code)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+// Package client implements an outbound HTTP client for the handlers of
+// rum calling other services, with pooled connections, per-host limits,
+// budgeted retries, and the deadline and the tracing headers carried from
+// the inbound request.
+//
+//	c := client.New(&client.Options{Retries: 2, RetryBudget: 0.1})
+//	m.HandleFunc("/user/:id", func(w http.ResponseWriter, r *http.Request) {
+//		res, err := c.Get(r, "http://users/"+m.Params(r)["id"])
+//		...
+//	})
+//
+// A Client is safe for concurrent use and should be reused, typically one
+// per upstream service.
+package client
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultPropagateHeaders is the default headers copied from the inbound
+// requests to the outbound requests: the request ID, and the W3C trace
+// context and baggage.
+var DefaultPropagateHeaders = []string{"X-Request-Id", "Traceparent", "Tracestate", "Baggage"}
+
+const maxRetryTokens = 10
+
+// Options configures a Client.
+type Options struct {
+	// Transport performs the requests. If nil, a pooled transport
+	// configured by the options below is used.
+	Transport http.RoundTripper
+	// MaxConnsPerHost is the maximum number of connections per host, the
+	// dials beyond it waiting for a connection. Zero means no limit.
+	MaxConnsPerHost int
+	// MaxIdleConnsPerHost is the maximum number of idle connections kept
+	// per host. If zero, 64 is used.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is the maximum duration of an idle connection. If
+	// zero, 90 seconds is used.
+	IdleConnTimeout time.Duration
+	// DialTimeout is the maximum duration of a dial. If zero, 5 seconds is used.
+	DialTimeout time.Duration
+	// Timeout is the maximum duration of an attempt, bounded by the
+	// deadline of the inbound request. If zero, 30 seconds is used, and a
+	// negative Timeout means no limit.
+	Timeout time.Duration
+	// Retries is the maximum number of retries of an idempotent request,
+	// retried on the errors and the 502, 503 and 504 status codes.
+	Retries int
+	// RetryBudget is the ratio of the retries to the requests. Each request
+	// earns RetryBudget retries, up to 10 saved ones. Zero means no budget.
+	RetryBudget float64
+	// RetryBackoff is the delay before the first retry, doubled by each
+	// retry. If zero, 50 milliseconds is used.
+	RetryBackoff time.Duration
+	// PropagateHeaders holds the headers copied from the inbound requests.
+	// If nil, DefaultPropagateHeaders is used.
+	PropagateHeaders []string
+}
+
+// Client sends the outbound requests.
+type Client struct {
+	client  http.Client
+	options Options
+	mu      sync.Mutex
+	tokens  float64
+}
+
+// New returns a new Client. The options may be nil.
+func New(options *Options) *Client {
+	c := &Client{}
+	if options != nil {
+		c.options = *options
+	}
+	o := &c.options
+	if o.MaxIdleConnsPerHost <= 0 {
+		o.MaxIdleConnsPerHost = 64
+	}
+	if o.IdleConnTimeout <= 0 {
+		o.IdleConnTimeout = 90 * time.Second
+	}
+	if o.DialTimeout <= 0 {
+		o.DialTimeout = 5 * time.Second
+	}
+	if o.Timeout == 0 {
+		o.Timeout = 30 * time.Second
+	}
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = 50 * time.Millisecond
+	}
+	if o.PropagateHeaders == nil {
+		o.PropagateHeaders = DefaultPropagateHeaders
+	}
+	c.client.Transport = o.Transport
+	if c.client.Transport == nil {
+		c.client.Transport = &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   o.DialTimeout,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxConnsPerHost:       o.MaxConnsPerHost,
+			MaxIdleConnsPerHost:   o.MaxIdleConnsPerHost,
+			IdleConnTimeout:       o.IdleConnTimeout,
+			TLSHandshakeTimeout:   o.DialTimeout,
+			ExpectContinueTimeout: time.Second,
+		}
+	}
+	return c
+}
+
+// NewRequest returns a new outbound request carrying the context and the
+// propagated headers of the inbound request, which may be nil.
+func (c *Client) NewRequest(inbound *http.Request, method, url string, body io.Reader) (*http.Request, error) {
+	ctx := context.Background()
+	if inbound != nil {
+		ctx = inbound.Context()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if inbound != nil {
+		for _, k := range c.options.PropagateHeaders {
+			if v := inbound.Header.Get(k); v != "" {
+				req.Header.Set(k, v)
+			}
+		}
+	}
+	return req, nil
+}
+
+// Get issues a GET to the url on behalf of the inbound request.
+func (c *Client) Get(inbound *http.Request, url string) (*http.Response, error) {
+	req, err := c.NewRequest(inbound, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Do sends the request, retrying the idempotent ones within the retries
+// and the retry budget. The bodies of the retried requests are obtained by
+// the GetBody of the request.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	c.earn()
+	for attempt := 0; ; attempt++ {
+		res, err := c.do(req)
+		if attempt >= c.options.Retries || !c.retryable(req, res, err) || !c.retry() {
+			return res, err
+		}
+		if res != nil {
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+		timer := time.NewTimer(c.options.RetryBackoff << uint(attempt))
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+		if req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// CloseIdleConnections closes the idle connections of the transport.
+func (c *Client) CloseIdleConnections() {
+	c.client.CloseIdleConnections()
+}
+
+// do sends the request within the timeout, which is canceled when the
+// body of the response is closed.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.options.Timeout < 0 {
+		return c.client.Do(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), c.options.Timeout)
+	res, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// retryable reports whether the request can be retried after the result.
+func (c *Client) retryable(req *http.Request, res *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+	default:
+		if req.Header.Get("Idempotency-Key") == "" {
+			return false
+		}
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retry reports whether the retry budget allows a retry.
+func (c *Client) retry() bool {
+	if c.options.RetryBudget <= 0 {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tokens < 1 {
+		return false
+	}
+	c.tokens--
+	return true
+}
+
+func (c *Client) earn() {
+	if c.options.RetryBudget <= 0 {
+		return
+	}
+	c.mu.Lock()
+	c.tokens += c.options.RetryBudget
+	if c.tokens > maxRetryTokens {
+		c.tokens = maxRetryTokens
+	}
+	c.mu.Unlock()
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientPropagate(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Request-Id") + " " + r.Header.Get("Traceparent") + " " + r.Header.Get("Cookie")))
+	}))
+	defer s.Close()
+	c := New(nil)
+	defer c.CloseIdleConnections()
+	inbound := httptest.NewRequest("GET", "/", nil)
+	inbound.Header.Set("X-Request-Id", "abc")
+	inbound.Header.Set("Traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	inbound.Header.Set("Cookie", "secret=1")
+	res, err := c.Get(inbound, s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "abc 00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01 " {
+		t.Error(string(body))
+	}
+}
+
+func TestClientDeadline(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer s.Close()
+	c := New(&Options{Retries: 3})
+	defer c.CloseIdleConnections()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	inbound := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	start := time.Now()
+	if _, err := c.Get(inbound, s.URL); err == nil {
+		t.Error("should time out")
+	}
+	if d := time.Since(start); d > time.Millisecond*500 {
+		t.Error(d)
+	}
+	c = New(&Options{Timeout: time.Millisecond * 50})
+	defer c.CloseIdleConnections()
+	if _, err := c.Get(nil, s.URL); err == nil {
+		t.Error("should time out")
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	var count int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&count, 1)%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(body)
+	}))
+	defer s.Close()
+	c := New(&Options{Retries: 2, RetryBackoff: time.Millisecond})
+	defer c.CloseIdleConnections()
+	req, _ := c.NewRequest(nil, "PUT", s.URL, strings.NewReader("hello"))
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != "hello" || atomic.LoadInt32(&count) != 3 {
+		t.Error(res.StatusCode, string(body), count)
+	}
+
+	req, _ = c.NewRequest(nil, "POST", s.URL, strings.NewReader("hello"))
+	if res, err = c.Do(req); err != nil || res.StatusCode != http.StatusServiceUnavailable {
+		t.Error(err)
+	}
+	res.Body.Close()
+
+	atomic.StoreInt32(&count, 0)
+	c = New(&Options{Retries: 2, RetryBudget: 0.5, RetryBackoff: time.Millisecond})
+	defer c.CloseIdleConnections()
+	if res, err = c.Get(nil, s.URL); err != nil || res.StatusCode != http.StatusServiceUnavailable {
+		t.Error(err)
+	}
+	res.Body.Close()
+	if atomic.LoadInt32(&count) != 1 {
+		t.Error(count)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+// Command rum serves a directory or reverse proxies to an upstream.
+//
+// Usage:
+//
+//	rum -addr :8080 -dir ./public
+//	rum -addr :8443 -cert cert.pem -key key.pem -proxy http://127.0.0.1:9000,http://127.0.0.1:9001
+//	rum -poll -fast -gzip -log -dir .
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/hslam/rum"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	addr     string
+	dir      string
+	proxy    string
+	certFile string
+	keyFile  string
+	fast     bool
+	poll     bool
+	compress bool
+	logging  bool
+)
+
+func init() {
+	flag.StringVar(&addr, "addr", ":8080", "listening address")
+	flag.StringVar(&dir, "dir", ".", "directory to serve")
+	flag.StringVar(&proxy, "proxy", "", "comma separated upstream urls to reverse proxy to instead of serving a directory")
+	flag.StringVar(&certFile, "cert", "", "TLS certificate file")
+	flag.StringVar(&keyFile, "key", "", "TLS private key file")
+	flag.BoolVar(&fast, "fast", false, "use the fast request parser")
+	flag.BoolVar(&poll, "poll", false, "use netpoll based on epoll/kqueue")
+	flag.BoolVar(&compress, "gzip", false, "gzip responses when the client accepts it")
+	flag.BoolVar(&logging, "log", false, "log requests")
+}
+
+func main() {
+	flag.Parse()
+	handler, err := newHandler()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rum:", err)
+		os.Exit(2)
+	}
+	if compress {
+		handler = (&rum.Compression{}).Handler(handler)
+	}
+	if logging {
+		handler = logHandler(handler)
+	}
+	server := rum.New()
+	server.SetFast(fast)
+	server.SetPoll(poll)
+	server.Handler = handler
+	if certFile != "" || keyFile != "" {
+		err = server.RunTLS(addr, certFile, keyFile)
+	} else {
+		err = server.Run(addr)
+	}
+	log.Fatal(err)
+}
+
+func newHandler() (http.Handler, error) {
+	if proxy == "" {
+		return http.FileServer(http.Dir(dir)), nil
+	}
+	p, err := rum.NewProxy(strings.Split(proxy, ",")...)
+	if err != nil {
+		return nil, err
+	}
+	p.Balancer = rum.LeastConn()
+	p.Retries = len(p.Upstreams()) - 1
+	return p, nil
+}
+
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *statusResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+func logHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusResponseWriter{ResponseWriter: w}
+		handler.ServeHTTP(sw, r)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		log.Printf("%s %s %s %d %d %s", r.RemoteAddr, r.Method, r.URL.RequestURI(), sw.status, sw.size, time.Since(start))
+	})
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+// Command rumbench is an HTTP load generator.
+//
+// Usage:
+//
+//	rumbench -url http://127.0.0.1:8080/ -c 64 -d 10s
+//
+// It reports the requests per second, the throughput and the
+// latency percentiles.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	url         string
+	method      string
+	concurrency int
+	requests    int64
+	duration    time.Duration
+	bodySize    int
+	keepAlive   bool
+)
+
+func init() {
+	flag.StringVar(&url, "url", "http://127.0.0.1:8080/", "target url")
+	flag.StringVar(&method, "method", "GET", "HTTP method")
+	flag.IntVar(&concurrency, "c", 64, "number of concurrent clients")
+	flag.Int64Var(&requests, "n", 0, "total number of requests, 0 means limited by -d")
+	flag.DurationVar(&duration, "d", 10*time.Second, "test duration")
+	flag.IntVar(&bodySize, "body", 0, "request body size in bytes")
+	flag.BoolVar(&keepAlive, "k", true, "use keep-alive connections")
+}
+
+type result struct {
+	latencies []time.Duration
+	bytes     int64
+	errors    int64
+}
+
+func main() {
+	flag.Parse()
+	if concurrency < 1 {
+		fmt.Fprintln(os.Stderr, "rumbench: -c must be positive")
+		os.Exit(2)
+	}
+	body := bytes.Repeat([]byte("a"), bodySize)
+	client := &http.Client{
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost: concurrency,
+			DisableKeepAlives:   !keepAlive,
+		},
+	}
+	var count int64
+	deadline := time.Now().Add(duration)
+	results := make([]result, concurrency)
+	wg := sync.WaitGroup{}
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(res *result) {
+			defer wg.Done()
+			for {
+				if requests > 0 {
+					if atomic.AddInt64(&count, 1) > requests {
+						return
+					}
+				} else if time.Now().After(deadline) {
+					return
+				}
+				req, err := http.NewRequest(method, url, bytes.NewReader(body))
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "rumbench:", err)
+					os.Exit(1)
+				}
+				t := time.Now()
+				resp, err := client.Do(req)
+				if err != nil {
+					res.errors++
+					continue
+				}
+				n, err := io.Copy(ioutil.Discard, resp.Body)
+				resp.Body.Close()
+				if err != nil || resp.StatusCode >= 500 {
+					res.errors++
+					continue
+				}
+				res.latencies = append(res.latencies, time.Since(t))
+				res.bytes += n
+			}
+		}(&results[i])
+	}
+	wg.Wait()
+	report(results, time.Since(start))
+}
+
+func report(results []result, elapsed time.Duration) {
+	var latencies []time.Duration
+	var transferred, errors int64
+	for _, res := range results {
+		latencies = append(latencies, res.latencies...)
+		transferred += res.bytes
+		errors += res.errors
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	seconds := elapsed.Seconds()
+	fmt.Printf("Requests:   %d\n", len(latencies))
+	fmt.Printf("Errors:     %d\n", errors)
+	fmt.Printf("Duration:   %s\n", elapsed)
+	fmt.Printf("Requests/s: %.2f\n", float64(len(latencies))/seconds)
+	fmt.Printf("Transfer/s: %.2fMB\n", float64(transferred)/seconds/1024/1024)
+	if len(latencies) == 0 {
+		return
+	}
+	for _, p := range []float64{50, 90, 99, 99.9} {
+		fmt.Printf("P%-9v %s\n", p, percentile(latencies, p))
+	}
+	fmt.Printf("Max:        %s\n", latencies[len(latencies)-1])
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted))*p/100+0.5) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
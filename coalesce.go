@@ -0,0 +1,135 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// CoalesceKey returns the key of the requests that are coalesced, or an
+// empty string if the request is not coalesced. It coalesces the GET and
+// HEAD requests without credentials by the method, the host, the URI and
+// the content negotiation headers Accept, Accept-Encoding and
+// Accept-Language.
+func CoalesceKey(r *http.Request) string {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		return ""
+	}
+	if r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+		return ""
+	}
+	return r.Method + " " + r.Host + r.RequestURI +
+		"\n" + strings.Join(r.Header.Values("Accept"), ",") +
+		"\n" + strings.Join(r.Header.Values("Accept-Encoding"), ",") +
+		"\n" + strings.Join(r.Header.Values("Accept-Language"), ",")
+}
+
+type flight struct {
+	wg       sync.WaitGroup
+	code     int
+	header   http.Header
+	body     []byte
+	vary     map[string]string
+	shared   bool
+	panicked bool
+}
+
+type coalescer struct {
+	handler http.Handler
+	key     func(r *http.Request) string
+	flights sync.Map
+}
+
+// CoalesceHandler returns a handler that coalesces the concurrent requests
+// with the same key into a single execution of the handler, and fans the
+// response out to all of them, protecting the slow backends from thundering
+// herds. If the key function is nil, the CoalesceKey is used. The requests
+// not matching the Vary of the response, or all of them with Vary: *, are
+// served by the handler themselves.
+func CoalesceHandler(handler http.Handler, key func(r *http.Request) string) http.Handler {
+	if key == nil {
+		key = CoalesceKey
+	}
+	return &coalescer{handler: handler, key: key}
+}
+
+func (c *coalescer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := c.key(r)
+	if key == "" {
+		c.handler.ServeHTTP(w, r)
+		return
+	}
+	f := &flight{}
+	f.wg.Add(1)
+	if v, loaded := c.flights.LoadOrStore(key, f); loaded {
+		f = v.(*flight)
+		f.wg.Wait()
+		if f.panicked || !f.shared || !varyMatches(f.vary, r) {
+			c.handler.ServeHTTP(w, r)
+			return
+		}
+		f.write(w)
+		return
+	}
+	c.do(f, key, r)
+	f.write(w)
+}
+
+// do serves the request of the flight with the handler and records the response.
+func (c *coalescer) do(f *flight, key string, r *http.Request) {
+	rec := &recordResponseWriter{header: make(http.Header)}
+	f.panicked = true
+	defer func() {
+		c.flights.Delete(key)
+		f.wg.Done()
+	}()
+	c.handler.ServeHTTP(rec, r)
+	if rec.code == 0 {
+		rec.code = http.StatusOK
+	}
+	f.code, f.header, f.body = rec.code, rec.header, rec.body.Bytes()
+	f.vary, f.shared = varyValues(rec.header, r)
+	f.panicked = false
+}
+
+func (f *flight) write(w http.ResponseWriter) {
+	header := w.Header()
+	for k, v := range f.header {
+		header[k] = append([]string(nil), v...)
+	}
+	w.WriteHeader(f.code)
+	w.Write(f.body)
+}
+
+// recordResponseWriter records the response of a handler.
+type recordResponseWriter struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *recordResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *recordResponseWriter) WriteHeader(code int) {
+	if w.code == 0 && !informational(code) {
+		w.code = code
+	}
+}
+
+func (w *recordResponseWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.body.Write(p)
+}
+
+// Flush implements the http.Flusher interface. The recorded response is
+// written by the caller after the handler returns, so there is nothing to
+// flush.
+func (w *recordResponseWriter) Flush() {}
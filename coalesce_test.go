@@ -0,0 +1,139 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalesceHandler(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	handler := CoalesceHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Method == "GET" {
+			<-release
+		}
+		w.Header().Set("X-Backend", "slow")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("Hello World"))
+	}), nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+			if w.Code != http.StatusAccepted || w.Body.String() != "Hello World" || w.Header().Get("X-Backend") != "slow" {
+				t.Error(w.Code, w.Body.String(), w.Header())
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Error(n)
+	}
+	atomic.StoreInt32(&calls, 0)
+	for _, method := range []string{"POST", "PUT"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/slow", nil))
+	}
+	req := httptest.NewRequest("GET", "/slow", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Error(n)
+	}
+}
+
+func TestCoalesceHandlerPanic(t *testing.T) {
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := CoalesceHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+			panic("panic test")
+		}
+		w.Write([]byte("Hello World"))
+	}), func(r *http.Request) string { return "key" })
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			if e := recover(); e == nil {
+				t.Error("should panic")
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+	<-started
+	follower := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		follower <- w
+	}()
+	time.Sleep(time.Millisecond * 10)
+	close(release)
+	<-done
+	if w := <-follower; w.Body.String() != "Hello World" {
+		t.Error(w.Body.String())
+	}
+}
+
+func TestCoalesceHandlerVary(t *testing.T) {
+	gzip := httptest.NewRequest("GET", "/", nil)
+	gzip.Header.Set("Accept-Encoding", "gzip")
+	if CoalesceKey(gzip) == CoalesceKey(httptest.NewRequest("GET", "/", nil)) {
+		t.Error(CoalesceKey(gzip))
+	}
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := CoalesceHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+		}
+		w.Header().Set("Vary", "Origin")
+		w.Write([]byte(r.Header.Get("Origin")))
+	}), func(r *http.Request) string { return "key" })
+	serve := func(origin string) chan string {
+		body := make(chan string, 1)
+		go func() {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Origin", origin)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			body <- w.Body.String()
+		}()
+		return body
+	}
+	leader := serve("a")
+	<-started
+	same, other := serve("a"), serve("b")
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	if body := <-leader; body != "a" {
+		t.Error(body)
+	}
+	if body := <-same; body != "a" {
+		t.Error(body)
+	}
+	if body := <-other; body != "b" {
+		t.Error(body)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Error(n)
+	}
+}
@@ -0,0 +1,299 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultCompressMinSize = 1024
+
+// DefaultCompressTypes is the default media type prefixes of the responses
+// compressed by a Compression.
+var DefaultCompressTypes = []string{"text/", "application/json", "application/javascript",
+	"application/xml", "application/problem+json", "image/svg+xml"}
+
+// CompressWriter is a writer of a content coding, reused by Reset. The
+// *gzip.Writer of the compress/gzip package implements it.
+type CompressWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Encoder creates the pooled writers of a content coding at a level.
+// GzipEncoder returns the gzip one, and the github.com/hslam/rum/zstd
+// module provides the Zstandard one, for example:
+//
+//	c := &rum.Compression{Encoders: []*rum.Encoder{zstd.NewEncoder(zstd.DefaultLevel), rum.GzipEncoder(gzip.DefaultCompression)}}
+type Encoder struct {
+	// Name is the content coding negotiated by the Accept-Encoding, such
+	// as "gzip" or "zstd".
+	Name string
+	// Level is the compression level passed to New.
+	Level int
+	// New returns a new writer of the content coding to w at the level.
+	New func(w io.Writer, level int) (CompressWriter, error)
+
+	pool sync.Pool
+}
+
+// GzipEncoder returns an Encoder of gzip at the level of the compress/gzip package.
+func GzipEncoder(level int) *Encoder {
+	return &Encoder{Name: "gzip", Level: level, New: func(w io.Writer, level int) (CompressWriter, error) {
+		return gzip.NewWriterLevel(w, level)
+	}}
+}
+
+// get returns a pooled writer to w.
+func (e *Encoder) get(w io.Writer) (CompressWriter, error) {
+	if v := e.pool.Get(); v != nil {
+		cw := v.(CompressWriter)
+		cw.Reset(w)
+		return cw, nil
+	}
+	return e.New(w, e.Level)
+}
+
+func (e *Encoder) put(cw CompressWriter) {
+	cw.Reset(nil)
+	e.pool.Put(cw)
+}
+
+var defaultEncoders = []*Encoder{GzipEncoder(gzip.DefaultCompression)}
+
+// Compression compresses the responses with the content coding of the
+// highest quality in the Accept-Encoding of the requests.
+//
+//	c := &rum.Compression{MinSize: 512}
+//	m.Handler = c.Handler(mux)
+type Compression struct {
+	// Encoders holds the encoders by preference, breaking the ties of the
+	// qualities. If empty, gzip at the default level is used.
+	Encoders []*Encoder
+	// MinSize is the minimum size in bytes of the compressed responses. The
+	// smaller ones are replied as is. If zero, 1024 is used.
+	MinSize int
+	// Types holds the media type prefixes of the compressed responses. If
+	// nil, DefaultCompressTypes is used.
+	Types []string
+}
+
+// Handler returns a handler compressing the responses of the handler. The
+// responses with a Content-Encoding, a partial content or without a body
+// are not compressed, and the strong ETags of the compressed responses are
+// weakened.
+func (c *Compression) Handler(handler http.Handler) http.Handler {
+	encoders := c.Encoders
+	if len(encoders) == 0 {
+		encoders = defaultEncoders
+	}
+	minSize := c.MinSize
+	if minSize <= 0 {
+		minSize = defaultCompressMinSize
+	}
+	types := c.Types
+	if types == nil {
+		types = DefaultCompressTypes
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoder := negotiateEncoder(r, encoders)
+		if encoder == nil || r.Method == "HEAD" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressResponseWriter{forwarder: forwarder{w}, encoder: encoder, minSize: minSize, types: types}
+		defer cw.finish()
+		handler.ServeHTTP(expose(cw, w), r)
+	})
+}
+
+// negotiateEncoder returns the encoder of the highest quality in the
+// Accept-Encoding of the request, or nil for the identity.
+func negotiateEncoder(r *http.Request, encoders []*Encoder) *Encoder {
+	var best *Encoder
+	var bestQ float64
+	for _, e := range encoders {
+		if q := encodingQuality(r, e.Name); q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best
+}
+
+// encodingQuality returns the quality of the encoding in the
+// Accept-Encoding of the request, matched by name or else by "*", or zero
+// if the encoding is not accepted.
+func encodingQuality(r *http.Request, encoding string) float64 {
+	q, wildcard := -1.0, -1.0
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(v, ",") {
+			token, params := part, ""
+			if i := strings.IndexByte(part, ';'); i >= 0 {
+				token, params = part[:i], part[i+1:]
+			}
+			token = strings.TrimSpace(token)
+			quality := 1.0
+			if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+				var err error
+				if quality, err = strconv.ParseFloat(params[2:], 64); err != nil {
+					quality = 0
+				}
+			}
+			if strings.EqualFold(token, encoding) {
+				q = quality
+			} else if token == "*" {
+				wildcard = quality
+			}
+		}
+	}
+	if q >= 0 {
+		return q
+	} else if wildcard >= 0 {
+		return wildcard
+	}
+	return 0
+}
+
+// compressResponseWriter buffers the body up to the minimum size before
+// deciding whether to compress it.
+type compressResponseWriter struct {
+	forwarder
+	encoder *Encoder
+	minSize int
+	types   []string
+	code    int
+	buf     []byte
+	decided bool
+	writer  CompressWriter
+}
+
+func (w *compressResponseWriter) WriteHeader(code int) {
+	if w.decided || informational(code) {
+		w.ResponseWriter.WriteHeader(code)
+	} else if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *compressResponseWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if !w.decided {
+		if len(w.buf)+len(p) < w.minSize {
+			w.buf = append(w.buf, p...)
+			return len(p), nil
+		}
+		if err := w.decide(append(w.buf, p...), true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.writer != nil {
+		return w.writer.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// decide writes the header and the buffered body, compressing them if the
+// response is large enough and compressible.
+func (w *compressResponseWriter) decide(body []byte, large bool) error {
+	w.decided = true
+	w.buf = nil
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	header := w.Header()
+	if _, ok := header["Content-Type"]; !ok && len(body) > 0 {
+		header.Set("Content-Type", http.DetectContentType(body))
+	}
+	if large && w.compressible(header) {
+		writer, err := w.encoder.get(w.ResponseWriter)
+		if err != nil {
+			return err
+		}
+		w.writer = writer
+		header.Set("Content-Encoding", w.encoder.Name)
+		header.Del("Content-Length")
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		w.ResponseWriter.WriteHeader(w.code)
+		_, err = w.writer.Write(body)
+		return err
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+	if len(body) > 0 {
+		_, err := w.ResponseWriter.Write(body)
+		return err
+	}
+	return nil
+}
+
+func (w *compressResponseWriter) compressible(header http.Header) bool {
+	if w.code == http.StatusPartialContent || !bodyAllowedForStatus(w.code) || header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	for _, t := range w.types {
+		if strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// finish writes the buffered response, or closes the compressor.
+func (w *compressResponseWriter) finish() {
+	if !w.decided {
+		if w.code == 0 {
+			return
+		}
+		w.decide(w.buf, false)
+	}
+	if w.writer != nil {
+		w.writer.Close()
+		w.encoder.put(w.writer)
+		w.writer = nil
+	}
+}
+
+// Flush implements the http.Flusher interface, compressing the response
+// if compressible regardless of the size.
+func (w *compressResponseWriter) Flush() {
+	w.FlushError()
+}
+
+// FlushError implements the error-returning Flush.
+func (w *compressResponseWriter) FlushError() error {
+	if !w.decided {
+		if err := w.decide(w.buf, true); err != nil {
+			return err
+		}
+	}
+	if w.writer != nil {
+		if err := w.writer.Flush(); err != nil {
+			return err
+		}
+	}
+	return Flush(w.ResponseWriter)
+}
+
+// Hijack implements the http.Hijacker interface.
+func (w *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.decided = true
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testZstdEncoder stands for a zstd encoder with the flate writer.
+func testZstdEncoder() *Encoder {
+	return &Encoder{Name: "zstd", Level: flate.BestSpeed, New: func(w io.Writer, level int) (CompressWriter, error) {
+		return flate.NewWriter(w, level)
+	}}
+}
+
+func TestCompression(t *testing.T) {
+	text := strings.Repeat("Hello World ", 200)
+	zstd := testZstdEncoder()
+	c := &Compression{Encoders: []*Encoder{zstd, GzipEncoder(gzip.BestSpeed)}}
+	mux := NewMux()
+	mux.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(text[:1000]))
+		w.Write([]byte(text[1000:]))
+	}).GET().HEAD()
+	mux.HandleFunc("/small", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	}).GET()
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte(text))
+	}).GET()
+	mux.HandleFunc("/encoded", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		w.Write([]byte(text))
+	}).GET()
+	mux.HandleFunc("/created", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(text))
+	}).POST()
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+	}).GET()
+	handler := c.Handler(mux)
+	serve := func(method, target, acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	decode := func(encoding string, body io.Reader) string {
+		var rd io.Reader
+		switch encoding {
+		case "gzip":
+			gr, err := gzip.NewReader(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rd = gr
+		case "zstd":
+			rd = flate.NewReader(body)
+		default:
+			rd = body
+		}
+		b, err := ioutil.ReadAll(rd)
+		if err != nil {
+			t.Error(err)
+		}
+		return string(b)
+	}
+	for _, test := range []struct {
+		acceptEncoding, encoding string
+	}{
+		{"gzip, zstd", "zstd"},
+		{"gzip", "gzip"},
+		{"zstd;q=0.5, gzip", "gzip"},
+		{"*", "zstd"},
+		{"identity", ""},
+		{"", ""},
+	} {
+		for i := 0; i < 2; i++ {
+			w := serve("GET", "/text", test.acceptEncoding)
+			if w.Header().Get("Content-Encoding") != test.encoding || w.Header().Get("Vary") != "Accept-Encoding" ||
+				w.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+				t.Error(test.acceptEncoding, w.Header())
+			}
+			if etag := w.Header().Get("ETag"); test.encoding != "" && etag != `W/"v1"` || test.encoding == "" && etag != `"v1"` {
+				t.Error(test.acceptEncoding, etag)
+			}
+			if body := decode(test.encoding, w.Body); body != text {
+				t.Error(test.acceptEncoding, len(body))
+			}
+		}
+	}
+	for _, target := range []string{"/small", "/image", "/encoded"} {
+		if w := serve("GET", target, "gzip"); w.Header().Get("Content-Encoding") == "gzip" || w.Code != http.StatusOK {
+			t.Error(target, w.Header())
+		}
+	}
+	if w := serve("HEAD", "/text", "gzip"); w.Header().Get("Content-Encoding") != "" {
+		t.Error(w.Header())
+	}
+	if w := serve("POST", "/created", "gzip"); w.Code != http.StatusCreated || decode(w.Header().Get("Content-Encoding"), w.Body) != text ||
+		w.Header().Get("Content-Encoding") != "gzip" {
+		t.Error(w.Code, w.Header())
+	}
+	if w := serve("GET", "/stream", "gzip"); w.Header().Get("Content-Encoding") != "gzip" || decode("gzip", w.Body) != "data: 1\n\n" {
+		t.Error(w.Header())
+	}
+}
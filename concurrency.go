@@ -0,0 +1,18 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+// MaxConcurrency limits the number of the requests served by the entry at
+// the same time to n, replying 503 Service Unavailable with a Retry-After
+// header to the requests beyond, so the expensive endpoints like report
+// generation are protected without throttling the whole server.
+// Zero or less removes the limit. It should be called before serving.
+func (entry *Entry) MaxConcurrency(n int) *Entry {
+	if n > 0 {
+		entry.concurrency = make(chan struct{}, n)
+	} else {
+		entry.concurrency = nil
+	}
+	return entry
+}
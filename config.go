@@ -0,0 +1,203 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrInvalidConfig is the error wrapped by the validation errors of a Config.
+var ErrInvalidConfig = errors.New("Invalid Config")
+
+// Config is the configuration of a Server. The zero value is the default
+// configuration of New. The config tags are the keys of LoadConfig.
+type Config struct {
+	// Fast enables the simple request parser. See SetFast.
+	Fast bool `config:"fast"`
+	// Poll enables the netpoll based on epoll/kqueue. See SetPoll.
+	Poll bool `config:"poll"`
+	// Shards is the number of the shards in the poll mode. See SetShards.
+	Shards int `config:"shards"`
+	// ShardAffinity locks the accept loop of each shard to an OS thread.
+	// See SetShardAffinity.
+	ShardAffinity bool `config:"shard_affinity"`
+	// ReadTimeout is the maximum duration of reading a request. Zero means
+	// no timeout. See SetReadTimeout.
+	ReadTimeout time.Duration `config:"read_timeout"`
+	// WriteTimeout is the maximum duration of writing a response. Zero means
+	// no timeout. See SetWriteTimeout.
+	WriteTimeout time.Duration `config:"write_timeout"`
+	// MaxBodySize is the maximum size in bytes of the request bodies. Zero
+	// means no limit. See SetMaxBodySize.
+	MaxBodySize int64 `config:"max_body_size"`
+	// MaxAcceptErrors is the maximum number of consecutive temporary Accept
+	// errors. Zero means retrying forever. See SetMaxAcceptErrors.
+	MaxAcceptErrors int `config:"max_accept_errors"`
+	// FdWatermark is the fraction of the file descriptor limit above which
+	// the new connections are shed. Zero disables the load shedding.
+	// See SetFdWatermark.
+	FdWatermark float64 `config:"fd_watermark"`
+	// ShedReply replies 503 Service Unavailable to the shed connections.
+	// See SetShedReply.
+	ShedReply bool `config:"shed_reply"`
+	// ServerName is the Server header of the responses. See SetServerName.
+	ServerName string `config:"server_name"`
+	// NoDate disables the Date header. See SetNoDate.
+	NoDate bool `config:"no_date"`
+	// NoSniff disables the automatic Content-Type detection. See SetNoSniff.
+	NoSniff bool `config:"no_sniff"`
+	// ResponseBuffer is the size in bytes of the response buffer. Zero uses
+	// DefaultResponseBuffer. See SetResponseBuffer.
+	ResponseBuffer int `config:"response_buffer"`
+	// MaxHeaders is the maximum number of the header fields of the
+	// requests. Zero means no limit. See SetMaxHeaders.
+	MaxHeaders int `config:"max_headers"`
+	// MaxHeaderLength is the maximum length in bytes of a header field.
+	// Zero means no limit. See SetMaxHeaderLength.
+	MaxHeaderLength int `config:"max_header_length"`
+	// AdaptiveBuffers enables the adaptive buffers. See SetAdaptiveBuffers.
+	AdaptiveBuffers bool `config:"adaptive_buffers"`
+	// TLSConfig is the TLS configuration of Serve and ServeTLS.
+	TLSConfig *tls.Config
+	// ErrorLog logs the errors of the connections. If nil, the standard
+	// logger is used. See SetErrorLog.
+	ErrorLog *log.Logger
+}
+
+// Validate returns an error wrapping ErrInvalidConfig if the Config is invalid.
+func (c *Config) Validate() error {
+	switch {
+	case c.Shards < 0:
+		return fmt.Errorf("%w: negative shards %d", ErrInvalidConfig, c.Shards)
+	case c.Shards > 1 && !c.Poll:
+		return fmt.Errorf("%w: shards require poll", ErrInvalidConfig)
+	case c.ShardAffinity && c.Shards <= 1:
+		return fmt.Errorf("%w: shard affinity requires shards", ErrInvalidConfig)
+	case c.ReadTimeout < 0:
+		return fmt.Errorf("%w: negative read timeout %v", ErrInvalidConfig, c.ReadTimeout)
+	case c.WriteTimeout < 0:
+		return fmt.Errorf("%w: negative write timeout %v", ErrInvalidConfig, c.WriteTimeout)
+	case c.MaxBodySize < 0:
+		return fmt.Errorf("%w: negative max body size %d", ErrInvalidConfig, c.MaxBodySize)
+	case c.MaxHeaders < 0:
+		return fmt.Errorf("%w: negative max headers %d", ErrInvalidConfig, c.MaxHeaders)
+	case c.MaxHeaderLength < 0:
+		return fmt.Errorf("%w: negative max header length %d", ErrInvalidConfig, c.MaxHeaderLength)
+	case c.MaxAcceptErrors < 0:
+		return fmt.Errorf("%w: negative max accept errors %d", ErrInvalidConfig, c.MaxAcceptErrors)
+	case c.FdWatermark < 0 || c.FdWatermark > 1:
+		return fmt.Errorf("%w: fd watermark %v not in [0, 1]", ErrInvalidConfig, c.FdWatermark)
+	case c.ShedReply && c.FdWatermark == 0:
+		return fmt.Errorf("%w: shed reply requires fd watermark", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// NewWithConfig returns a new Rum instance configured by the Config,
+// or an error if the Config is invalid.
+func NewWithConfig(c Config) (*Rum, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	m := New()
+	m.SetFast(c.Fast)
+	m.SetPoll(c.Poll)
+	m.SetShards(c.Shards)
+	m.SetShardAffinity(c.ShardAffinity)
+	m.SetReadTimeout(c.ReadTimeout)
+	m.SetWriteTimeout(c.WriteTimeout)
+	m.SetMaxBodySize(c.MaxBodySize)
+	m.SetMaxAcceptErrors(c.MaxAcceptErrors)
+	if c.FdWatermark > 0 {
+		m.SetFdWatermark(c.FdWatermark)
+	}
+	m.SetShedReply(c.ShedReply)
+	m.SetServerName(c.ServerName)
+	m.SetNoDate(c.NoDate)
+	m.SetNoSniff(c.NoSniff)
+	m.SetResponseBuffer(c.ResponseBuffer)
+	m.SetMaxHeaders(c.MaxHeaders)
+	m.SetMaxHeaderLength(c.MaxHeaderLength)
+	m.SetAdaptiveBuffers(c.AdaptiveBuffers)
+	m.TLSConfig = c.TLSConfig
+	m.SetErrorLog(c.ErrorLog)
+	return m, nil
+}
+
+// Option configures a Config.
+type Option func(c *Config)
+
+// NewWithOptions returns a new Rum instance configured by the options,
+// or an error if the resulting Config is invalid.
+//
+//	m, err := rum.NewWithOptions(rum.WithPoll(), rum.WithTimeouts(5*time.Second, 10*time.Second))
+func NewWithOptions(opts ...Option) (*Rum, error) {
+	var c Config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return NewWithConfig(c)
+}
+
+// WithFast enables the simple request parser.
+func WithFast() Option {
+	return func(c *Config) { c.Fast = true }
+}
+
+// WithPoll enables the netpoll based on epoll/kqueue.
+func WithPoll() Option {
+	return func(c *Config) { c.Poll = true }
+}
+
+// WithShards sets the number of the shards in the poll mode.
+func WithShards(n int) Option {
+	return func(c *Config) { c.Shards = n }
+}
+
+// WithTimeouts sets the read timeout and the write timeout.
+func WithTimeouts(read, write time.Duration) Option {
+	return func(c *Config) {
+		c.ReadTimeout = read
+		c.WriteTimeout = write
+	}
+}
+
+// WithMaxBodySize sets the maximum size in bytes of the request bodies.
+func WithMaxBodySize(n int64) Option {
+	return func(c *Config) { c.MaxBodySize = n }
+}
+
+// WithFdWatermark sets the file descriptor watermark of the load shedding,
+// replying 503 Service Unavailable to the shed connections if reply is true.
+func WithFdWatermark(watermark float64, reply bool) Option {
+	return func(c *Config) {
+		c.FdWatermark = watermark
+		c.ShedReply = reply
+	}
+}
+
+// WithServerName sets the Server header of the responses.
+func WithServerName(name string) Option {
+	return func(c *Config) { c.ServerName = name }
+}
+
+// WithTLS sets the TLS configuration.
+func WithTLS(config *tls.Config) Option {
+	return func(c *Config) { c.TLSConfig = config }
+}
+
+// WithLogger sets the logger of the errors of the connections.
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Config) { c.ErrorLog = logger }
+}
+
+// WithConfig replaces the Config, for applying options on top of a base
+// configuration.
+func WithConfig(config Config) Option {
+	return func(c *Config) { *c = config }
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewWithConfig(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	tlsConfig := &tls.Config{}
+	m, err := NewWithConfig(Config{
+		Fast:            true,
+		ReadTimeout:     time.Second,
+		WriteTimeout:    2 * time.Second,
+		MaxBodySize:     1024,
+		MaxAcceptErrors: 3,
+		ServerName:      "rum",
+		NoDate:          true,
+		NoSniff:         true,
+		AdaptiveBuffers: true,
+		TLSConfig:       tlsConfig,
+		ErrorLog:        logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.fast || m.poll || m.readTimeout != time.Second || m.writeTimeout != 2*time.Second ||
+		m.maxBodySize != 1024 || m.maxAcceptErrors != 3 || m.serverName[0] != "rum" ||
+		!m.noDate || !m.noSniff || !m.adaptiveBuffers || m.TLSConfig != tlsConfig || m.errorLog != logger {
+		t.Error(m)
+	}
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	}).GET()
+	addr := ":8080"
+	m.TLSConfig = nil
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	m.Close()
+	<-done
+}
+
+func TestNewWithOptions(t *testing.T) {
+	logger := log.New(bytes.NewBuffer(nil), "", 0)
+	m, err := NewWithOptions(
+		WithConfig(Config{NoDate: true}),
+		WithFast(),
+		WithPoll(),
+		WithShards(2),
+		WithTimeouts(time.Second, 2*time.Second),
+		WithMaxBodySize(1024),
+		WithFdWatermark(0.9, true),
+		WithServerName("rum"),
+		WithTLS(&tls.Config{}),
+		WithLogger(logger),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.noDate || !m.fast || !m.poll || m.shards != 2 || m.readTimeout != time.Second ||
+		m.writeTimeout != 2*time.Second || m.maxBodySize != 1024 || m.fdWatermark != 0.9 ||
+		!m.shedReply || m.serverName[0] != "rum" || m.TLSConfig == nil || m.errorLog != logger {
+		t.Error(m)
+	}
+	if m, err := NewWithOptions(); err != nil || m.fast || m.serverName != nil {
+		t.Error(err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	invalid := []Config{
+		{Shards: -1},
+		{Shards: 2},
+		{ShardAffinity: true},
+		{ReadTimeout: -1},
+		{WriteTimeout: -1},
+		{MaxBodySize: -1},
+		{MaxAcceptErrors: -1},
+		{FdWatermark: 1.5},
+		{ShedReply: true},
+	}
+	for _, c := range invalid {
+		if _, err := NewWithConfig(c); !errors.Is(err, ErrInvalidConfig) {
+			t.Error(c, err)
+		}
+	}
+	if err := (&Config{Poll: true, Shards: 4, ShardAffinity: true, FdWatermark: 1}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultEnvPrefix is the prefix of the environment variables of LoadConfig.
+const DefaultEnvPrefix = "RUM_"
+
+// LoadConfig loads the Config from the file, if the path is not empty, then
+// from the environment variables, and validates it. The environment
+// variables take precedence over the file, which takes precedence over the
+// defaults. See LoadFile and LoadEnv.
+//
+//	c, err := rum.LoadConfig(os.Getenv("RUM_CONFIG"), rum.DefaultEnvPrefix)
+func LoadConfig(path string, envPrefix string) (Config, error) {
+	var c Config
+	if path != "" {
+		if err := c.LoadFile(path); err != nil {
+			return c, err
+		}
+	}
+	if err := c.LoadEnv(envPrefix); err != nil {
+		return c, err
+	}
+	return c, c.Validate()
+}
+
+// LoadFile loads the keys of the JSON file, or the YAML file if the
+// extension is .yaml or .yml, into the Config. The keys are the config tags
+// of the Config, such as "read_timeout". The durations are strings like
+// "5s". Only the flat YAML mappings of scalars are supported. An unknown
+// key or an invalid value is an error wrapping ErrInvalidConfig.
+func (c *Config) LoadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var values map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		values, err = parseYAML(data)
+	default:
+		values, err = parseJSON(data)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidConfig, path, err)
+	}
+	for key, value := range values {
+		if err := c.set(key, value); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidConfig, path, err)
+		}
+	}
+	return nil
+}
+
+// LoadEnv loads the environment variables of the prefix into the Config.
+// The name of a variable is the prefix and the upper case config tag,
+// such as RUM_READ_TIMEOUT. An invalid value is an error wrapping
+// ErrInvalidConfig.
+func (c *Config) LoadEnv(prefix string) error {
+	for _, key := range configKeys() {
+		name := prefix + strings.ToUpper(key)
+		if value, ok := os.LookupEnv(name); ok {
+			if err := c.set(key, value); err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrInvalidConfig, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// configKeys returns the config tags of the Config.
+func configKeys() []string {
+	var keys []string
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("config"); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// set sets the field of the config tag key to the value.
+func (c *Config) set(key, value string) error {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("config") != key {
+			continue
+		}
+		field := v.Field(i)
+		value = strings.TrimSpace(value)
+		switch field.Interface().(type) {
+		case time.Duration:
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+			field.SetInt(int64(d))
+			return nil
+		}
+		switch field.Kind() {
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s: invalid bool %q", key, value)
+			}
+			field.SetBool(b)
+		case reflect.Int, reflect.Int64:
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s: invalid integer %q", key, value)
+			}
+			field.SetInt(n)
+		case reflect.Float64:
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("%s: invalid number %q", key, value)
+			}
+			field.SetFloat(f)
+		case reflect.String:
+			field.SetString(value)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown key %q", key)
+}
+
+// parseJSON parses the JSON object of scalars into the strings.
+func parseJSON(data []byte) (map[string]string, error) {
+	var object map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(object))
+	for key, value := range object {
+		switch value := value.(type) {
+		case string:
+			values[key] = value
+		case json.Number:
+			values[key] = value.String()
+		case bool:
+			values[key] = strconv.FormatBool(value)
+		default:
+			return nil, fmt.Errorf("%s: not a scalar", key)
+		}
+	}
+	return values, nil
+}
+
+// parseYAML parses the flat YAML mapping of scalars into the strings.
+func parseYAML(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || trimmed[0] == '#' || trimmed == "---" {
+			continue
+		}
+		if text[0] == ' ' || text[0] == '\t' || trimmed[0] == '-' {
+			return nil, fmt.Errorf("line %d: not a flat mapping", line)
+		}
+		i := strings.Index(trimmed, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("line %d: missing colon", line)
+		}
+		key, value := strings.TrimSpace(trimmed[:i]), strings.TrimSpace(trimmed[i+1:])
+		if len(value) > 1 && (value[0] == '"' || value[0] == '\'') {
+			end := strings.IndexByte(value[1:], value[0])
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			value = value[1 : end+1]
+		} else {
+			if j := strings.Index(" "+value, " #"); j >= 0 {
+				value = strings.TrimSpace(value[:j])
+			}
+			if value == "" {
+				return nil, fmt.Errorf("line %d: %s: not a scalar", line, key)
+			}
+		}
+		values[key] = value
+	}
+	return values, scanner.Err()
+}
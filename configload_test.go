@@ -0,0 +1,97 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "rum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigYAML(t *testing.T) {
+	path := writeConfigFile(t, "rum.yaml", `---
+# rum
+poll: true
+shards: 4 # per CPU
+read_timeout: 5s
+write_timeout: "10s"
+server_name: 'rum #1'
+fd_watermark: 0.9
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+	os.Setenv("TEST_RUM_WRITE_TIMEOUT", "20s")
+	os.Setenv("TEST_RUM_NO_DATE", "true")
+	defer os.Unsetenv("TEST_RUM_WRITE_TIMEOUT")
+	defer os.Unsetenv("TEST_RUM_NO_DATE")
+	c, err := LoadConfig(path, "TEST_RUM_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Poll || c.Shards != 4 || c.ReadTimeout != 5*time.Second || c.WriteTimeout != 20*time.Second ||
+		c.ServerName != "rum #1" || c.FdWatermark != 0.9 || !c.NoDate {
+		t.Error(c)
+	}
+}
+
+func TestLoadConfigJSON(t *testing.T) {
+	path := writeConfigFile(t, "rum.json", `{"fast": true, "max_body_size": 1048576, "read_timeout": "1m"}`)
+	defer os.RemoveAll(filepath.Dir(path))
+	c, err := LoadConfig(path, DefaultEnvPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Fast || c.MaxBodySize != 1<<20 || c.ReadTimeout != time.Minute {
+		t.Error(c)
+	}
+	if c, err := LoadConfig("", "TEST_RUM_MISSING_"); err != nil || c.Fast {
+		t.Error(c, err)
+	}
+	if _, err := LoadConfig(path+".missing", DefaultEnvPrefix); err == nil || errors.Is(err, ErrInvalidConfig) {
+		t.Error(err)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	files := map[string]string{
+		"unknown.json":  `{"unknown": 1}`,
+		"nested.json":   `{"fast": {"a": 1}}`,
+		"bool.json":     `{"fast": "yes"}`,
+		"syntax.json":   `{`,
+		"duration.yaml": "read_timeout: 5",
+		"int.yaml":      "shards: four",
+		"float.yaml":    "fd_watermark: high",
+		"nested.yaml":   "tls:\n  cert: a",
+		"list.yaml":     "- a",
+		"colon.yaml":    "fast",
+		"quote.yaml":    "server_name: \"rum",
+		"invalid.yaml":  "shards: 2",
+	}
+	for name, content := range files {
+		path := writeConfigFile(t, name, content)
+		if _, err := LoadConfig(path, "TEST_RUM_MISSING_"); !errors.Is(err, ErrInvalidConfig) {
+			t.Error(name, err)
+		}
+		os.RemoveAll(filepath.Dir(path))
+	}
+	os.Setenv("TEST_RUM_SHARDS", "x")
+	defer os.Unsetenv("TEST_RUM_SHARDS")
+	if _, err := LoadConfig("", "TEST_RUM_"); !errors.Is(err, ErrInvalidConfig) || err.Error() != `Invalid Config: TEST_RUM_SHARDS: shards: invalid integer "x"` {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/hslam/request"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+)
+
+// Conformance makes the entries registered afterwards to the Mux,
+// typically in a Group, conformance entries.
+func (m *Mux) Conformance() {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.conformance = true
+}
+
+// Conformance marks the entry to always use the net/http request parser,
+// even when the Server uses the fast request parser, for the routes needing
+// chunked bodies, trailers or exotic headers.
+func (entry *Entry) Conformance() *Entry {
+	if !entry.conformance {
+		entry.conformance = true
+		if entry.conformances != nil {
+			atomic.AddInt32(entry.conformances, 1)
+		}
+	}
+	return entry
+}
+
+// conformant reports whether the path matches a conformance entry.
+func (m *Mux) conformant(path string) bool {
+	m.mut.RLock()
+	entry := m.searchEntry(m.replace(path), nil, nil)
+	m.mut.RUnlock()
+	return entry != nil && entry.conformance
+}
+
+// readFastRequest reads a request with the fast request parser, or with
+// the net/http request parser when the request targets a conformance
+// entry, has a Transfer-Encoding or a head larger than the buffer. The
+// ambiguous requests are rejected with ErrAmbiguousRequest. It reports
+// whether the fast request parser is used, in which case the request must
+// be freed.
+func (m *Rum) readFastRequest(reader *bufio.Reader, handler http.Handler) (*http.Request, bool, error) {
+	if handler == http.Handler(m) && atomic.LoadInt32(m.Mux.conformances) > 0 {
+		if path, ok := peekRequestPath(reader); ok && m.Mux.conformant(path) {
+			req, err := m.readRequest(reader)
+			return req, false, err
+		}
+	}
+	if fast, err := checkFastRequest(reader); err != nil {
+		return nil, false, err
+	} else if !fast {
+		req, err := m.readRequest(reader)
+		return req, false, err
+	}
+	if err := m.scanHeaders(reader); err != nil {
+		return nil, false, err
+	}
+	req, err := request.ReadFastRequest(reader)
+	if err == nil {
+		absoluteForm(req)
+		if err = m.limitHeaders(req, true); err != nil {
+			request.FreeRequest(req)
+			return nil, false, err
+		}
+	}
+	return req, true, err
+}
+
+// peekRequestPath returns the path of the request line without consuming it.
+func peekRequestPath(reader *bufio.Reader) (string, bool) {
+	for n := 1; n <= reader.Size(); {
+		if _, err := reader.Peek(n); err != nil {
+			return "", false
+		}
+		b, _ := reader.Peek(reader.Buffered())
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			fields := bytes.Fields(b[:i])
+			if len(fields) != 3 {
+				return "", false
+			}
+			u, err := url.ParseRequestURI(string(fields[1]))
+			if err != nil {
+				return "", false
+			}
+			return u.Path, true
+		}
+		n = len(b) + 1
+	}
+	return "", false
+}
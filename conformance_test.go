@@ -0,0 +1,93 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPeekRequestPath(t *testing.T) {
+	tests := []struct {
+		line string
+		path string
+		ok   bool
+	}{
+		{"GET /foo?a=b HTTP/1.1\r\n", "/foo", true},
+		{"GET http://example.com/bar HTTP/1.1\r\n", "/bar", true},
+		{"GET /foo\r\n", "", false},
+		{"GET foo HTTP/1.1\r\n", "", false},
+		{"GET /foo HTTP/1.1", "", false},
+	}
+	for _, test := range tests {
+		reader := bufio.NewReader(strings.NewReader(test.line))
+		path, ok := peekRequestPath(reader)
+		if path != test.path || ok != test.ok {
+			t.Error(test.line, path, ok)
+		}
+		if reader.Buffered() != len(test.line) {
+			t.Error(reader.Buffered())
+		}
+	}
+}
+
+func TestConformance(t *testing.T) {
+	m := New()
+	m.SetFast(true)
+	m.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fast"))
+	})
+	m.HandleFunc("/chunked", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}).Conformance()
+	m.Group("/strict", func(m *Mux) {
+		m.Conformance()
+		m.HandleFunc("/:id", func(w http.ResponseWriter, r *http.Request) {})
+	})
+	if *m.conformances != 2 {
+		t.Error(*m.conformances)
+	}
+	tests := []struct {
+		request string
+		fast    bool
+	}{
+		{"GET /fast HTTP/1.1\r\nHost: localhost\r\n\r\n", true},
+		{"POST /chunked HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n", false},
+		{"GET /strict/1 HTTP/1.1\r\nHost: localhost\r\n\r\n", false},
+	}
+	for _, test := range tests {
+		req, fast, err := m.readFastRequest(bufio.NewReader(strings.NewReader(test.request)), m)
+		if err != nil {
+			t.Error(err)
+		} else if fast != test.fast {
+			t.Error(req.URL.Path, fast)
+		}
+	}
+	addr := ":8080"
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Post("http://"+addr+"/chunked", "text/plain", ioutil.NopCloser(strings.NewReader("chunked body")))
+	if err != nil {
+		t.Error(err)
+	} else {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "chunked body" {
+			t.Error(string(body))
+		}
+	}
+	testHTTP("GET", "http://"+addr+"/fast", http.StatusOK, "fast", t)
+	m.Close()
+	<-done
+}
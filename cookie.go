@@ -0,0 +1,164 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrCookieKeysEmpty is the error returned by the signed and encrypted cookie
+// functions when no key is set.
+var ErrCookieKeysEmpty = errors.New("Cookie keys must be not empty")
+
+// ErrCookieInvalid is the error returned by GetSignedCookie and GetEncryptedCookie
+// when the cookie can not be verified or decrypted.
+var ErrCookieInvalid = errors.New("Cookie invalid")
+
+// SetCookieKeys sets the HMAC keys of the signed cookies.
+// The first key signs the cookies, all of the keys verify them,
+// so a new key can be prepended to rotate the keys.
+func (m *Rum) SetCookieKeys(keys ...[]byte) {
+	m.mut.Lock()
+	m.cookieKeys = keys
+	m.mut.Unlock()
+}
+
+// SetCookieEncryptionKeys sets the AES-GCM keys of the encrypted cookies.
+// Each key must be 16, 24 or 32 bytes long. The first key encrypts the
+// cookies, all of the keys decrypt them.
+func (m *Rum) SetCookieEncryptionKeys(keys ...[]byte) error {
+	aeads := make([]cipher.AEAD, 0, len(keys))
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+		aeads = append(aeads, aead)
+	}
+	m.mut.Lock()
+	m.cookieAEADs = aeads
+	m.mut.Unlock()
+	return nil
+}
+
+// SetSignedCookie adds a Set-Cookie header with the value of the cookie
+// signed by the first cookie key.
+func (m *Rum) SetSignedCookie(w http.ResponseWriter, cookie *http.Cookie) error {
+	m.mut.Lock()
+	keys := m.cookieKeys
+	m.mut.Unlock()
+	if len(keys) == 0 {
+		return ErrCookieKeysEmpty
+	}
+	signed := *cookie
+	value := base64.RawURLEncoding.EncodeToString([]byte(cookie.Value))
+	signed.Value = value + "." + base64.RawURLEncoding.EncodeToString(signCookie(keys[0], cookie.Name, value))
+	http.SetCookie(w, &signed)
+	return nil
+}
+
+// GetSignedCookie returns the named cookie of the request with the value
+// verified by one of the cookie keys.
+func (m *Rum) GetSignedCookie(r *http.Request, name string) (*http.Cookie, error) {
+	m.mut.Lock()
+	keys := m.cookieKeys
+	m.mut.Unlock()
+	if len(keys) == 0 {
+		return nil, ErrCookieKeysEmpty
+	}
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return nil, err
+	}
+	i := strings.LastIndexByte(cookie.Value, '.')
+	if i < 0 {
+		return nil, ErrCookieInvalid
+	}
+	value := cookie.Value[:i]
+	sig, err := base64.RawURLEncoding.DecodeString(cookie.Value[i+1:])
+	if err != nil {
+		return nil, ErrCookieInvalid
+	}
+	for _, key := range keys {
+		if hmac.Equal(sig, signCookie(key, name, value)) {
+			raw, err := base64.RawURLEncoding.DecodeString(value)
+			if err != nil {
+				return nil, ErrCookieInvalid
+			}
+			cookie.Value = string(raw)
+			return cookie, nil
+		}
+	}
+	return nil, ErrCookieInvalid
+}
+
+func signCookie(key []byte, name, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name))
+	mac.Write([]byte{'='})
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// SetEncryptedCookie adds a Set-Cookie header with the value of the cookie
+// encrypted by the first cookie encryption key.
+func (m *Rum) SetEncryptedCookie(w http.ResponseWriter, cookie *http.Cookie) error {
+	m.mut.Lock()
+	aeads := m.cookieAEADs
+	m.mut.Unlock()
+	if len(aeads) == 0 {
+		return ErrCookieKeysEmpty
+	}
+	aead := aeads[0]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(cookie.Value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	encrypted := *cookie
+	encrypted.Value = base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(cookie.Value), []byte(cookie.Name)))
+	http.SetCookie(w, &encrypted)
+	return nil
+}
+
+// GetEncryptedCookie returns the named cookie of the request with the value
+// decrypted by one of the cookie encryption keys.
+func (m *Rum) GetEncryptedCookie(r *http.Request, name string) (*http.Cookie, error) {
+	m.mut.Lock()
+	aeads := m.cookieAEADs
+	m.mut.Unlock()
+	if len(aeads) == 0 {
+		return nil, ErrCookieKeysEmpty
+	}
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil, ErrCookieInvalid
+	}
+	for _, aead := range aeads {
+		if len(data) < aead.NonceSize() {
+			continue
+		}
+		nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+		if plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(name)); err == nil {
+			cookie.Value = string(plaintext)
+			return cookie, nil
+		}
+	}
+	return nil, ErrCookieInvalid
+}
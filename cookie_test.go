@@ -0,0 +1,90 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func cookieRequest(w *httptest.ResponseRecorder) *http.Request {
+	r := httptest.NewRequest("GET", "/", nil)
+	for _, cookie := range w.Result().Cookies() {
+		r.AddCookie(cookie)
+	}
+	return r
+}
+
+func TestSignedCookie(t *testing.T) {
+	m := New()
+	w := httptest.NewRecorder()
+	if err := m.SetSignedCookie(w, &http.Cookie{Name: "a", Value: "b"}); err != ErrCookieKeysEmpty {
+		t.Error(err)
+	}
+	if _, err := m.GetSignedCookie(cookieRequest(w), "a"); err != ErrCookieKeysEmpty {
+		t.Error(err)
+	}
+	m.SetCookieKeys([]byte("old"))
+	w = httptest.NewRecorder()
+	if err := m.SetSignedCookie(w, &http.Cookie{Name: "session", Value: "hello world"}); err != nil {
+		t.Fatal(err)
+	}
+	m.SetCookieKeys([]byte("new"), []byte("old"))
+	cookie, err := m.GetSignedCookie(cookieRequest(w), "session")
+	if err != nil {
+		t.Fatal(err)
+	} else if cookie.Value != "hello world" {
+		t.Error(cookie.Value)
+	}
+	m.SetCookieKeys([]byte("new"))
+	if _, err := m.GetSignedCookie(cookieRequest(w), "session"); err != ErrCookieInvalid {
+		t.Error(err)
+	}
+	if _, err := m.GetSignedCookie(cookieRequest(w), "missing"); err != http.ErrNoCookie {
+		t.Error(err)
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: "unsigned"})
+	if _, err := m.GetSignedCookie(r, "session"); err != ErrCookieInvalid {
+		t.Error(err)
+	}
+}
+
+func TestEncryptedCookie(t *testing.T) {
+	m := New()
+	w := httptest.NewRecorder()
+	if err := m.SetEncryptedCookie(w, &http.Cookie{Name: "a", Value: "b"}); err != ErrCookieKeysEmpty {
+		t.Error(err)
+	}
+	if _, err := m.GetEncryptedCookie(cookieRequest(w), "a"); err != ErrCookieKeysEmpty {
+		t.Error(err)
+	}
+	if err := m.SetCookieEncryptionKeys([]byte("short")); err == nil {
+		t.Error()
+	}
+	oldKey := []byte("0123456789abcdef")
+	newKey := []byte("fedcba9876543210")
+	m.SetCookieEncryptionKeys(oldKey)
+	w = httptest.NewRecorder()
+	if err := m.SetEncryptedCookie(w, &http.Cookie{Name: "secret", Value: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	m.SetCookieEncryptionKeys(newKey, oldKey)
+	cookie, err := m.GetEncryptedCookie(cookieRequest(w), "secret")
+	if err != nil {
+		t.Fatal(err)
+	} else if cookie.Value != "hello" {
+		t.Error(cookie.Value)
+	}
+	m.SetCookieEncryptionKeys(newKey)
+	if _, err := m.GetEncryptedCookie(cookieRequest(w), "secret"); err != ErrCookieInvalid {
+		t.Error(err)
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "secret", Value: "!"})
+	if _, err := m.GetEncryptedCookie(r, "secret"); err != ErrCookieInvalid {
+		t.Error(err)
+	}
+}
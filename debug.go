@@ -0,0 +1,211 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultDebugMaxBody is the default number of body bytes dumped by the debug mode.
+const DefaultDebugMaxBody = 1024
+
+// DebugFilter selects the requests dumped by the debug mode.
+type DebugFilter struct {
+	// Path is the path prefix of the requests. Empty matches all of the paths.
+	Path string
+	// Header and Token select the requests with the header set to the token.
+	// Empty Header matches all of the requests. The header is redacted from
+	// the dumps, like the headers of DefaultRedact.
+	Header string
+	Token  string
+	// MaxBody is the number of body bytes dumped. Zero means DefaultDebugMaxBody.
+	MaxBody int
+}
+
+func (f *DebugFilter) match(r *http.Request) bool {
+	if !strings.HasPrefix(r.URL.Path, f.Path) {
+		return false
+	}
+	return f.Header == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get(f.Header)), []byte(f.Token)) == 1
+}
+
+// debugRouteKey is the context key of the route duration of a dumped
+// request, measured by the Mux.
+var debugRouteKey = &contextKey{"debug-route"}
+
+type debugger struct {
+	filter DebugFilter
+	logger *log.Logger
+	redact []string
+}
+
+// SetDebug enables the debug mode that dumps the requests matching the
+// filter and their responses to the logger, with the headers, the
+// truncated bodies and the timing of the parse, route, handler and write
+// phases. A nil filter disables the debug mode. A nil logger writes to
+// the standard logger.
+//
+// SetDebug must be called before serving.
+func (m *Rum) SetDebug(filter *DebugFilter, logger *log.Logger) {
+	m.Mux.debugRoute = filter != nil
+	if filter == nil {
+		m.debug = nil
+		return
+	}
+	if logger == nil {
+		logger = log.New(log.Writer(), "", log.LstdFlags)
+	}
+	d := &debugger{filter: *filter, logger: logger, redact: DefaultRedact}
+	if d.filter.Header != "" {
+		d.redact = append(append([]string(nil), DefaultRedact...), d.filter.Header)
+	}
+	if d.filter.MaxBody <= 0 {
+		d.filter.MaxBody = DefaultDebugMaxBody
+	}
+	m.debug = d
+}
+
+// debugStart returns the start time of parsing a request in debug mode.
+// The reader of a blocking connection is peeked so that the idle time
+// is not counted.
+func (m *Rum) debugStart(reader *bufio.Reader) time.Time {
+	if m.debug == nil {
+		return time.Time{}
+	}
+	if reader != nil {
+		reader.Peek(1)
+	}
+	return time.Now()
+}
+
+type debugTrace struct {
+	debugger *debugger
+	req      *http.Request
+	start    time.Time
+	parse    time.Duration
+	route    time.Duration
+	handler  time.Duration
+	reqBody  *limitedBuffer
+	res      *debugResponseWriter
+}
+
+func (d *debugger) trace(m *Rum, handler http.Handler, req *http.Request, start time.Time) *debugTrace {
+	if !d.filter.match(req) {
+		return nil
+	}
+	t := &debugTrace{debugger: d, start: start, parse: time.Since(start)}
+	if req.Body != nil && req.Body != http.NoBody {
+		t.reqBody = &limitedBuffer{limit: d.filter.MaxBody}
+		req.Body = &teeReadCloser{Reader: io.TeeReader(req.Body, t.reqBody), Closer: req.Body}
+	}
+	// The Mux of the server stores the duration of its lookup in the route.
+	t.req = req.WithContext(context.WithValue(req.Context(), debugRouteKey, &t.route))
+	return t
+}
+
+func (t *debugTrace) serve(handler http.Handler, w http.ResponseWriter) {
+	t.res = &debugResponseWriter{forwarder: forwarder{w}, body: limitedBuffer{limit: t.debugger.filter.MaxBody}}
+	now := time.Now()
+	handler.ServeHTTP(expose(t.res, w), t.req)
+	t.handler = time.Since(now) - t.route
+}
+
+func (t *debugTrace) dump() {
+	write := time.Since(t.start) - t.parse - t.route - t.handler
+	status := t.res.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "rum debug method=%s uri=%q proto=%s remote=%s status=%d parse=%s route=%s handler=%s write=%s total=%s\n",
+		t.req.Method, redactURL(t.req, t.debugger.redact), t.req.Proto, t.req.RemoteAddr, status,
+		t.parse, t.route, t.handler, write, time.Since(t.start))
+	dumpHeader(&b, "> ", redactHeader(t.req.Header, t.debugger.redact))
+	if t.reqBody != nil {
+		dumpBody(&b, "> ", t.reqBody)
+	}
+	dumpHeader(&b, "< ", redactHeader(t.res.Header(), t.debugger.redact))
+	dumpBody(&b, "< ", &t.res.body)
+	t.debugger.logger.Print(b.String())
+}
+
+func dumpHeader(b *bytes.Buffer, prefix string, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range header[key] {
+			fmt.Fprintf(b, "%s%s: %s\n", prefix, key, value)
+		}
+	}
+}
+
+func dumpBody(b *bytes.Buffer, prefix string, body *limitedBuffer) {
+	if body.Len() == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%s\n%s%q", prefix, prefix, body.Bytes())
+	if body.truncated > 0 {
+		fmt.Fprintf(b, " ... %d bytes truncated", body.truncated)
+	}
+	b.WriteByte('\n')
+}
+
+// limitedBuffer keeps the first limit bytes written to it.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if free := b.limit - b.Len(); free < len(p) {
+		if free < 0 {
+			free = 0
+		}
+		b.truncated += len(p) - free
+		p = p[:free]
+	}
+	b.Buffer.Write(p)
+	return n, nil
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type debugResponseWriter struct {
+	forwarder
+	status int
+	body   limitedBuffer
+}
+
+func (w *debugResponseWriter) WriteHeader(code int) {
+	if w.status == 0 && !informational(code) {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *debugResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebug(t *testing.T) {
+	addr := ":8080"
+	var buf bytes.Buffer
+	m := New()
+	m.SetDebug(&DebugFilter{Path: "/echo", Header: "X-Debug", Token: "secret", MaxBody: 4}, log.New(&buf, "", 0))
+	m.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Echo", "yes")
+		w.Write(body)
+	})
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	for _, token := range []string{"", "secret"} {
+		req, _ := http.NewRequest("POST", "http://"+addr+"/echo", strings.NewReader("hello world"))
+		req.Header.Set("X-Debug", token)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "hello world" {
+			t.Error(string(body))
+		}
+	}
+	m.Close()
+	<-done
+	out := buf.String()
+	if strings.Count(out, "rum debug ") != 1 {
+		t.Fatal(out)
+	}
+	for _, s := range []string{`method=POST uri="/echo"`, "status=200", "> X-Debug: [REDACTED]", "> Authorization: [REDACTED]", "< X-Echo: yes", `"hell" ... 7 bytes truncated`} {
+		if !strings.Contains(out, s) {
+			t.Error(s, out)
+		}
+	}
+	if strings.Contains(out, "secret") || strings.Contains(out, "route=0s") {
+		t.Error(out)
+	}
+	m.SetDebug(nil, nil)
+	if m.debug != nil {
+		t.Error()
+	}
+	m.SetDebug(&DebugFilter{}, nil)
+	if m.debug.filter.MaxBody != DefaultDebugMaxBody {
+		t.Error(m.debug.filter.MaxBody)
+	}
+}
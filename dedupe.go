@@ -0,0 +1,129 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	dedupeProcessing = []byte("processing")
+	dedupeDone       = []byte("done")
+)
+
+// Dedupe is a middleware acknowledging the duplicate deliveries of the
+// at-least-once webhook producers with 200 OK and the "X-Duplicate: true"
+// header, without calling the handler again. A delivery is identified by
+// the Header, such as "X-GitHub-Delivery", or by the hash of its body, and
+// is a duplicate within the Window after the last delivery. A duplicate
+// of a delivery being processed is replied with 409 Conflict, and the
+// deliveries failing with a server error are forgotten, so that the
+// producer retries them.
+type Dedupe struct {
+	// Store stores the deliveries. If nil, a MemoryStore is used.
+	Store IdempotencyStore
+	// Header is the header identifying a delivery. If empty or missing,
+	// the deliveries are identified by the hash of the method, the path
+	// and the body.
+	Header string
+	// Window is the sliding window of the duplicates. Zero means one hour.
+	Window time.Duration
+	// MaxBodySize is the maximum size in bytes of the bodies hashed. Zero
+	// means one megabyte.
+	MaxBodySize int64
+
+	once sync.Once
+}
+
+// Handler returns a handler calling the handler once per delivery.
+func (d *Dedupe) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d.once.Do(d.init)
+		id := ""
+		if d.Header != "" {
+			id = r.Header.Get(d.Header)
+		}
+		if id == "" {
+			if r.ContentLength > d.MaxBodySize {
+				http.Error(w, "413 Request Entity Too Large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			body, err := ioutil.ReadAll(io.LimitReader(r.Body, d.MaxBodySize+1))
+			if err != nil {
+				http.Error(w, "400 Bad Request", http.StatusBadRequest)
+				return
+			} else if int64(len(body)) > d.MaxBodySize {
+				http.Error(w, "413 Request Entity Too Large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			sum := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\n"), body...))
+			id = hex.EncodeToString(sum[:])
+		}
+		key := "dedupe:" + r.URL.Path + "\x00" + id
+		added, err := d.Store.Add(r.Context(), key, dedupeProcessing, d.Window)
+		if err != nil {
+			http.Error(w, "503 Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if !added {
+			d.duplicate(w, r, key)
+			return
+		}
+		d.serve(h, w, r, key)
+	})
+}
+
+func (d *Dedupe) init() {
+	if d.Store == nil {
+		d.Store = NewMemoryStore()
+	}
+	if d.Window <= 0 {
+		d.Window = time.Hour
+	}
+	if d.MaxBodySize <= 0 {
+		d.MaxBodySize = 1 << 20
+	}
+}
+
+// serve serves the first delivery and marks it done unless it fails.
+func (d *Dedupe) serve(h http.Handler, w http.ResponseWriter, r *http.Request, key string) {
+	ctx := &detachedContext{Context: context.Background(), values: r.Context()}
+	done := false
+	defer func() {
+		if !done {
+			d.Store.Delete(ctx, key)
+		}
+	}()
+	aw := &accessResponseWriter{forwarder: forwarder{w}}
+	h.ServeHTTP(expose(aw, w), r)
+	if aw.code < 500 {
+		done = d.Store.Set(ctx, key, dedupeDone, d.Window) == nil
+	}
+}
+
+// duplicate replies to a duplicate delivery, and slides its window.
+func (d *Dedupe) duplicate(w http.ResponseWriter, r *http.Request, key string) {
+	value, err := d.Store.Get(r.Context(), key)
+	if err == ErrCacheMiss || err == nil && !bytes.Equal(value, dedupeDone) {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "409 Conflict", http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "503 Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	d.Store.Set(r.Context(), key, dedupeDone, d.Window)
+	w.Header().Set("X-Duplicate", "true")
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusOK)
+}
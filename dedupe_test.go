@@ -0,0 +1,94 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDedupe(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	var calls int32
+	release := make(chan struct{})
+	d := &Dedupe{Store: store, Header: "X-GitHub-Delivery", Window: time.Minute}
+	h := d.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		body, _ := ioutil.ReadAll(r.Body)
+		switch string(body) {
+		case "slow":
+			<-release
+		case "fail":
+			http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	serve := func(delivery, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/hooks", strings.NewReader(body))
+		if delivery != "" {
+			r.Header.Set("X-GitHub-Delivery", delivery)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	tests := []struct {
+		delivery  string
+		body      string
+		status    int
+		duplicate bool
+		calls     int32
+	}{
+		{"d1", "a", http.StatusAccepted, false, 1},
+		{"d1", "b", http.StatusOK, true, 1},
+		{"d2", "a", http.StatusAccepted, false, 2},
+		{"", "c", http.StatusAccepted, false, 3},
+		{"", "c", http.StatusOK, true, 3},
+		{"", "d", http.StatusAccepted, false, 4},
+		{"d3", "fail", http.StatusInternalServerError, false, 5},
+		{"d3", "fail", http.StatusInternalServerError, false, 6},
+	}
+	for i, test := range tests {
+		w := serve(test.delivery, test.body)
+		if w.Code != test.status || (w.Header().Get("X-Duplicate") == "true") != test.duplicate ||
+			atomic.LoadInt32(&calls) != test.calls {
+			t.Error(i, w.Code, w.Header(), calls)
+		}
+	}
+	now = now.Add(time.Second * 50)
+	if w := serve("d1", "a"); !(w.Code == http.StatusOK && w.Header().Get("X-Duplicate") == "true") {
+		t.Error("the delivery should be a duplicate", w.Code)
+	}
+	now = now.Add(time.Second * 50)
+	if w := serve("d1", "a"); w.Header().Get("X-Duplicate") != "true" {
+		t.Error("the window should slide")
+	}
+	if w := serve("d2", "a"); w.Code != http.StatusAccepted {
+		t.Error("the window should be expired", w.Code)
+	}
+	done := make(chan struct{})
+	go func() {
+		serve("d4", "slow")
+		close(done)
+	}()
+	for atomic.LoadInt32(&calls) != 8 {
+		time.Sleep(time.Millisecond)
+	}
+	if w := serve("d4", "slow"); w.Code != http.StatusConflict {
+		t.Error(w.Code)
+	}
+	close(release)
+	<-done
+	if w := serve("d4", "slow"); w.Code != http.StatusOK || atomic.LoadInt32(&calls) != 8 {
+		t.Error(w.Code, calls)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// HandlerE is an HTTP handler function that returns an error.
+// The error is converted into a response by an ErrorHandler.
+type HandlerE func(w http.ResponseWriter, r *http.Request) error
+
+// ServeHTTP calls h(w, r) and converts the returned error with the DefaultErrorHandler.
+func (h HandlerE) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h.call(w, r); err != nil {
+		DefaultErrorHandler(w, r, err)
+	}
+}
+
+func (h HandlerE) call(w http.ResponseWriter, r *http.Request) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = &PanicError{Value: e}
+		}
+	}()
+	return h(w, r)
+}
+
+// ErrorHandler converts an error into a response.
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// HTTPError is an error with an HTTP status code.
+type HTTPError struct {
+	Code    int
+	Message string
+	Err     error
+}
+
+// NewHTTPError returns a new HTTPError with the status code and the message.
+// If the message is empty, the status text is used.
+func NewHTTPError(code int, message string) *HTTPError {
+	return &HTTPError{Code: code, Message: message}
+}
+
+// Error returns the message of the error.
+func (e *HTTPError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = StatusText(e.Code)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s : %v", e.Code, message, e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Code, message)
+}
+
+// Unwrap returns the underlying error.
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is the error converted from a panic in a HandlerE.
+type PanicError struct {
+	Value interface{}
+}
+
+// Error returns the panic value as a string.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// DefaultErrorHandler replies with the status code and the message of an
+// *HTTPError, or with a 500 status code for other errors, which are logged
+// to the standard logger instead of being sent to the client.
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		message := httpErr.Message
+		if message == "" {
+			message = StatusText(httpErr.Code)
+		}
+		http.Error(w, fmt.Sprintf("%d %s", httpErr.Code, message), httpErr.Code)
+		return
+	}
+	logError(r, err)
+	http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+}
+
+// logError logs the unexpected error of the request to the standard logger.
+func logError(r *http.Request, err error) {
+	if r != nil {
+		log.Printf("rum: %s %s: %v", r.Method, r.URL.Path, err)
+	} else {
+		log.Printf("rum: %v", err)
+	}
+}
+
+type handlerE struct {
+	entry   *Entry
+	handler HandlerE
+}
+
+func (h *handlerE) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
+
+// HandleFuncE registers an error-returning handler function with the given
+// pattern to the Mux. The returned errors and the panics are converted into
+// responses by the entry's ErrorHandler, the Mux's ErrorHandler or the
+// DefaultErrorHandler, in that order.
+func (m *Mux) HandleFuncE(pattern string, handler HandlerE) *Entry {
+	h := &handlerE{handler: handler}
+	h.entry = m.Handle(pattern, h)
+	return h.entry
+}
+
+// ErrorHandler registers an error handler function to the Mux.
+// It also converts the panics of the handlers into *PanicError
+// when no recovery handler is registered.
+func (m *Mux) ErrorHandler(handler ErrorHandler) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.context.errorHandler = handler
+}
+
+func (m *Mux) serveError(entry *Entry, w http.ResponseWriter, r *http.Request, err error) {
+	if entry != nil && entry.errorHandler != nil {
+		entry.errorHandler(w, r, err)
+	} else if m.context.errorHandler != nil {
+		m.context.errorHandler(w, r, err)
+	} else if m.context.problems {
+		ProblemErrorHandler(w, r, err)
+	} else {
+		DefaultErrorHandler(w, r, err)
+	}
+}
+
+// ErrorHandler sets the error handler function of the entry.
+func (entry *Entry) ErrorHandler(handler ErrorHandler) *Entry {
+	entry.errorHandler = handler
+	return entry
+}
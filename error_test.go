@@ -0,0 +1,95 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPError(t *testing.T) {
+	err := NewHTTPError(http.StatusNotFound, "")
+	if err.Error() != "404 Not Found" {
+		t.Error(err.Error())
+	}
+	base := errors.New("base")
+	err = &HTTPError{Code: http.StatusBadRequest, Message: "bad", Err: base}
+	if err.Error() != "400 bad : base" {
+		t.Error(err.Error())
+	}
+	if !errors.Is(err, base) {
+		t.Error()
+	}
+	if (&PanicError{Value: "foo"}).Error() != "panic: foo" {
+		t.Error()
+	}
+}
+
+func TestHandlerE(t *testing.T) {
+	var h http.Handler = HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+		return NewHTTPError(http.StatusTeapot, "")
+	})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusTeapot || w.Body.String() != "418 I'm a teapot\n" {
+		t.Error(w.Code, w.Body.String())
+	}
+}
+
+func TestHandleFuncE(t *testing.T) {
+	m := NewMux()
+	m.HandleFuncE("/ok", func(w http.ResponseWriter, r *http.Request) error {
+		w.Write([]byte("ok"))
+		return nil
+	})
+	m.HandleFuncE("/error", func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("foo")
+	}).GET()
+	m.HandleFuncE("/http", func(w http.ResponseWriter, r *http.Request) error {
+		return NewHTTPError(http.StatusForbidden, "denied")
+	})
+	m.HandleFuncE("/panic", func(w http.ResponseWriter, r *http.Request) error {
+		panic("bar")
+	})
+	m.HandleFuncE("/entry", func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("entry")
+	}).ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+	})
+	m.HandleFunc("/std", func(w http.ResponseWriter, r *http.Request) {
+		panic("std")
+	})
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/ok", http.StatusOK, "ok"},
+		{"/error", http.StatusInternalServerError, "500 Internal Server Error\n"},
+		{"/http", http.StatusForbidden, "403 denied\n"},
+		{"/panic", http.StatusInternalServerError, "500 Internal Server Error\n"},
+		{"/entry", http.StatusConflict, "entry"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.status || w.Body.String() != test.body {
+			t.Error(test.path, w.Code, w.Body.String())
+		}
+	}
+	m.ErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(err.Error()))
+	})
+	for path, body := range map[string]string{"/error": "foo", "/std": "panic: std", "/entry": "entry"} {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Body.String() != body {
+			t.Error(path, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+//go:build windows || plan9 || js || wasip1
+// +build windows plan9 js wasip1
+
+package rum
+
+func fdLimit() int64 {
+	return 0
+}
+
+// OpenFds returns the number of the open file descriptors of the process,
+// or zero if unknown.
+func OpenFds() int64 {
+	return 0
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+//go:build !windows && !plan9 && !js && !wasip1
+// +build !windows,!plan9,!js,!wasip1
+
+package rum
+
+import (
+	"os"
+	"syscall"
+)
+
+func fdLimit() int64 {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return 0
+	}
+	return int64(rlimit.Cur)
+}
+
+// OpenFds returns the number of the open file descriptors of the process,
+// or zero if unknown.
+func OpenFds() int64 {
+	dir, err := os.Open("/dev/fd")
+	if err != nil {
+		return 0
+	}
+	names, err := dir.Readdirnames(-1)
+	dir.Close()
+	if err != nil {
+		return 0
+	}
+	// The directory itself was open while listing.
+	return int64(len(names)) - 1
+}
@@ -0,0 +1,253 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// MaxFormSize is the maximum size in bytes of a form body parsed by ParseForm.
+	MaxFormSize = 10 << 20
+	// maxPooledFormBuffer is the maximum capacity of the body buffer kept by
+	// a pooled Form.
+	maxPooledFormBuffer = 64 << 10
+	formContentType     = "application/x-www-form-urlencoded"
+)
+
+var (
+	// ErrFormContentType is returned by ParseForm if the Content-Type of the
+	// request is not application/x-www-form-urlencoded.
+	ErrFormContentType = errors.New("Content-Type Not Form Urlencoded")
+	// ErrFormTooLarge is returned by ParseForm if the body is larger than
+	// MaxFormSize.
+	ErrFormTooLarge = errors.New("Form Too Large")
+	// ErrFormField is returned by the typed getters of a Form for a missing key.
+	ErrFormField = errors.New("Form Field Not Found")
+)
+
+var formPool = sync.Pool{New: func() interface{} {
+	return &Form{}
+}}
+
+// Form is a parsed application/x-www-form-urlencoded body. The fields are
+// kept in the order of the body in slices instead of a map, and the values
+// without escapes share one string of the body, so parsing a form of a
+// pooled Form costs about one allocation. The lookups are linear, which is
+// faster than a map for the forms of a few dozens of fields.
+type Form struct {
+	buf    bytes.Buffer
+	keys   []string
+	values []string
+}
+
+// ParseForm parses the application/x-www-form-urlencoded body of the
+// request into a pooled Form, which should be freed by FreeForm after use.
+// The body is limited to MaxFormSize, and further by SetMaxBodySize.
+//
+//	f, err := rum.ParseForm(r)
+//	if err != nil {
+//		http.Error(w, err.Error(), http.StatusBadRequest)
+//		return
+//	}
+//	defer rum.FreeForm(f)
+//	age, err := f.Int("age")
+func ParseForm(r *http.Request) (*Form, error) {
+	ct := r.Header.Get("Content-Type")
+	if ct != formContentType && !strings.HasPrefix(ct, formContentType+";") {
+		return nil, ErrFormContentType
+	}
+	f := formPool.Get().(*Form)
+	if r.Body != nil && r.Body != http.NoBody {
+		if r.ContentLength > MaxFormSize {
+			FreeForm(f)
+			return nil, ErrFormTooLarge
+		} else if r.ContentLength > 0 {
+			f.buf.Grow(int(r.ContentLength))
+		}
+		n, err := f.buf.ReadFrom(io.LimitReader(r.Body, MaxFormSize+1))
+		if err != nil {
+			FreeForm(f)
+			return nil, err
+		} else if n > MaxFormSize {
+			FreeForm(f)
+			return nil, ErrFormTooLarge
+		}
+	}
+	if err := f.parse(string(f.buf.Bytes())); err != nil {
+		FreeForm(f)
+		return nil, err
+	}
+	return f, nil
+}
+
+// ParseFormString parses the urlencoded form into a pooled Form, which
+// should be freed by FreeForm after use.
+func ParseFormString(s string) (*Form, error) {
+	f := formPool.Get().(*Form)
+	if err := f.parse(s); err != nil {
+		FreeForm(f)
+		return nil, err
+	}
+	return f, nil
+}
+
+// FreeForm resets the Form and returns it to the pool. The Form and the
+// strings obtained from it must not be used after.
+func FreeForm(f *Form) {
+	f.Reset()
+	if f.buf.Cap() > maxPooledFormBuffer {
+		f.buf = bytes.Buffer{}
+	}
+	formPool.Put(f)
+}
+
+// Reset removes the fields of the Form.
+func (f *Form) Reset() {
+	f.buf.Reset()
+	for i := range f.keys {
+		f.keys[i], f.values[i] = "", ""
+	}
+	f.keys = f.keys[:0]
+	f.values = f.values[:0]
+}
+
+// parse appends the fields of the urlencoded form s.
+func (f *Form) parse(s string) error {
+	for s != "" {
+		var field string
+		if i := strings.IndexByte(s, '&'); i >= 0 {
+			field, s = s[:i], s[i+1:]
+		} else {
+			field, s = s, ""
+		}
+		if field == "" {
+			continue
+		}
+		key, value := field, ""
+		if i := strings.IndexByte(field, '='); i >= 0 {
+			key, value = field[:i], field[i+1:]
+		}
+		key, err := unescapeForm(key)
+		if err != nil {
+			return err
+		}
+		value, err = unescapeForm(value)
+		if err != nil {
+			return err
+		}
+		f.keys = append(f.keys, key)
+		f.values = append(f.values, value)
+	}
+	return nil
+}
+
+// unescapeForm unescapes s, without allocating if s has no escapes.
+func unescapeForm(s string) (string, error) {
+	if strings.IndexByte(s, '%') < 0 && strings.IndexByte(s, '+') < 0 {
+		return s, nil
+	}
+	return url.QueryUnescape(s)
+}
+
+// Len returns the number of the fields.
+func (f *Form) Len() int {
+	return len(f.keys)
+}
+
+// Has reports whether the form has the key.
+func (f *Form) Has(key string) bool {
+	return f.index(key) >= 0
+}
+
+// Get returns the first value of the key, or the empty string.
+func (f *Form) Get(key string) string {
+	if i := f.index(key); i >= 0 {
+		return f.values[i]
+	}
+	return ""
+}
+
+// Values returns the values of the key.
+func (f *Form) Values(key string) []string {
+	var values []string
+	for i, k := range f.keys {
+		if k == key {
+			values = append(values, f.values[i])
+		}
+	}
+	return values
+}
+
+// VisitAll calls visit with the fields in the order of the form.
+func (f *Form) VisitAll(visit func(key, value string)) {
+	for i, key := range f.keys {
+		visit(key, f.values[i])
+	}
+}
+
+// URLValues returns the fields as url.Values.
+func (f *Form) URLValues() url.Values {
+	values := make(url.Values, len(f.keys))
+	for i, key := range f.keys {
+		values[key] = append(values[key], f.values[i])
+	}
+	return values
+}
+
+// Int returns the first value of the key as an int.
+func (f *Form) Int(key string) (int, error) {
+	n, err := f.Int64(key)
+	return int(n), err
+}
+
+// Int64 returns the first value of the key as an int64.
+func (f *Form) Int64(key string) (int64, error) {
+	i := f.index(key)
+	if i < 0 {
+		return 0, ErrFormField
+	}
+	return strconv.ParseInt(f.values[i], 10, 64)
+}
+
+// Float64 returns the first value of the key as a float64.
+func (f *Form) Float64(key string) (float64, error) {
+	i := f.index(key)
+	if i < 0 {
+		return 0, ErrFormField
+	}
+	return strconv.ParseFloat(f.values[i], 64)
+}
+
+// Bool returns the first value of the key as a bool. An empty value, as of
+// a key without one, and "on" of a checked checkbox are true.
+func (f *Form) Bool(key string) (bool, error) {
+	i := f.index(key)
+	if i < 0 {
+		return false, ErrFormField
+	}
+	switch f.values[i] {
+	case "", "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return strconv.ParseBool(f.values[i])
+}
+
+func (f *Form) index(key string) int {
+	for i, k := range f.keys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestForm(t *testing.T) {
+	f, err := ParseFormString("name=Meng+Huang&age=30&score=9.5&tag=a&tag=b%26c&admin&subscribed=on&&empty=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer FreeForm(f)
+	if f.Len() != 8 {
+		t.Error(f.Len())
+	}
+	if name := f.Get("name"); name != "Meng Huang" {
+		t.Error(name)
+	}
+	if age, err := f.Int("age"); err != nil || age != 30 {
+		t.Error(age, err)
+	}
+	if score, err := f.Float64("score"); err != nil || score != 9.5 {
+		t.Error(score, err)
+	}
+	if tags := f.Values("tag"); len(tags) != 2 || tags[0] != "a" || tags[1] != "b&c" {
+		t.Error(tags)
+	}
+	for _, key := range []string{"admin", "subscribed"} {
+		if b, err := f.Bool(key); err != nil || !b {
+			t.Error(key, b, err)
+		}
+	}
+	if !f.Has("empty") || f.Has("missing") || f.Get("missing") != "" {
+		t.Error(f.Get("empty"))
+	}
+	if _, err := f.Int("missing"); err != ErrFormField {
+		t.Error(err)
+	}
+	if _, err := f.Int("name"); err == nil {
+		t.Error("expected a syntax error")
+	}
+	var keys []string
+	f.VisitAll(func(key, value string) { keys = append(keys, key) })
+	if strings.Join(keys, ",") != "name,age,score,tag,tag,admin,subscribed,empty" {
+		t.Error(keys)
+	}
+	if values := f.URLValues(); values.Get("name") != "Meng Huang" || len(values["tag"]) != 2 {
+		t.Error(values)
+	}
+	if _, err := ParseFormString("a=%zz"); err == nil {
+		t.Error("expected an escape error")
+	}
+}
+
+func TestParseForm(t *testing.T) {
+	m := New()
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		f, err := ParseForm(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer FreeForm(f)
+		w.Write([]byte(f.Get("a") + f.Get("b")))
+	}).POST()
+	for _, test := range []struct {
+		contentType string
+		body        string
+		status      int
+		result      string
+	}{
+		{"application/x-www-form-urlencoded", "a=Hello+&b=World", http.StatusOK, "Hello World"},
+		{"application/x-www-form-urlencoded; charset=utf-8", "b=World", http.StatusOK, "World"},
+		{"application/json", `{"a":"b"}`, http.StatusBadRequest, ErrFormContentType.Error() + "\n"},
+		{"application/x-www-form-urlencoded", "a=" + strings.Repeat("a", MaxFormSize), http.StatusBadRequest, ErrFormTooLarge.Error() + "\n"},
+	} {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(test.body))
+		r.Header.Set("Content-Type", test.contentType)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		if w.Code != test.status || w.Body.String() != test.result {
+			t.Error(test.contentType, w.Code, w.Body.String())
+		}
+	}
+}
+
+const benchmarkForm = "name=Meng+Huang&email=mhboy%40outlook.com&age=30&city=Shanghai&tag=a&tag=b"
+
+func BenchmarkParseForm(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f, _ := ParseFormString(benchmarkForm)
+		f.Get("city")
+		FreeForm(f)
+	}
+}
+
+func BenchmarkParseQuery(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		values, _ := url.ParseQuery(benchmarkForm)
+		values.Get("city")
+	}
+}
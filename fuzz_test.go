@@ -0,0 +1,149 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package rum
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/hslam/request"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fuzzSeeds = []string{
+	"GET / HTTP/1.1\r\nHost: localhost\r\n\r\n",
+	"GET /users/8?tag=a&tag=b HTTP/1.1\r\nHost: localhost\r\nAccept: */*\r\nAccept: text/html\r\n\r\n",
+	"POST /echo HTTP/1.1\r\nHost: localhost\r\nContent-Length: 5\r\n\r\nhello",
+	"POST /echo HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\n\r\n",
+	"GET /a HTTP/1.1\r\nHost: localhost\r\n\r\nGET /b HTTP/1.1\r\nHost: localhost\r\n\r\n",
+	"GET /%2e%2e/a//b HTTP/1.0\r\n\r\n",
+	"HEAD / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n",
+	"GET / HTTP/1.1\r\nHost: localhost\r\nContent-Length: -1\r\n\r\n",
+	"GET / HTTP/1.1\r\nHost: localhost\r\nX-Folded: a\r\n b\r\n\r\n",
+	"GARBAGE\r\n\r\n",
+	"GET / HTTP/9.9\r\n\r\n",
+	"",
+}
+
+// FuzzReadFastRequest checks that the fast request parser does not panic
+// and agrees with net/http on the requests accepted by net/http.
+func FuzzReadFastRequest(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		expected, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			if req, err := request.ReadFastRequest(bufio.NewReader(bytes.NewReader(data))); err == nil {
+				request.FreeRequest(req)
+			}
+			return
+		}
+		expectedBody, expectedErr := ioutil.ReadAll(expected.Body)
+		req, err := request.ReadFastRequest(bufio.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			t.Fatalf("net/http accepted %q, the fast parser failed: %v", data, err)
+		}
+		defer request.FreeRequest(req)
+		if req.Method != expected.Method || req.RequestURI != expected.RequestURI ||
+			req.Proto != expected.Proto || req.Host != expected.Host ||
+			req.ContentLength != expected.ContentLength {
+			t.Fatalf("%q: got %s %s %s %s %d, expected %s %s %s %s %d", data,
+				req.Method, req.RequestURI, req.Proto, req.Host, req.ContentLength,
+				expected.Method, expected.RequestURI, expected.Proto, expected.Host, expected.ContentLength)
+		}
+		if !reflect.DeepEqual(req.Header, expected.Header) {
+			t.Fatalf("%q: header %v, expected %v", data, req.Header, expected.Header)
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		if (err == nil) != (expectedErr == nil) || !bytes.Equal(body, expectedBody) {
+			t.Fatalf("%q: body %q %v, expected %q %v", data, body, err, expectedBody, expectedErr)
+		}
+	})
+}
+
+// FuzzServeConn feeds arbitrary byte streams through the connection loops,
+// which the poll mode shares through readFastRequest and serveRequest, and
+// checks that they do not panic, release the connection and allocate
+// memory bounded by the input.
+func FuzzServeConn(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed), false)
+		f.Add([]byte(seed), true)
+	}
+	m := New()
+	m.SetReadTimeout(time.Second)
+	m.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	})
+	m.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(m.Params(r)["id"]))
+	}).GET()
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		w.Write([]byte("Hello World"))
+	})
+	f.Fuzz(func(t *testing.T, data []byte, fast bool) {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		conn := &fuzzConn{reader: bytes.NewReader(data)}
+		atomic.AddInt64(&m.conns, 1)
+		if fast {
+			m.serveFastConn(conn)
+		} else {
+			m.serveConn(conn)
+		}
+		runtime.ReadMemStats(&after)
+		if conns := atomic.LoadInt64(&m.conns); conns != 0 {
+			t.Fatalf("%q: %d connections left", data, conns)
+		}
+		if !conn.closed {
+			t.Fatalf("%q: connection not closed", data)
+		}
+		if alloc := after.TotalAlloc - before.TotalAlloc; alloc > uint64(1<<20+256*len(data)) {
+			t.Fatalf("%q: allocated %d bytes", data, alloc)
+		}
+	})
+}
+
+type fuzzConn struct {
+	reader *bytes.Reader
+	writer bytes.Buffer
+	closed bool
+}
+
+func (c *fuzzConn) Read(b []byte) (int, error) {
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	return c.reader.Read(b)
+}
+
+func (c *fuzzConn) Write(b []byte) (int, error) {
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	return c.writer.Write(b)
+}
+
+func (c *fuzzConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func (c *fuzzConn) LocalAddr() net.Addr                { return &net.TCPAddr{Port: 8080} }
+func (c *fuzzConn) RemoteAddr() net.Addr               { return &net.TCPAddr{Port: 1024} }
+func (c *fuzzConn) SetDeadline(t time.Time) error      { return nil }
+func (c *fuzzConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *fuzzConn) SetWriteDeadline(t time.Time) error { return nil }
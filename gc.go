@@ -0,0 +1,139 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// GCConfig tunes the garbage collector for the latency-sensitive
+// deployments, where fewer collections trade memory for tail latency.
+type GCConfig struct {
+	// Percent sets the GOGC percentage. A negative percentage disables
+	// the collector. If zero, the percentage is kept.
+	Percent int
+	// MemoryLimit sets the soft memory limit in bytes, which requires
+	// Go 1.19 or later. If zero, the limit is kept.
+	MemoryLimit int64
+	// Ballast allocates a heap ballast of the size in bytes, which is
+	// never touched, so it costs no physical memory but raises the heap
+	// target of the collector. The MemoryLimit with a higher Percent is
+	// preferred on Go 1.19 or later. If zero, no ballast is allocated.
+	Ballast int64
+}
+
+var ballast struct {
+	sync.Mutex
+	b []byte
+}
+
+// TuneGC applies the GC configuration to the process, and returns a
+// function restoring the previous configuration.
+func TuneGC(config GCConfig) (restore func()) {
+	percent, limit := -1, int64(-1)
+	if config.Percent != 0 {
+		percent = debug.SetGCPercent(config.Percent)
+	}
+	if config.MemoryLimit > 0 {
+		limit = setMemoryLimit(config.MemoryLimit)
+	}
+	ballast.Lock()
+	previous := ballast.b
+	if config.Ballast > 0 {
+		ballast.b = make([]byte, config.Ballast)
+	}
+	ballast.Unlock()
+	return func() {
+		if config.Percent != 0 {
+			debug.SetGCPercent(percent)
+		}
+		if config.MemoryLimit > 0 {
+			setMemoryLimit(limit)
+		}
+		ballast.Lock()
+		ballast.b = previous
+		ballast.Unlock()
+	}
+}
+
+// AllocStats are the allocation metrics of the process sampled by the Server.
+type AllocStats struct {
+	// AllocBytesPerSecond is the rate of the bytes allocated.
+	AllocBytesPerSecond float64 `json:"allocBytesPerSecond"`
+	// MallocsPerSecond is the rate of the heap objects allocated.
+	MallocsPerSecond float64 `json:"mallocsPerSecond"`
+	// AllocBytesPerRequest is the bytes allocated per request served.
+	AllocBytesPerRequest float64 `json:"allocBytesPerRequest"`
+	// HeapAlloc is the bytes of the allocated heap objects.
+	HeapAlloc uint64 `json:"heapAlloc"`
+	// NumGC is the number of the completed GC cycles.
+	NumGC uint32 `json:"numGC"`
+	// PauseTotal is the cumulative GC pause time.
+	PauseTotal time.Duration `json:"pauseTotal"`
+}
+
+// SetAllocStats samples the allocation metrics at the interval, which
+// stops the world briefly, so it should not be shorter than a second.
+// Zero stops the sampling.
+func (m *Rum) SetAllocStats(interval time.Duration) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	if m.allocSampling != nil {
+		close(m.allocSampling)
+		m.allocSampling = nil
+	}
+	if interval <= 0 {
+		m.allocStats.Store(AllocStats{})
+		return
+	}
+	done := make(chan struct{})
+	m.allocSampling = done
+	go m.sampleAllocs(interval, done)
+}
+
+// AllocStats returns the last sampled allocation metrics.
+func (m *Rum) AllocStats() AllocStats {
+	stats, _ := m.allocStats.Load().(AllocStats)
+	return stats
+}
+
+// Requests returns the number of the requests served.
+func (m *Rum) Requests() uint64 {
+	return atomic.LoadUint64(&m.requests)
+}
+
+func (m *Rum) sampleAllocs(interval time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last runtime.MemStats
+	runtime.ReadMemStats(&last)
+	lastTime, lastRequests := time.Now(), m.Requests()
+	for {
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		now, requests := time.Now(), m.Requests()
+		seconds := now.Sub(lastTime).Seconds()
+		stats := AllocStats{
+			AllocBytesPerSecond: float64(ms.TotalAlloc-last.TotalAlloc) / seconds,
+			MallocsPerSecond:    float64(ms.Mallocs-last.Mallocs) / seconds,
+			HeapAlloc:           ms.HeapAlloc,
+			NumGC:               ms.NumGC,
+			PauseTotal:          time.Duration(ms.PauseTotalNs),
+		}
+		if requests > lastRequests {
+			stats.AllocBytesPerRequest = float64(ms.TotalAlloc-last.TotalAlloc) / float64(requests-lastRequests)
+		}
+		m.allocStats.Store(stats)
+		last, lastTime, lastRequests = ms, now, requests
+	}
+}
@@ -0,0 +1,16 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+//go:build go1.19
+// +build go1.19
+
+package rum
+
+import (
+	"runtime/debug"
+)
+
+// setMemoryLimit sets the soft memory limit and returns the previous one.
+func setMemoryLimit(limit int64) int64 {
+	return debug.SetMemoryLimit(limit)
+}
@@ -0,0 +1,12 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+//go:build !go1.19
+// +build !go1.19
+
+package rum
+
+// setMemoryLimit is a no-op before Go 1.19, which has no soft memory limit.
+func setMemoryLimit(limit int64) int64 {
+	return -1
+}
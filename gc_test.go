@@ -0,0 +1,62 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"runtime/debug"
+	"testing"
+	"time"
+)
+
+func TestTuneGC(t *testing.T) {
+	percent := debug.SetGCPercent(100)
+	defer debug.SetGCPercent(percent)
+	restore := TuneGC(GCConfig{Percent: 400, Ballast: 64 << 20})
+	if len(ballast.b) != 64<<20 {
+		t.Error(len(ballast.b))
+	}
+	if p := debug.SetGCPercent(400); p != 400 {
+		t.Error(p)
+	}
+	restore()
+	if p := debug.SetGCPercent(100); p != 100 {
+		t.Error(p)
+	}
+	if ballast.b != nil {
+		t.Error("should release the ballast")
+	}
+}
+
+func TestAllocStats(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 64<<10))
+	})
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	start := time.Now()
+	m.SetAllocStats(time.Millisecond * 200)
+	for i := 0; i < 10; i++ {
+		testHTTP("GET", "http://"+addr+"/", http.StatusOK, string(make([]byte, 64<<10)), t)
+	}
+	// waits for the first sample, which covers the requests.
+	time.Sleep(time.Millisecond*250 - time.Since(start))
+	if m.Requests() != 10 {
+		t.Error(m.Requests())
+	}
+	if stats := m.AllocStats(); stats.AllocBytesPerSecond <= 0 || stats.AllocBytesPerRequest < 64<<10 || stats.HeapAlloc == 0 {
+		t.Errorf("%+v", stats)
+	}
+	m.Close()
+	<-done
+	if m.allocSampling != nil {
+		t.Error("should stop the sampling")
+	}
+}
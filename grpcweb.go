@@ -0,0 +1,169 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	grpcContentType        = "application/grpc"
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text"
+)
+
+// GRPCWebHandler returns a handler translating the gRPC-Web requests to
+// gRPC requests served by the handler, typically a Proxy to gRPC upstreams,
+// and the gRPC responses back to gRPC-Web, with the trailers in a trailer
+// frame. Both the binary and the base64 text encodings are supported. The
+// other requests are served by the handler as is, so that a single gateway
+// can front both REST and gRPC services.
+//
+// The gRPC upstreams require HTTP/2, so the Transport of the Proxy must
+// speak HTTP/2 to them, which the http.DefaultTransport does over TLS.
+// The native gRPC clients require HTTP/2 too, which the Server does not
+// speak; they can be served by an http.Server with TLS and the same Proxy,
+// which passes the trailers through.
+func GRPCWebHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType := r.Header.Get("Content-Type")
+		if !strings.HasPrefix(contentType, grpcWebContentType) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		text := strings.HasPrefix(contentType, grpcWebTextContentType)
+		var subtype string
+		if text {
+			subtype = contentType[len(grpcWebTextContentType):]
+		} else {
+			subtype = contentType[len(grpcWebContentType):]
+		}
+		out := r.Clone(r.Context())
+		out.Header.Set("Content-Type", grpcContentType+subtype)
+		out.Header.Set("Te", "trailers")
+		if text {
+			out.Header.Del("Content-Length")
+			out.ContentLength = -1
+			out.Body = struct {
+				io.Reader
+				io.Closer
+			}{base64.NewDecoder(base64.StdEncoding, r.Body), r.Body}
+		}
+		gw := &grpcWebResponseWriter{forwarder: forwarder{w}, contentType: contentType}
+		if text {
+			gw.encoder = base64.NewEncoder(base64.StdEncoding, w)
+		}
+		handler.ServeHTTP(expose(gw, w), out)
+		gw.finish()
+	})
+}
+
+type grpcWebResponseWriter struct {
+	forwarder
+	contentType string
+	encoder     io.WriteCloser
+	wroteHeader bool
+	// declared holds the trailer names declared before writing the header.
+	declared []string
+}
+
+func (w *grpcWebResponseWriter) WriteHeader(code int) {
+	if informational(code) {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	header := w.Header()
+	for _, v := range header["Trailer"] {
+		for _, k := range strings.Split(v, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				w.declared = append(w.declared, http.CanonicalHeaderKey(k))
+			}
+		}
+	}
+	header.Del("Trailer")
+	header.Del("Content-Length")
+	header.Set("Content-Type", w.contentType)
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *grpcWebResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements the http.Flusher interface.
+func (w *grpcWebResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.forwarder.Flush()
+}
+
+// FlushError implements the error-returning Flush.
+func (w *grpcWebResponseWriter) FlushError() error {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.forwarder.FlushError()
+}
+
+// finish writes the trailers in a trailer frame.
+func (w *grpcWebResponseWriter) finish() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	header := w.Header()
+	trailers := make(map[string]string)
+	for k, v := range header {
+		if strings.HasPrefix(k, http.TrailerPrefix) && len(v) > 0 {
+			trailers[strings.ToLower(k[len(http.TrailerPrefix):])] = v[0]
+			header[k] = nil
+		}
+	}
+	for _, k := range w.declared {
+		if v := header.Get(k); v != "" {
+			trailers[strings.ToLower(k)] = v
+		}
+	}
+	// A trailers-only response carries the status in the header.
+	for _, k := range []string{"Grpc-Status", "Grpc-Message"} {
+		if v := header.Get(k); v != "" {
+			if _, ok := trailers[strings.ToLower(k)]; !ok {
+				trailers[strings.ToLower(k)] = v
+			}
+		}
+	}
+	keys := make([]string, 0, len(trailers))
+	for k := range trailers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var payload bytes.Buffer
+	for _, k := range keys {
+		payload.WriteString(k + ": " + trailers[k] + "\r\n")
+	}
+	frame := make([]byte, 5, 5+payload.Len())
+	frame[0] = 0x80
+	binary.BigEndian.PutUint32(frame[1:], uint32(payload.Len()))
+	frame = append(frame, payload.Bytes()...)
+	w.Write(frame)
+	if w.encoder != nil {
+		w.encoder.Close()
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testGRPCFrame(flag byte, payload string) []byte {
+	n := len(payload)
+	return append([]byte{flag, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}, payload...)
+}
+
+func TestGRPCWebHandler(t *testing.T) {
+	handler := GRPCWebHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/grpc" {
+			w.Write([]byte("REST"))
+			return
+		}
+		if r.Header.Get("Te") != "trailers" {
+			t.Error(r.Header.Get("Te"))
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if !bytes.Equal(body, testGRPCFrame(0, "ping")) {
+			t.Error(body)
+		}
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Write(testGRPCFrame(0, "pong"))
+		w.Header().Set("Grpc-Status", "0")
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", "OK")
+	}))
+	trailer := testGRPCFrame(0x80, "grpc-message: OK\r\ngrpc-status: 0\r\n")
+	want := append(testGRPCFrame(0, "pong"), trailer...)
+
+	req := httptest.NewRequest("POST", "/echo.Echo/Ping", bytes.NewReader(testGRPCFrame(0, "ping")))
+	req.Header.Set("Content-Type", "application/grpc-web")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if ct := w.Header().Get("Content-Type"); ct != "application/grpc-web" {
+		t.Error(ct)
+	}
+	if !bytes.Equal(w.Body.Bytes(), want) {
+		t.Errorf("%q", w.Body.Bytes())
+	}
+
+	body := base64.StdEncoding.EncodeToString(testGRPCFrame(0, "ping"))
+	req = httptest.NewRequest("POST", "/echo.Echo/Ping", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/grpc-web-text")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if ct := w.Header().Get("Content-Type"); ct != "application/grpc-web-text" {
+		t.Error(ct)
+	}
+	if got, err := base64.StdEncoding.DecodeString(w.Body.String()); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("%q %v", got, err)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Body.String() != "REST" {
+		t.Error(w.Body.String())
+	}
+}
+
+func TestGRPCWebTrailersOnly(t *testing.T) {
+	handler := GRPCWebHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Grpc-Status", "12")
+		w.Header().Set("Grpc-Message", "unimplemented")
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest("POST", "/echo.Echo/Unknown", nil)
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	want := testGRPCFrame(0x80, "grpc-message: unimplemented\r\ngrpc-status: 12\r\n")
+	if !bytes.Equal(w.Body.Bytes(), want) {
+		t.Errorf("%q", w.Body.Bytes())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/grpc-web+proto" {
+		t.Error(ct)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+type date struct {
+	unix  int64
+	value string
+}
+
+// dateCache caches the formatted Date header, which is refreshed
+// at most once per second.
+type dateCache struct {
+	v atomic.Value
+}
+
+func (c *dateCache) get() string {
+	now := time.Now()
+	if d, ok := c.v.Load().(*date); ok && d.unix == now.Unix() {
+		return d.value
+	}
+	d := &date{unix: now.Unix(), value: now.UTC().Format(http.TimeFormat)}
+	c.v.Store(d)
+	return d.value
+}
+
+var defaultDateCache dateCache
+
+// SetServerName sets the Server header of the responses.
+// The header is disabled when the name is empty, which is the default.
+func (m *Rum) SetServerName(name string) {
+	if name == "" {
+		m.serverName = nil
+		return
+	}
+	m.serverName = []string{name}
+}
+
+// SetNoDate disables the Date header set by the Server. The Date header
+// is cached and refreshed once per second, so it costs nothing at high QPS.
+func (m *Rum) SetNoDate(noDate bool) {
+	m.noDate = noDate
+}
+
+// setHeader sets the default headers of the response before calling the
+// handler. The values are copied, as the handler may modify the slices.
+func (m *Rum) setHeader(w http.ResponseWriter) {
+	header := w.Header()
+	if !m.noDate {
+		header["Date"] = []string{defaultDateCache.get()}
+	}
+	if m.serverName != nil {
+		header["Server"] = []string{m.serverName[0]}
+	}
+	if altSvc, ok := m.altSvc.Load().([]string); ok && altSvc != nil {
+		header["Alt-Svc"] = []string{altSvc[0]}
+	}
+	if m.noSniff {
+		header["Content-Type"] = nil
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDateCache(t *testing.T) {
+	var c dateCache
+	d := c.get()
+	if _, err := http.ParseTime(d); err != nil {
+		t.Error(err)
+	}
+	if c.get() != d {
+		time.Sleep(time.Millisecond)
+		if c.get() == d {
+			t.Error()
+		}
+	}
+}
+
+func TestServerName(t *testing.T) {
+	m := New()
+	w := httptest.NewRecorder()
+	m.setHeader(w)
+	if w.Header().Get("Server") != "" || w.Header().Get("Date") == "" {
+		t.Error(w.Header())
+	}
+	m.SetServerName("rum")
+	m.SetNoDate(true)
+	w = httptest.NewRecorder()
+	m.setHeader(w)
+	if w.Header().Get("Server") != "rum" || w.Header().Get("Date") != "" {
+		t.Error(w.Header())
+	}
+	w.Header()["Server"][0] = "modified"
+	m.SetNoDate(false)
+	w = httptest.NewRecorder()
+	m.setHeader(w)
+	if w.Header().Get("Server") != "rum" {
+		t.Error(w.Header())
+	}
+	w.Header()["Date"][0] = "modified"
+	if date := defaultDateCache.get(); date == "modified" {
+		t.Error(date)
+	}
+	m.SetServerName("")
+	w = httptest.NewRecorder()
+	m.setHeader(w)
+	if w.Header().Get("Server") != "" {
+		t.Error(w.Header())
+	}
+}
+
+func TestServerHeader(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	m.SetFast(true)
+	m.SetServerName("rum")
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	})
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get("http://localhost" + addr + "/")
+	if err != nil {
+		t.Error(err)
+	} else {
+		resp.Body.Close()
+		if resp.Header.Get("Server") != "rum" || resp.Header.Get("Date") == "" {
+			t.Error(resp.Header)
+		}
+	}
+	m.Close()
+	<-done
+}
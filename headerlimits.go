@@ -0,0 +1,214 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrHeaderFieldsTooLarge is the error of reading a request exceeding the
+// maximum number of headers or the maximum header length. The request is
+// replied with 431 Request Header Fields Too Large.
+var ErrHeaderFieldsTooLarge = errors.New("Request Header Fields Too Large")
+
+// ErrDuplicateHeader is the error of reading a request repeating a
+// singleton header under DuplicateReject. The request is replied with
+// 400 Bad Request.
+var ErrDuplicateHeader = errors.New("Duplicate Header")
+
+var headerFieldsTooLargeResponse = []byte("HTTP/1.1 431 Request Header Fields Too Large\r\nContent-Type: text/plain; charset=utf-8\r\nConnection: close\r\nContent-Length: 36\r\n\r\n431 Request Header Fields Too Large\n")
+
+// DuplicateHeaders is the policy of the repeated header fields of the requests.
+type DuplicateHeaders int
+
+const (
+	// DuplicateKeep keeps the values of the repeated fields, as net/http does.
+	DuplicateKeep DuplicateHeaders = iota
+	// DuplicateMerge merges the values of a repeated field into a single
+	// comma separated value, or semicolon separated for the Cookie.
+	DuplicateMerge
+	// DuplicateReject rejects the requests repeating a singleton field,
+	// such as the Host, the Content-Length or the Authorization, whose
+	// ambiguity may be exploited.
+	DuplicateReject
+)
+
+// singletonHeaders is the lowercase names of the fields rejected when
+// repeated by DuplicateReject.
+var singletonHeaders = []string{"host", "content-length", "content-type", "transfer-encoding",
+	"authorization", "proxy-authorization", "from", "referer", "user-agent", "max-forwards",
+	"range", "if-modified-since", "if-unmodified-since"}
+
+// SetMaxHeaders sets the maximum number of the header fields of the
+// requests. Zero means no limit.
+func (m *Rum) SetMaxHeaders(n int) {
+	m.maxHeaders = n
+}
+
+// SetMaxHeaderLength sets the maximum length in bytes of a header field
+// line of the requests. Zero means no limit.
+func (m *Rum) SetMaxHeaderLength(n int) {
+	m.maxHeaderLength = n
+}
+
+// SetDuplicateHeaders sets the policy of the repeated header fields. The
+// fast request parser keeps the last value of a repeated field, so only
+// DuplicateReject applies to it.
+func (m *Rum) SetDuplicateHeaders(policy DuplicateHeaders) {
+	m.duplicateHeaders = policy
+}
+
+// headerLimited reports whether the headers are checked before parsing.
+func (m *Rum) headerLimited() bool {
+	return m.maxHeaders > 0 || m.maxHeaderLength > 0 || m.duplicateHeaders == DuplicateReject
+}
+
+// readRequest reads a request with the net/http request parser within the
+// header limits.
+func (m *Rum) readRequest(reader *bufio.Reader) (*http.Request, error) {
+	if err := m.scanHeaders(reader); err != nil {
+		return nil, err
+	}
+	req, err := http.ReadRequest(reader)
+	if err == nil {
+		err = m.limitHeaders(req, false)
+	}
+	return req, err
+}
+
+// scanHeaders checks the header block buffered by the reader against the
+// limits without consuming it, rejecting an abusive request before the
+// header map is allocated. The fields beyond the buffer are checked by
+// limitHeaders after parsing.
+func (m *Rum) scanHeaders(reader *bufio.Reader) error {
+	if !m.headerLimited() {
+		return nil
+	}
+	b, _ := peekHead(reader)
+	i := bytes.IndexByte(b, '\n')
+	if i < 0 {
+		return nil
+	}
+	var seen []string
+	var count int
+	for b = b[i+1:]; len(b) > 0; {
+		line := b
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			line, b = b[:i], b[i+1:]
+		} else {
+			b = nil
+		}
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if len(line) == 0 {
+			break
+		}
+		if m.maxHeaderLength > 0 && len(line) > m.maxHeaderLength {
+			return ErrHeaderFieldsTooLarge
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if count++; m.maxHeaders > 0 && count > m.maxHeaders {
+			return ErrHeaderFieldsTooLarge
+		}
+		if m.duplicateHeaders == DuplicateReject {
+			if colon := bytes.IndexByte(line, ':'); colon > 0 {
+				name := strings.ToLower(string(bytes.TrimSpace(line[:colon])))
+				if singletonHeader(name) {
+					for _, s := range seen {
+						if s == name {
+							return ErrDuplicateHeader
+						}
+					}
+					seen = append(seen, name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// limitHeaders checks the parsed headers against the limits, and applies
+// the duplicate policy. The fast request parser splits the values by the
+// spaces and keeps the last value of a repeated field, so its fields are
+// counted by name.
+func (m *Rum) limitHeaders(req *http.Request, fast bool) error {
+	if !m.headerLimited() && m.duplicateHeaders != DuplicateMerge {
+		return nil
+	}
+	var count int
+	for k, v := range req.Header {
+		if fast {
+			count++
+		} else {
+			count += len(v)
+		}
+		if m.maxHeaderLength > 0 {
+			if fast {
+				if n := len(k) + 2 + len(strings.Join(v, " ")); n > m.maxHeaderLength {
+					return ErrHeaderFieldsTooLarge
+				}
+			} else {
+				for _, value := range v {
+					if len(k)+2+len(value) > m.maxHeaderLength {
+						return ErrHeaderFieldsTooLarge
+					}
+				}
+			}
+		}
+		if fast || len(v) < 2 {
+			continue
+		}
+		switch m.duplicateHeaders {
+		case DuplicateReject:
+			if singletonHeader(strings.ToLower(k)) {
+				return ErrDuplicateHeader
+			}
+		case DuplicateMerge:
+			sep := ", "
+			if k == "Cookie" {
+				sep = "; "
+			}
+			req.Header[k] = []string{strings.Join(v, sep)}
+		}
+	}
+	if m.maxHeaders > 0 && count > m.maxHeaders {
+		return ErrHeaderFieldsTooLarge
+	}
+	return nil
+}
+
+// peekHead returns the head of the request buffered by the reader without
+// consuming it, up to the line ending the header block, and whether the
+// head is complete. It is incomplete if it does not fit in the buffer or
+// the reading fails.
+func peekHead(reader *bufio.Reader) ([]byte, bool) {
+	var b []byte
+	for n := 1; n <= reader.Size(); {
+		if _, err := reader.Peek(n); err != nil {
+			return b, false
+		}
+		b, _ = reader.Peek(reader.Buffered())
+		if i := bytes.Index(b, []byte("\n\r\n")); i >= 0 {
+			return b[:i+1], true
+		} else if i := bytes.Index(b, []byte("\n\n")); i >= 0 {
+			return b[:i+1], true
+		}
+		n = len(b) + 1
+	}
+	return b, false
+}
+
+func singletonHeader(name string) bool {
+	for _, s := range singletonHeaders {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
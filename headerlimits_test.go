@@ -0,0 +1,92 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeaderLimits(t *testing.T) {
+	m := New()
+	m.SetMaxHeaders(3)
+	m.SetMaxHeaderLength(32)
+	m.SetDuplicateHeaders(DuplicateReject)
+	tests := []struct {
+		request string
+		err     error
+	}{
+		{"GET / HTTP/1.1\r\nHost: a\r\nAccept: */*\r\nAccept: text/html\r\n\r\n", nil},
+		{"GET / HTTP/1.1\r\nHost: a\r\nA: 1\r\nB: 2\r\nC: 3\r\n\r\n", ErrHeaderFieldsTooLarge},
+		{"GET / HTTP/1.1\r\nHost: a\r\nA: " + strings.Repeat("x", 32) + "\r\n\r\n", ErrHeaderFieldsTooLarge},
+		{"GET / HTTP/1.1\r\nHost: a\r\nAuthorization: a\r\nauthorization: b\r\n\r\n", ErrDuplicateHeader},
+	}
+	for _, test := range tests {
+		if _, err := m.readRequest(bufio.NewReader(strings.NewReader(test.request))); err != test.err {
+			t.Error(test.request, err)
+		}
+		if _, _, err := m.readFastRequest(bufio.NewReader(strings.NewReader(test.request)), m); err != test.err {
+			t.Error(test.request, err)
+		}
+	}
+	long := "GET / HTTP/1.1\r\nHost: a\r\n" + strings.Repeat("X-Long: "+strings.Repeat("x", 20)+"\r\n", 4) + "\r\n"
+	if err := m.limitHeaders(mustReadRequest(t, long), false); err != ErrHeaderFieldsTooLarge {
+		t.Error(err)
+	}
+	m.SetMaxHeaders(0)
+	m.SetMaxHeaderLength(0)
+	m.SetDuplicateHeaders(DuplicateMerge)
+	req, err := m.readRequest(bufio.NewReader(strings.NewReader("GET / HTTP/1.1\r\nHost: a\r\nAccept: a\r\nAccept: b\r\nCookie: x=1\r\nCookie: y=2\r\n\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := req.Header["Accept"]; len(v) != 1 || v[0] != "a, b" {
+		t.Error(v)
+	}
+	if c, err := req.Cookie("y"); err != nil || c.Value != "2" {
+		t.Error(req.Header["Cookie"], err)
+	}
+}
+
+func mustReadRequest(t *testing.T, s string) *http.Request {
+	req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(s)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestHeaderLimitsServe(t *testing.T) {
+	m := New()
+	m.SetMaxHeaders(2)
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	addr := ":8080"
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	conn, err := net.Dial("tcp", "127.0.0.1"+addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: a\r\nA: 1\r\nB: 2\r\n\r\n"))
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Error(err)
+	} else if res.StatusCode != http.StatusRequestHeaderFieldsTooLarge {
+		t.Error(res.Status)
+	}
+	conn.Close()
+	if m.BadRequests() != 1 {
+		t.Error(m.BadRequests())
+	}
+	m.Close()
+	<-done
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultHealthTimeout = time.Second
+
+// ErrDraining is reported by the readiness endpoint of a draining Server.
+var ErrDraining = errors.New("Server Draining")
+
+// Health checks the dependencies of the Server, such as the databases and
+// the caches. The zero value is ready to use.
+type Health struct {
+	// Timeout is the maximum duration of each check. If zero, one second is used.
+	Timeout time.Duration
+	// TTL is the duration a check result is cached, so that frequent probes
+	// do not overload the dependencies. Zero means no cache.
+	TTL time.Duration
+
+	mu     sync.RWMutex
+	checks []*healthCheck
+}
+
+type healthCheck struct {
+	name    string
+	check   func(ctx context.Context) error
+	mu      sync.Mutex
+	result  CheckResult
+	checked time.Time
+}
+
+// HealthReport reports the results of the checks.
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the result of a named check.
+type CheckResult struct {
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Time     time.Time     `json:"time"`
+}
+
+// Ready reports whether all checks passed.
+func (r *HealthReport) Ready() bool {
+	return r.Status == "ok"
+}
+
+// Check registers the named dependency check. The check should return when
+// the ctx is done.
+func (h *Health) Check(name string, check func(ctx context.Context) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, c := range h.checks {
+		if c.name == name {
+			c.mu.Lock()
+			c.check = check
+			c.checked = time.Time{}
+			c.mu.Unlock()
+			return
+		}
+	}
+	h.checks = append(h.checks, &healthCheck{name: name, check: check})
+}
+
+// Names returns the names of the checks, sorted.
+func (h *Health) Names() []string {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.checks))
+	for _, c := range h.checks {
+		names = append(names, c.name)
+	}
+	h.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// Run runs the checks concurrently, or uses the cached results, and
+// returns the report.
+func (h *Health) Run(ctx context.Context) *HealthReport {
+	h.mu.RLock()
+	checks := make([]*healthCheck, len(h.checks))
+	copy(checks, h.checks)
+	h.mu.RUnlock()
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *healthCheck) {
+			defer wg.Done()
+			results[i] = c.run(ctx, timeout, h.TTL)
+		}(i, c)
+	}
+	wg.Wait()
+	report := &HealthReport{Status: "ok"}
+	if len(checks) > 0 {
+		report.Checks = make(map[string]CheckResult, len(checks))
+	}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != "ok" {
+			report.Status = "fail"
+		}
+	}
+	return report
+}
+
+// ServeHTTP replies to the request with the JSON report, with a 503 status
+// code if any check failed.
+func (h *Health) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveHealth(w, h.Run(r.Context()))
+}
+
+func serveHealth(w http.ResponseWriter, report *HealthReport) {
+	w.Header().Set("Cache-Control", "no-store")
+	code := http.StatusOK
+	if !report.Ready() {
+		code = http.StatusServiceUnavailable
+	}
+	writeAdminJSON(w, code, report)
+}
+
+// run runs the check, holding the lock so that the concurrent probes
+// share the result.
+func (c *healthCheck) run(ctx context.Context, timeout, ttl time.Duration) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ttl > 0 && !c.checked.IsZero() && time.Since(c.checked) < ttl {
+		return c.result
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if e := recover(); e != nil {
+				done <- fmt.Errorf("panic: %v", e)
+			}
+		}()
+		done <- c.check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	c.result = CheckResult{Status: "ok", Duration: time.Since(start), Time: start}
+	if err != nil {
+		c.result.Status = "fail"
+		c.result.Error = err.Error()
+	}
+	c.checked = start
+	return c.result
+}
+
+// Readiness registers the readiness endpoint of the Server at the pattern,
+// replying with the JSON report of the checks of the Health. The Server is
+// not ready while draining.
+func (m *Rum) Readiness(pattern string, h *Health) *Entry {
+	return m.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		report := h.Run(r.Context())
+		if m.Draining() {
+			if report.Checks == nil {
+				report.Checks = make(map[string]CheckResult)
+			}
+			report.Status = "fail"
+			report.Checks["server"] = CheckResult{Status: "fail", Error: ErrDraining.Error(), Time: time.Now()}
+		}
+		serveHealth(w, report)
+	}).GET().HEAD()
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	var calls int32
+	h := &Health{Timeout: 20 * time.Millisecond, TTL: time.Hour}
+	h.Check("db", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+	h.Check("cache", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	h.Check("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	h.Check("panic", func(ctx context.Context) error {
+		panic("boom")
+	})
+	if names := h.Names(); len(names) != 4 || names[0] != "cache" || names[3] != "slow" {
+		t.Error(names)
+	}
+	start := time.Now()
+	report := h.Run(context.Background())
+	if time.Since(start) > time.Second {
+		t.Error(time.Since(start))
+	}
+	if report.Ready() || report.Status != "fail" || len(report.Checks) != 4 {
+		t.Fatal(report)
+	}
+	if c := report.Checks["db"]; c.Status != "ok" || c.Error != "" {
+		t.Error(c)
+	}
+	if c := report.Checks["cache"]; c.Status != "fail" || c.Error != "connection refused" {
+		t.Error(c)
+	}
+	if c := report.Checks["slow"]; c.Status != "fail" || c.Error != context.DeadlineExceeded.Error() {
+		t.Error(c)
+	}
+	if c := report.Checks["panic"]; c.Status != "fail" || c.Error != "panic: boom" {
+		t.Error(c)
+	}
+	h.Run(context.Background())
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Error(calls)
+	}
+	h.Check("cache", func(ctx context.Context) error { return nil })
+	h.Check("slow", func(ctx context.Context) error { return nil })
+	h.Check("panic", func(ctx context.Context) error { return nil })
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+	var decoded HealthReport
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &decoded) != nil || !decoded.Ready() || len(decoded.Checks) != 4 {
+		t.Error(w.Code, w.Body.String())
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Error(w.Header())
+	}
+	if report := (&Health{}).Run(context.Background()); !report.Ready() || report.Checks != nil {
+		t.Error(report)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	m := New()
+	h := &Health{}
+	h.Check("db", func(ctx context.Context) error { return nil })
+	m.Readiness("/ready", h)
+	w := httptest.NewRecorder()
+	m.Mux.ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+	if w.Code != http.StatusOK {
+		t.Error(w.Code, w.Body.String())
+	}
+	m.Drain()
+	w = httptest.NewRecorder()
+	m.Mux.ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+	var report HealthReport
+	if w.Code != http.StatusServiceUnavailable || json.Unmarshal(w.Body.Bytes(), &report) != nil {
+		t.Fatal(w.Code, w.Body.String())
+	}
+	if report.Checks["server"].Error != ErrDraining.Error() || report.Checks["db"].Status != "ok" {
+		t.Error(report)
+	}
+}
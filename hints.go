@@ -0,0 +1,87 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// EarlyHints sends a 103 Early Hints interim response with the links, such
+// as the ones returned by Preload, letting the client preload the resources
+// while the handler prepares the final response. The links are added to the
+// Link header, so the final response carries them as well. It must be called
+// before the final response header is written.
+func EarlyHints(w http.ResponseWriter, links ...string) {
+	for _, link := range links {
+		w.Header().Add("Link", link)
+	}
+	w.WriteHeader(http.StatusEarlyHints)
+}
+
+// Preload returns a Link header value preloading the url as the
+// destination, such as "style", "script", "font" or "image".
+func Preload(url, as string) string {
+	link := "<" + url + ">; rel=preload"
+	if as != "" {
+		link += "; as=" + as
+	}
+	return link
+}
+
+// informational reports whether the code is of an interim response,
+// which precedes the final response header.
+func informational(code int) bool {
+	return code >= 100 && code < 200 && code != http.StatusSwitchingProtocols
+}
+
+// hintsResponseWriter writes the interim responses ahead of the final response.
+type hintsResponseWriter struct {
+	forwarder
+	req         *http.Request
+	rw          *bufio.ReadWriter
+	wroteHeader bool
+	hijacked    bool
+}
+
+func (w *hintsResponseWriter) WriteHeader(code int) {
+	if !informational(code) || w.wroteHeader {
+		w.wroteHeader = true
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	// HTTP/1.0 clients do not understand the interim responses.
+	if !w.req.ProtoAtLeast(1, 1) {
+		return
+	}
+	writeResponseHeader(w.rw.Writer, code, w.Header())
+	w.rw.Writer.Flush()
+}
+
+func (w *hintsResponseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements the http.Flusher interface.
+func (w *hintsResponseWriter) Flush() {
+	w.wroteHeader = true
+	w.forwarder.Flush()
+}
+
+// FlushError implements the error-returning Flush.
+func (w *hintsResponseWriter) FlushError() error {
+	w.wroteHeader = true
+	return Flush(w.ResponseWriter)
+}
+
+// Hijack implements the http.Hijacker interface.
+func (w *hintsResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.hijacked = true
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEarlyHints(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		EarlyHints(w, Preload("/style.css", "style"), Preload("/app.js", "script"))
+		w.Write([]byte("Hello World"))
+	})
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	conn, err := net.Dial("tcp", "127.0.0.1"+addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	reader := bufio.NewReader(conn)
+	hints, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hints.StatusCode != http.StatusEarlyHints {
+		t.Error(hints.StatusCode)
+	}
+	if links := strings.Join(hints.Header["Link"], ", "); links != "</style.css>; rel=preload; as=style, </app.js>; rel=preload; as=script" {
+		t.Error(links)
+	}
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "Hello World" {
+		t.Error(resp.StatusCode, string(body))
+	}
+	if len(resp.Header["Link"]) != 2 {
+		t.Error(resp.Header)
+	}
+	conn.Close()
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	m.Close()
+	<-done
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+type allowedHosts struct {
+	hosts    map[string]bool
+	suffixes []string
+}
+
+// SetAllowedHosts sets the hosts served by the Server, preventing the
+// host header injection and the DNS rebinding attacks. A host matches the
+// Host of the requests without the port, unless it has a port itself, and
+// a host beginning with "*." matches the subdomains, such as
+// "*.example.com". The requests without a valid Host are replied with a
+// 400 status code, and those of the other hosts with a 421 status code.
+// No hosts allow all of the hosts.
+func (m *Rum) SetAllowedHosts(hosts ...string) {
+	if len(hosts) == 0 {
+		m.allowedHosts.Store((*allowedHosts)(nil))
+		return
+	}
+	a := &allowedHosts{hosts: make(map[string]bool)}
+	for _, host := range hosts {
+		host = strings.TrimSuffix(strings.ToLower(host), ".")
+		if strings.HasPrefix(host, "*.") {
+			a.suffixes = append(a.suffixes, host[1:])
+		} else {
+			a.hosts[host] = true
+		}
+	}
+	m.allowedHosts.Store(a)
+}
+
+// allows reports whether the host of the request is allowed.
+func (a *allowedHosts) allows(hostport string) bool {
+	hostport = strings.ToLower(hostport)
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(host, ".")
+	if a.hosts[host] || a.hosts[hostport] {
+		return true
+	}
+	for _, suffix := range a.suffixes {
+		if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostHandler returns the handler rejecting the request if its host is
+// not allowed, or nil.
+func (m *Rum) hostHandler(r *http.Request) http.Handler {
+	a, _ := m.allowedHosts.Load().(*allowedHosts)
+	if a == nil {
+		return nil
+	}
+	if !validHost(r.Host) {
+		return http.HandlerFunc(serveInvalidHost)
+	}
+	if !a.allows(r.Host) {
+		return http.HandlerFunc(serveMisdirected)
+	}
+	return nil
+}
+
+// validHost reports whether the host is a valid reg-name or IP literal
+// with an optional port.
+func validHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	for i := 0; i < len(host); i++ {
+		c := host[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '.', c == '-', c == '_', c == ':', c == '[', c == ']':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func serveInvalidHost(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "400 Bad Request : Invalid Host", http.StatusBadRequest)
+}
+
+func serveMisdirected(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "421 Misdirected Request", http.StatusMisdirectedRequest)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAllowedHosts(t *testing.T) {
+	m := New()
+	m.SetAllowedHosts("example.com", "*.example.org", "localhost:8080")
+	tests := []struct {
+		host   string
+		status int
+	}{
+		{"example.com", 0},
+		{"EXAMPLE.com:443", 0},
+		{"example.com.", 0},
+		{"api.example.org", 0},
+		{"example.org", http.StatusMisdirectedRequest},
+		{"localhost:8080", 0},
+		{"localhost:9090", http.StatusMisdirectedRequest},
+		{"evil.com", http.StatusMisdirectedRequest},
+		{"", http.StatusBadRequest},
+		{"example.com/x", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Host = test.host
+		h := m.hostHandler(r)
+		if test.status == 0 {
+			if h != nil {
+				t.Error(test.host)
+			}
+			continue
+		}
+		if h == nil {
+			t.Error(test.host)
+			continue
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Error(test.host, w.Code)
+		}
+	}
+	m.SetAllowedHosts()
+	if m.hostHandler(httptest.NewRequest("GET", "http://evil.com/", nil)) != nil {
+		t.Error("all of the hosts should be allowed")
+	}
+}
+
+func TestAllowedHostsServe(t *testing.T) {
+	m := New()
+	m.SetAllowedHosts("localhost")
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	addr := ":8080"
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	for _, test := range []struct {
+		request string
+		status  int
+	}{
+		{"GET / HTTP/1.1\r\nHost: localhost:8080\r\nConnection: close\r\n\r\n", http.StatusOK},
+		{"GET / HTTP/1.1\r\nHost: attacker.com\r\nConnection: close\r\n\r\n", http.StatusMisdirectedRequest},
+		{"GET / HTTP/1.0\r\n\r\n", http.StatusBadRequest},
+	} {
+		conn, err := net.Dial("tcp", "127.0.0.1"+addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write([]byte(test.request))
+		res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Error(err)
+		} else if res.StatusCode != test.status {
+			t.Error(test.request, res.Status)
+		}
+		conn.Close()
+	}
+	m.Close()
+	<-done
+}
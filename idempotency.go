@@ -0,0 +1,293 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxIdempotencyKey is the maximum length of an idempotency key.
+const maxIdempotencyKey = 255
+
+// IdempotencyStore stores the responses of the Idempotency middleware. The
+// Add must be atomic, so that the replicas of a service sharing the store
+// reject the concurrent duplicates.
+//
+// It is implemented by *MemoryStore and *RedisStore.
+type IdempotencyStore interface {
+	CacheStore
+	// Add stores the value of the key for the ttl unless the key exists,
+	// reporting whether it is stored.
+	Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+}
+
+// MemoryStore is a CacheStore and an IdempotencyStore in memory.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	now     func() time.Time
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryStore returns a new MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry), now: time.Now}
+}
+
+// Get returns the value of the key, or ErrCacheMiss.
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || !e.expires.IsZero() && !s.now().Before(e.expires) {
+		return nil, ErrCacheMiss
+	}
+	return e.value, nil
+}
+
+// Set stores the value of the key for the ttl.
+func (s *MemoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(key, value, ttl)
+	return nil
+}
+
+// Add stores the value of the key for the ttl unless the key exists,
+// reporting whether it is stored.
+func (s *MemoryStore) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok && (e.expires.IsZero() || s.now().Before(e.expires)) {
+		return false, nil
+	}
+	s.set(key, value, ttl)
+	return true, nil
+}
+
+// Delete deletes the key. The key is expired, and removed by the eviction.
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[key]; ok {
+		s.entries[key] = memoryEntry{expires: time.Unix(0, 1)}
+	}
+	return nil
+}
+
+// set must be called with the lock held.
+func (s *MemoryStore) set(key string, value []byte, ttl time.Duration) {
+	e := memoryEntry{value: value}
+	if ttl > 0 {
+		e.expires = s.now().Add(ttl)
+	}
+	s.entries[key] = e
+	if len(s.entries) >= maxBuckets {
+		s.evict()
+	}
+}
+
+// evict removes the expired entries, and must be called with the lock held.
+func (s *MemoryStore) evict() {
+	now := s.now()
+	entries := make(map[string]memoryEntry, len(s.entries))
+	for k, e := range s.entries {
+		if e.expires.IsZero() || now.Before(e.expires) {
+			entries[k] = e
+		}
+	}
+	s.entries = entries
+}
+
+// idempotencyRecord is the record of a key, processing if the code is zero.
+type idempotencyRecord struct {
+	Fingerprint string      `json:"fingerprint"`
+	Code        int         `json:"code,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// Idempotency is a middleware storing the responses of the requests
+// carrying an Idempotency-Key, and replaying them to the retries with the
+// "Idempotent-Replayed: true" header. A retry while the first request is
+// being processed is replied with 409 Conflict, and a key reused with a
+// different request with 422 Unprocessable Entity. The server errors are
+// not stored, so that the requests can be retried.
+type Idempotency struct {
+	// Store stores the responses. If nil, a MemoryStore is used.
+	Store IdempotencyStore
+	// Header is the header carrying the key. If empty, "Idempotency-Key"
+	// is used.
+	Header string
+	// Methods are the methods of the requests. If empty, POST and PATCH.
+	Methods []string
+	// Required replies 400 Bad Request to the requests without a key.
+	Required bool
+	// Scope optionally partitions the keys, for example by the API key, so
+	// that the clients cannot replay the responses of each other.
+	Scope func(r *http.Request) string
+	// TTL is the lifetime of the stored responses. Zero means 24 hours.
+	TTL time.Duration
+	// LockTimeout is the lifetime of the key while the request is being
+	// processed, releasing it if the process crashes. Zero means one minute.
+	LockTimeout time.Duration
+	// MaxBodySize is the maximum size in bytes of the request bodies and of
+	// the stored responses. The larger responses are not stored. Zero means
+	// one megabyte.
+	MaxBodySize int64
+
+	once sync.Once
+}
+
+// Handler returns a handler storing and replaying the responses of the handler.
+func (i *Idempotency) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i.once.Do(i.init)
+		key := r.Header.Get(i.Header)
+		if key == "" || !strSliceContains(i.Methods, r.Method) {
+			if key == "" && i.Required && strSliceContains(i.Methods, r.Method) {
+				http.Error(w, "400 Bad Request : Missing "+i.Header, http.StatusBadRequest)
+				return
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			http.Error(w, "400 Bad Request : Invalid "+i.Header, http.StatusBadRequest)
+			return
+		}
+		if i.Scope != nil {
+			key = i.Scope(r) + "\x00" + key
+		}
+		key = "idempotency:" + key
+		if r.ContentLength > i.MaxBodySize {
+			http.Error(w, "413 Request Entity Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, i.MaxBodySize+1))
+		if err != nil {
+			http.Error(w, "400 Bad Request", http.StatusBadRequest)
+			return
+		} else if int64(len(body)) > i.MaxBodySize {
+			http.Error(w, "413 Request Entity Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		fingerprint := idempotencyFingerprint(r, body)
+		processing, _ := json.Marshal(&idempotencyRecord{Fingerprint: fingerprint})
+		added, err := i.Store.Add(r.Context(), key, processing, i.LockTimeout)
+		if err != nil {
+			http.Error(w, "503 Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if !added {
+			i.replay(w, r, key, fingerprint)
+			return
+		}
+		i.serve(h, w, r, key, fingerprint)
+	})
+}
+
+func (i *Idempotency) init() {
+	if i.Store == nil {
+		i.Store = NewMemoryStore()
+	}
+	if i.Header == "" {
+		i.Header = "Idempotency-Key"
+	}
+	if len(i.Methods) == 0 {
+		i.Methods = []string{"POST", "PATCH"}
+	}
+	if i.TTL <= 0 {
+		i.TTL = time.Hour * 24
+	}
+	if i.LockTimeout <= 0 {
+		i.LockTimeout = time.Minute
+	}
+	if i.MaxBodySize <= 0 {
+		i.MaxBodySize = 1 << 20
+	}
+}
+
+// serve serves the first request of the key and stores the response.
+func (i *Idempotency) serve(h http.Handler, w http.ResponseWriter, r *http.Request, key, fingerprint string) {
+	ctx := &detachedContext{Context: context.Background(), values: r.Context()}
+	stored := false
+	defer func() {
+		if !stored {
+			i.Store.Delete(ctx, key)
+		}
+	}()
+	aw := &accessResponseWriter{forwarder: forwarder{w}, body: &bytes.Buffer{}, limit: int(i.MaxBodySize)}
+	h.ServeHTTP(expose(aw, w), r)
+	if aw.code == 0 {
+		aw.code = http.StatusOK
+	}
+	if aw.code >= 500 || aw.size > i.MaxBodySize {
+		return
+	}
+	header := w.Header().Clone()
+	for _, k := range []string{"Date", "Set-Cookie"} {
+		header.Del(k)
+	}
+	value, err := json.Marshal(&idempotencyRecord{Fingerprint: fingerprint, Code: aw.code, Header: header, Body: aw.body.Bytes()})
+	if err == nil && i.Store.Set(ctx, key, value, i.TTL) == nil {
+		stored = true
+	}
+}
+
+// replay replies to a retry of the key.
+func (i *Idempotency) replay(w http.ResponseWriter, r *http.Request, key, fingerprint string) {
+	value, err := i.Store.Get(r.Context(), key)
+	var record idempotencyRecord
+	if err == ErrCacheMiss {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "409 Conflict", http.StatusConflict)
+		return
+	} else if err != nil || json.Unmarshal(value, &record) != nil {
+		http.Error(w, "503 Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if record.Fingerprint != fingerprint {
+		http.Error(w, "422 Unprocessable Entity", http.StatusUnprocessableEntity)
+		return
+	}
+	if record.Code == 0 {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "409 Conflict", http.StatusConflict)
+		return
+	}
+	for k, v := range record.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.Header().Set("Content-Length", strconv.Itoa(len(record.Body)))
+	w.WriteHeader(record.Code)
+	if r.Method != "HEAD" {
+		w.Write(record.Body)
+	}
+}
+
+// idempotencyFingerprint returns the fingerprint of the method, the URI
+// and the body of the request.
+func idempotencyFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
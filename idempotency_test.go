@@ -0,0 +1,145 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+	if added, err := s.Add(ctx, "a", []byte("1"), time.Minute); !added || err != nil {
+		t.Error(added, err)
+	}
+	if added, _ := s.Add(ctx, "a", []byte("2"), time.Minute); added {
+		t.Error("the key exists")
+	}
+	if v, err := s.Get(ctx, "a"); err != nil || string(v) != "1" {
+		t.Error(string(v), err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := s.Get(ctx, "a"); err != ErrCacheMiss {
+		t.Error(err)
+	}
+	if added, _ := s.Add(ctx, "a", []byte("3"), 0); !added {
+		t.Error("the key is expired")
+	}
+	s.Delete(ctx, "a")
+	if _, err := s.Get(ctx, "a"); err != ErrCacheMiss {
+		t.Error(err)
+	}
+	s.Set(ctx, "b", []byte("4"), 0)
+	if v, err := s.Get(ctx, "b"); err != nil || string(v) != "4" {
+		t.Error(string(v), err)
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	i := &Idempotency{Required: true}
+	h := i.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) == "slow" {
+			<-release
+		}
+		if string(body) == "fail" {
+			http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Location", "/payments/"+strconv.Itoa(int(n)))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("payment " + strconv.Itoa(int(n))))
+	}))
+	serve := func(method, key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/payments", strings.NewReader(body))
+		if key != "" {
+			r.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	if w := serve("POST", "", "a"); w.Code != http.StatusBadRequest {
+		t.Error(w.Code)
+	}
+	if w := serve("GET", "", ""); w.Code != http.StatusCreated {
+		t.Error(w.Code)
+	}
+	w := serve("POST", "k1", "a")
+	if w.Code != http.StatusCreated || w.Body.String() != "payment 2" || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Error(w.Code, w.Body.String())
+	}
+	w = serve("POST", "k1", "a")
+	if w.Code != http.StatusCreated || w.Body.String() != "payment 2" || w.Header().Get("Location") != "/payments/2" ||
+		w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error(w.Code, w.Body.String(), w.Header())
+	}
+	if w := serve("POST", "k1", "b"); w.Code != http.StatusUnprocessableEntity {
+		t.Error(w.Code)
+	}
+	if w := serve("POST", "k2", "fail"); w.Code != http.StatusInternalServerError {
+		t.Error(w.Code)
+	}
+	if w := serve("POST", "k2", "fail"); w.Code != http.StatusInternalServerError || atomic.LoadInt32(&calls) != 4 {
+		t.Error(w.Code, calls)
+	}
+	if w := serve("POST", strings.Repeat("k", 256), "a"); w.Code != http.StatusBadRequest {
+		t.Error(w.Code)
+	}
+	done := make(chan struct{})
+	go func() {
+		serve("POST", "k3", "slow")
+		close(done)
+	}()
+	for atomic.LoadInt32(&calls) != 5 {
+		time.Sleep(time.Millisecond)
+	}
+	if w := serve("POST", "k3", "slow"); w.Code != http.StatusConflict || w.Header().Get("Retry-After") != "1" {
+		t.Error(w.Code)
+	}
+	close(release)
+	<-done
+	if w := serve("POST", "k3", "slow"); w.Code != http.StatusCreated || w.Body.String() != "payment 5" {
+		t.Error(w.Code, w.Body.String())
+	}
+}
+
+func TestIdempotencyRedis(t *testing.T) {
+	addr, close := testRedis(t)
+	defer close()
+	var calls int32
+	i := &Idempotency{
+		Store: &RedisStore{Addr: addr},
+		Scope: func(r *http.Request) string { return r.Header.Get("X-API-Key") },
+	}
+	h := i.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strconv.Itoa(int(atomic.AddInt32(&calls, 1)))))
+	}))
+	for _, test := range []struct {
+		apiKey string
+		body   string
+	}{{"a", "1"}, {"a", "1"}, {"b", "2"}} {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Idempotency-Key", "k")
+		r.Header.Set("X-API-Key", test.apiKey)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Body.String() != test.body {
+			t.Error(test.apiKey, w.Body.String())
+		}
+	}
+}
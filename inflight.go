@@ -0,0 +1,126 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// InflightRequest describes a request being served.
+type InflightRequest struct {
+	ID         uint64        `json:"id"`
+	Method     string        `json:"method"`
+	URL        string        `json:"url"`
+	Route      string        `json:"route,omitempty"`
+	ClientIP   string        `json:"clientIP"`
+	Start      time.Time     `json:"start"`
+	Duration   time.Duration `json:"duration"`
+	Goroutine  uint64        `json:"goroutine"`
+	remoteAddr string
+}
+
+type inflight struct {
+	seq      uint64
+	requests sync.Map
+}
+
+// SetInflight enables the tracking of the requests being served, listed
+// by Inflight, to diagnose the stuck handlers. It costs a goroutine stack
+// read and a route lookup per request.
+//
+// SetInflight must be called before serving.
+func (m *Rum) SetInflight(enabled bool) {
+	if !enabled {
+		m.inflight = nil
+		return
+	}
+	m.inflight = &inflight{}
+}
+
+// Inflight returns the requests being served, the longest running first,
+// if the tracking is enabled by SetInflight.
+func (m *Rum) Inflight() []InflightRequest {
+	t := m.inflight
+	if t == nil {
+		return nil
+	}
+	now := time.Now()
+	var requests []InflightRequest
+	t.requests.Range(func(key, value interface{}) bool {
+		requests = append(requests, *value.(*InflightRequest))
+		return true
+	})
+	for i := range requests {
+		r := &requests[i]
+		r.Duration = now.Sub(r.Start)
+		r.ClientIP = r.remoteAddr
+		if host, _, err := net.SplitHostPort(r.remoteAddr); err == nil {
+			r.ClientIP = host
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Start.Before(requests[j].Start) })
+	return requests
+}
+
+// add tracks the request served by the current goroutine. The route is
+// resolved with the mux now, since the request may be reused by the time
+// it is listed.
+func (t *inflight) add(mux *Mux, req *http.Request, conn net.Conn) uint64 {
+	r := &InflightRequest{
+		Method:     req.Method,
+		URL:        req.URL.RequestURI(),
+		Start:      time.Now(),
+		Goroutine:  goroutineID(),
+		remoteAddr: req.RemoteAddr,
+	}
+	if mux != nil {
+		r.Route = mux.routeOf(req)
+	}
+	if r.remoteAddr == "" && conn != nil {
+		r.remoteAddr = conn.RemoteAddr().String()
+	}
+	r.ID = atomic.AddUint64(&t.seq, 1)
+	t.requests.Store(r.ID, r)
+	return r.ID
+}
+
+func (t *inflight) done(id uint64) {
+	t.requests.Delete(id)
+}
+
+// goroutineID returns the id of the current goroutine, parsed from the
+// "goroutine 18 [running]:" header of its stack.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// routeOf returns the route pattern of the request, or empty if not found.
+func (m *Mux) routeOf(r *http.Request) string {
+	path, _ := m.canonical(r)
+	m.mut.RLock()
+	entry := m.searchEntry(path, nil, r)
+	m.mut.RUnlock()
+	if entry == nil {
+		return ""
+	}
+	if entry.aliased != nil {
+		entry = entry.aliased.entry
+	}
+	return entry.route()
+}
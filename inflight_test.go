@@ -0,0 +1,92 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestInflight(t *testing.T) {
+	m := New()
+	m.SetInflight(true)
+	m.Admin("/admin", AdminToken("secret"))
+	started := make(chan uint64)
+	release := make(chan struct{})
+	m.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		started <- goroutineID()
+		<-release
+		w.Write([]byte("Hello World"))
+	}).GET()
+	addr := ":8080"
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	served := make(chan struct{})
+	go func() {
+		testHTTP("GET", "http://"+addr+"/users/8?q=1", http.StatusOK, "Hello World", t)
+		close(served)
+	}()
+	goroutine := <-started
+	time.Sleep(time.Millisecond * 10)
+	requests := m.Inflight()
+	if len(requests) != 1 {
+		t.Fatal(requests)
+	}
+	r := requests[0]
+	if r.Method != "GET" || r.URL != "/users/8?q=1" || r.Route != "/users/:id" || r.ClientIP != "127.0.0.1" ||
+		r.Goroutine != goroutine || r.Goroutine == 0 || r.Duration < 10*time.Millisecond {
+		t.Error(r)
+	}
+	req, _ := http.NewRequest("GET", "http://"+addr+"/admin/inflight", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	var listed []InflightRequest
+	if resp.StatusCode != http.StatusOK || json.Unmarshal(b, &listed) != nil || len(listed) != 2 ||
+		listed[0].Route != "/users/:id" || listed[1].URL != "/admin/inflight" {
+		t.Error(resp.StatusCode, string(b))
+	}
+	close(release)
+	<-served
+	if requests := m.Inflight(); len(requests) != 0 {
+		t.Error(requests)
+	}
+	m.SetInflight(false)
+	if requests := m.Inflight(); requests != nil {
+		t.Error(requests)
+	}
+	m.Close()
+	<-done
+}
+
+func TestRouteOf(t *testing.T) {
+	m := NewMux()
+	m.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {}).Alias("/members/:id")
+	m.Group("/api", func(m *Mux) {
+		m.HandleFunc("/items", func(w http.ResponseWriter, r *http.Request) {})
+	})
+	for path, route := range map[string]string{
+		"/users/1":   "/users/:id",
+		"/members/1": "/users/:id",
+		"/api/items": "/api/items",
+		"/missing":   "",
+	} {
+		r, _ := http.NewRequest("GET", path, nil)
+		if got := m.routeOf(r); got != route {
+			t.Error(path, got)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"runtime"
+	"time"
+)
+
+const defaultLeakThreshold = 100
+
+// LeakStats compares the goroutines and the file descriptors of the process
+// with the connections tracked by the Server.
+type LeakStats struct {
+	// Conns is the number of the open connections.
+	Conns int64 `json:"conns"`
+	// Tasks is the number of the background goroutines started by Go.
+	Tasks int64 `json:"tasks"`
+	// Goroutines is the number of the goroutines.
+	Goroutines int64 `json:"goroutines"`
+	// Fds is the number of the open file descriptors, or zero if unknown.
+	Fds int64 `json:"fds"`
+	// ExcessGoroutines is the number of the goroutines beyond the baseline
+	// and the goroutines expected by the connections and the tasks.
+	ExcessGoroutines int64 `json:"excessGoroutines"`
+	// ExcessFds is the number of the file descriptors beyond the baseline
+	// and the connections.
+	ExcessFds int64 `json:"excessFds"`
+	// Leaking reports whether an excess is above the threshold in
+	// consecutive samples.
+	Leaking bool `json:"leaking"`
+}
+
+// LeakDetector detects the leaks of the goroutines and the file descriptors
+// by comparing them with the connections tracked by the Server. The
+// baseline is the lowest count sampled without connections.
+type LeakDetector struct {
+	// Interval is the sampling interval. If zero, one minute is used.
+	Interval time.Duration
+	// Threshold is the tolerated excess. If zero, 100 is used.
+	Threshold int64
+	// OnLeak is called with the stats when a leak is detected. If nil,
+	// the leak is logged to the error log.
+	OnLeak func(stats LeakStats)
+}
+
+type leakBaseline struct {
+	goroutines int64
+	fds        int64
+	exceeded   int
+}
+
+// SetLeakDetector starts the leak detector sampling in the background.
+// A nil detector stops the sampling.
+func (m *Rum) SetLeakDetector(d *LeakDetector) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	if m.leakSampling != nil {
+		close(m.leakSampling)
+		m.leakSampling = nil
+	}
+	if d == nil {
+		m.leakStats.Store(LeakStats{})
+		return
+	}
+	detector := *d
+	if detector.Interval <= 0 {
+		detector.Interval = time.Minute
+	}
+	if detector.Threshold <= 0 {
+		detector.Threshold = defaultLeakThreshold
+	}
+	done := make(chan struct{})
+	m.leakSampling = done
+	go m.sampleLeaks(&detector, done)
+}
+
+// LeakStats returns the last sampled leak stats.
+func (m *Rum) LeakStats() LeakStats {
+	stats, _ := m.leakStats.Load().(LeakStats)
+	return stats
+}
+
+func (m *Rum) sampleLeaks(d *LeakDetector, done chan struct{}) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+	baseline := &leakBaseline{goroutines: -1, fds: -1}
+	for {
+		stats := m.sampleLeak(d, baseline)
+		m.leakStats.Store(stats)
+		if stats.Leaking {
+			if d.OnLeak != nil {
+				d.OnLeak(stats)
+			} else {
+				m.logf("rum: leak detected: %d conns, %d goroutines (%d excess), %d fds (%d excess)",
+					stats.Conns, stats.Goroutines, stats.ExcessGoroutines, stats.Fds, stats.ExcessFds)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
+
+// sampleLeak samples the stats and updates the baseline.
+func (m *Rum) sampleLeak(d *LeakDetector, baseline *leakBaseline) LeakStats {
+	stats := LeakStats{
+		Conns:      m.Conns(),
+		Tasks:      m.Tasks(),
+		Goroutines: int64(runtime.NumGoroutine()),
+		Fds:        OpenFds(),
+	}
+	if stats.Conns == 0 && stats.Tasks == 0 {
+		if baseline.goroutines < 0 || stats.Goroutines < baseline.goroutines {
+			baseline.goroutines = stats.Goroutines
+		}
+		if baseline.fds < 0 || stats.Fds < baseline.fds {
+			baseline.fds = stats.Fds
+		}
+	}
+	if baseline.goroutines < 0 {
+		return stats
+	}
+	// A blocking connection is served by one goroutine, a polled one by none.
+	expected := stats.Tasks
+	if !m.poll {
+		expected += stats.Conns
+	}
+	stats.ExcessGoroutines = stats.Goroutines - baseline.goroutines - expected
+	if stats.Fds > 0 {
+		stats.ExcessFds = stats.Fds - baseline.fds - stats.Conns
+	}
+	if stats.ExcessGoroutines > d.Threshold || stats.ExcessFds > d.Threshold {
+		baseline.exceeded++
+	} else {
+		baseline.exceeded = 0
+	}
+	stats.Leaking = baseline.exceeded >= 2
+	return stats
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLeakDetector(t *testing.T) {
+	m := New()
+	d := &LeakDetector{Threshold: 10}
+	baseline := &leakBaseline{goroutines: -1, fds: -1}
+	if stats := m.sampleLeak(d, baseline); stats.Goroutines == 0 || stats.ExcessGoroutines != 0 || stats.Leaking {
+		t.Error(stats)
+	}
+	stop := make(chan struct{})
+	for i := 0; i < 20; i++ {
+		go func() { <-stop }()
+	}
+	if stats := m.sampleLeak(d, baseline); stats.ExcessGoroutines < 20 || stats.Leaking {
+		t.Error(stats)
+	}
+	if stats := m.sampleLeak(d, baseline); !stats.Leaking {
+		t.Error(stats)
+	}
+	close(stop)
+	time.Sleep(10 * time.Millisecond)
+	if stats := m.sampleLeak(d, baseline); stats.ExcessGoroutines > 0 || stats.Leaking {
+		t.Error(stats)
+	}
+	if OpenFds() < 0 {
+		t.Error(OpenFds())
+	}
+}
+
+func TestSetLeakDetector(t *testing.T) {
+	buf := &syncBuffer{}
+	m := New()
+	m.SetErrorLog(log.New(buf, "", 0))
+	leaks := make(chan LeakStats, 4)
+	stop := make(chan struct{})
+	m.SetLeakDetector(&LeakDetector{Interval: 10 * time.Millisecond, Threshold: 5, OnLeak: func(stats LeakStats) {
+		select {
+		case leaks <- stats:
+		default:
+		}
+	}})
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		go func() { <-stop }()
+	}
+	select {
+	case stats := <-leaks:
+		if stats.ExcessGoroutines < 10 || m.LeakStats().Goroutines == 0 {
+			t.Error(stats)
+		}
+	case <-time.After(time.Second):
+		t.Error("no leak detected")
+	}
+	m.SetLeakDetector(nil)
+	if stats := m.LeakStats(); stats.Goroutines != 0 {
+		t.Error(stats)
+	}
+	close(stop)
+	time.Sleep(10 * time.Millisecond)
+	m.SetLeakDetector(&LeakDetector{Interval: 10 * time.Millisecond, Threshold: 5})
+	time.Sleep(30 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		go func() { <-time.After(time.Second) }()
+	}
+	for i := 0; i < 100 && !strings.Contains(buf.String(), "rum: leak detected"); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s := buf.String(); !strings.Contains(s, "rum: leak detected") {
+		t.Error(s)
+	}
+	m.Close()
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+)
+
+// OnStart registers a hook run before the Server starts serving, such as
+// opening the databases. The hooks are run once in the order registered,
+// by the first call of Run, RunTLS, Serve or ServeTLS. If a hook returns
+// an error, the later hooks are not run and the error is returned by the
+// serving calls.
+func (m *Rum) OnStart(hook func() error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.startHooks = append(m.startHooks, hook)
+}
+
+// OnStop registers a hook run by Shutdown after the Server is closed, such
+// as closing the databases. The hooks are run once in the reverse order
+// registered, like deferred calls, with the ctx of Shutdown.
+func (m *Rum) OnStop(hook func(ctx context.Context) error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.stopHooks = append(m.stopHooks, hook)
+}
+
+// start runs the start hooks once.
+func (m *Rum) start() error {
+	m.startOnce.Do(func() {
+		m.mut.Lock()
+		hooks := m.startHooks
+		m.mut.Unlock()
+		for _, hook := range hooks {
+			if err := hook(); err != nil {
+				m.startErr = err
+				return
+			}
+		}
+	})
+	return m.startErr
+}
+
+// stop runs the stop hooks once and returns the first error.
+func (m *Rum) stop(ctx context.Context) (err error) {
+	m.stopOnce.Do(func() {
+		m.mut.Lock()
+		hooks := m.stopHooks
+		m.mut.Unlock()
+		for i := len(hooks) - 1; i >= 0; i-- {
+			if e := hooks[i](ctx); e != nil && err == nil {
+				err = e
+			}
+		}
+	})
+	return
+}
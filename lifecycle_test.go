@@ -0,0 +1,73 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	var calls []string
+	m := New()
+	m.OnStart(func() error {
+		calls = append(calls, "start db")
+		return nil
+	})
+	m.OnStart(func() error {
+		calls = append(calls, "start cache")
+		return nil
+	})
+	m.OnStop(func(ctx context.Context) error {
+		calls = append(calls, "stop db")
+		return errors.New("db")
+	})
+	m.OnStop(func(ctx context.Context) error {
+		calls = append(calls, "stop cache")
+		return nil
+	})
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	}).GET()
+	addr := ":8080"
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	if err := m.Shutdown(context.Background()); err == nil || err.Error() != "db" {
+		t.Error(err)
+	}
+	<-done
+	m.Shutdown(context.Background())
+	if s := strings.Join(calls, ","); s != "start db,start cache,stop cache,stop db" {
+		t.Error(s)
+	}
+}
+
+func TestLifecycleStartError(t *testing.T) {
+	var started bool
+	m := New()
+	m.OnStart(func() error { return errors.New("db") })
+	m.OnStart(func() error {
+		started = true
+		return nil
+	})
+	if err := m.Run(":8080"); err == nil || err.Error() != "db" {
+		t.Error(err)
+	}
+	if err := m.Run(":8080"); err == nil || err.Error() != "db" {
+		t.Error(err)
+	}
+	if started {
+		t.Error()
+	}
+	m.Close()
+}
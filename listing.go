@@ -0,0 +1,136 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package rum
+
+import (
+	"bytes"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// DirListing is the data of the template of a directory listing.
+type DirListing struct {
+	// Path is the URL path of the directory, ending with a slash.
+	Path string
+	// Parent reports whether the directory is not the root of the files.
+	Parent  bool
+	Entries []DirEntry
+}
+
+// DirEntry is an entry of a directory listing.
+type DirEntry struct {
+	Name    string
+	Dir     bool
+	Size    int64
+	ModTime time.Time
+}
+
+var defaultListingTemplate = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Index of {{.Path}}</title>
+<style>
+body{font-family:sans-serif;margin:2em}
+table{border-collapse:collapse}
+th,td{padding:.25em 1em;text-align:left}
+tr:nth-child(even){background:#f4f4f4}
+td.size{text-align:right}
+</style>
+</head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<table>
+<tr><th>Name</th><th>Size</th><th>Modified</th></tr>
+{{if .Parent}}<tr><td><a href="../">../</a></td><td></td><td></td></tr>
+{{end}}{{range .Entries}}<tr><td><a href="{{.Name}}{{if .Dir}}/{{end}}">{{.Name}}{{if .Dir}}/{{end}}</a></td><td class="size">{{if not .Dir}}{{.Size}}{{end}}</td><td>{{if not .ModTime.IsZero}}{{.ModTime.UTC.Format "2006-01-02 15:04"}}{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// list replies with the listing of the directory.
+func (s *staticFS) list(w http.ResponseWriter, r *http.Request, dir string) {
+	entries, err := fs.ReadDir(s.fsys, dir)
+	if err != nil {
+		http.Error(w, "500 Internal Server Error : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	listing := &DirListing{Path: r.URL.Path, Parent: dir != "."}
+	for _, entry := range entries {
+		if s.hidden(path.Join(dir, entry.Name())) {
+			continue
+		}
+		e := DirEntry{Name: entry.Name(), Dir: entry.IsDir()}
+		if info, err := entry.Info(); err == nil {
+			e.Size, e.ModTime = info.Size(), info.ModTime()
+		}
+		listing.Entries = append(listing.Entries, e)
+	}
+	tmpl := s.options.ListingTemplate
+	if tmpl == nil {
+		tmpl = defaultListingTemplate
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, listing); err != nil {
+		http.Error(w, "500 Internal Server Error : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if r.Method != "HEAD" {
+		w.Write(buf.Bytes())
+	}
+}
+
+// hidden reports whether an element of the name matches a hidden pattern.
+func (s *staticFS) hidden(name string) bool {
+	if len(s.options.Hidden) == 0 || name == "." {
+		return false
+	}
+	for _, elem := range strings.Split(name, "/") {
+		for _, pattern := range s.options.Hidden {
+			if ok, _ := path.Match(pattern, elem); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowed applies the access rule of the deepest directory containing the name.
+func (s *staticFS) allowed(r *http.Request, name string) bool {
+	if len(s.options.Access) == 0 {
+		return true
+	}
+	dir := name
+	if !s.dirs[dir] {
+		dir = path.Dir(dir)
+	}
+	for {
+		if rule, ok := s.options.Access[dir]; ok {
+			return rule(r)
+		}
+		if dir == "." {
+			return true
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+func cleanDir(dir string) string {
+	dir = path.Clean("/" + dir)
+	if dir == "/" {
+		return "."
+	}
+	return dir[1:]
+}
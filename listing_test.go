@@ -0,0 +1,99 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package rum
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDirListing(t *testing.T) {
+	fsys := fstest.MapFS{
+		"readme.txt":           {Data: []byte("readme")},
+		".env":                 {Data: []byte("secret")},
+		"docs/guide.txt":       {Data: []byte("guide")},
+		"docs/.git/config":     {Data: []byte("config")},
+		"site/index.html":      {Data: []byte("site")},
+		"private/report.txt":   {Data: []byte("report")},
+		"private/public/a.txt": {Data: []byte("a")},
+	}
+	m := NewMux()
+	_, err := m.StaticFS("/files/", fsys, Static{
+		Listing: true,
+		Hidden:  []string{".*"},
+		Access: map[string]func(r *http.Request) bool{
+			"/private/": func(r *http.Request) bool {
+				return r.Header.Get("Authorization") == "secret"
+			},
+			"private/public": func(r *http.Request) bool { return true },
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := func(target, auth string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", target, nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		return w
+	}
+	w := serve("/files/", "")
+	body := w.Body.String()
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/html; charset=utf-8" ||
+		!strings.Contains(body, `<a href="docs/">docs/</a>`) || !strings.Contains(body, `<a href="readme.txt">readme.txt</a>`) ||
+		strings.Contains(body, ".env") || strings.Contains(body, "../") {
+		t.Error(w.Code, body)
+	}
+	w = serve("/files/docs/", "")
+	if body := w.Body.String(); !strings.Contains(body, "guide.txt") || strings.Contains(body, ".git") || !strings.Contains(body, `href="../"`) {
+		t.Error(body)
+	}
+	tests := []struct {
+		target, auth string
+		code         int
+		body         string
+	}{
+		{"/files/.env", "", http.StatusNotFound, ""},
+		{"/files/docs/.git/config", "", http.StatusNotFound, ""},
+		{"/files/docs/.git/", "", http.StatusNotFound, ""},
+		{"/files/site/", "", http.StatusOK, "site"},
+		{"/files/private/report.txt", "", http.StatusForbidden, ""},
+		{"/files/private/", "", http.StatusForbidden, ""},
+		{"/files/private/report.txt", "secret", http.StatusOK, "report"},
+		{"/files/private/public/a.txt", "", http.StatusOK, "a"},
+	}
+	for _, test := range tests {
+		w := serve(test.target, test.auth)
+		if w.Code != test.code || test.body != "" && w.Body.String() != test.body {
+			t.Error(test.target, w.Code, w.Body.String())
+		}
+	}
+	m = NewMux()
+	m.StaticFS("/", fsys, Static{
+		Listing:         true,
+		ListingTemplate: template.Must(template.New("").Parse(`{{.Path}}:{{range .Entries}} {{.Name}}{{end}}`)),
+	})
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/docs/", nil))
+	if w.Body.String() != "/docs/: .git guide.txt" {
+		t.Error(w.Body.String())
+	}
+	m = NewMux()
+	m.StaticFS("/", fsys, Static{})
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/docs/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Error(w.Code)
+	}
+}
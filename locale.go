@@ -0,0 +1,105 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LocaleContextKey is a context key. The associated value will be of type string.
+var LocaleContextKey = &contextKey{"locale"}
+
+type language struct {
+	tag string
+	q   float64
+}
+
+// Negotiate returns the supported language that best matches the
+// Accept-Language header of the request. A language tag also matches
+// its base language, so "en-US" matches "en" and "en" matches "en-GB".
+// The first supported language is returned when nothing matches.
+func Negotiate(r *http.Request, supported ...string) string {
+	if len(supported) == 0 {
+		return ""
+	}
+	languages := parseAcceptLanguage(r.Header.Get("Accept-Language"))
+	for _, lang := range languages {
+		if lang.tag == "*" {
+			return supported[0]
+		}
+		for _, s := range supported {
+			if strings.EqualFold(lang.tag, s) {
+				return s
+			}
+		}
+		base := baseLanguage(lang.tag)
+		for _, s := range supported {
+			if strings.EqualFold(base, baseLanguage(s)) {
+				return s
+			}
+		}
+	}
+	return supported[0]
+}
+
+func baseLanguage(tag string) string {
+	if i := strings.IndexAny(tag, "-_"); i > 0 {
+		return tag[:i]
+	}
+	return tag
+}
+
+func parseAcceptLanguage(header string) []language {
+	var languages []language
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lang := language{tag: part, q: 1}
+		if i := strings.Index(part, ";"); i >= 0 {
+			lang.tag = strings.TrimSpace(part[:i])
+			for _, param := range strings.Split(part[i+1:], ";") {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") {
+					if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+						lang.q = q
+					}
+				}
+			}
+		}
+		if lang.q > 0 && lang.tag != "" {
+			languages = append(languages, lang)
+		}
+	}
+	sort.SliceStable(languages, func(i, j int) bool { return languages[i].q > languages[j].q })
+	return languages
+}
+
+// Locale returns the locale stored by the LocaleHandler in the request context.
+func Locale(r *http.Request) string {
+	if locale, ok := r.Context().Value(LocaleContextKey).(string); ok {
+		return locale
+	}
+	return ""
+}
+
+// LocaleHandler returns a handler that negotiates the locale of the request
+// with Negotiate, stores it in the request context and sets the
+// Content-Language header before calling the handler.
+func LocaleHandler(handler http.Handler, supported ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := Negotiate(r, supported...)
+		w.Header().Add("Vary", "Accept-Language")
+		if locale != "" {
+			w.Header().Set("Content-Language", locale)
+		}
+		ctx := context.WithValue(r.Context(), LocaleContextKey, locale)
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	supported := []string{"en", "zh-CN", "fr-FR"}
+	tests := []struct {
+		header string
+		locale string
+	}{
+		{"", "en"},
+		{"zh-CN", "zh-CN"},
+		{"zh-cn,en;q=0.8", "zh-CN"},
+		{"en;q=0.5, fr;q=0.9", "fr-FR"},
+		{"en-US,en;q=0.9", "en"},
+		{"de, *;q=0.1", "en"},
+		{"de;q=0, ja", "en"},
+		{"fr-CA", "fr-FR"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Language", test.header)
+		if locale := Negotiate(r, supported...); locale != test.locale {
+			t.Error(test.header, locale)
+		}
+	}
+	if Negotiate(httptest.NewRequest("GET", "/", nil)) != "" {
+		t.Error()
+	}
+}
+
+func TestLocaleHandler(t *testing.T) {
+	h := LocaleHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(Locale(r)))
+	}), "en", "zh")
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Language", "zh-TW")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Body.String() != "zh" || w.Header().Get("Content-Language") != "zh" {
+		t.Error(w.Body.String())
+	}
+	if Locale(httptest.NewRequest("GET", "/", nil)) != "" {
+		t.Error()
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"strings"
+)
+
+type maintenance struct {
+	enabled bool
+	handler http.Handler
+	allow   []string
+}
+
+// SetMaintenance enables or disables the maintenance mode at runtime. In the
+// maintenance mode the requests not allowed by SetMaintenanceAllow are served
+// by the handler. If the handler is nil, they are replied with a 503 status code.
+func (m *Rum) SetMaintenance(enabled bool, handler http.Handler) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	old := m.loadMaintenance()
+	m.maintenance.Store(&maintenance{enabled: enabled, handler: handler, allow: old.allow})
+}
+
+// SetMaintenanceAllow sets the paths kept live in the maintenance mode, such
+// as the health endpoints and the admin paths. A path ending in a slash
+// allows all of the paths beginning with it.
+func (m *Rum) SetMaintenanceAllow(paths ...string) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	old := m.loadMaintenance()
+	m.maintenance.Store(&maintenance{enabled: old.enabled, handler: old.handler, allow: paths})
+}
+
+// Maintenance reports whether the maintenance mode is enabled.
+func (m *Rum) Maintenance() bool {
+	return m.loadMaintenance().enabled
+}
+
+func (m *Rum) loadMaintenance() *maintenance {
+	if mt, ok := m.maintenance.Load().(*maintenance); ok {
+		return mt
+	}
+	return &maintenance{}
+}
+
+// maintenanceHandler returns the handler of the request in the maintenance mode.
+// The allowed paths are matched against the canonical path routed by the Mux,
+// so that a path such as /admin/../orders is not allowed by /admin/.
+func (m *Rum) maintenanceHandler(r *http.Request) http.Handler {
+	mt := m.loadMaintenance()
+	if !mt.enabled {
+		return nil
+	}
+	if path, ok := m.Mux.canonical(r); ok && allowedPath(mt.allow, path) {
+		return nil
+	}
+	if mt.handler != nil {
+		return mt.handler
+	}
+	return http.HandlerFunc(serveMaintenance)
+}
+
+// allowedPath reports whether the path is one of the allowed paths, or
+// begins with one ending in a slash.
+func allowedPath(allow []string, path string) bool {
+	for _, a := range allow {
+		if path == a || strings.HasSuffix(a, "/") && strings.HasPrefix(path, a) {
+			return true
+		}
+	}
+	return false
+}
+
+func serveMaintenance(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "503 Service Unavailable : Maintenance", http.StatusServiceUnavailable)
+}
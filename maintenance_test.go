@@ -0,0 +1,51 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMaintenance(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	m.SetMaintenanceAllow("/health", "/admin/")
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	})
+	m.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	m.HandleFunc("/admin/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("stats"))
+	})
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	m.SetMaintenance(true, nil)
+	if !m.Maintenance() {
+		t.Error("should be in the maintenance mode")
+	}
+	testHTTP("GET", "http://"+addr+"/", http.StatusServiceUnavailable, "503 Service Unavailable : Maintenance\n", t)
+	testHTTP("GET", "http://"+addr+"/health", http.StatusOK, "ok", t)
+	testHTTP("GET", "http://"+addr+"/admin/stats", http.StatusOK, "stats", t)
+	testHTTP("GET", "http://"+addr+"/admin/../", http.StatusServiceUnavailable, "503 Service Unavailable : Maintenance\n", t)
+	testHTTP("GET", "http://"+addr+"//health", http.StatusOK, "ok", t)
+	m.SetMaintenance(true, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("Back soon"))
+	}))
+	testHTTP("GET", "http://"+addr+"/", http.StatusServiceUnavailable, "Back soon", t)
+	m.SetMaintenance(false, nil)
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	m.Close()
+	<-done
+}
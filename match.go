@@ -0,0 +1,95 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrMatchAfterFallback is the error of the panic of Match when a pattern is
+// registered with predicates after its fallback entry without predicates.
+var ErrMatchAfterFallback = errors.New("Match registered after the fallback")
+
+// Match adds a predicate of the requests to the entry. An entry with
+// predicates serves only the requests satisfying all of them, so that
+// several entries registered with the same pattern can share a path.
+//
+// The entries sharing a pattern are tried in registration order, and the
+// entry without predicates is the fallback. The entries with predicates
+// must be registered before the fallback entry, since registering a
+// pattern again returns the fallback entry. Match panics with
+// ErrMatchAfterFallback otherwise, instead of replacing the fallback.
+func (entry *Entry) Match(predicate func(r *http.Request) bool) *Entry {
+	if entry.reregistered && len(entry.matchers) == 0 {
+		panic(ErrMatchAfterFallback)
+	}
+	entry.matchers = append(entry.matchers, predicate)
+	return entry
+}
+
+// Header adds a predicate matching the requests whose header of the key
+// has the value. An empty value matches the requests with the header.
+func (entry *Entry) Header(key, value string) *Entry {
+	key = http.CanonicalHeaderKey(key)
+	return entry.Match(func(r *http.Request) bool {
+		if value == "" {
+			return len(r.Header[key]) > 0
+		}
+		return r.Header.Get(key) == value
+	})
+}
+
+// Query adds a predicate matching the requests whose query value of the
+// key is the value. An empty value matches the requests with the key.
+func (entry *Entry) Query(key, value string) *Entry {
+	return entry.Match(func(r *http.Request) bool {
+		values, ok := r.URL.Query()[key]
+		if value == "" {
+			return ok
+		}
+		return ok && values[0] == value
+	})
+}
+
+// matches reports whether the request satisfies all the predicates of the entry.
+func (entry *Entry) matches(r *http.Request) bool {
+	for _, matcher := range entry.matchers {
+		if !matcher(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// choose returns the first entry sharing the pattern that matches the request.
+func (entry *Entry) choose(r *http.Request) *Entry {
+	if entry.matches(r) {
+		return entry
+	}
+	for _, variant := range entry.variants {
+		if variant.matches(r) {
+			return variant
+		}
+	}
+	return nil
+}
+
+// variant returns the entry to register a handler with the same pattern,
+// which is the fallback entry without predicates, or a new entry.
+func (entry *Entry) variant(m *Mux) *Entry {
+	if len(entry.matchers) == 0 {
+		entry.reregistered = true
+		return entry
+	}
+	for _, variant := range entry.variants {
+		if len(variant.matchers) == 0 {
+			variant.reregistered = true
+			return variant
+		}
+	}
+	variant := m.newEntry()
+	entry.variants = append(entry.variants, variant)
+	return variant
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	m := NewMux()
+	handler := func(name string) func(w http.ResponseWriter, r *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		}
+	}
+	m.HandleFunc("/webhook", handler("push")).Header("X-Event", "push").POST()
+	m.HandleFunc("/webhook", handler("issues")).Header("x-event", "issues").POST()
+	m.HandleFunc("/webhook", handler("debug")).Query("debug", "").POST()
+	m.HandleFunc("/webhook", handler("beta")).Match(func(r *http.Request) bool {
+		return strings.HasPrefix(r.UserAgent(), "beta")
+	}).POST()
+	test := func(path, key, value, result string, status int) {
+		req := httptest.NewRequest("POST", path, nil)
+		if key != "" {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != status || w.Body.String() != result && status == http.StatusOK {
+			t.Error(path, key, value, w.Code, w.Body.String())
+		}
+	}
+	test("/webhook", "X-Event", "push", "push", http.StatusOK)
+	test("/webhook", "X-Event", "issues", "issues", http.StatusOK)
+	test("/webhook?debug", "X-Event", "other", "debug", http.StatusOK)
+	test("/webhook", "User-Agent", "beta/1.0", "beta", http.StatusOK)
+	test("/webhook", "X-Event", "other", "", http.StatusNotFound)
+	m.HandleFunc("/webhook", handler("fallback")).POST()
+	test("/webhook", "X-Event", "other", "fallback", http.StatusOK)
+	test("/webhook", "X-Event", "push", "push", http.StatusOK)
+	m.HandleFunc("/webhook", handler("replaced")).POST()
+	test("/webhook", "X-Event", "other", "replaced", http.StatusOK)
+	test("/webhook", "X-Event", "issues", "issues", http.StatusOK)
+	func() {
+		defer func() {
+			if e := recover(); e != ErrMatchAfterFallback {
+				t.Error(e)
+			}
+		}()
+		m.HandleFunc("/webhook", handler("late")).Header("X-Event", "late").POST()
+	}()
+}
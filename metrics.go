@@ -0,0 +1,76 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Metrics exports the metrics of the requests, such as to statsd.
+// The tags are formatted as "key:value".
+type Metrics interface {
+	// Count adds the value to the counter of the name.
+	Count(name string, value int64, tags ...string)
+	// Timing records the duration to the timer of the name.
+	Timing(name string, d time.Duration, tags ...string)
+}
+
+// SetMetrics sets the exporter of the metrics of the requests routed by the
+// Mux and its groups. For each request, the "requests" counter and the
+// "request.duration" timer are tagged with the method, route and status.
+// A nil Metrics disables the metrics.
+func (m *Mux) SetMetrics(metrics Metrics) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.metrics = metrics
+}
+
+// observe exports the metrics of the request served by the entry.
+func observe(metrics Metrics, entry *Entry, r *http.Request, w *accessResponseWriter, start time.Time) {
+	d := time.Since(start)
+	code := w.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+	tags := []string{"method:" + r.Method, "route:" + entry.route(), "status:" + strconv.Itoa(code)}
+	metrics.Count("requests", 1, tags...)
+	metrics.Timing("request.duration", d, tags...)
+}
+
+// Gauger is implemented by the Metrics supporting the gauges.
+type Gauger interface {
+	// Gauge sets the gauge of the name to the value.
+	Gauge(name string, value float64, tags ...string)
+}
+
+// MultiMetrics returns a Metrics exporting to all of the metrics, such as
+// to statsd and to an SLO. It implements Gauger, exporting the gauges to
+// the metrics implementing Gauger.
+func MultiMetrics(metrics ...Metrics) Metrics {
+	return multiMetrics(append([]Metrics(nil), metrics...))
+}
+
+type multiMetrics []Metrics
+
+func (m multiMetrics) Count(name string, value int64, tags ...string) {
+	for _, metrics := range m {
+		metrics.Count(name, value, tags...)
+	}
+}
+
+func (m multiMetrics) Timing(name string, d time.Duration, tags ...string) {
+	for _, metrics := range m {
+		metrics.Timing(name, d, tags...)
+	}
+}
+
+func (m multiMetrics) Gauge(name string, value float64, tags ...string) {
+	for _, metrics := range m {
+		if g, ok := metrics.(Gauger); ok {
+			g.Gauge(name, value, tags...)
+		}
+	}
+}
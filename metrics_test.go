@@ -0,0 +1,74 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testMetrics struct {
+	mu      sync.Mutex
+	counts  []string
+	timings []string
+}
+
+func (m *testMetrics) Count(name string, value int64, tags ...string) {
+	m.mu.Lock()
+	m.counts = append(m.counts, name+" "+strings.Join(tags, ","))
+	m.mu.Unlock()
+}
+
+func (m *testMetrics) Timing(name string, d time.Duration, tags ...string) {
+	m.mu.Lock()
+	m.timings = append(m.timings, name+" "+strings.Join(tags, ","))
+	m.mu.Unlock()
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := &testMetrics{}
+	m := NewMux()
+	m.SetMetrics(metrics)
+	m.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}).GET().DELETE()
+	m.Group("/api", func(m *Mux) {
+		m.HandleFunc("/items", func(w http.ResponseWriter, r *http.Request) {}).GET()
+	})
+	for _, r := range []*http.Request{
+		httptest.NewRequest("GET", "/users/8", nil),
+		httptest.NewRequest("DELETE", "/users/9", nil),
+		httptest.NewRequest("GET", "/api/items", nil),
+		httptest.NewRequest("GET", "/missing", nil),
+	} {
+		m.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	expect := []string{
+		"requests method:GET,route:/users/:id,status:200",
+		"requests method:DELETE,route:/users/:id,status:204",
+		"requests method:GET,route:/api/items,status:200",
+	}
+	if len(metrics.counts) != len(expect) || len(metrics.timings) != len(expect) {
+		t.Fatal(metrics.counts, metrics.timings)
+	}
+	for i := range expect {
+		if metrics.counts[i] != expect[i] {
+			t.Error(metrics.counts[i])
+		}
+		if metrics.timings[i] != strings.Replace(expect[i], "requests", "request.duration", 1) {
+			t.Error(metrics.timings[i])
+		}
+	}
+	m.SetMetrics(nil)
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/8", nil))
+	if len(metrics.counts) != len(expect) {
+		t.Error(len(metrics.counts))
+	}
+}
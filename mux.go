@@ -8,8 +8,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -18,7 +21,7 @@ const (
 	head
 	post
 	put
-	delete
+	del
 	trace
 	connect
 	patch
@@ -48,11 +51,40 @@ type Mux struct {
 	prefixes map[string]*prefix
 	group    string
 	groups   map[string]*Mux
-	context  struct {
-		middlewares []http.Handler
-		recovery    http.Handler
-		notFound    http.Handler
+	// parent is the Mux of the Group, nil for the root Mux.
+	parent *Mux
+	// sortedGroups holds the groups sorted by name, for a deterministic search.
+	sortedGroups []*Mux
+	class        string
+	limiters     map[string]*rateLimiter
+	// authSchemes is the registry of the AuthSchemes, auth is set by Auth.
+	authSchemes map[string]AuthScheme
+	auth        *routeAuth
+	subtrees    map[string]*Entry
+	// conformances counts the conformance entries of the Mux and its groups.
+	conformances *int32
+	conformance  bool
+	// versions holds the API versions registered by Version.
+	versions       []string
+	defaultVersion string
+	versionHeader  string
+	context        struct {
+		middlewares  []http.Handler
+		recovery     http.Handler
+		notFound     http.Handler
+		errorHandler ErrorHandler
+		problems     bool
 	}
+	// canonicalization is set by SetCanonicalization.
+	canonicalization atomic.Value
+	overload         *OverloadController
+	audit            *Audit
+	metrics          Metrics
+	recorder         *Recorder
+	chaos            *Chaos
+	chaosAllow       []string
+	// debugRoute is set by Rum.SetDebug to measure the lookups.
+	debugRoute bool
 }
 
 type prefix struct {
@@ -62,27 +94,53 @@ type prefix struct {
 
 // Entry represents an HTTP HandlerFunc entry.
 type Entry struct {
-	handler  http.Handler
-	handlers [9]http.Handler
-	key      string
-	match    []string
-	params   map[string]string
+	handler      http.Handler
+	handlers     [9]http.Handler
+	key          string
+	match        []string
+	params       map[string]string
+	errorHandler ErrorHandler
+	class        string
+	auth         *routeAuth
+	conformance  bool
+	conformances *int32
+	matchers     []func(r *http.Request) bool
+	subtree      bool
+	breaker      *CircuitBreaker
+	swapped      atomic.Value
+	split        atomic.Value
+	pushes       []string
+	concurrency  chan struct{}
+	priority     Priority
+	prioritized  bool
+	mux          *Mux
+	aliased      *alias
+	// variants holds the entries sharing the pattern, in registration order.
+	variants []*Entry
+	// reregistered is set when Handle returns the entry again for its pattern.
+	reregistered bool
+	// responseBuffer overrides the response buffer of the Server if not zero.
+	responseBuffer int
+	// values is injected into the contexts of the requests by WithValue.
+	values []entryValue
 }
 
 // NewMux returns a new Mux.
 func NewMux() *Mux {
 	m := &Mux{
-		prefixes: make(map[string]*prefix),
-		groups:   make(map[string]*Mux),
+		prefixes:     make(map[string]*prefix),
+		groups:       make(map[string]*Mux),
+		conformances: new(int32),
 	}
 	return m
 }
 
-func newGroup(group string) *Mux {
+func newGroup(group string, conformances *int32) *Mux {
 	m := &Mux{
-		prefixes: make(map[string]*prefix),
-		groups:   make(map[string]*Mux),
-		group:    group,
+		prefixes:     make(map[string]*prefix),
+		groups:       make(map[string]*Mux),
+		group:        group,
+		conformances: conformances,
 	}
 	return m
 }
@@ -90,42 +148,186 @@ func newGroup(group string) *Mux {
 // ServeHTTP dispatches the request to the handler whose
 // pattern most closely matches the request URL.
 func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	path := m.replace(r.URL.Path)
+	if asteriskForm(r) {
+		m.serveAsterisk(w, r)
+		return
+	}
+	var start time.Time
+	if m.debugRoute {
+		start = time.Now()
+	}
+	path, r, ok := m.canonicalRequest(r)
+	if !ok {
+		m.badRequest(w, r)
+		return
+	}
 	m.mut.RLock()
 	entry := m.searchEntry(path, w, r)
+	if entry == nil && len(m.versions) > 0 {
+		entry, r = m.searchVersion(path, w, r)
+	}
+	if entry != nil && len(entry.matchers) > 0 {
+		entry = entry.choose(r)
+	}
+	var aliased *alias
+	if entry != nil && entry.aliased != nil {
+		aliased, entry = entry.aliased, entry.aliased.entry
+		if len(entry.matchers) > 0 {
+			entry = entry.choose(r)
+		}
+	}
 	m.mut.RUnlock()
+	if m.debugRoute {
+		if route, ok := r.Context().Value(debugRouteKey).(*time.Duration); ok {
+			*route = time.Since(start)
+		}
+	}
+	if (entry == nil || entry.subtree) && m.redirectSubtree(path, w, r) {
+		return
+	}
 	if entry != nil {
-		m.serveEntry(entry, w, r)
+		if aliased == nil || aliased.serve(w, r) {
+			m.serveEntry(entry, w, r)
+		}
 		return
 	}
 	if m.context.notFound != nil {
 		m.context.notFound.ServeHTTP(w, r)
 		return
 	}
+	if m.context.problems {
+		ProblemJSON(w, http.StatusNotFound, "", r.URL.String())
+		return
+	}
 	http.Error(w, "404 Not Found : "+r.URL.String(), http.StatusNotFound)
 }
 
+// searchEntry returns the entry of the path. The routes of the Mux and all
+// its groups are matched before the subtrees, of which the longest wins.
 func (m *Mux) searchEntry(path string, w http.ResponseWriter, r *http.Request) *Entry {
+	if entry := m.searchRoute(path); entry != nil {
+		return entry
+	}
+	entry, _ := m.searchSubtrees(path)
+	return entry
+}
+
+// searchRoute returns the entry of the route matching the path in the Mux
+// or its groups.
+func (m *Mux) searchRoute(path string) *Entry {
 	if entry := m.getHandlerFunc(path); entry != nil {
 		return entry
 	}
-	for _, groupMux := range m.groups {
-		if entry := groupMux.searchEntry(path, w, r); entry != nil {
+	for _, groupMux := range m.sortedGroups {
+		if entry := groupMux.searchRoute(path); entry != nil {
 			return entry
 		}
 	}
 	return nil
 }
 
+// searchSubtrees returns the entry of the longest subtree pattern matching
+// the path in the Mux or its groups, and the length of the pattern. On a
+// tie, the Mux wins over its groups.
+func (m *Mux) searchSubtrees(path string) (*Entry, int) {
+	entry := m.searchSubtree(path)
+	var n int
+	if entry != nil {
+		n = len(entry.key)
+	}
+	for _, groupMux := range m.sortedGroups {
+		if e, l := groupMux.searchSubtrees(path); l > n {
+			entry, n = e, l
+		}
+	}
+	return entry, n
+}
+
 func (m *Mux) serveEntry(entry *Entry, w http.ResponseWriter, r *http.Request) {
-	if r.Method == "GET" && entry.handlers[get] != nil {
+	if metrics := m.metrics; metrics != nil {
+		mw := &accessResponseWriter{forwarder: forwarder{w}}
+		w = expose(mw, w)
+		defer observe(metrics, entry, r, mw, time.Now())
+	}
+	if rec := m.recorder; rec != nil {
+		route := entry.route()
+		if rw, body := rec.begin(route, w, r); rw != nil {
+			w = expose(rw, w)
+			defer rec.record(route, r, body, rw)
+		}
+	}
+	if a := m.audit; a != nil && a.audits(r.Method) {
+		aw := &accessResponseWriter{forwarder: forwarder{w}}
+		w = expose(aw, w)
+		r = a.begin(r)
+		defer func(start time.Time) {
+			if aw.code == 0 {
+				aw.code = http.StatusOK
+			}
+			a.record(entry, r, aw.code, start)
+		}(time.Now())
+	}
+	if c := m.chaos; c != nil && !allowedPath(m.chaosAllow, r.URL.Path) && c.inject(entry, w, r) {
+		return
+	}
+	if c := m.overload; c != nil {
+		if !c.admit(entry, w, r) {
+			return
+		}
+		defer c.done(time.Now())
+	}
+	if m.limited(entry, w, r) {
+		return
+	}
+	if entry.auth != nil {
+		var ok bool
+		if r, ok = m.authenticate(entry, w, r); !ok {
+			return
+		}
+	}
+	if entry.concurrency != nil {
+		select {
+		case entry.concurrency <- struct{}{}:
+			defer func() { <-entry.concurrency }()
+		default:
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "503 Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+	}
+	if entry.breaker != nil {
+		bw, ok := entry.breaker.begin(w, r)
+		if !ok {
+			return
+		}
+		defer bw.end()
+		w = expose(bw, w)
+	}
+	if entry.responseBuffer != 0 {
+		bw := newBufferResponseWriter(w, entry.responseBuffer)
+		defer bw.finish()
+		w = expose(bw, w)
+	}
+	if len(entry.values) > 0 {
+		r = entry.withValues(r)
+	}
+	if len(entry.pushes) > 0 && r.Method == "GET" {
+		entry.push(w, r)
+	}
+	if handler := entry.splitHandler(r); handler != nil {
+		m.serveHandler(handler, w, r)
+	} else if handler := entry.swappedHandler(); handler != nil {
+		if !m.context.problems || entry.allows(r.Method) || !m.methodNotAllowed(entry, w, r) {
+			m.serveHandler(handler, w, r)
+		}
+	} else if r.Method == "GET" && entry.handlers[get] != nil {
 		m.serveHandler(entry.handlers[get], w, r)
 	} else if r.Method == "POST" && entry.handlers[post] != nil {
 		m.serveHandler(entry.handlers[post], w, r)
 	} else if r.Method == "PUT" && entry.handlers[put] != nil {
 		m.serveHandler(entry.handlers[put], w, r)
-	} else if r.Method == "DELETE" && entry.handlers[delete] != nil {
-		m.serveHandler(entry.handlers[delete], w, r)
+	} else if r.Method == "DELETE" && entry.handlers[del] != nil {
+		m.serveHandler(entry.handlers[del], w, r)
 	} else if r.Method == "PATCH" && entry.handlers[patch] != nil {
 		m.serveHandler(entry.handlers[patch], w, r)
 	} else if r.Method == "HEAD" && entry.handlers[head] != nil {
@@ -136,7 +338,7 @@ func (m *Mux) serveEntry(entry *Entry, w http.ResponseWriter, r *http.Request) {
 		m.serveHandler(entry.handlers[trace], w, r)
 	} else if r.Method == "CONNECT" && entry.handlers[connect] != nil {
 		m.serveHandler(entry.handlers[connect], w, r)
-	} else {
+	} else if !m.context.problems || !m.methodNotAllowed(entry, w, r) {
 		m.serveHandler(entry.handler, w, r)
 	}
 }
@@ -158,9 +360,25 @@ func (m *Mux) serveHandler(handler http.Handler, w http.ResponseWriter, r *http.
 				m.context.recovery.ServeHTTP(w, r.WithContext(ctx))
 			}
 		}()
+	} else if m.context.errorHandler != nil {
+		defer func() {
+			if err := recover(); err != nil {
+				m.serveError(nil, w, r, &PanicError{Value: err})
+			}
+		}()
+	} else if m.context.problems {
+		defer func() {
+			if err := recover(); err != nil {
+				ProblemErrorHandler(w, r, &PanicError{Value: err})
+			}
+		}()
 	}
 	m.middleware(w, r)
-	if handler != nil {
+	if h, ok := handler.(*handlerE); ok {
+		if err := h.handler.call(w, r); err != nil {
+			m.serveError(h.entry, w, r, err)
+		}
+	} else if handler != nil {
 		handler.ServeHTTP(w, r)
 	}
 }
@@ -187,14 +405,14 @@ func (m *Mux) Handle(pattern string, handler http.Handler) *Entry {
 	pre, key, match, params := m.parseParams(m.group + pattern)
 	if v, ok := m.prefixes[pre]; ok {
 		if entry, ok := v.m[key]; ok {
+			entry = entry.variant(m)
 			entry.handler = handler
 			entry.key = key
 			entry.match = match
 			entry.params = params
-			m.prefixes[pre].m[key] = entry
 			return entry
 		}
-		entry := &Entry{}
+		entry := m.newEntry()
 		entry.handler = handler
 		entry.key = key
 		entry.match = match
@@ -203,7 +421,7 @@ func (m *Mux) Handle(pattern string, handler http.Handler) *Entry {
 		return entry
 	}
 	m.prefixes[pre] = &prefix{m: make(map[string]*Entry), prefix: pre}
-	entry := &Entry{}
+	entry := m.newEntry()
 	entry.handler = handler
 	entry.key = key
 	entry.match = match
@@ -212,18 +430,31 @@ func (m *Mux) Handle(pattern string, handler http.Handler) *Entry {
 	return entry
 }
 
+func (m *Mux) newEntry() *Entry {
+	entry := &Entry{class: m.class, auth: m.auth, conformances: m.conformances, mux: m}
+	if m.conformance {
+		entry.Conformance()
+	}
+	return entry
+}
+
 // Group registers a group with the given pattern to the Mux.
 func (m *Mux) Group(group string, f func(m *Mux)) {
 	m.mut.Lock()
 	defer m.mut.Unlock()
 	group = m.replace(group)
-	groupMux := newGroup(group)
+	groupMux := newGroup(group, m.conformances)
+	groupMux.parent = m
 	f(groupMux)
 	if _, ok := m.groups[group]; ok {
 		panic(ErrGroupExisted)
 	}
 	groupMux.context = m.context
 	m.groups[group] = groupMux
+	i := sort.Search(len(m.sortedGroups), func(i int) bool { return m.sortedGroups[i].group > group })
+	m.sortedGroups = append(m.sortedGroups, nil)
+	copy(m.sortedGroups[i+1:], m.sortedGroups[i:])
+	m.sortedGroups[i] = groupMux
 }
 
 // NotFound registers a not found handler function to the Mux.
@@ -240,11 +471,13 @@ func (m *Mux) Recovery(handler http.HandlerFunc) {
 	m.context.recovery = handler
 }
 
-// Use uses middleware.
-func (m *Mux) Use(handler http.HandlerFunc) {
+// Use uses middleware. The returned Middleware can skip paths with Except.
+func (m *Mux) Use(handler http.HandlerFunc) *Middleware {
 	m.mut.Lock()
 	defer m.mut.Unlock()
-	m.context.middlewares = append(m.context.middlewares, handler)
+	middleware := &Middleware{handler: handler}
+	m.context.middlewares = append(m.context.middlewares, middleware)
+	return middleware
 }
 
 func (m *Mux) middleware(w http.ResponseWriter, r *http.Request) {
@@ -253,19 +486,41 @@ func (m *Mux) middleware(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Params returns http request params.
+// Params returns http request params. The params are percent-decoded,
+// so they may contain the slashes encoded in the path.
 func (m *Mux) Params(r *http.Request) map[string]string {
+	return m.params(r, false)
+}
+
+// RawParams returns http request params as they appear in the escaped path.
+func (m *Mux) RawParams(r *http.Request) map[string]string {
+	return m.params(r, true)
+}
+
+func (m *Mux) params(r *http.Request, raw bool) map[string]string {
 	params := make(map[string]string)
-	path := m.replace(r.URL.Path)
+	path, _ := m.canonical(r)
 	m.mut.RLock()
 	if prefix, key, ok := m.matchParams(path); ok {
 		if entry, ok := m.prefixes[prefix].m[key]; ok &&
 			len(entry.match) > 0 && len(path) > len(prefix) {
 			strs := strings.Split(path[len(prefix):], "/")
+			var raws []string
+			if raw {
+				if raws = strings.Split(escapedPath(r, path), "/"); len(raws) >= len(strs) {
+					raws = raws[len(raws)-len(strs):]
+				} else {
+					raws = strs
+				}
+			}
 			if len(strs) == len(entry.match) {
 				for i := 0; i < len(strs); i++ {
-					if entry.match[i] != "" {
-						params[entry.match[i]] = strs[i]
+					if entry.match[i] == "" {
+						continue
+					} else if raw {
+						params[entry.match[i]] = raws[i]
+					} else {
+						params[entry.match[i]] = unescapeSegment(strs[i])
 					}
 				}
 			}
@@ -367,7 +622,7 @@ func (entry *Entry) PUT() *Entry {
 
 // DELETE adds a DELETE HTTP method to the entry.
 func (entry *Entry) DELETE() *Entry {
-	entry.handlers[delete] = entry.handler
+	entry.handlers[del] = entry.handler
 	return entry
 }
 
@@ -0,0 +1,277 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultObjectMaxSize  = 1 << 20
+	defaultObjectCacheTTL = time.Minute
+)
+
+var (
+	// ErrObjectNotFound is returned by an ObjectBackend for a missing object.
+	ErrObjectNotFound = errors.New("Object Not Found")
+	// ErrObjectNotModified is returned by an ObjectBackend if the object
+	// matches the ETag of a conditional open.
+	ErrObjectNotModified = errors.New("Object Not Modified")
+)
+
+// Object is an object opened from an ObjectBackend.
+type Object struct {
+	// Body is the content of the object, closed after serving.
+	Body io.ReadCloser
+	// Size is the size in bytes of the content, or -1 if unknown.
+	Size int64
+	// ModTime is the modification time, or zero if unknown.
+	ModTime time.Time
+	// ETag is the entity tag, such as `"d41d8cd98f00b204e9800998ecf8427e"`.
+	ETag string
+	// ContentType is the media type, or empty to use the extension.
+	ContentType string
+}
+
+// ObjectBackend is a source of the static objects opened on demand, such
+// as an object storage. See S3.
+type ObjectBackend interface {
+	// Open opens the object of the name, a slash-separated path without a
+	// leading slash. If the etag is not empty and matches the object, Open
+	// returns ErrObjectNotModified without fetching the content.
+	Open(ctx context.Context, name string, etag string) (*Object, error)
+}
+
+// ObjectStatic configures the serving of the objects of StaticObjects.
+type ObjectStatic struct {
+	// MaxAge is the max-age of the Cache-Control, or no-cache if zero.
+	MaxAge time.Duration
+	// Index is the object served for a directory, index.html if empty.
+	Index string
+	// CacheSize is the maximum total size in bytes of the objects cached in
+	// memory, the least recently used being evicted. Zero disables the cache.
+	CacheSize int64
+	// MaxObjectSize is the maximum size in bytes of a cached object. The
+	// larger objects are streamed. If zero, 1 MB is used.
+	MaxObjectSize int64
+	// CacheTTL is the duration a cached object is served before being
+	// revalidated by a conditional open. If zero, one minute is used.
+	CacheTTL time.Duration
+	// ErrorLog logs the errors of the backend, replied with 502 Bad Gateway.
+	// If nil, the standard logger is used.
+	ErrorLog *log.Logger
+}
+
+// StaticObjects serves the objects of the backend under the prefix, such
+// as an S3-compatible bucket, so the server can front the object storage
+// directly. The small objects are cached in memory and revalidated with
+// their ETags. The conditional requests are answered from the cache, or
+// forwarded to the backend, and the Range requests are handled for the
+// cached objects.
+//
+//	m.StaticObjects("/assets/", &rum.S3{Endpoint: "https://s3.us-east-1.amazonaws.com", Bucket: "assets"},
+//		rum.ObjectStatic{MaxAge: time.Hour, CacheSize: 64 << 20})
+func (m *Mux) StaticObjects(prefix string, backend ObjectBackend, options ObjectStatic) *Entry {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	if options.Index == "" {
+		options.Index = "index.html"
+	}
+	if options.MaxObjectSize <= 0 {
+		options.MaxObjectSize = defaultObjectMaxSize
+	}
+	if options.CacheTTL <= 0 {
+		options.CacheTTL = defaultObjectCacheTTL
+	}
+	s := &staticObjects{
+		backend:      backend,
+		prefix:       m.replace(m.group + prefix),
+		options:      options,
+		cacheControl: "no-cache",
+		lru:          list.New(),
+		now:          time.Now,
+	}
+	if options.MaxAge > 0 {
+		s.cacheControl = "public, max-age=" + strconv.FormatInt(int64(options.MaxAge/time.Second), 10)
+	}
+	return m.HandlePrefix(prefix, s).GET().HEAD()
+}
+
+type staticObjects struct {
+	backend      ObjectBackend
+	prefix       string
+	options      ObjectStatic
+	cacheControl string
+
+	mu      sync.Mutex
+	objects sync.Map
+	lru     *list.List
+	size    int64
+	now     func() time.Time
+}
+
+// cachedObject is an object cached in memory.
+type cachedObject struct {
+	name        string
+	content     []byte
+	modtime     time.Time
+	etag        string
+	contentType string
+	expires     time.Time
+	element     *list.Element
+}
+
+func (s *staticObjects) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(r.URL.Path, s.prefix)), "/")
+	if name == "" || name == "." || strings.HasSuffix(r.URL.Path, "/") {
+		name = path.Join(name, s.options.Index)
+	}
+	cached, fresh := s.get(name)
+	if fresh {
+		s.serveCached(w, r, cached)
+		return
+	}
+	etag := r.Header.Get("If-None-Match")
+	if cached != nil {
+		etag = cached.etag
+	}
+	obj, err := s.backend.Open(r.Context(), name, etag)
+	switch {
+	case err == ErrObjectNotModified && cached != nil:
+		s.mu.Lock()
+		cached.expires = s.now().Add(s.options.CacheTTL)
+		s.mu.Unlock()
+		s.serveCached(w, r, cached)
+		return
+	case err == ErrObjectNotModified:
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", s.cacheControl)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	case err == ErrObjectNotFound:
+		s.remove(name)
+		http.Error(w, "404 Not Found : "+r.URL.String(), http.StatusNotFound)
+		return
+	case err != nil:
+		s.badGateway(w, r, err)
+		return
+	}
+	defer obj.Body.Close()
+	if s.options.CacheSize > 0 && obj.Size >= 0 && obj.Size <= s.options.MaxObjectSize && obj.Size <= s.options.CacheSize {
+		content, err := ioutil.ReadAll(io.LimitReader(obj.Body, obj.Size+1))
+		if err != nil || int64(len(content)) != obj.Size {
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			s.badGateway(w, r, err)
+			return
+		}
+		cached = &cachedObject{name: name, content: content, modtime: obj.ModTime, etag: obj.ETag,
+			contentType: obj.ContentType, expires: s.now().Add(s.options.CacheTTL)}
+		s.put(cached)
+		s.serveCached(w, r, cached)
+		return
+	}
+	header := w.Header()
+	s.setHeader(header, name, obj.ContentType, obj.ETag)
+	if obj.ETag != "" && etagMatch(r.Header.Get("If-None-Match"), obj.ETag) {
+		header.Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	ServeReader(w, r, name, obj.ModTime, obj.Body, obj.Size)
+}
+
+// badGateway logs the error of the backend, which may contain its address
+// or response, and replies with 502 Bad Gateway without it.
+func (s *staticObjects) badGateway(w http.ResponseWriter, r *http.Request, err error) {
+	if s.options.ErrorLog != nil {
+		s.options.ErrorLog.Printf("rum: object %s: %v", r.URL.Path, err)
+	} else {
+		log.Printf("rum: object %s: %v", r.URL.Path, err)
+	}
+	http.Error(w, "502 Bad Gateway", http.StatusBadGateway)
+}
+
+func (s *staticObjects) setHeader(header http.Header, name, contentType, etag string) {
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(name))
+	}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	if etag != "" {
+		header.Set("ETag", etag)
+	}
+	header.Set("Cache-Control", s.cacheControl)
+}
+
+func (s *staticObjects) serveCached(w http.ResponseWriter, r *http.Request, cached *cachedObject) {
+	s.setHeader(w.Header(), cached.name, cached.contentType, cached.etag)
+	http.ServeContent(w, r, cached.name, cached.modtime, bytes.NewReader(cached.content))
+}
+
+// get returns the cached object of the name, marking it recently used, and
+// whether it is fresh.
+func (s *staticObjects) get(name string) (*cachedObject, bool) {
+	v, ok := s.objects.Load(name)
+	if !ok {
+		return nil, false
+	}
+	cached := v.(*cachedObject)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached.element != nil {
+		s.lru.MoveToFront(cached.element)
+	}
+	return cached, s.now().Before(cached.expires)
+}
+
+// put caches the object, evicting the least recently used objects beyond
+// the cache size.
+func (s *staticObjects) put(cached *cachedObject) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.objects.Load(cached.name); ok {
+		s.evict(v.(*cachedObject))
+	}
+	cached.element = s.lru.PushFront(cached)
+	s.objects.Store(cached.name, cached)
+	s.size += int64(len(cached.content))
+	for s.size > s.options.CacheSize {
+		s.evict(s.lru.Back().Value.(*cachedObject))
+	}
+}
+
+func (s *staticObjects) remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.objects.Load(name); ok {
+		s.evict(v.(*cachedObject))
+	}
+}
+
+func (s *staticObjects) evict(cached *cachedObject) {
+	if cached.element == nil {
+		return
+	}
+	s.lru.Remove(cached.element)
+	cached.element = nil
+	s.objects.Delete(cached.name)
+	s.size -= int64(len(cached.content))
+}
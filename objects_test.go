@@ -0,0 +1,122 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testBackend struct {
+	objects map[string]string
+	opens   []string
+	err     error
+}
+
+func (b *testBackend) Open(ctx context.Context, name string, etag string) (*Object, error) {
+	b.opens = append(b.opens, name+" "+etag)
+	if b.err != nil {
+		return nil, b.err
+	}
+	content, ok := b.objects[name]
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+	objectETag := `"` + content + `"`
+	if etag == objectETag {
+		return nil, ErrObjectNotModified
+	}
+	return &Object{Body: ioutil.NopCloser(strings.NewReader(content)), Size: int64(len(content)), ETag: objectETag}, nil
+}
+
+func TestStaticObjects(t *testing.T) {
+	backend := &testBackend{objects: map[string]string{
+		"index.html": "<html>home</html>",
+		"app.js":     "console.log(1)",
+		"big.txt":    strings.Repeat("a", 64),
+	}}
+	m := New()
+	entry := m.StaticObjects("/assets", backend, ObjectStatic{MaxAge: time.Hour, CacheSize: 40, MaxObjectSize: 32})
+	s := entry.handler.(*staticObjects)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	get := func(path string, header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		for key, value := range header {
+			r.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		return w
+	}
+	w := get("/assets/", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "<html>home</html>" || w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Error(w.Code, w.Body.String(), w.Header())
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=3600" {
+		t.Error(cc)
+	}
+	get("/assets/index.html", nil)
+	if w = get("/assets/index.html", map[string]string{"If-None-Match": `"<html>home</html>"`}); w.Code != http.StatusNotModified {
+		t.Error(w.Code)
+	}
+	if w = get("/assets/index.html", map[string]string{"Range": "bytes=1-4"}); w.Code != http.StatusPartialContent || w.Body.String() != "html" {
+		t.Error(w.Code, w.Body.String())
+	}
+	if len(backend.opens) != 1 {
+		t.Error(backend.opens)
+	}
+	now = now.Add(time.Minute)
+	if w = get("/assets/index.html", nil); w.Code != http.StatusOK || w.Body.String() != "<html>home</html>" {
+		t.Error(w.Code, w.Body.String())
+	}
+	if len(backend.opens) != 2 || backend.opens[1] != `index.html "<html>home</html>"` {
+		t.Error(backend.opens)
+	}
+	get("/assets/app.js", nil)
+	if s.size != 31 {
+		t.Error(s.size)
+	}
+	get("/assets/app.js", nil)
+	if _, ok := s.objects.Load("index.html"); !ok {
+		t.Error("expected index.html to be cached")
+	}
+	backend.objects["style.css"] = "body{margin:0}"
+	get("/assets/style.css", nil)
+	if _, ok := s.objects.Load("index.html"); ok || s.size != 28 {
+		t.Error("expected index.html to be evicted", s.size)
+	}
+	backend.opens = nil
+	for i := 0; i < 2; i++ {
+		if w = get("/assets/big.txt", nil); w.Code != http.StatusOK || w.Body.Len() != 64 || w.Header().Get("ETag") == "" {
+			t.Error(w.Code, w.Body.Len(), w.Header())
+		}
+	}
+	if len(backend.opens) != 2 {
+		t.Error(backend.opens)
+	}
+	if w = get("/assets/big.txt", map[string]string{"If-None-Match": `"` + strings.Repeat("a", 64) + `"`}); w.Code != http.StatusNotModified {
+		t.Error(w.Code)
+	}
+	if w = get("/assets/missing.txt", nil); w.Code != http.StatusNotFound {
+		t.Error(w.Code)
+	}
+	backend.err = errors.New("connection refused")
+	var logs bytes.Buffer
+	s.options.ErrorLog = log.New(&logs, "", 0)
+	if w = get("/assets/other.txt", nil); w.Code != http.StatusBadGateway || strings.Contains(w.Body.String(), "refused") {
+		t.Error(w.Code, w.Body.String())
+	}
+	if !strings.Contains(logs.String(), "connection refused") {
+		t.Error(logs.String())
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// ErrOCSPServer is the error of stapling a certificate without an OCSP server.
+var ErrOCSPServer = errors.New("No OCSP Server")
+
+// ErrOCSPResponse is the error of an OCSP response which is not successful,
+// malformed, or does not match the certificate.
+var ErrOCSPResponse = errors.New("Invalid OCSP Response")
+
+// ErrOCSPStatus is the error of an OCSP response whose certificate status
+// is not good.
+var ErrOCSPStatus = errors.New("OCSP Status Not Good")
+
+// maxOCSPResponseSize is the maximum size of an OCSP response.
+const maxOCSPResponseSize = 1 << 20
+
+var (
+	oidSHA1              = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidOCSPBasicResponse = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+)
+
+type ocspCertID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type ocspRequest struct {
+	TBSRequest struct {
+		Version     int `asn1:"explicit,tag:0,default:0,optional"`
+		RequestList []struct {
+			Cert ocspCertID
+		}
+	}
+}
+
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response struct {
+		ResponseType asn1.ObjectIdentifier
+		Response     []byte
+	} `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspBasicResponse struct {
+	TBSResponseData struct {
+		Version     int `asn1:"explicit,tag:0,default:0,optional"`
+		ResponderID asn1.RawValue
+		ProducedAt  time.Time `asn1:"generalized"`
+		Responses   []ocspSingleResponse
+	}
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspSingleResponse struct {
+	CertID     ocspCertID
+	Good       asn1.Flag     `asn1:"tag:0,optional"`
+	Revoked    asn1.RawValue `asn1:"tag:1,optional"`
+	Unknown    asn1.Flag     `asn1:"tag:2,optional"`
+	ThisUpdate time.Time     `asn1:"generalized"`
+	NextUpdate time.Time     `asn1:"generalized,explicit,tag:0,optional"`
+}
+
+// OCSPClient is an OCSPResponder requesting the OCSP server of the leaf
+// certificates by HTTP. The signature of the responses is verified by the
+// TLS clients.
+type OCSPClient struct {
+	// Client is the HTTP client. If nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// Staple implements the OCSPResponder interface.
+func (c *OCSPClient) Staple(ctx context.Context, leaf, issuer *x509.Certificate) ([]byte, time.Time, error) {
+	if len(leaf.OCSPServer) == 0 {
+		return nil, time.Time{}, ErrOCSPServer
+	}
+	id, err := newOCSPCertID(leaf, issuer)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var ocspReq ocspRequest
+	ocspReq.TBSRequest.RequestList = append(ocspReq.TBSRequest.RequestList, struct{ Cert ocspCertID }{id})
+	body, err := asn1.Marshal(ocspReq)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	req, err := http.NewRequest("POST", leaf.OCSPServer[0], bytes.NewReader(body))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, time.Time{}, ErrOCSPResponse
+	}
+	staple, err := ioutil.ReadAll(http.MaxBytesReader(nil, res.Body, maxOCSPResponseSize))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	nextUpdate, err := parseOCSPResponse(staple, id.SerialNumber)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return staple, nextUpdate, nil
+}
+
+// newOCSPCertID returns the SHA-1 CertID of the leaf certificate.
+func newOCSPCertID(leaf, issuer *x509.Certificate) (ocspCertID, error) {
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return ocspCertID{}, err
+	}
+	nameHash := sha1.Sum(issuer.RawSubject)
+	keyHash := sha1.Sum(publicKeyInfo.PublicKey.RightAlign())
+	return ocspCertID{
+		HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA1, Parameters: asn1.NullRawValue},
+		NameHash:      nameHash[:],
+		IssuerKeyHash: keyHash[:],
+		SerialNumber:  leaf.SerialNumber,
+	}, nil
+}
+
+// parseOCSPResponse returns the next update of the OCSP response of the
+// certificate of the serial number if its status is good.
+func parseOCSPResponse(der []byte, serialNumber *big.Int) (time.Time, error) {
+	var res ocspResponse
+	if rest, err := asn1.Unmarshal(der, &res); err != nil || len(rest) > 0 {
+		return time.Time{}, ErrOCSPResponse
+	}
+	if res.Status != 0 || !res.Response.ResponseType.Equal(oidOCSPBasicResponse) {
+		return time.Time{}, ErrOCSPResponse
+	}
+	var basic ocspBasicResponse
+	if _, err := asn1.Unmarshal(res.Response.Response, &basic); err != nil {
+		return time.Time{}, ErrOCSPResponse
+	}
+	for _, single := range basic.TBSResponseData.Responses {
+		if single.CertID.SerialNumber == nil || single.CertID.SerialNumber.Cmp(serialNumber) != 0 {
+			continue
+		}
+		if !single.Good {
+			return time.Time{}, ErrOCSPStatus
+		}
+		return single.NextUpdate, nil
+	}
+	return time.Time{}, ErrOCSPResponse
+}
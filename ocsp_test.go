@@ -0,0 +1,126 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestChain returns the PEM encoded chain and key of a leaf certificate
+// of the host issued by a new CA, with the OCSP server.
+func newTestChain(t *testing.T, host, ocspServer string) ([]byte, []byte) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "rum test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ = x509.ParseCertificate(caDER)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ocspServer != "" {
+		leaf.OCSPServer = []string{ocspServer}
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	certPEM := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})...)
+	return certPEM, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// newTestOCSPServer returns an OCSP server answering the requests with the
+// good status, or the revoked status if revoked.
+func newTestOCSPServer(t *testing.T, revoked bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var req ocspRequest
+		if _, err := asn1.Unmarshal(body, &req); err != nil || len(req.TBSRequest.RequestList) != 1 {
+			http.Error(w, "400 Bad Request", http.StatusBadRequest)
+			return
+		}
+		single := ocspSingleResponse{
+			CertID:     req.TBSRequest.RequestList[0].Cert,
+			Good:       asn1.Flag(!revoked),
+			ThisUpdate: time.Now().UTC().Truncate(time.Second),
+			NextUpdate: time.Now().UTC().Truncate(time.Second).Add(time.Hour),
+		}
+		if revoked {
+			single.Revoked = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true,
+				Bytes: []byte{0x18, 0x0f, '2', '0', '2', '0', '0', '1', '0', '1', '0', '0', '0', '0', '0', '0', 'Z'}}
+		}
+		var basic ocspBasicResponse
+		basic.TBSResponseData.ResponderID = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, IsCompound: true, Bytes: []byte{0x04, 0x00}}
+		basic.TBSResponseData.ProducedAt = single.ThisUpdate
+		basic.TBSResponseData.Responses = []ocspSingleResponse{single}
+		basic.SignatureAlgorithm.Algorithm = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+		basic.Signature = asn1.BitString{Bytes: []byte{0}, BitLength: 8}
+		var res ocspResponse
+		res.Response.ResponseType = oidOCSPBasicResponse
+		res.Response.Response, _ = asn1.Marshal(basic)
+		der, err := asn1.Marshal(res)
+		if err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Write(der)
+	}))
+}
+
+func TestOCSPClient(t *testing.T) {
+	for _, revoked := range []bool{false, true} {
+		server := newTestOCSPServer(t, revoked)
+		certPEM, _ := newTestChain(t, "localhost", server.URL)
+		leafBlock, rest := pem.Decode(certPEM)
+		issuerBlock, _ := pem.Decode(rest)
+		leaf, _ := x509.ParseCertificate(leafBlock.Bytes)
+		issuer, _ := x509.ParseCertificate(issuerBlock.Bytes)
+		staple, nextUpdate, err := (&OCSPClient{}).Staple(context.Background(), leaf, issuer)
+		if revoked {
+			if err != ErrOCSPStatus {
+				t.Error(err)
+			}
+		} else if err != nil || len(staple) == 0 || nextUpdate.Before(time.Now()) {
+			t.Error(err, nextUpdate)
+		} else if _, err := parseOCSPResponse(staple, big.NewInt(1)); err != ErrOCSPResponse {
+			t.Error(err)
+		}
+		leaf.OCSPServer = nil
+		if _, _, err := (&OCSPClient{}).Staple(context.Background(), leaf, issuer); err != ErrOCSPServer {
+			t.Error(err)
+		}
+		server.Close()
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HandlePrefix registers a handler with the given pattern to the Mux,
+// following the subtree semantics of the http.ServeMux. A pattern ending
+// in a slash, such as "/static/", matches all the paths beginning with it
+// that are not matched by other entries, the longest pattern winning. A
+// request for the subtree root without the trailing slash is redirected to
+// it. A pattern not ending in a slash is registered as by Handle.
+func (m *Mux) HandlePrefix(pattern string, handler http.Handler) *Entry {
+	if !strings.HasSuffix(pattern, "/") {
+		return m.Handle(pattern, handler)
+	}
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	pattern = m.replace(m.group + pattern)
+	if m.subtrees == nil {
+		m.subtrees = make(map[string]*Entry)
+	}
+	entry, ok := m.subtrees[pattern]
+	if !ok {
+		entry = m.newEntry()
+		entry.key = pattern
+		entry.subtree = true
+		m.subtrees[pattern] = entry
+	}
+	entry.handler = handler
+	return entry
+}
+
+// searchSubtree returns the entry of the longest subtree pattern matching the path.
+func (m *Mux) searchSubtree(path string) *Entry {
+	var entry *Entry
+	var n int
+	for pattern, e := range m.subtrees {
+		if len(pattern) > n && strings.HasPrefix(path, pattern) {
+			entry, n = e, len(pattern)
+		}
+	}
+	return entry
+}
+
+// hasSubtree reports whether the pattern is a subtree pattern of the Mux or its groups.
+func (m *Mux) hasSubtree(pattern string) bool {
+	if _, ok := m.subtrees[pattern]; ok {
+		return true
+	}
+	for _, groupMux := range m.groups {
+		if groupMux.hasSubtree(pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// redirectSubtree redirects the request for a subtree root without the
+// trailing slash, and reports whether it has redirected.
+func (m *Mux) redirectSubtree(path string, w http.ResponseWriter, r *http.Request) bool {
+	if strings.HasSuffix(path, "/") {
+		return false
+	}
+	m.mut.RLock()
+	ok := m.hasSubtree(path + "/")
+	m.mut.RUnlock()
+	if !ok {
+		return false
+	}
+	u := &url.URL{Path: path + "/", RawQuery: r.URL.RawQuery}
+	http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+	return true
+}
+
+// FromServeMux returns a Mux serving the requests with the http.ServeMux,
+// easing the migration of the existing applications. Since the http.ServeMux
+// does not expose its registrations, they are not copied but delegated to
+// as a subtree of "/", so the routes registered to the returned Mux take
+// precedence and the others keep the http.ServeMux behavior.
+func FromServeMux(mux *http.ServeMux) *Mux {
+	m := NewMux()
+	m.HandlePrefix("/", mux)
+	return m
+}
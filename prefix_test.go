@@ -0,0 +1,94 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandlePrefix(t *testing.T) {
+	patterns := []string{"/", "/static/", "/static/images/", "/about", "/api/v1/"}
+	serveMux := http.NewServeMux()
+	m := NewMux()
+	for _, pattern := range patterns {
+		pattern := pattern
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(pattern))
+		})
+		serveMux.Handle(pattern, handler)
+		m.HandlePrefix(pattern, handler)
+	}
+	paths := []string{"/", "/index.html", "/static", "/static/", "/static/css/main.css",
+		"/static/images", "/static/images/logo.png", "/about", "/about/", "/api/v1", "/api/v1/users?id=1"}
+	for _, path := range paths {
+		want := httptest.NewRecorder()
+		serveMux.ServeHTTP(want, httptest.NewRequest("GET", path, nil))
+		got := httptest.NewRecorder()
+		m.ServeHTTP(got, httptest.NewRequest("GET", path, nil))
+		if got.Code != want.Code || got.Header().Get("Location") != want.Header().Get("Location") {
+			t.Error(path, got.Code, want.Code, got.Header().Get("Location"), want.Header().Get("Location"))
+		} else if got.Code == http.StatusOK && got.Body.String() != want.Body.String() {
+			t.Error(path, got.Body.String(), want.Body.String())
+		}
+	}
+}
+
+func TestHandlePrefixGroups(t *testing.T) {
+	m := NewMux()
+	for _, group := range []string{"/a", "/a/b/d", "/b", "/c"} {
+		group := group
+		m.Group(group, func(m *Mux) {
+			m.HandlePrefix("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(group + "/"))
+			}))
+		})
+	}
+	m.Group("/a/b", func(m *Mux) {
+		m.HandleFunc("/c", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("route"))
+		})
+	})
+	for i := 0; i < 32; i++ {
+		for path, result := range map[string]string{
+			"/a/b/c":   "route",
+			"/a/x":     "/a/",
+			"/a/b/e":   "/a/",
+			"/a/b/d/e": "/a/b/d/",
+		} {
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			if w.Code != http.StatusOK || w.Body.String() != result {
+				t.Fatal(path, w.Code, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestFromServeMux(t *testing.T) {
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello ServeMux"))
+	})
+	m := FromServeMux(serveMux)
+	m.HandleFunc("/hello/:name", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello " + m.Params(r)["name"]))
+	}).GET()
+	for path, result := range map[string]string{
+		"/hello":     "Hello ServeMux",
+		"/hello/rum": "Hello rum",
+	} {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != result {
+			t.Error(path, w.Code, w.Body.String())
+		}
+	}
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/favicon.ico", nil))
+	if w.Code != http.StatusNotFound {
+		t.Error(w.Code)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// String returns the route tree of the Mux, as written by Print.
+func (m *Mux) String() string {
+	var b strings.Builder
+	m.Print(&b)
+	return b.String()
+}
+
+// Print writes the route tree of the Mux to w for startup logging and
+// debugging. Each group is followed by the number of its middlewares and
+// by its routes, sorted by pattern, with their methods, where * means any.
+//
+//	/                1 middleware
+//	  /hello/:name   GET
+//	  /static/*      GET HEAD
+//	  /api           1 middleware
+//	    /api/users   GET POST
+func (m *Mux) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	m.mut.RLock()
+	m.print(tw, "")
+	m.mut.RUnlock()
+	return tw.Flush()
+}
+
+func (m *Mux) print(w io.Writer, indent string) {
+	group := m.group
+	if group == "" {
+		group = "/"
+	}
+	count := fmt.Sprintf("%d middlewares", len(m.context.middlewares))
+	if len(m.context.middlewares) == 1 {
+		count = "1 middleware"
+	}
+	fmt.Fprintf(w, "%s%s\t%s\n", indent, group, count)
+	routes := m.entries()
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Pattern < routes[j].Pattern })
+	for _, route := range routes {
+		methods := "*"
+		if len(route.Methods) > 0 {
+			methods = strings.Join(route.Methods, " ")
+		}
+		fmt.Fprintf(w, "%s  %s\t%s\n", indent, route.Pattern, methods)
+	}
+	groups := make([]string, 0, len(m.groups))
+	for group := range m.groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		groupMux := m.groups[group]
+		groupMux.mut.RLock()
+		groupMux.print(w, indent+"  ")
+		groupMux.mut.RUnlock()
+	}
+}
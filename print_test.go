@@ -0,0 +1,42 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPrint(t *testing.T) {
+	m := NewMux()
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	m.Use(handler)
+	m.HandleFunc("/hello/:name", handler).GET()
+	m.HandleFunc("/static/*", handler).GET().HEAD()
+	m.HandleFunc("/any", handler)
+	m.Use(handler)
+	m.Group("/api", func(m *Mux) {
+		m.HandleFunc("/users", handler).GET().POST()
+		m.HandleFunc("/users/:id", handler).DELETE()
+	})
+	m.Group("/admin", func(m *Mux) {
+		m.HandleFunc("/stats", handler).GET()
+	})
+	expected := "" +
+		"/                   2 middlewares\n" +
+		"  /any              *\n" +
+		"  /hello/:name      GET\n" +
+		"  /static/*         GET HEAD\n" +
+		"  /admin            2 middlewares\n" +
+		"    /admin/stats    GET\n" +
+		"  /api              2 middlewares\n" +
+		"    /api/users      GET POST\n" +
+		"    /api/users/:id  DELETE\n"
+	if s := m.String(); s != expected {
+		t.Errorf("\n%s", s)
+	}
+	if s := NewMux().String(); s != "/  0 middlewares\n" {
+		t.Errorf("%q", s)
+	}
+}
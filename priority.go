@@ -0,0 +1,170 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"math"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Priority is the priority of a request under overload.
+type Priority int
+
+const (
+	// PriorityLow is shed first, when the load reaches 1.
+	PriorityLow Priority = iota - 1
+	// PriorityNormal is the default priority, shed when the load reaches 1.25.
+	PriorityNormal
+	// PriorityHigh is shed when the load reaches 1.5.
+	PriorityHigh
+	// PriorityCritical is never shed.
+	PriorityCritical
+)
+
+// String returns the name of the priority.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	case PriorityCritical:
+		return "critical"
+	}
+	return "unknown"
+}
+
+// Priority sets the priority of the requests of the entry under overload.
+func (entry *Entry) Priority(p Priority) *Entry {
+	entry.priority = p
+	entry.prioritized = true
+	return entry
+}
+
+// OverloadController sheds the requests by priority when the Mux is
+// overloaded. The load is the greatest of the ratios of the in-flight
+// requests to MaxInFlight and of the moving average latency of the
+// admitted requests to MaxLatency. The low priority requests are shed
+// first, when the load reaches 1, then the normal and the high ones
+// by steps of 0.25, replying 503 Service Unavailable.
+type OverloadController struct {
+	inFlight int64
+	latency  int64
+	shed     [4]uint64
+	// MaxInFlight is the number of the in-flight requests at the load 1.
+	// Zero means no limit.
+	MaxInFlight int
+	// MaxLatency is the moving average latency at the load 1.
+	// Zero means no limit.
+	MaxLatency time.Duration
+	// Classes maps the rate-limit classes of the entries to priorities.
+	Classes map[string]Priority
+	// Classify optionally classifies the requests, overriding the
+	// priorities of the entries and of the classes.
+	Classify func(r *http.Request) (Priority, bool)
+}
+
+// OverloadStats is the statistics of an OverloadController.
+type OverloadStats struct {
+	InFlight int64             `json:"inFlight"`
+	Latency  time.Duration     `json:"latency"`
+	Load     float64           `json:"load"`
+	Shed     map[string]uint64 `json:"shed"`
+}
+
+// SetOverloadController sets the OverloadController of the Mux and
+// its groups. A nil controller disables the shedding.
+func (m *Mux) SetOverloadController(c *OverloadController) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.overload = c
+}
+
+// OverloadStats returns the statistics of the OverloadController of the Mux.
+func (m *Mux) OverloadStats() (stats OverloadStats, ok bool) {
+	m.mut.RLock()
+	c := m.overload
+	m.mut.RUnlock()
+	if c == nil {
+		return stats, false
+	}
+	return c.Stats(), true
+}
+
+// Stats returns the statistics of the controller.
+func (c *OverloadController) Stats() OverloadStats {
+	stats := OverloadStats{
+		InFlight: atomic.LoadInt64(&c.inFlight),
+		Latency:  time.Duration(atomic.LoadInt64(&c.latency)),
+		Load:     c.load(),
+		Shed:     make(map[string]uint64, len(c.shed)),
+	}
+	for i := range c.shed {
+		stats.Shed[(Priority(i) + PriorityLow).String()] = atomic.LoadUint64(&c.shed[i])
+	}
+	return stats
+}
+
+func (c *OverloadController) load() float64 {
+	var load float64
+	if c.MaxInFlight > 0 {
+		load = float64(atomic.LoadInt64(&c.inFlight)) / float64(c.MaxInFlight)
+	}
+	if c.MaxLatency > 0 {
+		load = math.Max(load, float64(atomic.LoadInt64(&c.latency))/float64(c.MaxLatency))
+	}
+	return load
+}
+
+func (c *OverloadController) priority(entry *Entry, r *http.Request) Priority {
+	if c.Classify != nil {
+		if p, ok := c.Classify(r); ok {
+			return p
+		}
+	}
+	if entry.prioritized {
+		return entry.priority
+	}
+	if p, ok := c.Classes[entry.class]; ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// admit reports whether the request is admitted, replying 503 Service
+// Unavailable if not. The admitted requests must be finished with done.
+func (c *OverloadController) admit(entry *Entry, w http.ResponseWriter, r *http.Request) bool {
+	p := c.priority(entry, r)
+	if p < PriorityLow {
+		p = PriorityLow
+	}
+	if p < PriorityCritical && c.load() >= 1+0.25*float64(p-PriorityLow) {
+		atomic.AddUint64(&c.shed[p-PriorityLow], 1)
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "503 Service Unavailable", http.StatusServiceUnavailable)
+		return false
+	}
+	atomic.AddInt64(&c.inFlight, 1)
+	return true
+}
+
+// done records the latency of an admitted request in the moving average.
+func (c *OverloadController) done(start time.Time) {
+	atomic.AddInt64(&c.inFlight, -1)
+	sample := int64(time.Since(start))
+	for {
+		old := atomic.LoadInt64(&c.latency)
+		latency := sample
+		if old > 0 {
+			latency = old + (sample-old)/10
+		}
+		if atomic.CompareAndSwapInt64(&c.latency, old, latency) {
+			return
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOverloadController(t *testing.T) {
+	m := NewMux()
+	c := &OverloadController{
+		MaxInFlight: 4,
+		Classes:     map[string]Priority{"batch": PriorityLow},
+		Classify: func(r *http.Request) (Priority, bool) {
+			if r.Header.Get("X-Priority") == "critical" {
+				return PriorityCritical, true
+			}
+			return 0, false
+		},
+	}
+	m.SetOverloadController(c)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	m.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}).Priority(PriorityCritical)
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	m.HandleFunc("/batch", handler).RateLimit("batch")
+	m.HandleFunc("/normal", handler)
+	m.HandleFunc("/high", handler).Priority(PriorityHigh)
+	code := func(target string, critical bool) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", target, nil)
+		if critical {
+			r.Header.Set("X-Priority", "critical")
+		}
+		m.ServeHTTP(w, r)
+		return w.Code
+	}
+	done := make(chan struct{})
+	slow := func(n int) {
+		for i := 0; i < n; i++ {
+			go func() {
+				code("/slow", false)
+				done <- struct{}{}
+			}()
+			<-started
+		}
+	}
+	if code("/batch", false) != http.StatusOK {
+		t.Error()
+	}
+	slow(4)
+	if code("/batch", false) != http.StatusServiceUnavailable || code("/normal", false) != http.StatusOK {
+		t.Error()
+	}
+	slow(1)
+	if code("/normal", false) != http.StatusServiceUnavailable || code("/high", false) != http.StatusOK {
+		t.Error()
+	}
+	slow(1)
+	if code("/high", false) != http.StatusServiceUnavailable || code("/batch", true) != http.StatusOK {
+		t.Error()
+	}
+	stats, ok := m.OverloadStats()
+	if !ok || stats.InFlight != 6 || stats.Load != 1.5 ||
+		stats.Shed["low"] != 1 || stats.Shed["normal"] != 1 || stats.Shed["high"] != 1 || stats.Shed["critical"] != 0 {
+		t.Error(stats)
+	}
+	close(release)
+	for i := 0; i < 6; i++ {
+		<-done
+	}
+	if stats := c.Stats(); stats.InFlight != 0 || stats.Latency <= 0 {
+		t.Error(stats)
+	}
+	m.SetOverloadController(nil)
+	if _, ok := m.OverloadStats(); ok {
+		t.Error()
+	}
+}
+
+func TestOverloadLatency(t *testing.T) {
+	c := &OverloadController{MaxLatency: time.Millisecond}
+	c.done(time.Now().Add(-time.Millisecond * 2))
+	if stats := c.Stats(); stats.Load < 1.5 {
+		t.Error(stats.Load)
+	}
+	w := httptest.NewRecorder()
+	if c.admit(&Entry{}, w, httptest.NewRequest("GET", "/", nil)) || w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "1" {
+		t.Error(w.Code)
+	}
+	if PriorityLow.String() != "low" || Priority(9).String() != "unknown" {
+		t.Error()
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ProblemContentType is the media type of the problem details JSON responses.
+const ProblemContentType = "application/problem+json"
+
+var methods = [...]string{
+	options: "OPTIONS",
+	get:     "GET",
+	head:    "HEAD",
+	post:    "POST",
+	put:     "PUT",
+	del:     "DELETE",
+	trace:   "TRACE",
+	connect: "CONNECT",
+	patch:   "PATCH",
+}
+
+// Problem represents the problem details of an HTTP API error, as defined by RFC 7807.
+type Problem struct {
+	Type     string
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+	// Extensions holds the extension members.
+	Extensions map[string]interface{}
+}
+
+// MarshalJSON encodes the problem details as a JSON object with the
+// extension members at the top level.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	members["type"] = p.Type
+	if p.Type == "" {
+		members["type"] = "about:blank"
+	}
+	members["title"] = p.Title
+	if p.Title == "" {
+		members["title"] = StatusText(p.Status)
+	}
+	members["status"] = p.Status
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+	return json.Marshal(members)
+}
+
+// Error returns the title and the detail of the problem.
+func (p *Problem) Error() string {
+	title := p.Title
+	if title == "" {
+		title = StatusText(p.Status)
+	}
+	if p.Detail != "" {
+		return fmt.Sprintf("%d %s : %s", p.Status, title, p.Detail)
+	}
+	return fmt.Sprintf("%d %s", p.Status, title)
+}
+
+// ServeHTTP replies to the request with the problem details.
+func (p *Problem) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, "500 Internal Server Error : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	w.Write(body)
+}
+
+// ProblemJSON replies with the problem details of the status code, the title
+// and the detail. If the title is empty, the status text is used. The fields
+// are the key-value pairs of the extension members.
+func ProblemJSON(w http.ResponseWriter, status int, title, detail string, fields ...interface{}) {
+	p := &Problem{Title: title, Status: status, Detail: detail}
+	for i := 0; i+1 < len(fields); i += 2 {
+		if p.Extensions == nil {
+			p.Extensions = make(map[string]interface{})
+		}
+		p.Extensions[fmt.Sprint(fields[i])] = fields[i+1]
+	}
+	p.ServeHTTP(w, nil)
+}
+
+// ProblemErrorHandler replies with the problem details of the error. It uses
+// a *Problem as is, and the status code and the message of an *HTTPError.
+// The other errors are logged to the standard logger and replied with a 500
+// status code without details.
+func ProblemErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		problem.ServeHTTP(w, r)
+		return
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		ProblemJSON(w, httpErr.Code, httpErr.Message, "")
+		return
+	}
+	logError(r, err)
+	ProblemJSON(w, http.StatusInternalServerError, "", "")
+}
+
+// ProblemRecovery is a recovery handler function that recovers from any
+// panics and replies with the problem details of a 500 status code. The
+// panic value is logged to the standard logger instead of being sent to
+// the client.
+func ProblemRecovery(w http.ResponseWriter, r *http.Request) {
+	logError(r, &PanicError{Value: r.Context().Value(RecoveryContextKey)})
+	ProblemJSON(w, http.StatusInternalServerError, "", "")
+}
+
+// Problems makes the Mux reply with the problem details JSON responses
+// by default. The routes not found are replied with a 404 status code, the
+// errors and the panics without an ErrorHandler or a Recovery are converted
+// by the ProblemErrorHandler, and the entries restricted to some HTTP methods reply to the other methods
+// with a 405 status code and the Allow header.
+func (m *Mux) Problems() {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.context.problems = true
+}
+
+// methodNotAllowed replies with a 405 status code if the entry is restricted
+// to some HTTP methods, and reports whether it has replied.
+func (m *Mux) methodNotAllowed(entry *Entry, w http.ResponseWriter, r *http.Request) bool {
+	var allowed []string
+	for i, handler := range entry.handlers {
+		if handler != nil {
+			allowed = append(allowed, methods[i])
+		}
+	}
+	if len(allowed) == 0 {
+		return false
+	}
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	ProblemJSON(w, http.StatusMethodNotAllowed, "", r.Method+" "+r.URL.Path)
+	return true
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testProblem(w *httptest.ResponseRecorder, status int, detail string, t *testing.T) map[string]interface{} {
+	if w.Code != status {
+		t.Error(w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ProblemContentType {
+		t.Error(ct)
+	}
+	var problem map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Error(err)
+	}
+	if problem["type"] != "about:blank" || problem["title"] != http.StatusText(status) || problem["status"] != float64(status) {
+		t.Error(problem)
+	}
+	if detail != "" && problem["detail"] != detail {
+		t.Error(problem["detail"])
+	}
+	return problem
+}
+
+func TestProblemJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	ProblemJSON(w, http.StatusBadRequest, "", "invalid name", "field", "name", "odd")
+	problem := testProblem(w, http.StatusBadRequest, "invalid name", t)
+	if problem["field"] != "name" {
+		t.Error(problem)
+	}
+	if _, ok := problem["odd"]; ok {
+		t.Error(problem)
+	}
+	p := &Problem{Type: "https://example.com/probs/out-of-credit", Title: "Out of credit", Status: http.StatusForbidden}
+	if p.Error() != "403 Out of credit" {
+		t.Error(p.Error())
+	}
+	w = httptest.NewRecorder()
+	ProblemErrorHandler(w, nil, p)
+	if w.Code != http.StatusForbidden || !json.Valid(w.Body.Bytes()) {
+		t.Error(w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	ProblemErrorHandler(w, nil, NewHTTPError(http.StatusConflict, ""))
+	testProblem(w, http.StatusConflict, "", t)
+	w = httptest.NewRecorder()
+	ProblemErrorHandler(w, nil, errors.New("failed"))
+	if problem := testProblem(w, http.StatusInternalServerError, "", t); problem["detail"] != nil {
+		t.Error(problem["detail"])
+	}
+}
+
+func TestProblems(t *testing.T) {
+	m := NewMux()
+	m.Problems()
+	m.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	}).GET().HEAD()
+	m.HandleFuncE("/error", func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("failed")
+	})
+	m.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("panic test")
+	})
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/hello", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "Hello World" {
+		t.Error(w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/favicon.ico", nil))
+	testProblem(w, http.StatusNotFound, "/favicon.ico", t)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("POST", "/hello", nil))
+	testProblem(w, http.StatusMethodNotAllowed, "POST /hello", t)
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD" {
+		t.Error(allow)
+	}
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/error", nil))
+	if problem := testProblem(w, http.StatusInternalServerError, "", t); problem["detail"] != nil {
+		t.Error(problem["detail"])
+	}
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if problem := testProblem(w, http.StatusInternalServerError, "", t); problem["detail"] != nil {
+		t.Error(problem["detail"])
+	}
+	m.Recovery(ProblemRecovery)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if problem := testProblem(w, http.StatusInternalServerError, "", t); problem["detail"] != nil {
+		t.Error(problem["detail"])
+	}
+}
@@ -0,0 +1,418 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoUpstream is the error returned by the Proxy when no upstream is available.
+var ErrNoUpstream = errors.New("No Upstream Available")
+
+// maxRetryTokens is the maximum number of retries saved by the retry budget.
+const maxRetryTokens = 10
+
+// hopHeaders are the hop-by-hop headers removed by the Proxy.
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Upstream is an upstream server of a Proxy.
+type Upstream struct {
+	conns int64
+	down  int32
+	// URL is the base URL of the upstream.
+	URL *url.URL
+	id  string
+}
+
+// ID returns the identifier of the upstream, derived from its URL.
+func (u *Upstream) ID() string {
+	return u.id
+}
+
+// Conns returns the number of the active requests of the upstream.
+func (u *Upstream) Conns() int64 {
+	return atomic.LoadInt64(&u.conns)
+}
+
+// Healthy reports whether the upstream passed the last health check.
+func (u *Upstream) Healthy() bool {
+	return atomic.LoadInt32(&u.down) == 0
+}
+
+// Proxy is a reverse proxy balancing the requests over several upstreams.
+// The requests with idempotent methods and without bodies are retried on
+// another upstream when the connection to an upstream fails.
+type Proxy struct {
+	// Balancer selects the upstreams. If nil, RoundRobin is used.
+	Balancer Balancer
+	// Transport performs the proxied requests. If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+	// Retries is the maximum number of retries of a request.
+	Retries int
+	// RetryBudget is the ratio of the retries to the requests. Each request
+	// earns RetryBudget retries, up to 10 saved ones. Zero means no budget.
+	RetryBudget float64
+	// IdleTimeout is the maximum duration of the upgraded connections, such
+	// as the WebSocket connections, without any data. Zero means no timeout.
+	IdleTimeout time.Duration
+	// ErrorHandler replies to the requests failed by all of the upstreams.
+	// If nil, the errors are logged, and the requests are replied with a
+	// 502 or a 503 status code.
+	ErrorHandler ErrorHandler
+	// ErrorLog logs the errors of the upstreams. If nil, the standard logger
+	// is used.
+	ErrorLog *log.Logger
+	// Decompress decodes the upstream responses of gzip, deflate or a
+	// content coding of the Decoders, so that the Transformers see the
+	// identity bodies. The Accept-Encoding of the proxied requests is
+	// limited to the decodable codings.
+	Decompress bool
+	// Decoders holds the decoders of the other content codings, such as
+	// zstd, by name.
+	Decoders map[string]Decoder
+	// Transformers rewrite the bodies of the upstream responses.
+	Transformers []Transformer
+	// Compression compresses the responses for the clients. Combined with
+	// Decompress, the responses are re-compressed by the codings accepted
+	// by the clients.
+	Compression *Compression
+
+	upstreams  []*Upstream
+	roundRobin roundRobin
+	mu         sync.Mutex
+	tokens     float64
+	done       chan struct{}
+	closed     bool
+}
+
+// NewProxy returns a new Proxy to the upstream URLs.
+func NewProxy(targets ...string) (*Proxy, error) {
+	p := &Proxy{}
+	for _, target := range targets {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid upstream url %q", target)
+		}
+		h := fnv.New64a()
+		h.Write([]byte(u.String()))
+		id := strconv.FormatUint(h.Sum64(), 36)
+		p.upstreams = append(p.upstreams, &Upstream{URL: u, id: id})
+	}
+	return p, nil
+}
+
+// Upstreams returns the upstreams of the Proxy.
+func (p *Proxy) Upstreams() []*Upstream {
+	return p.upstreams
+}
+
+// HealthCheck checks the upstreams with GET requests of the path every
+// interval. An upstream failing to reply, or replying with a 5xx status
+// code, is not selected until it passes a check.
+func (p *Proxy) HealthCheck(path string, interval, timeout time.Duration) {
+	p.mu.Lock()
+	if p.done != nil || p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.done = make(chan struct{})
+	done := p.done
+	p.mu.Unlock()
+	p.check(path, timeout)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.check(path, timeout)
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+func (p *Proxy) check(path string, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for _, u := range p.upstreams {
+		wg.Add(1)
+		go func(u *Upstream) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			req, _ := http.NewRequest("GET", singleJoiningSlash(u.URL.String(), path), nil)
+			res, err := p.transport().RoundTrip(req.WithContext(ctx))
+			if err == nil {
+				res.Body.Close()
+			}
+			if err != nil || res.StatusCode >= 500 {
+				atomic.StoreInt32(&u.down, 1)
+			} else {
+				atomic.StoreInt32(&u.down, 0)
+			}
+		}(u)
+	}
+	wg.Wait()
+}
+
+// Close stops the health checks.
+func (p *Proxy) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		if p.done != nil {
+			close(p.done)
+		}
+	}
+	return nil
+}
+
+func (p *Proxy) transport() http.RoundTripper {
+	if p.Transport != nil {
+		return p.Transport
+	}
+	return http.DefaultTransport
+}
+
+// candidates returns the healthy upstreams not tried yet.
+func (p *Proxy) candidates(tried []*Upstream) []*Upstream {
+	candidates := make([]*Upstream, 0, len(p.upstreams))
+	for _, u := range p.upstreams {
+		if !u.Healthy() {
+			continue
+		}
+		skip := false
+		for _, t := range tried {
+			if t == u {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			candidates = append(candidates, u)
+		}
+	}
+	return candidates
+}
+
+// retry reports whether the retry budget allows a retry.
+func (p *Proxy) retry() bool {
+	if p.RetryBudget <= 0 {
+		return true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tokens < 1 {
+		return false
+	}
+	p.tokens--
+	return true
+}
+
+func (p *Proxy) earn() {
+	if p.RetryBudget <= 0 {
+		return
+	}
+	p.mu.Lock()
+	p.tokens += p.RetryBudget
+	if p.tokens > maxRetryTokens {
+		p.tokens = maxRetryTokens
+	}
+	p.mu.Unlock()
+}
+
+// idempotent reports whether the request can be retried.
+func idempotent(r *http.Request) bool {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0
+	}
+	return false
+}
+
+// ServeHTTP proxies the request to an upstream.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.earn()
+	balancer := p.Balancer
+	if balancer == nil {
+		balancer = &p.roundRobin
+	}
+	var tried []*Upstream
+	var err error
+	for attempt := 0; ; attempt++ {
+		u := balancer.Pick(r, p.candidates(tried))
+		if u == nil {
+			if err == nil {
+				err = ErrNoUpstream
+			}
+			break
+		}
+		tried = append(tried, u)
+		var res *http.Response
+		res, err = p.roundTrip(u, r)
+		if err == nil {
+			if binder, ok := balancer.(Binder); ok {
+				binder.Bind(w, r, u)
+			}
+			if res.StatusCode == http.StatusSwitchingProtocols {
+				p.switchProtocols(w, r, res)
+			} else {
+				p.serveResponse(w, r, res)
+			}
+			atomic.AddInt64(&u.conns, -1)
+			return
+		}
+		if attempt >= p.Retries || !idempotent(r) || r.Context().Err() != nil || !p.retry() {
+			break
+		}
+	}
+	p.fail(w, r, err)
+}
+
+func (p *Proxy) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if p.ErrorHandler != nil {
+		p.ErrorHandler(w, r, err)
+		return
+	}
+	if err == ErrNoUpstream {
+		http.Error(w, "503 Service Unavailable : "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	// The error may contain the addresses of the upstreams.
+	if p.ErrorLog != nil {
+		p.ErrorLog.Printf("rum: proxy %s %s: %v", r.Method, r.URL.Path, err)
+	} else {
+		log.Printf("rum: proxy %s %s: %v", r.Method, r.URL.Path, err)
+	}
+	http.Error(w, "502 Bad Gateway", http.StatusBadGateway)
+}
+
+// roundTrip sends the request to the upstream. The active requests of the
+// upstream are decreased on failure, or after copying the response.
+func (p *Proxy) roundTrip(u *Upstream, r *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&u.conns, 1)
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	out.URL.Scheme = u.URL.Scheme
+	out.URL.Host = u.URL.Host
+	out.URL.Path = singleJoiningSlash(u.URL.Path, r.URL.Path)
+	out.URL.RawPath = ""
+	if u.URL.RawQuery != "" && r.URL.RawQuery != "" {
+		out.URL.RawQuery = u.URL.RawQuery + "&" + r.URL.RawQuery
+	} else if u.URL.RawQuery != "" {
+		out.URL.RawQuery = u.URL.RawQuery
+	}
+	if r.ContentLength == 0 {
+		out.Body = nil
+	}
+	removeHopHeaders(out.Header)
+	if strings.Contains(strings.ToLower(r.Header.Get("Te")), "trailers") {
+		out.Header.Set("Te", "trailers")
+	}
+	if p.Decompress {
+		out.Header.Set("Accept-Encoding", p.acceptEncoding())
+	}
+	if upgrade := upgradeType(r.Header); upgrade != "" {
+		out.Header.Set("Connection", "Upgrade")
+		out.Header.Set("Upgrade", upgrade)
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		if prior := out.Header["X-Forwarded-For"]; len(prior) > 0 {
+			host = strings.Join(prior, ", ") + ", " + host
+		}
+		out.Header.Set("X-Forwarded-For", host)
+	}
+	res, err := p.transport().RoundTrip(out)
+	if err != nil {
+		atomic.AddInt64(&u.conns, -1)
+	}
+	return res, err
+}
+
+func (p *Proxy) copyResponse(w http.ResponseWriter, res *http.Response) {
+	defer res.Body.Close()
+	removeHopHeaders(res.Header)
+	header := w.Header()
+	for k, v := range res.Header {
+		if k == "Vary" {
+			header[k] = append(header[k], v...)
+			continue
+		}
+		header[k] = v
+	}
+	w.WriteHeader(res.StatusCode)
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+	flusher, stream := w.(http.Flusher)
+	stream = stream && res.ContentLength < 0
+	for {
+		n, err := res.Body.Read(*buf)
+		if n > 0 {
+			if _, werr := w.Write((*buf)[:n]); werr != nil {
+				return
+			}
+			if stream {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	for k, v := range res.Trailer {
+		header[http.TrailerPrefix+k] = v
+	}
+}
+
+func removeHopHeaders(header http.Header) {
+	for _, f := range header["Connection"] {
+		for _, k := range strings.Split(f, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				header.Del(k)
+			}
+		}
+	}
+	for _, k := range hopHeaders {
+		header.Del(k)
+	}
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testUpstream(name string, healthy *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/health") {
+			if atomic.LoadInt32(healthy) == 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			return
+		}
+		w.Header().Set("Connection", "X-Hop")
+		w.Header().Set("X-Hop", "1")
+		w.Write([]byte(name + " " + r.URL.Path + " " + r.Header.Get("X-Forwarded-For")))
+	}))
+}
+
+func testProxy(p *Proxy, method, path string, status int, t *testing.T) string {
+	req := httptest.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, req)
+	if w.Code != status {
+		t.Error(path, w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Hop") != "" {
+		t.Error(w.Header())
+	}
+	return w.Body.String()
+}
+
+func TestProxy(t *testing.T) {
+	healthy := [2]int32{1, 1}
+	a := testUpstream("a", &healthy[0])
+	defer a.Close()
+	b := testUpstream("b", &healthy[1])
+	defer b.Close()
+	p, err := NewProxy(a.URL, b.URL+"/base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewProxy("127.0.0.1"); err == nil {
+		t.Error("should be an invalid url")
+	}
+	if result := testProxy(p, "GET", "/hello", http.StatusOK, t); result != "a /hello 192.0.2.1" {
+		t.Error(result)
+	}
+	if result := testProxy(p, "GET", "/hello", http.StatusOK, t); result != "b /base/hello 192.0.2.1" {
+		t.Error(result)
+	}
+	atomic.StoreInt32(&healthy[0], 0)
+	p.HealthCheck("/health", time.Millisecond*10, time.Second)
+	defer p.Close()
+	if p.Upstreams()[0].Healthy() || !p.Upstreams()[1].Healthy() {
+		t.Error("a should be down")
+	}
+	for i := 0; i < 2; i++ {
+		if result := testProxy(p, "GET", "/", http.StatusOK, t); result != "b /base/ 192.0.2.1" {
+			t.Error(result)
+		}
+	}
+	atomic.StoreInt32(&healthy[1], 0)
+	time.Sleep(time.Millisecond * 50)
+	testProxy(p, "GET", "/", http.StatusServiceUnavailable, t)
+	atomic.StoreInt32(&healthy[0], 1)
+	time.Sleep(time.Millisecond * 50)
+	if result := testProxy(p, "GET", "/", http.StatusOK, t); result != "a / 192.0.2.1" {
+		t.Error(result)
+	}
+}
+
+type firstBalancer struct{}
+
+func (firstBalancer) Pick(r *http.Request, candidates []*Upstream) *Upstream {
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[0]
+}
+
+func TestProxyRetry(t *testing.T) {
+	var healthy int32 = 1
+	up := testUpstream("up", &healthy)
+	defer up.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	p, _ := NewProxy(down.URL, up.URL)
+	p.Balancer = firstBalancer{}
+	var logs bytes.Buffer
+	p.ErrorLog = log.New(&logs, "", 0)
+	if result := testProxy(p, "GET", "/", http.StatusBadGateway, t); result != "502 Bad Gateway\n" {
+		t.Error(result)
+	}
+	if !strings.Contains(logs.String(), strings.TrimPrefix(down.URL, "http://")) {
+		t.Error(logs.String())
+	}
+	p.Retries = 1
+	for i := 0; i < 2; i++ {
+		if result := testProxy(p, "GET", "/", http.StatusOK, t); result != "up / 192.0.2.1" {
+			t.Error(result)
+		}
+	}
+	testProxy(p, "POST", "/", http.StatusBadGateway, t)
+	p.RetryBudget = 0.5
+	testProxy(p, "GET", "/", http.StatusBadGateway, t)
+	testProxy(p, "GET", "/", http.StatusOK, t)
+	p.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		ProblemJSON(w, http.StatusBadGateway, "", err.Error())
+	}
+	p.Retries = 0
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusBadGateway || w.Header().Get("Content-Type") != ProblemContentType {
+		t.Error(w.Code, w.Body.String())
+	}
+}
+
+func TestProxySticky(t *testing.T) {
+	healthy := [2]int32{1, 1}
+	a := testUpstream("a", &healthy[0])
+	defer a.Close()
+	b := testUpstream("b", &healthy[1])
+	defer b.Close()
+	p, _ := NewProxy(a.URL, b.URL)
+	p.Balancer = Sticky("upstream", nil)
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatal(cookies)
+	}
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(cookies[0])
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, req)
+		if w.Body.String() != "a / 192.0.2.1" {
+			t.Error(w.Body.String())
+		}
+	}
+}
+
+func TestProxyTrailers(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Te") != "trailers" {
+			t.Error(r.Header.Get("Te"))
+		}
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write([]byte("Hello World"))
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	defer upstream.Close()
+	p, _ := NewProxy(upstream.URL)
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("Te", "trailers")
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, req)
+	if res := w.Result(); res.Trailer.Get("Grpc-Status") != "0" {
+		t.Error(res.Trailer)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Decoder returns a reader decoding a content coding from r.
+type Decoder func(r io.Reader) (io.ReadCloser, error)
+
+var defaultDecoders = map[string]Decoder{
+	"gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"deflate": func(r io.Reader) (io.ReadCloser, error) {
+		return zlib.NewReader(r)
+	},
+}
+
+// decoder returns the decoder of the content coding, or nil if unknown.
+func (p *Proxy) decoder(coding string) Decoder {
+	if d, ok := p.Decoders[coding]; ok {
+		return d
+	}
+	return defaultDecoders[coding]
+}
+
+// acceptEncoding returns the Accept-Encoding of the decodable codings.
+func (p *Proxy) acceptEncoding() string {
+	codings := []string{"gzip", "deflate"}
+	for coding := range p.Decoders {
+		if _, ok := defaultDecoders[coding]; !ok {
+			codings = append(codings, coding)
+		}
+	}
+	sort.Strings(codings[2:])
+	return strings.Join(codings, ", ")
+}
+
+// decode replaces the encoded body of the response by the decoded one. The
+// responses of an unknown or a stacked content coding are left untouched.
+func (p *Proxy) decode(r *http.Request, res *http.Response) error {
+	coding := strings.ToLower(strings.TrimSpace(res.Header.Get("Content-Encoding")))
+	if coding == "" || coding == "identity" || r.Method == "HEAD" ||
+		res.ContentLength == 0 || !bodyAllowedForStatus(res.StatusCode) {
+		return nil
+	}
+	decoder := p.decoder(coding)
+	if decoder == nil {
+		return nil
+	}
+	body, err := decoder(res.Body)
+	if err != nil {
+		return err
+	}
+	res.Body = &decodedBody{ReadCloser: body, body: res.Body}
+	res.ContentLength = -1
+	res.Uncompressed = true
+	res.Header.Del("Content-Encoding")
+	res.Header.Del("Content-Length")
+	if etag := res.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		res.Header.Set("ETag", "W/"+etag)
+	}
+	return nil
+}
+
+// serveResponse decodes, transforms and compresses the response as
+// configured, and copies it to w.
+func (p *Proxy) serveResponse(w http.ResponseWriter, r *http.Request, res *http.Response) {
+	if p.Decompress {
+		if err := p.decode(r, res); err != nil {
+			res.Body.Close()
+			p.fail(w, r, err)
+			return
+		}
+	}
+	if len(p.Transformers) == 0 && p.Compression == nil {
+		p.copyResponse(w, res)
+		return
+	}
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		p.copyResponse(w, res)
+	})
+	if len(p.Transformers) > 0 {
+		handler = TransformHandler(handler, p.Transformers...)
+	}
+	if p.Compression != nil {
+		handler = p.Compression.Handler(handler)
+	}
+	handler.ServeHTTP(w, r)
+}
+
+type decodedBody struct {
+	io.ReadCloser
+	body io.ReadCloser
+}
+
+func (b *decodedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if cerr := b.body.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProxyRecompress(t *testing.T) {
+	page := "<html><head></head><body>" + strings.Repeat("hello ", 100) + "</body></html>"
+	var accepted string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted = r.Header.Get("Accept-Encoding")
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		gw.Write([]byte(page))
+		gw.Close()
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Vary", "Cookie")
+		w.Write(buf.Bytes())
+	}))
+	defer upstream.Close()
+	p, err := NewProxy(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Decompress = true
+	p.Decoders = map[string]Decoder{"x-test": func(r io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(r), nil
+	}}
+	p.Transformers = []Transformer{ReplaceTransformer("text/html", "</head>", "<script></script></head>", 1)}
+	p.Compression = &Compression{MinSize: 1}
+	want := strings.Replace(page, "</head>", "<script></script></head>", 1)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, req)
+	if accepted != "gzip, deflate, x-test" {
+		t.Error(accepted)
+	}
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Content-Length") != "" {
+		t.Error(w.Header())
+	}
+	if w.Header().Get("ETag") != `W/"v1"` {
+		t.Error(w.Header().Get("ETag"))
+	}
+	if vary := strings.Join(w.Header()["Vary"], ","); !strings.Contains(vary, "Accept-Encoding") || !strings.Contains(vary, "Cookie") {
+		t.Error(vary)
+	}
+	gr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := ioutil.ReadAll(gr); string(body) != want {
+		t.Error(string(body))
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != want {
+		t.Error(w.Header(), w.Body.String())
+	}
+
+	p.Transformers, p.Compression = nil, nil
+	req = httptest.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != page {
+		t.Error(w.Header(), w.Body.String())
+	}
+}
+
+func TestProxyDecodeError(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write([]byte("not gzip"))
+	}))
+	defer upstream.Close()
+	p, err := NewProxy(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Decompress = true
+	testProxy(p, "GET", "/", http.StatusBadGateway, t)
+	p.Decompress = false
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "not gzip" {
+		t.Error(w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+)
+
+// Push declares the assets pushed with the GET responses of the entry, such
+// as the stylesheets and the scripts of a page. The assets are pushed only
+// if the response writer implements http.Pusher, as the net/http HTTP/2
+// server does when it serves the Mux. The Server serves HTTP/1.1, on which
+// pushing is a no-op. The pushing is suppressed when the client disables it.
+func (entry *Entry) Push(targets ...string) *Entry {
+	entry.pushes = append(entry.pushes, targets...)
+	return entry
+}
+
+func (entry *Entry) push(w http.ResponseWriter, r *http.Request) {
+	pusher, ok := w.(http.Pusher)
+	if !ok {
+		return
+	}
+	var opts *http.PushOptions
+	if encoding := r.Header.Get("Accept-Encoding"); encoding != "" {
+		opts = &http.PushOptions{Header: http.Header{"Accept-Encoding": {encoding}}}
+	}
+	for _, target := range entry.pushes {
+		// The push fails with http.ErrNotSupported if the client disabled
+		// it or the request is itself pushed, and the rest would fail too.
+		if err := pusher.Push(target, opts); err != nil {
+			return
+		}
+	}
+}
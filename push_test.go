@@ -0,0 +1,54 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testPusher struct {
+	*httptest.ResponseRecorder
+	pushed   []string
+	disabled bool
+}
+
+func (w *testPusher) Push(target string, opts *http.PushOptions) error {
+	if w.disabled {
+		return http.ErrNotSupported
+	}
+	w.pushed = append(w.pushed, target)
+	return nil
+}
+
+func TestPush(t *testing.T) {
+	m := New()
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	}).Push("/style.css", "/app.js").CircuitBreaker(&CircuitBreaker{})
+	w := &testPusher{ResponseRecorder: httptest.NewRecorder()}
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if len(w.pushed) != 2 || w.pushed[0] != "/style.css" || w.pushed[1] != "/app.js" {
+		t.Error(w.pushed)
+	}
+	if w.Body.String() != "Hello World" {
+		t.Error(w.Body.String())
+	}
+	w = &testPusher{ResponseRecorder: httptest.NewRecorder()}
+	m.ServeHTTP(w, httptest.NewRequest("POST", "/", nil))
+	if len(w.pushed) != 0 {
+		t.Error(w.pushed)
+	}
+	w = &testPusher{ResponseRecorder: httptest.NewRecorder(), disabled: true}
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if len(w.pushed) != 0 || w.Body.String() != "Hello World" {
+		t.Error(w.pushed, w.Body.String())
+	}
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	if recorder.Body.String() != "Hello World" {
+		t.Error(recorder.Body.String())
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// ErrQUICNil is the error returned by RunQUIC when the QUICServer is not set.
+var ErrQUICNil = errors.New("QUIC server must be not nil")
+
+// altSvcMaxAge is the max age in seconds of the Alt-Svc advertisement.
+const altSvcMaxAge = 86400
+
+// QUICServer serves HTTP/3 over QUIC. The support is experimental.
+//
+// rum owns the UDP conn, the certificates and the handler, and hands them
+// to the QUIC stack, such as the http3.Server of the
+// github.com/quic-go/quic-go/http3 package wrapped as:
+//
+//	type http3Server struct{ http3.Server }
+//
+//	func (s *http3Server) Serve(conn net.PacketConn, config *tls.Config, handler http.Handler) error {
+//		s.TLSConfig = http3.ConfigureTLSConfig(config)
+//		s.Handler = handler
+//		return s.Server.Serve(conn)
+//	}
+//
+//	m.SetQUIC(&http3Server{})
+//	go m.RunQUIC(":443", "server.crt", "server.key")
+//	m.RunTLS(":443", "server.crt", "server.key")
+type QUICServer interface {
+	// Serve serves the handler on the packet conn until the server is closed.
+	Serve(conn net.PacketConn, config *tls.Config, handler http.Handler) error
+	// Close closes the server.
+	Close() error
+}
+
+// SetQUIC sets the QUICServer used by RunQUIC and ServeQUIC.
+func (m *Rum) SetQUIC(server QUICServer) {
+	m.mut.Lock()
+	m.quic = server
+	m.mut.Unlock()
+}
+
+// RunQUIC listens on the UDP network address addr and serves HTTP/3 by the
+// QUICServer with the same handlers. The responses of the TCP listeners
+// advertise the HTTP/3 endpoint by the Alt-Svc header.
+func (m *Rum) RunQUIC(addr string, certFile, keyFile string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return m.ServeQUIC(conn, certFile, keyFile)
+}
+
+// ServeQUIC serves HTTP/3 on the packet conn by the QUICServer.
+func (m *Rum) ServeQUIC(conn net.PacketConn, certFile, keyFile string) error {
+	m.mut.Lock()
+	server := m.quic
+	m.mut.Unlock()
+	if server == nil {
+		return ErrQUICNil
+	}
+	config := m.tlsConfig()
+	config.MinVersion = tls.VersionTLS13
+	config.NextProtos = []string{"h3"}
+	configHasCert := len(config.Certificates) > 0 || m.TLSConfig != nil && m.TLSConfig.GetCertificate != nil || m.hasCerts()
+	if !configHasCert || certFile != "" || keyFile != "" {
+		if err := m.defaultCertFile(config, certFile, keyFile); err != nil {
+			return err
+		}
+	}
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		m.altSvc.Store([]string{`h3=":` + strconv.Itoa(addr.Port) + `"; ma=` + strconv.Itoa(altSvcMaxAge)})
+	}
+	defer m.altSvc.Store([]string(nil))
+	var handler = m.Handler
+	if handler == nil {
+		handler = m
+	}
+	return server.Serve(conn, config, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h := m.hostHandler(r); h != nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if h := m.maintenanceHandler(r); h != nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+}
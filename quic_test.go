@@ -0,0 +1,90 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testQUICServer struct {
+	config  *tls.Config
+	handler http.Handler
+	closed  chan struct{}
+}
+
+func (s *testQUICServer) Serve(conn net.PacketConn, config *tls.Config, handler http.Handler) error {
+	s.config = config
+	s.handler = handler
+	<-s.closed
+	return http.ErrServerClosed
+}
+
+func (s *testQUICServer) Close() error {
+	close(s.closed)
+	return nil
+}
+
+func TestQUIC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	ioutil.WriteFile(certFile, testCertPEM, 0600)
+	ioutil.WriteFile(keyFile, testKeyPEM, 0600)
+	addr := ":8080"
+	m := New()
+	if err := m.RunQUIC(addr, certFile, keyFile); err != ErrQUICNil {
+		t.Error(err)
+	}
+	server := &testQUICServer{closed: make(chan struct{})}
+	m.SetQUIC(server)
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	})
+	quicDone := make(chan error, 1)
+	go func() {
+		quicDone <- m.RunQUIC(addr, certFile, keyFile)
+	}()
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	if server.config.MinVersion != tls.VersionTLS13 || len(server.config.NextProtos) != 1 || server.config.NextProtos[0] != "h3" {
+		t.Error(server.config)
+	}
+	if cert, err := server.config.GetCertificate(&tls.ClientHelloInfo{}); err != nil || cert == nil {
+		t.Error(cert, err)
+	}
+	w := httptest.NewRecorder()
+	server.handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Body.String() != "Hello World" {
+		t.Error(w.Body.String())
+	}
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if altSvc := resp.Header.Get("Alt-Svc"); altSvc != `h3=":8080"; ma=86400` {
+		t.Error(altSvc)
+	}
+	m.Close()
+	<-done
+	if err := <-quicDone; err != http.ErrServerClosed {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"container/list"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxBuckets is the number of keyed buckets above which the least recently
+// used bucket is evicted.
+const maxBuckets = 8192
+
+// ErrRateNotPositive is the error returned by SetRateLimit when the Rate is not positive.
+var ErrRateNotPositive = errors.New("Rate must be positive")
+
+// RateLimit is the limit of a rate-limit class.
+type RateLimit struct {
+	// Rate is the number of requests allowed per second, which refills
+	// the budget. It must be positive.
+	Rate float64
+	// Burst is the maximum number of requests allowed at once.
+	Burst int
+	// Key optionally partitions the budget, for example by client
+	// or API key. If nil, all of the requests of the class share it.
+	Key func(r *http.Request) string
+}
+
+type bucket struct {
+	key     string
+	tokens  float64
+	last    time.Time
+	element *list.Element
+}
+
+type rateLimiter struct {
+	mu    sync.Mutex
+	limit RateLimit
+	// buckets maps the keys to the buckets, in the lru order.
+	buckets map[string]*bucket
+	lru     *list.List
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &rateLimiter{limit: limit, buckets: make(map[string]*bucket), lru: list.New()}
+}
+
+func (l *rateLimiter) allow(r *http.Request, now time.Time) bool {
+	var key string
+	if l.limit.Key != nil {
+		key = l.limit.Key(r)
+	}
+	burst := float64(l.limit.Burst)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if ok {
+		l.lru.MoveToFront(b.element)
+	} else {
+		if l.lru.Len() >= maxBuckets {
+			l.evict()
+		}
+		b = &bucket{key: key, tokens: burst, last: now}
+		b.element = l.lru.PushFront(b)
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*l.limit.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// evict removes the least recently used bucket. It must be called with
+// the lock held.
+func (l *rateLimiter) evict() {
+	if e := l.lru.Back(); e != nil {
+		b := e.Value.(*bucket)
+		l.lru.Remove(e)
+		delete(l.buckets, b.key)
+	}
+}
+
+func (l *rateLimiter) retryAfter() string {
+	return strconv.Itoa(int(math.Ceil(1 / l.limit.Rate)))
+}
+
+// SetRateLimit sets the limit of the rate-limit class. The entries
+// tagged with the class reply 429 Too Many Requests when the budget
+// is exhausted. A zero RateLimit removes the limit of the class. It panics
+// with ErrRateNotPositive if the Rate of another limit is not positive.
+//
+// The limit set on a Group applies to the entries of the Group and of
+// its nested groups, in place of a limit of the same class set on an
+// enclosing Mux.
+func (m *Mux) SetRateLimit(class string, limit RateLimit) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	limiters := make(map[string]*rateLimiter, len(m.limiters)+1)
+	for c, l := range m.limiters {
+		if c != class {
+			limiters[c] = l
+		}
+	}
+	if limit.Rate > 0 || limit.Burst > 0 {
+		if limit.Rate <= 0 {
+			panic(ErrRateNotPositive)
+		}
+		limiters[class] = newRateLimiter(limit)
+	}
+	m.limiters = limiters
+}
+
+// RateLimitClass sets the rate-limit class of the entries registered
+// afterwards to the Mux, typically in a Group.
+func (m *Mux) RateLimitClass(class string) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.class = class
+}
+
+// RateLimit tags the entry with the rate-limit class.
+func (entry *Entry) RateLimit(class string) *Entry {
+	entry.class = class
+	return entry
+}
+
+// limited replies 429 Too Many Requests if the entry's class is limited.
+func (m *Mux) limited(entry *Entry, w http.ResponseWriter, r *http.Request) bool {
+	if entry.class == "" {
+		return false
+	}
+	l := m.limiter(entry)
+	if l == nil || l.allow(r, time.Now()) {
+		return false
+	}
+	w.Header().Set("Retry-After", l.retryAfter())
+	http.Error(w, "429 Too Many Requests", http.StatusTooManyRequests)
+	return true
+}
+
+// limiter returns the limiter of the entry's class, set on the innermost
+// Mux enclosing the entry.
+func (m *Mux) limiter(entry *Entry) *rateLimiter {
+	g := entry.mux
+	if g == nil {
+		g = m
+	}
+	for ; g != nil; g = g.parent {
+		g.mut.RLock()
+		l, ok := g.limiters[entry.class]
+		g.mut.RUnlock()
+		if ok {
+			return l
+		}
+	}
+	return nil
+}
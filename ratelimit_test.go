@@ -0,0 +1,145 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(RateLimit{Rate: 1, Burst: 2, Key: func(r *http.Request) string {
+		return r.Header.Get("X-Key")
+	}})
+	r := httptest.NewRequest("GET", "/", nil)
+	now := time.Now()
+	if !l.allow(r, now) || !l.allow(r, now) || l.allow(r, now) {
+		t.Error()
+	}
+	if !l.allow(r, now.Add(time.Second)) {
+		t.Error()
+	}
+	r.Header.Set("X-Key", "other")
+	if !l.allow(r, now) {
+		t.Error()
+	}
+	for i := 0; i < maxBuckets; i++ {
+		r.Header.Set("X-Key", strconv.Itoa(i))
+		l.allow(r, now)
+		if i == 0 {
+			r.Header.Set("X-Key", "")
+			l.allow(r, now)
+		}
+	}
+	if l.lru.Len() != maxBuckets {
+		t.Error(l.lru.Len())
+	}
+	if _, ok := l.buckets["other"]; ok {
+		t.Error("the least recently used bucket is not evicted")
+	}
+	if b, ok := l.buckets[""]; !ok || b.tokens >= 1 {
+		t.Error("the recently used bucket is reset")
+	}
+	if l.retryAfter() != "1" {
+		t.Error(l.retryAfter())
+	}
+}
+
+func TestRateLimitRate(t *testing.T) {
+	m := NewMux()
+	m.SetRateLimit("api", RateLimit{})
+	defer func() {
+		if e := recover(); e != ErrRateNotPositive {
+			t.Error(e)
+		}
+	}()
+	m.SetRateLimit("api", RateLimit{Burst: 10})
+}
+
+func TestRateLimitClass(t *testing.T) {
+	m := NewMux()
+	m.SetRateLimit("public", RateLimit{Rate: 0.001, Burst: 1})
+	m.HandleFunc("/public", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("public"))
+	}).RateLimit("public")
+	m.HandleFunc("/free", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("free"))
+	})
+	m.Group("/admin", func(m *Mux) {
+		m.RateLimitClass("admin")
+		m.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("stats"))
+		})
+	})
+	m.SetRateLimit("admin", RateLimit{Rate: 0.001, Burst: 2})
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/public", http.StatusOK},
+		{"/public", http.StatusTooManyRequests},
+		{"/free", http.StatusOK},
+		{"/free", http.StatusOK},
+		{"/admin/stats", http.StatusOK},
+		{"/admin/stats", http.StatusOK},
+		{"/admin/stats", http.StatusTooManyRequests},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.status {
+			t.Error(test.path, w.Code)
+		}
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "1000" {
+			t.Error(w.Header().Get("Retry-After"))
+		}
+	}
+	m.SetRateLimit("public", RateLimit{})
+	if _, ok := m.limiters["public"]; ok {
+		t.Error("the limit is not removed")
+	}
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/public", nil))
+	if w.Code != http.StatusOK {
+		t.Error(w.Code)
+	}
+}
+
+func TestRateLimitGroup(t *testing.T) {
+	m := NewMux()
+	m.SetRateLimit("api", RateLimit{Rate: 0.001, Burst: 1})
+	m.HandleFunc("/root", func(w http.ResponseWriter, r *http.Request) {}).RateLimit("api")
+	var group *Mux
+	m.Group("/v1", func(m *Mux) {
+		group = m
+		m.SetRateLimit("api", RateLimit{Rate: 0.001, Burst: 2})
+		m.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {}).RateLimit("api")
+	})
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/root", http.StatusOK},
+		{"/root", http.StatusTooManyRequests},
+		{"/v1/users", http.StatusOK},
+		{"/v1/users", http.StatusOK},
+		{"/v1/users", http.StatusTooManyRequests},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.status {
+			t.Error(test.path, w.Code)
+		}
+	}
+	group.SetRateLimit("api", RateLimit{})
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/v1/users", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Error("the group does not fall back to the limit of the root", w.Code)
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultRecorderMax     = 10
+	defaultRecorderMaxBody = 64 << 10
+	// Redacted replaces the redacted values of the recorded exchanges.
+	Redacted = "[REDACTED]"
+)
+
+// DefaultRedact is the default names of the headers, the query parameters
+// and the JSON fields redacted by a Recorder.
+var DefaultRedact = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+	"X-Api-Key", "password", "token", "secret", "access_token", "refresh_token"}
+
+// Exchange is a recorded request and response pair of a route.
+type Exchange struct {
+	Route          string      `json:"route"`
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	RequestHeader  http.Header `json:"requestHeader,omitempty"`
+	RequestBody    string      `json:"requestBody,omitempty"`
+	Status         int         `json:"status"`
+	ResponseHeader http.Header `json:"responseHeader,omitempty"`
+	ResponseBody   string      `json:"responseBody,omitempty"`
+}
+
+// Recorder records the sampled exchanges of the routes into the files of
+// a directory, one JSON line per exchange, for the golden-file contract
+// tests and the generated examples. See rumtest.Replay.
+type Recorder struct {
+	// Dir is the directory of the files, named after the method and the
+	// route, such as "GET_users_id.jsonl".
+	Dir string
+	// Sample records one of every Sample requests of a route. Zero or one
+	// records every request.
+	Sample int
+	// Max is the maximum number of the exchanges recorded per route. If
+	// zero, 10 is used.
+	Max int
+	// MaxBody is the maximum size of the recorded bodies. The exchanges with
+	// larger bodies are not recorded. If zero, 64 KB is used.
+	MaxBody int
+	// Redact is the names of the headers, the query parameters and the JSON
+	// fields whose values are replaced by Redacted. If nil, DefaultRedact
+	// is used.
+	Redact []string
+	// ErrorLog logs the errors of writing. If nil, the standard logger is used.
+	ErrorLog *log.Logger
+
+	mu     sync.Mutex
+	routes map[string]*recordedRoute
+}
+
+type recordedRoute struct {
+	seen    int
+	written int
+}
+
+// SetRecorder sets the recorder of the exchanges of the routes of the Mux
+// and its groups. A nil Recorder disables the recording.
+func (m *Mux) SetRecorder(r *Recorder) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.recorder = r
+}
+
+// RecordFile returns the name of the file of the exchanges of the method
+// and the route.
+func RecordFile(method, route string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, strings.Trim(route, "/"))
+	if name == "" {
+		name = "_"
+	}
+	return method + "_" + name + ".jsonl"
+}
+
+// ReadExchanges reads the exchanges of a file written by a Recorder.
+func ReadExchanges(r io.Reader) ([]Exchange, error) {
+	var exchanges []Exchange
+	decoder := json.NewDecoder(r)
+	for decoder.More() {
+		var e Exchange
+		if err := decoder.Decode(&e); err != nil {
+			return nil, err
+		}
+		exchanges = append(exchanges, e)
+	}
+	return exchanges, nil
+}
+
+// sample reports whether the request of the route should be recorded.
+func (rec *Recorder) sample(method, route string) bool {
+	max := rec.Max
+	if max <= 0 {
+		max = defaultRecorderMax
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.routes == nil {
+		rec.routes = make(map[string]*recordedRoute)
+	}
+	key := method + " " + route
+	stats, ok := rec.routes[key]
+	if !ok {
+		stats = &recordedRoute{}
+		rec.routes[key] = stats
+	}
+	stats.seen++
+	if stats.written >= max {
+		return false
+	}
+	return rec.Sample <= 1 || (stats.seen-1)%rec.Sample == 0
+}
+
+func (rec *Recorder) maxBody() int {
+	if rec.MaxBody <= 0 {
+		return defaultRecorderMaxBody
+	}
+	return rec.MaxBody
+}
+
+// begin buffers the request body and returns the response writer recording
+// the response, or nil if the request is not sampled.
+func (rec *Recorder) begin(route string, w http.ResponseWriter, r *http.Request) (*accessResponseWriter, []byte) {
+	if !rec.sample(r.Method, route) {
+		return nil, nil
+	}
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		body, err = ioutil.ReadAll(io.LimitReader(r.Body, int64(rec.maxBody())+1))
+		r.Body = &struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		if err != nil || len(body) > rec.maxBody() {
+			return nil, nil
+		}
+	}
+	return &accessResponseWriter{forwarder: forwarder{w}, body: bytes.NewBuffer(nil), limit: rec.maxBody()}, body
+}
+
+// record writes the sanitized exchange of the route.
+func (rec *Recorder) record(route string, r *http.Request, body []byte, w *accessResponseWriter) {
+	if w.size > int64(rec.maxBody()) {
+		return
+	}
+	code := w.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+	redact := rec.Redact
+	if redact == nil {
+		redact = DefaultRedact
+	}
+	e := &Exchange{
+		Route:          route,
+		Method:         r.Method,
+		URL:            redactURL(r, redact),
+		RequestHeader:  redactHeader(r.Header, redact),
+		RequestBody:    redactBody(r.Header, body, redact),
+		Status:         code,
+		ResponseHeader: redactHeader(w.Header(), redact),
+		ResponseBody:   redactBody(w.Header(), w.body.Bytes(), redact),
+	}
+	line, err := json.Marshal(e)
+	if err == nil {
+		err = rec.write(r.Method+" "+route, RecordFile(r.Method, route), append(line, '\n'))
+	}
+	if err != nil {
+		if rec.ErrorLog != nil {
+			rec.ErrorLog.Printf("rum: recorder %s %s: %v", r.Method, route, err)
+		} else {
+			log.Printf("rum: recorder %s %s: %v", r.Method, route, err)
+		}
+	}
+}
+
+// write appends the line to the file of the route key.
+func (rec *Recorder) write(key, name string, line []byte) error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if err := os.MkdirAll(rec.Dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(rec.Dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(line)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		rec.routes[key].written++
+	}
+	return err
+}
+
+func redacted(name string, redact []string) bool {
+	for _, r := range redact {
+		if strings.EqualFold(name, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactHeader(header http.Header, redact []string) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	h := make(http.Header, len(header))
+	for key, values := range header {
+		if redacted(key, redact) {
+			h[key] = []string{Redacted}
+		} else {
+			h[key] = append([]string(nil), values...)
+		}
+	}
+	return h
+}
+
+func redactURL(r *http.Request, redact []string) string {
+	u := *r.URL
+	query := u.Query()
+	changed := false
+	for key := range query {
+		if redacted(key, redact) {
+			query[key] = []string{Redacted}
+			changed = true
+		}
+	}
+	if changed {
+		u.RawQuery = query.Encode()
+	}
+	return u.RequestURI()
+}
+
+// redactBody redacts the fields of a JSON body.
+func redactBody(header http.Header, body []byte, redact []string) string {
+	if len(body) == 0 || !strings.Contains(header.Get("Content-Type"), "json") {
+		return string(body)
+	}
+	var v interface{}
+	if json.Unmarshal(body, &v) != nil {
+		return string(body)
+	}
+	if !redactJSON(v, redact) {
+		return string(body)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func redactJSON(v interface{}, redact []string) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if redacted(key, redact) {
+				v[key] = Redacted
+				changed = true
+			} else if redactJSON(value, redact) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if redactJSON(value, redact) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	m := NewMux()
+	m.SetRecorder(&Recorder{Dir: dir, Sample: 2, Max: 2, MaxBody: 64})
+	m.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=1")
+		w.Write([]byte(`{"id":"` + m.Params(r)["id"] + `","token":"abc"}`))
+	}).GET()
+	m.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}).POST()
+	for i := 0; i < 6; i++ {
+		r := httptest.NewRequest("GET", "/users/8?access_token=x&q=1", nil)
+		r.Header.Set("Authorization", "Bearer x")
+		m.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	login := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/login", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		return w
+	}
+	large := `{"user":"` + strings.Repeat("a", 64) + `"}`
+	if w := login(large); w.Body.String() != large {
+		t.Error(w.Body.String())
+	}
+	login(`{}`)
+	login(`{"user":"admin","password":"secret"}`)
+
+	f, err := os.Open(filepath.Join(dir, RecordFile("GET", "/users/:id")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exchanges, err := ReadExchanges(f)
+	f.Close()
+	if err != nil || len(exchanges) != 2 {
+		t.Fatal(err, len(exchanges))
+	}
+	e := exchanges[0]
+	if e.Route != "/users/:id" || e.Method != "GET" || e.Status != http.StatusOK ||
+		e.URL != "/users/8?access_token=%5BREDACTED%5D&q=1" ||
+		e.RequestHeader.Get("Authorization") != Redacted || e.ResponseHeader.Get("Set-Cookie") != Redacted ||
+		e.ResponseBody != `{"id":"8","token":"[REDACTED]"}` {
+		t.Error(e)
+	}
+	b, _ := ioutil.ReadFile(filepath.Join(dir, RecordFile("POST", "/login")))
+	exchanges, _ = ReadExchanges(bytes.NewReader(b))
+	if len(exchanges) != 1 || exchanges[0].Status != http.StatusCreated ||
+		exchanges[0].RequestBody != `{"password":"[REDACTED]","user":"admin"}` {
+		t.Error(string(b))
+	}
+	if RecordFile("GET", "/") != "GET__.jsonl" || RecordFile("GET", "/a/*") != "GET_a__.jsonl" {
+		t.Error(RecordFile("GET", "/"), RecordFile("GET", "/a/*"))
+	}
+	if _, err := ReadExchanges(strings.NewReader("{")); err == nil {
+		t.Error()
+	}
+}
+
+func TestRecorderError(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	file, err := ioutil.TempFile("", "rum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+	m := NewMux()
+	m.SetRecorder(&Recorder{Dir: file.Name(), ErrorLog: log.New(buf, "", 0)})
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {}).GET()
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if !strings.HasPrefix(buf.String(), "rum: recorder GET /: ") {
+		t.Error(buf.String())
+	}
+	m.SetRecorder(nil)
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net"
+	"net/http"
+)
+
+// RedirectHTTPS is a handler function that replies to the request with
+// a 301 redirect to the same host and URI over HTTPS.
+func RedirectHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "" {
+		http.Error(w, "400 Bad Request : missing Host", http.StatusBadRequest)
+		return
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		host = "[" + host + "]"
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+}
+
+// RedirectHTTP listens on the TCP network address addr and redirects
+// all requests to HTTPS with RedirectHTTPS. The listener is closed
+// when the Rum is closed.
+//
+// RedirectHTTP always returns a non-nil error.
+func (m *Rum) RedirectHTTP(addr string) error {
+	redirect := New()
+	redirect.Handler = http.HandlerFunc(RedirectHTTPS)
+	m.mut.Lock()
+	m.servers = append(m.servers, redirect)
+	m.mut.Unlock()
+	return redirect.Run(addr)
+}
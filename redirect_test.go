@@ -0,0 +1,69 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRedirectHTTPS(t *testing.T) {
+	tests := []struct {
+		host     string
+		uri      string
+		location string
+	}{
+		{"example.com", "/a?b=c", "https://example.com/a?b=c"},
+		{"example.com:80", "/", "https://example.com/"},
+		{"[::1]:80", "/", "https://[::1]/"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", test.uri, nil)
+		r.Host = test.host
+		w := httptest.NewRecorder()
+		RedirectHTTPS(w, r)
+		if w.Code != http.StatusMovedPermanently {
+			t.Error(w.Code)
+		}
+		if location := w.Header().Get("Location"); location != test.location {
+			t.Error(location)
+		}
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Host = ""
+	w := httptest.NewRecorder()
+	RedirectHTTPS(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Error(w.Code)
+	}
+}
+
+func TestRedirectHTTP(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	done := make(chan struct{})
+	go func() {
+		m.RedirectHTTP(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	client := &http.Client{
+		Transport: &http.Transport{DisableKeepAlives: true},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get("http://localhost" + addr + "/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if location := resp.Header.Get("Location"); location != "https://localhost/foo" {
+		t.Error(location)
+	}
+	m.Close()
+	<-done
+}
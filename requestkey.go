@@ -0,0 +1,111 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// RequestKeyContextKey is a context key.
+var RequestKeyContextKey = &contextKey{"request-key"}
+
+// DefaultRequestKeyAttributes is the default attributes of a RequestHash.
+var DefaultRequestKeyAttributes = []string{"method", "host", "path", "query"}
+
+// RequestHash computes a stable key of the requests from their attributes,
+// to partition the caches or to shard the requests. The key is stored in
+// the request context by the Handler, so that the caches and the
+// ConsistentHash balancer of a Proxy can share it:
+//
+//	h := &rum.RequestHash{Attributes: []string{"path", "header:X-Tenant"}, Header: "X-Request-Key"}
+//	p.Balancer = rum.ConsistentHash(rum.RequestKey, nil)
+//	m.Handler = h.Handler(m)
+type RequestHash struct {
+	// Attributes holds the attributes of the key: "method", "host",
+	// "path", "query" for the whole query, "query:name", "header:Name"
+	// and "cookie:name". If empty, DefaultRequestKeyAttributes is used.
+	Attributes []string
+	// Header is the name of the request and the response header set to
+	// the key. If empty, the key is not exposed by a header.
+	Header string
+}
+
+// Key returns the key of the request as 16 hexadecimal digits.
+func (k *RequestHash) Key(r *http.Request) string {
+	attributes := k.Attributes
+	if len(attributes) == 0 {
+		attributes = DefaultRequestKeyAttributes
+	}
+	h := fnv.New64a()
+	var query map[string][]string
+	for _, attribute := range attributes {
+		var value string
+		name := attribute
+		if i := strings.IndexByte(attribute, ':'); i >= 0 {
+			name = attribute[i+1:]
+			attribute = attribute[:i]
+		}
+		switch attribute {
+		case "method":
+			value = r.Method
+		case "host":
+			value = strings.ToLower(r.Host)
+		case "path":
+			value = r.URL.Path
+		case "query":
+			if name == attribute {
+				value = r.URL.RawQuery
+				break
+			}
+			if query == nil {
+				query = r.URL.Query()
+			}
+			value = strings.Join(query[name], "\x00")
+		case "header":
+			value = strings.Join(r.Header.Values(name), "\x00")
+		case "cookie":
+			if c, err := r.Cookie(name); err == nil {
+				value = c.Value
+			}
+		}
+		h.Write([]byte(attribute))
+		h.Write([]byte{0})
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(value))
+		h.Write([]byte{0})
+	}
+	key := strconv.FormatUint(mix64(h.Sum64()), 16)
+	if len(key) < 16 {
+		key = strings.Repeat("0", 16-len(key)) + key
+	}
+	return key
+}
+
+// Handler returns a handler that computes the key of the request, stores
+// it in the request context and sets the Header before calling the handler.
+func (k *RequestHash) Handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := k.Key(r)
+		if k.Header != "" {
+			r.Header.Set(k.Header, key)
+			w.Header().Set(k.Header, key)
+		}
+		ctx := context.WithValue(r.Context(), RequestKeyContextKey, key)
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestKey returns the key stored by a RequestHash in the request
+// context, or an empty string.
+func RequestKey(r *http.Request) string {
+	if key, ok := r.Context().Value(RequestKeyContextKey).(string); ok {
+		return key
+	}
+	return ""
+}
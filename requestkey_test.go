@@ -0,0 +1,71 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestHash(t *testing.T) {
+	h := &RequestHash{Attributes: []string{"path", "query:id", "header:X-Tenant", "cookie:lang"}}
+	key := func(target, tenant, lang string) string {
+		r := httptest.NewRequest("GET", target, nil)
+		if tenant != "" {
+			r.Header.Set("X-Tenant", tenant)
+		}
+		if lang != "" {
+			r.AddCookie(&http.Cookie{Name: "lang", Value: lang})
+		}
+		return h.Key(r)
+	}
+	k := key("/a?id=1&x=2", "t", "en")
+	if len(k) != 16 {
+		t.Error(k)
+	}
+	if key("/a?x=3&id=1", "t", "en") != k {
+		t.Error("other query parameters should be ignored")
+	}
+	for _, other := range []string{key("/b?id=1", "t", "en"), key("/a?id=2", "t", "en"), key("/a?id=1", "u", "en"), key("/a?id=1", "t", "fr")} {
+		if other == k {
+			t.Error("should differ")
+		}
+	}
+	d := &RequestHash{}
+	if d.Key(httptest.NewRequest("GET", "/a?b=1", nil)) == d.Key(httptest.NewRequest("HEAD", "/a?b=1", nil)) {
+		t.Error("the method should be a default attribute")
+	}
+}
+
+func TestRequestHashHandler(t *testing.T) {
+	h := &RequestHash{Header: "X-Request-Key"}
+	var got, header string
+	handler := h.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, header = RequestKey(r), r.Header.Get("X-Request-Key")
+	}))
+	r := httptest.NewRequest("GET", "/a", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if got == "" || got != header || w.Header().Get("X-Request-Key") != got {
+		t.Error(got, header, w.Header())
+	}
+	if RequestKey(r) != "" {
+		t.Error("should be empty without the handler")
+	}
+	p, _ := NewProxy("http://127.0.0.1:9001", "http://127.0.0.1:9002")
+	b := ConsistentHash(RequestKey, nil)
+	var first *Upstream
+	handler = h.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u := b.Pick(r, p.Upstreams())
+		if first == nil {
+			first = u
+		} else if u != first {
+			t.Error("should pick the same upstream")
+		}
+	}))
+	for i := 0; i < 4; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/a", nil))
+	}
+}
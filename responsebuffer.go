@@ -0,0 +1,158 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// DefaultResponseBuffer is the default size in bytes of the response buffer.
+const DefaultResponseBuffer = 2048
+
+// SetResponseBuffer sets the size in bytes of the response buffer. A
+// response whose body fits in the buffer is replied with a Content-Length
+// header, a larger one is streamed with the chunked transfer encoding once
+// the buffer is full. Zero uses DefaultResponseBuffer, a negative size
+// streams every response body. See Entry.ResponseBuffer for the routes.
+func (m *Rum) SetResponseBuffer(size int) {
+	m.responseBuffer = size
+}
+
+func (m *Rum) responseBufferSize() int {
+	if m.responseBuffer == 0 {
+		return DefaultResponseBuffer
+	} else if m.responseBuffer < 0 {
+		return 0
+	}
+	return m.responseBuffer
+}
+
+// ResponseBuffer sets the size in bytes of the response buffer of the
+// entry, overriding the one of the Server, such as a large buffer for the
+// routes whose clients need a Content-Length, or a negative size for the
+// streaming routes. A response whose body fits in the buffer is replied with
+// a Content-Length header, a larger one is streamed with the chunked
+// transfer encoding. Zero uses the buffer of the Server. It should be
+// called before serving.
+func (entry *Entry) ResponseBuffer(size int) *Entry {
+	entry.responseBuffer = size
+	return entry
+}
+
+// bufferResponseWriter buffers the response body up to the size, to reply
+// with a Content-Length, and streams it beyond.
+type bufferResponseWriter struct {
+	forwarder
+	size      int
+	code      int
+	buf       bytes.Buffer
+	streaming bool
+}
+
+func newBufferResponseWriter(w http.ResponseWriter, size int) *bufferResponseWriter {
+	if size < 0 {
+		size = 0
+	}
+	return &bufferResponseWriter{forwarder: forwarder{w}, size: size}
+}
+
+func (w *bufferResponseWriter) WriteHeader(code int) {
+	if w.streaming || informational(code) {
+		w.ResponseWriter.WriteHeader(code)
+	} else if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *bufferResponseWriter) Write(p []byte) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.Write(p)
+	}
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if w.buf.Len()+len(p) <= w.size {
+		return w.buf.Write(p)
+	}
+	if err := w.stream(); err != nil {
+		return 0, err
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// stream writes the header with the chunked transfer encoding, unless the
+// handler sets the Content-Length, and the buffered body.
+func (w *bufferResponseWriter) stream() error {
+	w.streaming = true
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if w.Header().Get("Content-Length") == "" && bodyAllowedForStatus(w.code) {
+		w.Header().Set("Transfer-Encoding", "chunked")
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+	if w.buf.Len() > 0 {
+		_, err := w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+		return err
+	}
+	return nil
+}
+
+// finish replies the buffered response with a Content-Length.
+func (w *bufferResponseWriter) finish() {
+	if w.streaming || w.code == 0 {
+		return
+	}
+	w.streaming = true
+	if w.Header().Get("Content-Length") == "" && bodyAllowedForStatus(w.code) {
+		w.Header().Set("Content-Length", strconv.Itoa(w.buf.Len()))
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+	}
+}
+
+// Flush implements the http.Flusher interface, streaming the response.
+func (w *bufferResponseWriter) Flush() {
+	w.FlushError()
+}
+
+// FlushError implements the error-returning Flush.
+func (w *bufferResponseWriter) FlushError() error {
+	if !w.streaming {
+		if err := w.stream(); err != nil {
+			return err
+		}
+	}
+	return Flush(w.ResponseWriter)
+}
+
+// Hijack implements the http.Hijacker interface.
+func (w *bufferResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.streaming = true
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// bodyAllowedForStatus reports whether a given response status code
+// permits a body.
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent:
+		return false
+	case status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestResponseBuffer(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	if size := m.responseBufferSize(); size != DefaultResponseBuffer {
+		t.Error(size)
+	}
+	m.SetResponseBuffer(4)
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	}).GET()
+	m.HandleFunc("/buffered", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello"))
+		w.Write([]byte(" "))
+		w.Write([]byte("World"))
+	}).GET().ResponseBuffer(64)
+	m.HandleFunc("/streamed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hi"))
+	}).GET().ResponseBuffer(-1)
+	m.HandleFunc("/created", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("Hello World"))
+	}).POST().ResponseBuffer(64)
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	tests := []struct {
+		method  string
+		path    string
+		status  int
+		body    string
+		chunked bool
+	}{
+		{"GET", "/", http.StatusOK, "Hello World", true},
+		{"GET", "/buffered", http.StatusOK, "Hello World", false},
+		{"GET", "/streamed", http.StatusOK, "Hi", true},
+		{"POST", "/created", http.StatusCreated, "Hello World", false},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, "http://"+addr+test.path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != test.status || string(body) != test.body {
+			t.Error(test.path, resp.StatusCode, string(body))
+		}
+		chunked := len(resp.TransferEncoding) > 0 && resp.TransferEncoding[0] == "chunked"
+		if chunked != test.chunked {
+			t.Error(test.path, resp.TransferEncoding)
+		}
+		if !chunked && resp.ContentLength != int64(len(test.body)) {
+			t.Error(test.path, resp.ContentLength)
+		}
+	}
+	m.Close()
+	<-done
+}
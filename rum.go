@@ -6,13 +6,17 @@ package rum
 
 import (
 	"bufio"
+	"context"
+	"crypto/cipher"
 	"crypto/tls"
 	"github.com/hslam/netpoll"
 	"github.com/hslam/request"
-	"github.com/hslam/response"
+	"log"
 	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // DefaultServer is the default HTTP server.
@@ -20,21 +24,110 @@ var DefaultServer = New()
 
 // Rum is an HTTP server.
 type Rum struct {
+	conns       int64
+	active      int64
+	tasks       int64
+	shedded     uint64
+	writeErrors uint64
+	requests    uint64
+	badRequests uint64
+	slow        uint64
+	writeStall  int64
+	bodyBytes   int64
+	draining    int32
 	*Mux
 	Handler http.Handler
 	// TLSConfig optionally provides a TLS configuration for use
-	// by ServeTLS and ListenAndServeTLS. Note that this value is
+	// by Serve, ServeTLS and ListenAndServeTLS. If nil, the
+	// IntermediateTLSConfig is used. Note that this value is
 	// cloned by ServeTLS and ListenAndServeTLS, so it's not
 	// possible to modify the configuration with methods like
-	// tls.Config.SetSessionTicketKeys. To use
-	// SetSessionTicketKeys, use Server.Serve with a TLS Listener
-	// instead.
+	// tls.Config.SetSessionTicketKeys. Use Rum.SetSessionTicketKeys
+	// or Rum.SetSessionTicketRotation instead.
 	TLSConfig *tls.Config
 	fast      bool
 	poll      bool
 	mut       sync.Mutex
 	listeners []net.Listener
 	pollers   []*netpoll.Server
+	servers   []*Rum
+	autoCert  AutoCertManager
+
+	autoTLSAddr       string
+	autoChallengeAddr string
+
+	certs     atomic.Value
+	quic      QUICServer
+	altSvc    atomic.Value
+	upgraders atomic.Value
+
+	tlsConfigs       []*tls.Config
+	ticketKeys       [][32]byte
+	rotationInterval time.Duration
+	rotation         chan struct{}
+
+	defaultCerts        []*certEntry
+	certRefreshInterval time.Duration
+	certRefresh         chan struct{}
+	certRefreshing      sync.Mutex
+	ocsp                OCSPResponder
+
+	cookieKeys  [][]byte
+	cookieAEADs []cipher.AEAD
+
+	serverName []string
+	noDate     bool
+	noSniff    bool
+	sniffer    func(data []byte) string
+
+	adaptiveBuffers bool
+	shards          int
+	shardAffinity   bool
+
+	maxBodySize    int64
+	bodyBudget     int64
+	responseBuffer int
+	bodyTooLarge   ErrorHandler
+
+	maxHeaders       int
+	maxHeaderLength  int
+	duplicateHeaders DuplicateHeaders
+
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	errorLog     *log.Logger
+
+	maxAcceptErrors int
+	fdLimit         int64
+	fdWatermark     float64
+	shedReply       bool
+	slowClients     SlowClients
+	accessLog       *AccessLog
+
+	debug       *debugger
+	maintenance atomic.Value
+
+	allowedHosts atomic.Value
+
+	allocSampling chan struct{}
+	allocStats    atomic.Value
+	leakSampling  chan struct{}
+	leakStats     atomic.Value
+
+	taskGroup        sync.WaitGroup
+	taskMu           sync.Mutex
+	tasksClosed      bool
+	backgroundOnce   sync.Once
+	background       context.Context
+	cancelBackground context.CancelFunc
+
+	startHooks []func() error
+	stopHooks  []func(ctx context.Context) error
+	startOnce  sync.Once
+	startErr   error
+	stopOnce   sync.Once
+
+	inflight *inflight
 }
 
 // New returns a new Rum instance.
@@ -58,6 +151,9 @@ func (m *Rum) SetPoll(poll bool) {
 //
 // Run always returns a non-nil error.
 func (m *Rum) Run(addr string) error {
+	if m.poll && m.shards > 1 {
+		return m.runShards(addr)
+	}
 	ln, err := net.Listen("tcp", addr)
 	if err != nil {
 		return err
@@ -71,6 +167,7 @@ func (m *Rum) RunTLS(addr string, certFile, keyFile string) error {
 	if err != nil {
 		return err
 	}
+	defer ln.Close()
 	return m.ServeTLS(ln, certFile, keyFile)
 }
 
@@ -79,7 +176,11 @@ func (m *Rum) RunTLS(addr string, certFile, keyFile string) error {
 // that will trigger the fd to read requests and then call handler
 // to reply to them.
 func (m *Rum) Serve(l net.Listener) error {
-	return m.serve(l, m.TLSConfig)
+	var config *tls.Config
+	if m.TLSConfig != nil {
+		config = m.tlsConfig()
+	}
+	return m.serve(l, config, 0)
 }
 
 // ServeTLS accepts incoming connections on the Listener l, creating a
@@ -88,7 +189,8 @@ func (m *Rum) Serve(l net.Listener) error {
 //
 // Files containing a certificate and matching private key for the
 // server must be provided if neither the Server's
-// TLSConfig.Certificates nor TLSConfig.GetCertificate are populated.
+// TLSConfig.Certificates nor TLSConfig.GetCertificate are populated,
+// and no certificate has been added by AddCert.
 // If the certificate is signed by a certificate authority, the
 // certFile should be the concatenation of the server's certificate,
 // any intermediates, and the CA's certificate.
@@ -96,26 +198,26 @@ func (m *Rum) Serve(l net.Listener) error {
 // ServeTLS always returns a non-nil error. After Shutdown or Close, the
 // returned error is ErrServerClosed.
 func (m *Rum) ServeTLS(l net.Listener, certFile, keyFile string) error {
-	config := m.TLSConfig
-	if config == nil {
-		config = &tls.Config{}
-	}
-	if !strSliceContains(config.NextProtos, "http/1.1") {
-		config.NextProtos = append(config.NextProtos, "http/1.1")
-	}
-	configHasCert := len(config.Certificates) > 0 || config.GetCertificate != nil
+	config := m.tlsConfig()
+	configHasCert := len(config.Certificates) > 0 || m.TLSConfig != nil && m.TLSConfig.GetCertificate != nil || m.hasCerts()
 	if !configHasCert || certFile != "" || keyFile != "" {
-		var err error
-		config.Certificates = make([]tls.Certificate, 1)
-		config.Certificates[0], err = tls.LoadX509KeyPair(certFile, keyFile)
-		if err != nil {
+		if err := m.defaultCertFile(config, certFile, keyFile); err != nil {
 			return err
 		}
 	}
-	return m.serve(l, config)
+	return m.serve(l, config, 0)
 }
 
-func (m *Rum) serve(l net.Listener, config *tls.Config) error {
+// serve serves the listener. The workers sets the number of the event
+// loops of the poll mode, or the default if zero.
+func (m *Rum) serve(l net.Listener, config *tls.Config, workers int) error {
+	if err := m.start(); err != nil {
+		l.Close()
+		return err
+	}
+	if config != nil {
+		m.trackTLSConfig(config)
+	}
 	if m.poll {
 		var handler = m.Handler
 		if handler == nil {
@@ -123,23 +225,32 @@ func (m *Rum) serve(l net.Listener, config *tls.Config) error {
 		}
 		var h = &netpoll.ConnHandler{}
 		type Context struct {
-			reader  *bufio.Reader
-			rw      *bufio.ReadWriter
-			conn    net.Conn
-			serving sync.Mutex
+			rw       *bufio.ReadWriter
+			conn     net.Conn
+			buffers  *connBuffers
+			idle     *idleCloser
+			serving  sync.Mutex
+			hijacked bool
 		}
 		h.SetUpgrade(func(conn net.Conn) (netpoll.Context, error) {
+			if !m.admit(conn) {
+				return nil, ErrOverloaded
+			}
+			// The deadlines are only set once the conn is readable, so the
+			// idle keep-alive conns are closed by a timer instead.
+			idle := newIdleCloser(m.readTimeout, conn)
 			if config != nil {
 				tlsConn := tls.Server(conn, config)
 				if err := tlsConn.Handshake(); err != nil {
+					idle.stop()
+					m.release()
 					conn.Close()
 					return nil, err
 				}
 				conn = tlsConn
 			}
-			reader := bufio.NewReader(conn)
-			rw := bufio.NewReadWriter(reader, bufio.NewWriter(conn))
-			return &Context{reader: reader, conn: conn, rw: rw}, nil
+			conn, rw, buffers := m.newConn(conn)
+			return &Context{conn: conn, rw: rw, buffers: buffers, idle: idle}, nil
 		})
 		if m.fast {
 			h.SetServe(func(context netpoll.Context) error {
@@ -147,17 +258,37 @@ func (m *Rum) serve(l net.Listener, config *tls.Config) error {
 				var err error
 				var req *http.Request
 				ctx.serving.Lock()
-				req, err = request.ReadFastRequest(ctx.reader)
+				if ctx.hijacked {
+					// The events of a hijacked conn are served by its hijacker.
+					ctx.serving.Unlock()
+					return nil
+				}
+				ctx.idle.stop()
+				deadline := m.setReadDeadline(ctx.conn)
+				start := m.debugStart(nil)
+				var fast bool
+				req, fast, err = m.readFastRequest(ctx.rw.Reader, handler)
 				if err != nil {
+					m.badRequest(ctx.conn, err)
+					if ctx.buffers != nil {
+						ctx.buffers.free()
+					}
 					ctx.serving.Unlock()
+					m.release()
 					return err
 				}
-				res := response.NewResponse(req, ctx.conn, ctx.rw)
-				handler.ServeHTTP(res, req)
-				res.FinishRequest()
+				hijacked := m.serveRequest(handler, req, ctx.conn, ctx.rw, start, deadline)
+				if ctx.buffers != nil {
+					ctx.buffers.adapt(hijacked)
+				}
+				if !hijacked {
+					ctx.idle.touch()
+				}
+				ctx.hijacked = hijacked
 				ctx.serving.Unlock()
-				request.FreeRequest(req)
-				response.FreeResponse(res)
+				if fast && !hijacked {
+					request.FreeRequest(req)
+				}
 				return nil
 			})
 		} else {
@@ -166,23 +297,44 @@ func (m *Rum) serve(l net.Listener, config *tls.Config) error {
 				var err error
 				var req *http.Request
 				ctx.serving.Lock()
-				req, err = http.ReadRequest(ctx.reader)
+				if ctx.hijacked {
+					ctx.serving.Unlock()
+					return nil
+				}
+				ctx.idle.stop()
+				deadline := m.setReadDeadline(ctx.conn)
+				start := m.debugStart(nil)
+				req, err = m.readRequest(ctx.rw.Reader)
 				if err != nil {
+					m.badRequest(ctx.conn, err)
+					if ctx.buffers != nil {
+						ctx.buffers.free()
+					}
 					ctx.serving.Unlock()
+					m.release()
 					return err
 				}
-				res := response.NewResponse(req, ctx.conn, ctx.rw)
-				handler.ServeHTTP(res, req)
-				res.FinishRequest()
+				hijacked := m.serveRequest(handler, req, ctx.conn, ctx.rw, start, deadline)
+				if ctx.buffers != nil {
+					ctx.buffers.adapt(hijacked)
+				}
+				if !hijacked {
+					ctx.idle.touch()
+				}
+				ctx.hijacked = hijacked
 				ctx.serving.Unlock()
-				response.FreeResponse(res)
 				return nil
 			})
 		}
 		poller := &netpoll.Server{
 			Handler: h,
 		}
+		if workers > 0 {
+			poller.UnsharedWorkers = -1
+			poller.SharedWorkers = workers
+		}
 		m.mut.Lock()
+		m.listeners = append(m.listeners, l)
 		m.pollers = append(m.pollers, poller)
 		m.mut.Unlock()
 		return poller.Serve(l)
@@ -195,7 +347,7 @@ func (m *Rum) serve(l net.Listener, config *tls.Config) error {
 	m.mut.Unlock()
 	if m.fast {
 		for {
-			conn, err := l.Accept()
+			conn, err := m.accept(l)
 			if err != nil {
 				return err
 			}
@@ -203,7 +355,7 @@ func (m *Rum) serve(l net.Listener, config *tls.Config) error {
 		}
 	} else {
 		for {
-			conn, err := l.Accept()
+			conn, err := m.accept(l)
 			if err != nil {
 				return err
 			}
@@ -224,14 +376,41 @@ func (m *Rum) Close() error {
 		poller.Close()
 	}
 	m.pollers = []*netpoll.Server{}
+	for _, server := range m.servers {
+		server.Close()
+	}
+	m.servers = []*Rum{}
+	if m.quic != nil {
+		m.quic.Close()
+	}
+	m.tlsConfigs = nil
+	if m.rotation != nil {
+		close(m.rotation)
+		m.rotation = nil
+	}
+	if m.certRefresh != nil {
+		close(m.certRefresh)
+		m.certRefresh = nil
+	}
+	if m.allocSampling != nil {
+		close(m.allocSampling)
+		m.allocSampling = nil
+	}
+	if m.leakSampling != nil {
+		close(m.leakSampling)
+		m.leakSampling = nil
+	}
 	m.Handler = nil
 	return nil
 }
 
 func (m *Rum) serveConn(conn net.Conn) {
+	defer m.release()
 	defer conn.Close()
-	reader := bufio.NewReader(conn)
-	rw := bufio.NewReadWriter(reader, bufio.NewWriter(conn))
+	conn, rw, buffers := m.newConn(conn)
+	if buffers != nil {
+		defer buffers.free()
+	}
 	var err error
 	var req *http.Request
 	var handler = m.Handler
@@ -239,21 +418,27 @@ func (m *Rum) serveConn(conn net.Conn) {
 		handler = m
 	}
 	for {
-		req, err = http.ReadRequest(reader)
+		deadline := m.setReadDeadline(conn)
+		start := m.debugStart(rw.Reader)
+		req, err = m.readRequest(rw.Reader)
 		if err != nil {
+			m.badRequest(conn, err)
 			break
 		}
-		res := response.NewResponse(req, conn, rw)
-		handler.ServeHTTP(res, req)
-		res.FinishRequest()
-		response.FreeResponse(res)
+		hijacked := m.serveRequest(handler, req, conn, rw, start, deadline)
+		if buffers != nil {
+			buffers.adapt(hijacked)
+		}
 	}
 }
 
 func (m *Rum) serveFastConn(conn net.Conn) {
+	defer m.release()
 	defer conn.Close()
-	reader := bufio.NewReader(conn)
-	rw := bufio.NewReadWriter(reader, bufio.NewWriter(conn))
+	conn, rw, buffers := m.newConn(conn)
+	if buffers != nil {
+		defer buffers.free()
+	}
 	var err error
 	var req *http.Request
 	var handler = m.Handler
@@ -261,16 +446,112 @@ func (m *Rum) serveFastConn(conn net.Conn) {
 		handler = m
 	}
 	for {
-		req, err = request.ReadFastRequest(reader)
+		deadline := m.setReadDeadline(conn)
+		start := m.debugStart(rw.Reader)
+		var fast bool
+		req, fast, err = m.readFastRequest(rw.Reader, handler)
 		if err != nil {
+			m.badRequest(conn, err)
 			break
 		}
-		res := response.NewResponse(req, conn, rw)
-		handler.ServeHTTP(res, req)
-		res.FinishRequest()
-		request.FreeRequest(req)
-		response.FreeResponse(res)
+		hijacked := m.serveRequest(handler, req, conn, rw, start, deadline)
+		if fast {
+			request.FreeRequest(req)
+		}
+		if buffers != nil {
+			buffers.adapt(hijacked)
+		}
+	}
+}
+
+// serveRequest replies to the request with the handler.
+func (m *Rum) serveRequest(handler http.Handler, req *http.Request, conn net.Conn, rw *bufio.ReadWriter, start, deadline time.Time) (hijacked bool) {
+	atomic.AddUint64(&m.requests, 1)
+	atomic.AddInt64(&m.active, 1)
+	defer atomic.AddInt64(&m.active, -1)
+	if t := m.inflight; t != nil {
+		defer t.done(t.add(m.Mux, req, conn))
+	}
+	if h := m.hostHandler(req); h != nil {
+		handler = h
+	} else if h := m.maintenanceHandler(req); h != nil {
+		handler = h
+	} else if upgrader, status := m.upgrader(req); upgrader != nil {
+		m.serveUpgrade(upgrader, status, req, conn, rw)
+		return true
+	}
+	if deadline = m.requestDeadline(deadline); !deadline.IsZero() {
+		ctx, cancel := context.WithDeadline(req.Context(), deadline)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+	res := newWireResponse(req, conn, rw, m.responseBufferSize())
+	m.setHeader(res)
+	hw := &hintsResponseWriter{forwarder: forwarder{res}, req: req, rw: rw}
+	w := expose(hw, res)
+	wc, tracked := conn.(*writeConn)
+	if tracked {
+		wc.begin(deadline)
+		w = expose(&flushResponseWriter{forwarder: forwarder{w}, conn: wc, flusher: w}, w)
+	}
+	body, lw := m.limitBody(w, req)
+	if lw != nil {
+		w = expose(lw, w)
+	}
+	budget := m.budgetBody(req, body)
+	if budget != nil {
+		defer budget.release()
+	}
+	var sw *sniffResponseWriter
+	if m.sniffer != nil {
+		sw = &sniffResponseWriter{forwarder: forwarder{w}, sniffer: m.sniffer}
+		w = expose(sw, w)
+	}
+	if m.accessLog != nil {
+		handler = m.accessLog.Handler(handler)
+	}
+	var t *debugTrace
+	if m.debug != nil && !start.IsZero() {
+		t = m.debug.trace(m, handler, req, start)
+	}
+	if body != nil && body.exceeded {
+		m.replyBodyTooLarge(w, req, body)
+	} else if budget != nil && budget.exceeded && budget.ReadCloser == nil {
+		replyBodyBudget(w)
+	} else if t != nil {
+		t.serve(handler, w)
+	} else {
+		handler.ServeHTTP(w, req)
+	}
+	usable := (body == nil || m.finishBody(w, req, body, lw)) && (budget == nil || !budget.exceeded)
+	if !usable {
+		req.Body = http.NoBody
+	}
+	draining := m.Draining()
+	if !usable || draining {
+		res.Header().Set("Connection", "close")
+	}
+	if sw != nil {
+		sw.finish()
+	}
+	res.finish()
+	if body != nil {
+		req.Body = body.ReadCloser
+	} else if budget != nil && budget.ReadCloser != nil {
+		req.Body = budget.ReadCloser
+	}
+	if !usable || draining || res.closing {
+		conn.Close()
+	}
+	if tracked {
+		m.slowClient(wc, req)
+		m.writeError(wc, req)
+	}
+	if t != nil {
+		t.dump()
 	}
+	freeWireResponse(res)
+	return hw.hijacked
 }
 
 // ListenAndServe listens on the TCP network address addr and then calls
@@ -0,0 +1,76 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rumtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// AssertStatus reports an error if the status code of the response is not the code.
+func AssertStatus(t testing.TB, res *http.Response, code int) {
+	t.Helper()
+	if res.StatusCode != code {
+		t.Errorf("status code %d, expected %d", res.StatusCode, code)
+	}
+}
+
+// AssertHeader reports an error if the header value of the response is not the value.
+func AssertHeader(t testing.TB, res *http.Response, key, value string) {
+	t.Helper()
+	if v := res.Header.Get(key); v != value {
+		t.Errorf("header %s %q, expected %q", key, v, value)
+	}
+}
+
+// AssertBody reports an error if the body of the response is not the body.
+// The body of the response can be read again afterwards.
+func AssertBody(t testing.TB, res *http.Response, body string) {
+	t.Helper()
+	b, err := ReadBody(res)
+	if err != nil {
+		t.Errorf("read body: %v", err)
+	} else if string(b) != body {
+		t.Errorf("body %q, expected %q", b, body)
+	}
+}
+
+// AssertJSON reports an error if the JSON body of the response does not
+// equal the JSON encoding of the v. The body of the response can be read
+// again afterwards.
+func AssertJSON(t testing.TB, res *http.Response, v interface{}) {
+	t.Helper()
+	b, err := ReadBody(res)
+	if err != nil {
+		t.Errorf("read body: %v", err)
+		return
+	}
+	var got, expected interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Errorf("body %q: %v", b, err)
+		return
+	}
+	e, err := json.Marshal(v)
+	if err != nil {
+		t.Errorf("marshal: %v", err)
+		return
+	}
+	json.Unmarshal(e, &expected)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("body %s, expected %s", b, e)
+	}
+}
+
+// ReadBody reads the body of the response and replaces it with a reader
+// of the content, so it can be read again.
+func ReadBody(res *http.Response) ([]byte, error) {
+	b, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return b, err
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rumtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssert(t *testing.T) {
+	res := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(`{"id":1}`)),
+	}
+	r := &recorder{TB: t}
+	AssertStatus(r, res, http.StatusOK)
+	AssertHeader(r, res, "Content-Type", "application/json")
+	AssertJSON(r, res, struct {
+		ID int `json:"id"`
+	}{1})
+	AssertBody(r, res, `{"id":1}`)
+	if len(r.errors) > 0 {
+		t.Error(r.errors)
+	}
+	AssertStatus(r, res, http.StatusNotFound)
+	AssertHeader(r, res, "Content-Type", "text/plain")
+	AssertJSON(r, res, map[string]int{"id": 2})
+	AssertBody(r, res, "foo")
+	if len(r.errors) != 4 {
+		t.Error(r.errors)
+	}
+	res.Body = ioutil.NopCloser(strings.NewReader("foo"))
+	AssertJSON(r, res, "foo")
+	if len(r.errors) != 5 {
+		t.Error(r.errors)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rumtest
+
+import (
+	"bytes"
+	"github.com/hslam/rum"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// LeakTimeout is the maximum duration VerifyNoLeaks waits for the
+// goroutines and the file descriptors to be released.
+var LeakTimeout = time.Second
+
+// VerifyNoLeaks snapshots the goroutines and the open file descriptors and
+// returns a function reporting an error, with the stacks of the new
+// goroutines, if they are not released by the end of the test:
+//
+//	defer rumtest.VerifyNoLeaks(t)()
+//
+// The idle connections of the clients should be closed before the check.
+func VerifyNoLeaks(t testing.TB) func() {
+	goroutines := stacks()
+	fds := rum.OpenFds()
+	return func() {
+		t.Helper()
+		var leaked []string
+		var leakedFds int64
+		deadline := time.Now().Add(LeakTimeout)
+		for {
+			leaked = leaked[:0]
+			for id, stack := range stacks() {
+				if _, ok := goroutines[id]; !ok {
+					leaked = append(leaked, stack)
+				}
+			}
+			leakedFds = rum.OpenFds() - fds
+			if len(leaked) == 0 && leakedFds <= 0 || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		for _, stack := range leaked {
+			t.Errorf("leaked goroutine: %s", stack)
+		}
+		if leakedFds > 0 {
+			t.Errorf("leaked %d file descriptors", leakedFds)
+		}
+	}
+}
+
+// stacks returns the stacks of the goroutines, except the current one,
+// by their ids.
+func stacks() map[string]string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	goroutines := make(map[string]string)
+	for i, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if i == 0 {
+			continue
+		}
+		header := stack
+		if j := bytes.IndexByte(stack, '\n'); j >= 0 {
+			header = stack[:j]
+		}
+		id := header
+		if j := bytes.IndexByte(header, '['); j >= 0 {
+			id = bytes.TrimSpace(header[:j])
+		}
+		goroutines[string(id)] = string(stack)
+	}
+	return goroutines
+}
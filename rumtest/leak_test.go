@@ -0,0 +1,43 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rumtest
+
+import (
+	"github.com/hslam/rum"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyNoLeaks(t *testing.T) {
+	verify := VerifyNoLeaks(t)
+	m := rum.New()
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	}).GET()
+	c := NewClient(m)
+	res, err := c.Get(c.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertBody(t, res, "Hello World")
+	c.Close()
+	verify()
+
+	timeout := LeakTimeout
+	LeakTimeout = 50 * time.Millisecond
+	defer func() { LeakTimeout = timeout }()
+	r := &recorder{TB: t}
+	verify = VerifyNoLeaks(r)
+	stop := make(chan struct{})
+	go func() {
+		<-stop
+	}()
+	verify()
+	close(stop)
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "leaked goroutine: goroutine") {
+		t.Error(r.errors)
+	}
+}
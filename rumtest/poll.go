@@ -0,0 +1,79 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rumtest
+
+import (
+	"bufio"
+	"github.com/hslam/netpoll"
+	"net"
+	"net/http"
+)
+
+// PollContext drives a netpoll.Handler over an in-memory connection the way
+// an event loop of the netpoll does, calling Serve once per request without
+// file descriptors.
+type PollContext struct {
+	// Context is the netpoll.Context upgraded from the connection.
+	Context netpoll.Context
+	handler netpoll.Handler
+	conn    net.Conn
+	reader  *bufio.Reader
+}
+
+// NewPollContext upgrades an in-memory connection with the handler.
+func NewPollContext(handler netpoll.Handler) (*PollContext, error) {
+	l := NewListener()
+	defer l.Close()
+	upgraded := make(chan error, 1)
+	c := &PollContext{handler: handler}
+	go func() {
+		server, err := l.Accept()
+		if err == nil {
+			c.Context, err = handler.Upgrade(server)
+		}
+		upgraded <- err
+	}()
+	conn, err := l.Dial()
+	if err != nil {
+		return nil, err
+	}
+	if err := <-upgraded; err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	return c, nil
+}
+
+// Do writes the request to the connection, serves it with the handler and
+// returns the response, whose body has been read entirely.
+func (c *PollContext) Do(req *http.Request) (*http.Response, error) {
+	errs := make(chan error, 2)
+	go func() {
+		errs <- req.Write(c.conn)
+	}()
+	go func() {
+		errs <- c.handler.Serve(c.Context)
+	}()
+	res, err := http.ReadResponse(c.reader, req)
+	if err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+	if _, err := ReadBody(res); err != nil {
+		return nil, err
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// Close closes the connection.
+func (c *PollContext) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rumtest
+
+import (
+	"bufio"
+	"errors"
+	"github.com/hslam/netpoll"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPollContext(t *testing.T) {
+	type context struct {
+		conn   net.Conn
+		reader *bufio.Reader
+		served int
+	}
+	h := &netpoll.ConnHandler{}
+	h.SetUpgrade(func(conn net.Conn) (netpoll.Context, error) {
+		return &context{conn: conn, reader: bufio.NewReader(conn)}, nil
+	})
+	h.SetServe(func(c netpoll.Context) error {
+		ctx := c.(*context)
+		req, err := http.ReadRequest(ctx.reader)
+		if err != nil {
+			return err
+		}
+		ctx.served++
+		body := "served " + req.URL.Path
+		_, err = ctx.conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: " + itoa(len(body)) + "\r\n\r\n" + body))
+		return err
+	})
+	c, err := NewPollContext(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for i := 0; i < 2; i++ {
+		res, err := c.Do(httptest.NewRequest("GET", URL+"/foo", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertStatus(t, res, http.StatusOK)
+		AssertBody(t, res, "served /foo")
+	}
+	if c.Context.(*context).served != 2 {
+		t.Error(c.Context.(*context).served)
+	}
+}
+
+func TestPollContextUpgradeError(t *testing.T) {
+	h := &netpoll.ConnHandler{}
+	h.SetUpgrade(func(conn net.Conn) (netpoll.Context, error) {
+		return nil, errors.New("refused")
+	})
+	if _, err := NewPollContext(h); err == nil || err.Error() != "refused" {
+		t.Error(err)
+	}
+}
+
+func itoa(n int) string {
+	if n < 10 {
+		return string(rune('0' + n))
+	}
+	return itoa(n/10) + string(rune('0'+n%10))
+}
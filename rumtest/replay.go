@@ -0,0 +1,109 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rumtest
+
+import (
+	"encoding/json"
+	"github.com/hslam/rum"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Replay replays the exchanges of a golden file recorded by a rum.Recorder
+// against the handler, and reports an error for each response differing in
+// the status code or the body. The JSON bodies are compared by value,
+// ignoring the rum.Redacted fields. The requests are sent without the
+// redacted headers; the prepare, if not nil, modifies each request before
+// it is sent, such as to set the credentials.
+func Replay(t testing.TB, handler http.Handler, path string, prepare func(req *http.Request)) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	exchanges, err := rum.ReadExchanges(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("replay %s: %v", path, err)
+	}
+	c := NewClient(handler)
+	defer c.Close()
+	for _, e := range exchanges {
+		req, err := http.NewRequest(e.Method, c.URL+e.URL, strings.NewReader(e.RequestBody))
+		if err != nil {
+			t.Errorf("replay %s %s: %v", e.Method, e.URL, err)
+			continue
+		}
+		for key, values := range e.RequestHeader {
+			if key != "Content-Length" && (len(values) != 1 || values[0] != rum.Redacted) {
+				req.Header[key] = values
+			}
+		}
+		if prepare != nil {
+			prepare(req)
+		}
+		res, err := c.Do(req)
+		if err != nil {
+			t.Errorf("replay %s %s: %v", e.Method, e.URL, err)
+			continue
+		}
+		body, err := ReadBody(res)
+		if err != nil {
+			t.Errorf("replay %s %s: read body: %v", e.Method, e.URL, err)
+			continue
+		}
+		if res.StatusCode != e.Status {
+			t.Errorf("replay %s %s: status code %d, expected %d", e.Method, e.URL, res.StatusCode, e.Status)
+		}
+		if !matchBody(string(body), e.ResponseBody) {
+			t.Errorf("replay %s %s: body %q, expected %q", e.Method, e.URL, body, e.ResponseBody)
+		}
+	}
+}
+
+// matchBody reports whether the body matches the expected body, comparing
+// the JSON bodies by value except for the redacted fields.
+func matchBody(body, expected string) bool {
+	if body == expected {
+		return true
+	}
+	var got, want interface{}
+	if json.Unmarshal([]byte(body), &got) != nil || json.Unmarshal([]byte(expected), &want) != nil {
+		return false
+	}
+	return matchJSON(got, want)
+}
+
+func matchJSON(got, want interface{}) bool {
+	if want == rum.Redacted {
+		return true
+	}
+	switch want := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok || len(g) != len(want) {
+			return false
+		}
+		for key, value := range want {
+			if v, ok := g[key]; !ok || !matchJSON(v, value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(want) {
+			return false
+		}
+		for i := range want {
+			if !matchJSON(g[i], want[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return got == want
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rumtest
+
+import (
+	"github.com/hslam/rum"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rumtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	newMux := func(version string) *rum.Mux {
+		m := rum.NewMux()
+		m.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer valid" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"` + m.Params(r)["id"] + `","token":"` + r.URL.Query().Get("n") + `","version":"` + version + `"}`))
+		}).GET()
+		return m
+	}
+	recorded := newMux("1")
+	recorded.SetRecorder(&rum.Recorder{Dir: dir})
+	for _, n := range []string{"1", "2"} {
+		r := httptest.NewRequest("GET", "/users/8?n="+n, nil)
+		r.Header.Set("Authorization", "Bearer valid")
+		r.Header.Set("Accept", "application/json")
+		recorded.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	path := filepath.Join(dir, rum.RecordFile("GET", "/users/:id"))
+	Replay(t, newMux("1"), path, func(req *http.Request) {
+		if req.Header.Get("Accept") != "application/json" || req.Header.Get("Authorization") != "" {
+			t.Error(req.Header)
+		}
+		req.Header.Set("Authorization", "Bearer valid")
+	})
+
+	ft := &recorder{TB: t}
+	Replay(ft, newMux("2"), path, nil)
+	if len(ft.errors) != 4 || !strings.Contains(ft.errors[0], "status code 401, expected 200") {
+		t.Error(ft.errors)
+	}
+	ft = &recorder{TB: t}
+	Replay(ft, newMux("2"), path, func(req *http.Request) { req.Header.Set("Authorization", "Bearer valid") })
+	if len(ft.errors) != 2 || !strings.Contains(ft.errors[0], "GET /users/8?n=1: body") {
+		t.Error(ft.errors)
+	}
+}
+
+func TestMatchBody(t *testing.T) {
+	cases := []struct {
+		body, expected string
+		match          bool
+	}{
+		{"a", "a", true},
+		{"a", "b", false},
+		{`{"a":1,"b":[1,2]}`, `{"b":[1,2],"a":1}`, true},
+		{`{"a":1}`, `{"a":"[REDACTED]"}`, true},
+		{`{"a":1}`, `{"a":1,"b":2}`, false},
+		{`{"a":1,"c":2}`, `{"a":1,"b":2}`, false},
+		{`[1]`, `[1,2]`, false},
+		{`[1,3]`, `[1,2]`, false},
+		{`1`, `{"a":1}`, false},
+		{`1`, `[1]`, false},
+	}
+	for _, c := range cases {
+		if matchBody(c.body, c.expected) != c.match {
+			t.Error(c)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+// Package rumtest provides utilities for testing the handlers, the middleware
+// and the servers of the rum package without binding real ports.
+package rumtest
+
+import (
+	"context"
+	"errors"
+	"github.com/hslam/rum"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+)
+
+// URL is the base URL of the requests dispatched by a Client.
+const URL = "http://rum.test"
+
+// ErrListenerClosed is returned by Accept and Dial after the listener is closed.
+var ErrListenerClosed = errors.New("Listener Closed")
+
+// Client is an HTTP client dispatching the requests to a handler in memory.
+type Client struct {
+	*http.Client
+	// URL is the base URL of the requests, in the form http://rum.test.
+	URL      string
+	listener *Listener
+	done     chan struct{}
+}
+
+// NewClient returns a new Client dispatching the requests to the handler.
+//
+// A *rum.Rum is served on an in-memory Listener, so the requests go through
+// the connection handling of the server, including the fast and the poll mode.
+// Any other handler, such as a *rum.Mux, is called directly with an
+// httptest.ResponseRecorder, which does not support hijacking.
+func NewClient(handler http.Handler) *Client {
+	c := &Client{URL: URL}
+	if server, ok := handler.(*rum.Rum); ok {
+		c.listener = NewListener()
+		c.done = make(chan struct{})
+		go func() {
+			server.Serve(c.listener)
+			close(c.done)
+		}()
+		c.Client = &http.Client{Transport: &http.Transport{DialContext: c.listener.DialContext}}
+	} else {
+		c.Client = &http.Client{Transport: &handlerTransport{handler: handler}}
+	}
+	return c
+}
+
+// Close closes the idle connections and the in-memory Listener.
+func (c *Client) Close() error {
+	c.Client.CloseIdleConnections()
+	if c.listener != nil {
+		c.listener.Close()
+		<-c.done
+	}
+	return nil
+}
+
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t *handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.RequestURI = req.URL.RequestURI()
+	r.RemoteAddr = "127.0.0.1:1024"
+	if r.Host == "" {
+		r.Host = req.URL.Host
+	}
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, r)
+	res := w.Result()
+	res.Request = req
+	return res, nil
+}
+
+// Listener is an in-memory net.Listener whose connections are net.Pipe
+// connections with loopback TCP addresses.
+type Listener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+	addr   *net.TCPAddr
+	port   int32
+}
+
+// NewListener returns a new in-memory Listener.
+func NewListener() *Listener {
+	return &Listener{
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+		addr:   &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 80},
+		port:   1023,
+	}
+}
+
+// Accept waits for and returns the next connection dialed to the listener.
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, ErrListenerClosed
+	}
+}
+
+// Close closes the listener.
+func (l *Listener) Close() error {
+	l.once.Do(func() {
+		close(l.closed)
+	})
+	return nil
+}
+
+// Addr returns the listener's network address.
+func (l *Listener) Addr() net.Addr {
+	return l.addr
+}
+
+// Dial connects to the listener.
+func (l *Listener) Dial() (net.Conn, error) {
+	return l.DialContext(context.Background(), "tcp", l.addr.String())
+}
+
+// DialContext connects to the listener, ignoring the network and the address.
+func (l *Listener) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	client, server := net.Pipe()
+	remote := &net.TCPAddr{IP: l.addr.IP, Port: int(atomic.AddInt32(&l.port, 1))}
+	select {
+	case l.conns <- &conn{Conn: server, local: l.addr, remote: remote}:
+		return &conn{Conn: client, local: remote, remote: l.addr}, nil
+	case <-l.closed:
+		return nil, ErrListenerClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type conn struct {
+	net.Conn
+	local  net.Addr
+	remote net.Addr
+}
+
+func (c *conn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *conn) RemoteAddr() net.Addr {
+	return c.remote
+}
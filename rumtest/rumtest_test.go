@@ -0,0 +1,102 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rumtest
+
+import (
+	"github.com/hslam/rum"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestClientRum(t *testing.T) {
+	for _, mode := range []struct{ fast, poll bool }{{false, false}, {true, false}, {false, true}} {
+		m := rum.New()
+		m.SetFast(mode.fast)
+		m.SetPoll(mode.poll)
+		m.HandleFunc("/hello/:name", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello " + m.Params(r)["name"]))
+		}).GET()
+		c := NewClient(m)
+		for i := 0; i < 2; i++ {
+			res, err := c.Get(c.URL + "/hello/rum")
+			if err != nil {
+				t.Fatal(err)
+			}
+			AssertStatus(t, res, http.StatusOK)
+			AssertBody(t, res, "hello rum")
+		}
+		res, err := c.Post(c.URL+"/missing", "text/plain", strings.NewReader("foo"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertStatus(t, res, http.StatusNotFound)
+		res.Body.Close()
+		c.Close()
+		if _, err := c.Get(c.URL + "/hello/rum"); err == nil {
+			t.Error()
+		}
+	}
+}
+
+func TestClientMux(t *testing.T) {
+	m := rum.NewMux()
+	m.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		buf := make([]byte, 64)
+		n, _ := r.Body.Read(buf)
+		w.Write(buf[:n])
+	}).POST()
+	c := NewClient(m)
+	defer c.Close()
+	res, err := c.Post(c.URL+"/echo", "application/json", strings.NewReader(`{"a": [1, 2]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertStatus(t, res, http.StatusOK)
+	AssertHeader(t, res, "Content-Type", "application/json")
+	AssertJSON(t, res, map[string][]int{"a": {1, 2}})
+	AssertBody(t, res, `{"a": [1, 2]}`)
+	res, err = c.Get(c.URL + "/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertStatus(t, res, http.StatusNotFound)
+}
+
+func TestListener(t *testing.T) {
+	l := NewListener()
+	if l.Addr().String() != "127.0.0.1:80" {
+		t.Error(l.Addr())
+	}
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Write([]byte("ok"))
+		conn.Close()
+	}()
+	conn, err := l.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 2)
+	if n, _ := conn.Read(buf); string(buf[:n]) != "ok" {
+		t.Error(string(buf[:n]))
+	}
+	if conn.RemoteAddr().String() != "127.0.0.1:80" || conn.LocalAddr().String() != "127.0.0.1:1024" {
+		t.Error(conn.LocalAddr(), conn.RemoteAddr())
+	}
+	conn.Close()
+	l.Close()
+	l.Close()
+	if _, err := l.Accept(); err != ErrListenerClosed {
+		t.Error(err)
+	}
+	if _, err := l.Dial(); err != ErrListenerClosed {
+		t.Error(err)
+	}
+}
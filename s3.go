@@ -0,0 +1,204 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultS3Region = "us-east-1"
+	// emptySHA256 is the hex SHA-256 of the empty payload of the GET requests.
+	emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// S3 is an ObjectBackend of a bucket of an S3-compatible object storage,
+// such as Amazon S3, MinIO or Cloudflare R2. The objects are addressed in
+// the path style, as Endpoint/Bucket/Prefix+name, and the requests are
+// signed with the AWS Signature Version 4, or anonymous without an AccessKey.
+type S3 struct {
+	// Endpoint is the base URL of the storage, such as
+	// "https://s3.us-east-1.amazonaws.com" or "http://127.0.0.1:9000".
+	Endpoint string
+	// Region is the region of the bucket. If empty, us-east-1 is used.
+	Region string
+	// Bucket is the name of the bucket.
+	Bucket string
+	// Prefix is prepended to the names of the objects to get their keys,
+	// such as "public/".
+	Prefix string
+	// AccessKey is the access key ID of the credentials.
+	AccessKey string
+	// SecretKey is the secret access key of the credentials.
+	SecretKey string
+	// SessionToken is the session token of the temporary credentials.
+	SessionToken string
+	// Client is the HTTP client of the requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	now func() time.Time
+}
+
+// Open implements the ObjectBackend interface with a GET of the object,
+// conditional on the ETag if not empty.
+func (s *S3) Open(ctx context.Context, name string, etag string) (*Object, error) {
+	u := strings.TrimSuffix(s.Endpoint, "/") + "/" + s3Escape(s.Bucket+"/"+s.Prefix+name)
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if s.AccessKey != "" {
+		now := time.Now
+		if s.now != nil {
+			now = s.now
+		}
+		region := s.Region
+		if region == "" {
+			region = defaultS3Region
+		}
+		if s.SessionToken != "" {
+			req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+		}
+		signV4(req, s.AccessKey, s.SecretKey, region, "s3", now())
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		res.Body.Close()
+		return nil, ErrObjectNotModified
+	case http.StatusNotFound:
+		res.Body.Close()
+		return nil, ErrObjectNotFound
+	default:
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		res.Body.Close()
+		return nil, fmt.Errorf("s3 %s status %d: %s", name, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	obj := &Object{
+		Body:        res.Body,
+		Size:        res.ContentLength,
+		ETag:        res.Header.Get("ETag"),
+		ContentType: res.Header.Get("Content-Type"),
+	}
+	if t, err := http.ParseTime(res.Header.Get("Last-Modified")); err == nil {
+		obj.ModTime = t
+	}
+	return obj, nil
+}
+
+// s3Escape escapes the path as the canonical URI of the Signature Version
+// 4, keeping the unreserved characters and the slashes.
+func s3Escape(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '.' || c == '_' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// signV4 signs the request without a payload with the AWS Signature
+// Version 4, setting the X-Amz-Date, the X-Amz-Content-Sha256 and the
+// Authorization headers. The Host, the Range and the X-Amz-* headers are
+// signed.
+func signV4(req *http.Request, accessKey, secretKey, region, service string, t time.Time) {
+	amzDate := t.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	headers := map[string]string{"host": req.URL.Host}
+	for key, values := range req.Header {
+		lower := strings.ToLower(key)
+		if lower == "range" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	uri := req.URL.EscapedPath()
+	if uri == "" {
+		uri = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uri,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptySHA256,
+	}, "\n")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, s3QueryEscape(key)+"="+s3QueryEscape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func s3QueryEscape(s string) string {
+	return strings.Replace(s3Escape(s), "/", "%2F", -1)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const bufferSize = 32 * 1024
+
+const sniffLen = 512
+
+var bufferPool = sync.Pool{New: func() interface{} {
+	b := make([]byte, bufferSize)
+	return &b
+}}
+
+// writerOnly hides the io.ReaderFrom of the writer from io.CopyBuffer.
+type writerOnly struct {
+	io.Writer
+}
+
+// ServeReader replies to the request with the content of rd.
+//
+// If rd is an io.ReadSeeker, ServeReader uses http.ServeContent which
+// handles the Range and the conditional requests. Otherwise the content
+// is streamed with a pooled buffer, or with the io.ReaderFrom of the
+// response writer. The size sets the Content-Length when it is not negative.
+//
+// The Content-Type is set from the extension of the name, or sniffed
+// from the first 512 bytes of the content.
+func ServeReader(w http.ResponseWriter, r *http.Request, name string, modtime time.Time, rd io.Reader, size int64) {
+	if rs, ok := rd.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, name, modtime, rs)
+		return
+	}
+	header := w.Header()
+	if !modtime.IsZero() && !modtime.Equal(time.Unix(0, 0)) {
+		if t, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil &&
+			(r.Method == "GET" || r.Method == "HEAD") && !modtime.Truncate(time.Second).After(t) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		header.Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
+	}
+	bp := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(bp)
+	buf := *bp
+	var n int
+	if _, ok := header["Content-Type"]; !ok {
+		ctype := mime.TypeByExtension(filepath.Ext(name))
+		if ctype == "" {
+			limit := int64(sniffLen)
+			if size >= 0 && size < limit {
+				limit = size
+			}
+			var err error
+			n, err = io.ReadFull(rd, buf[:limit])
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				http.Error(w, "500 Internal Server Error : "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			ctype = http.DetectContentType(buf[:n])
+		}
+		header.Set("Content-Type", ctype)
+	}
+	if size >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method == "HEAD" {
+		return
+	}
+	if n > 0 {
+		if _, err := w.Write(buf[:n]); err != nil {
+			return
+		}
+	}
+	if size >= 0 {
+		rd = io.LimitReader(rd, size-int64(n))
+	}
+	if rf, ok := w.(io.ReaderFrom); ok {
+		rf.ReadFrom(rd)
+		return
+	}
+	io.CopyBuffer(writerOnly{w}, rd, buf)
+}
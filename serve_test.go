@@ -0,0 +1,69 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeReader(t *testing.T) {
+	modtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	content := "Hello World"
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	ServeReader(w, r, "hello", modtime, ioutil.NopCloser(strings.NewReader(content)), int64(len(content)))
+	if w.Body.String() != content {
+		t.Error(w.Body.String())
+	}
+	if w.Header().Get("Content-Length") != "11" || w.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Error(w.Header())
+	}
+	if w.Header().Get("Last-Modified") != modtime.Format(http.TimeFormat) {
+		t.Error(w.Header().Get("Last-Modified"))
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/", nil)
+	ServeReader(w, r, "hello", time.Time{}, ioutil.NopCloser(strings.NewReader(content)), 5)
+	if w.Body.String() != "Hello" || w.Header().Get("Content-Length") != "5" {
+		t.Error(w.Body.String(), w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("If-Modified-Since", modtime.Format(http.TimeFormat))
+	ServeReader(w, r, "hello", modtime, ioutil.NopCloser(strings.NewReader(content)), -1)
+	if w.Code != http.StatusNotModified {
+		t.Error(w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("HEAD", "/", nil)
+	ServeReader(w, r, "hello.json", time.Time{}, ioutil.NopCloser(strings.NewReader(content)), -1)
+	if w.Body.Len() != 0 || w.Header().Get("Content-Type") != "application/json" {
+		t.Error(w.Body.String(), w.Header())
+	}
+
+	large := bytes.Repeat([]byte("a"), bufferSize*3)
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/", nil)
+	ServeReader(w, r, "large.txt", time.Time{}, ioutil.NopCloser(bytes.NewReader(large)), -1)
+	if !bytes.Equal(w.Body.Bytes(), large) {
+		t.Error(w.Body.Len())
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Range", "bytes=0-4")
+	ServeReader(w, r, "hello.txt", modtime, strings.NewReader(content), int64(len(content)))
+	if w.Code != http.StatusPartialContent || w.Body.String() != "Hello" {
+		t.Error(w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultShadowMaxBody     = 64 << 10
+	defaultShadowTimeout     = 5 * time.Second
+	defaultShadowConcurrency = 64
+)
+
+// Shadow mirrors a sample of the requests to a shadow upstream, for testing
+// new backends with production traffic. The mirrored requests are sent
+// asynchronously and their responses are discarded, so the shadow upstream
+// never affects the client responses.
+type Shadow struct {
+	inflight int64
+	mirrored uint64
+	dropped  uint64
+	// URL is the shadow upstream.
+	URL *url.URL
+	// Sample is the fraction of the requests mirrored, between 0 and 1.
+	Sample float64
+	// MaxBody is the maximum size of a mirrored request body. The requests
+	// with larger bodies are not mirrored. If zero, 64 KB is used.
+	MaxBody int64
+	// Timeout limits each mirrored request. If zero, 5 seconds is used.
+	Timeout time.Duration
+	// Concurrency is the maximum number of in-flight mirrored requests,
+	// above which the requests are dropped. If zero, 64 is used.
+	Concurrency int
+	// Transport sends the mirrored requests. If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+}
+
+// NewShadow returns a new Shadow mirroring the sample of the requests to the target.
+func NewShadow(target string, sample float64) (*Shadow, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid shadow url %q", target)
+	}
+	return &Shadow{URL: u, Sample: sample}, nil
+}
+
+// Mirrored returns the number of the requests mirrored.
+func (s *Shadow) Mirrored() uint64 {
+	return atomic.LoadUint64(&s.mirrored)
+}
+
+// Dropped returns the number of the sampled requests that were not mirrored,
+// because of their body size, the concurrency limit or a failure.
+func (s *Shadow) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Handler returns a handler that mirrors the sample of the requests before
+// serving them by the handler.
+func (s *Shadow) Handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Sample > 0 && rand.Float64() < s.Sample {
+			s.mirror(r)
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (s *Shadow) mirror(r *http.Request) {
+	maxBody := s.MaxBody
+	if maxBody <= 0 {
+		maxBody = defaultShadowMaxBody
+	}
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		b, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBody+1))
+		r.Body = &readCloser{Reader: io.MultiReader(bytes.NewReader(b), r.Body), Closer: r.Body}
+		if err != nil || int64(len(b)) > maxBody {
+			atomic.AddUint64(&s.dropped, 1)
+			return
+		}
+		body = b
+	}
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultShadowConcurrency
+	}
+	if atomic.AddInt64(&s.inflight, 1) > int64(concurrency) {
+		atomic.AddInt64(&s.inflight, -1)
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+	u := *s.URL
+	u.Path = singleJoiningSlash(s.URL.Path, r.URL.Path)
+	u.RawPath = ""
+	u.RawQuery = r.URL.RawQuery
+	header := r.Header.Clone()
+	removeHopHeaders(header)
+	method, host := r.Method, r.Host
+	go func() {
+		defer atomic.AddInt64(&s.inflight, -1)
+		timeout := s.Timeout
+		if timeout <= 0 {
+			timeout = defaultShadowTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+		if err != nil {
+			atomic.AddUint64(&s.dropped, 1)
+			return
+		}
+		req.Header = header
+		req.Host = host
+		transport := s.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			atomic.AddUint64(&s.dropped, 1)
+			return
+		}
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+		atomic.AddUint64(&s.mirrored, 1)
+	}()
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShadow(t *testing.T) {
+	mirrored := make(chan string, 16)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mirrored <- r.Method + " " + r.URL.RequestURI() + " " + r.Header.Get("X-Test") + " " + string(body)
+		time.Sleep(time.Millisecond * 50)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+	if _, err := NewShadow("127.0.0.1", 1); err == nil {
+		t.Error("should be an invalid url")
+	}
+	s, err := NewShadow(upstream.URL+"/shadow", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.MaxBody = 8
+	handler := s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte("Hello " + string(body)))
+	}))
+	serve := func(method, target, body string) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("X-Test", "1")
+		w := httptest.NewRecorder()
+		start := time.Now()
+		handler.ServeHTTP(w, req)
+		if time.Since(start) > time.Millisecond*40 {
+			t.Error("should not wait for the shadow upstream")
+		}
+		if w.Code != http.StatusOK || w.Body.String() != "Hello "+body {
+			t.Error(w.Code, w.Body.String())
+		}
+	}
+	serve("POST", "/users?id=1", "World")
+	select {
+	case result := <-mirrored:
+		if result != "POST /shadow/users?id=1 1 World" {
+			t.Error(result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("should be mirrored")
+	}
+	serve("POST", "/users", "Too Large Body")
+	select {
+	case result := <-mirrored:
+		t.Error("should not be mirrored", result)
+	case <-time.After(time.Millisecond * 100):
+	}
+	if s.Mirrored() != 1 || s.Dropped() != 1 {
+		t.Error(s.Mirrored(), s.Dropped())
+	}
+	s.Sample = 0
+	serve("GET", "/", "")
+	select {
+	case result := <-mirrored:
+		t.Error("should not be sampled", result)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
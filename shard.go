@@ -0,0 +1,78 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"runtime"
+	"syscall"
+)
+
+// ErrShardsNotSupported is the error returned by Run when the shards
+// are not supported on the platform.
+var ErrShardsNotSupported = errors.New("Shards not supported")
+
+// SetShards sets the number of the shards of Run in the poll mode, such as
+// runtime.NumCPU(). Each shard listens on the address with SO_REUSEPORT and
+// is served by its own event loop, so the kernel spreads the connections
+// over the shards, reducing the cross-CPU wakeups for the small requests.
+// Zero or one disables the sharding.
+func (m *Rum) SetShards(n int) {
+	m.shards = n
+}
+
+// SetShardAffinity sets whether the accept loop of each shard is locked
+// to an OS thread, which is pinned to a CPU on Linux. It is a hint, as the
+// event loops of the connections are scheduled by the Go runtime.
+func (m *Rum) SetShardAffinity(affinity bool) {
+	m.shardAffinity = affinity
+}
+
+// runShards listens on the address once per shard and serves the shards
+// until one of them returns.
+func (m *Rum) runShards(addr string) error {
+	if !reusePortSupported {
+		return ErrShardsNotSupported
+	}
+	lc := net.ListenConfig{Control: reusePort}
+	listeners := make([]net.Listener, m.shards)
+	for i := range listeners {
+		l, err := lc.Listen(context.Background(), "tcp", addr)
+		if err != nil {
+			for _, l := range listeners[:i] {
+				l.Close()
+			}
+			return err
+		}
+		listeners[i] = l
+	}
+	var config *tls.Config
+	if m.TLSConfig != nil {
+		config = m.tlsConfig()
+	}
+	errs := make(chan error, len(listeners))
+	for i, l := range listeners {
+		go func(cpu int, l net.Listener) {
+			if m.shardAffinity {
+				runtime.LockOSThread()
+				setAffinity(cpu)
+			}
+			errs <- m.serve(l, config, 1)
+		}(i%runtime.NumCPU(), l)
+	}
+	return <-errs
+}
+
+func reusePort(network, address string, c syscall.RawConn) error {
+	var err error
+	if cerr := c.Control(func(fd uintptr) {
+		err = setReusePort(fd)
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
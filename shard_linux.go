@@ -0,0 +1,35 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package rum
+
+import (
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const reusePortSupported = true
+
+// setReusePort sets SO_REUSEPORT, which the syscall package lacks on some architectures.
+func setReusePort(fd uintptr) error {
+	soReusePort := 0xf
+	switch runtime.GOARCH {
+	case "mips", "mipsle", "mips64", "mips64le":
+		soReusePort = 0x200
+	}
+	return syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+}
+
+// setAffinity pins the current thread to the cpu.
+func setAffinity(cpu int) {
+	var mask [16]uint64
+	if cpu < 0 || cpu >= len(mask)*64 {
+		return
+	}
+	mask[cpu/64] |= 1 << uint(cpu%64)
+	syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestShards(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	m.SetPoll(true)
+	m.SetShards(4)
+	m.SetShardAffinity(true)
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Run(addr)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	if !reusePortSupported {
+		if err := <-done; err != ErrShardsNotSupported {
+			t.Error(err)
+		}
+		return
+	}
+	m.mut.Lock()
+	listeners := len(m.listeners)
+	m.mut.Unlock()
+	if listeners != 4 {
+		t.Error(listeners)
+	}
+	for i := 0; i < 16; i++ {
+		testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	}
+	m.Close()
+	<-done
+}
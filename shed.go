@@ -0,0 +1,96 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOverloaded is the error returned when a connection is shed because
+// the number of connections is above the file descriptor watermark.
+var ErrOverloaded = errors.New("Overloaded")
+
+var shedResponse = []byte("HTTP/1.1 503 Service Unavailable\r\nConnection: close\r\nContent-Length: 0\r\n\r\n")
+
+// SetFdWatermark sets the fraction of the process file descriptor limit
+// (RLIMIT_NOFILE) in (0, 1] above which new connections are shed, so that
+// connection floods can not exhaust the descriptors needed by handlers.
+// Zero, the default, disables the load shedding. It has no effect on
+// platforms without a file descriptor limit.
+func (m *Rum) SetFdWatermark(watermark float64) {
+	if watermark > 1 {
+		watermark = 1
+	}
+	m.fdLimit = fdLimit()
+	m.fdWatermark = watermark
+}
+
+// SetShedReply enables the Server to reply 503 Service Unavailable to
+// the shed connections instead of closing them immediately.
+func (m *Rum) SetShedReply(reply bool) {
+	m.shedReply = reply
+}
+
+// Conns returns the number of open connections.
+func (m *Rum) Conns() int64 {
+	return atomic.LoadInt64(&m.conns)
+}
+
+// Shedded returns the number of connections shed since the Server started.
+func (m *Rum) Shedded() uint64 {
+	return atomic.LoadUint64(&m.shedded)
+}
+
+// Overloaded reports whether the Server is shedding new connections.
+func (m *Rum) Overloaded() bool {
+	return m.fdWatermark > 0 && m.fdLimit > 0 &&
+		atomic.LoadInt64(&m.conns) >= int64(float64(m.fdLimit)*m.fdWatermark)
+}
+
+// admit counts the connection, or sheds it when the Server is overloaded.
+// The shed reply is written in a goroutine with a deadline, so that a TLS
+// handshake or a client not reading can not stall the accept loop.
+func (m *Rum) admit(conn net.Conn) bool {
+	if m.Overloaded() {
+		atomic.AddUint64(&m.shedded, 1)
+		if m.shedReply {
+			go func() {
+				conn.SetDeadline(time.Now().Add(time.Second))
+				conn.Write(shedResponse)
+				conn.Close()
+			}()
+		} else {
+			conn.Close()
+		}
+		return false
+	}
+	atomic.AddInt64(&m.conns, 1)
+	return true
+}
+
+func (m *Rum) release() {
+	atomic.AddInt64(&m.conns, -1)
+}
+
+// Drain stops accepting new connections and closes the open connections
+// after their current requests, for a graceful shutdown. The idle keep-alive
+// connections are closed on their next request or by the read timeout.
+// Conns reports the connections left.
+func (m *Rum) Drain() {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	atomic.StoreInt32(&m.draining, 1)
+	for _, lis := range m.listeners {
+		lis.Close()
+	}
+	m.listeners = []net.Listener{}
+}
+
+// Draining reports whether the Server is draining.
+func (m *Rum) Draining() bool {
+	return atomic.LoadInt32(&m.draining) == 1
+}
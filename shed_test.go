@@ -0,0 +1,98 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestShed(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	m.SetFdWatermark(2)
+	if m.fdWatermark != 1 {
+		t.Error(m.fdWatermark)
+	}
+	if m.fdLimit <= 0 {
+		t.Skip("no file descriptor limit")
+	}
+	m.SetShedReply(true)
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	})
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	keepAlive, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := testRawRequest(keepAlive, t); resp != nil && resp.StatusCode != http.StatusOK {
+		t.Error(resp.StatusCode)
+	}
+	m.fdLimit = 1
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := testRawRequest(conn, t); resp != nil && resp.StatusCode != http.StatusServiceUnavailable {
+		t.Error(resp.StatusCode)
+	}
+	conn.Close()
+	if !m.Overloaded() || m.Shedded() != 1 || m.Conns() != 1 {
+		t.Error(m.Overloaded(), m.Shedded(), m.Conns())
+	}
+	keepAlive.Close()
+	m.fdLimit = 1 << 20
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	m.Close()
+	<-done
+}
+
+func TestShedReplyNotBlocking(t *testing.T) {
+	m := New()
+	m.fdLimit = 1
+	m.fdWatermark = 1
+	m.shedReply = true
+	m.conns = 1
+	server, client := net.Pipe()
+	defer client.Close()
+	admitted := make(chan bool, 1)
+	go func() {
+		admitted <- m.admit(server)
+	}()
+	select {
+	case ok := <-admitted:
+		if ok {
+			t.Error(ok)
+		}
+	case <-time.After(time.Millisecond * 100):
+		t.Fatal("admit blocked on the shed reply")
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(client), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Error(resp.StatusCode)
+	}
+}
+
+func testRawRequest(conn net.Conn, t *testing.T) *http.Response {
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	resp.Body.Close()
+	return resp
+}
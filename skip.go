@@ -0,0 +1,74 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Middleware is a middleware registered by Use, which skips the requests
+// matched by Except or Skip, so the global middleware like authentication
+// or logging can exclude paths without checking them itself. Except and
+// Skip should be called before serving.
+type Middleware struct {
+	handler  http.Handler
+	matchers []func(r *http.Request) bool
+}
+
+// Except skips the requests whose path is one of the patterns. A pattern
+// ending with "/*" matches the subtree of the path before it.
+//
+//	m.Use(auth).Except("/health", "/metrics", "/static/*")
+func (mw *Middleware) Except(patterns ...string) *Middleware {
+	return mw.Skip(matchPaths(patterns))
+}
+
+// Skip skips the requests for which the matcher returns true.
+func (mw *Middleware) Skip(matcher func(r *http.Request) bool) *Middleware {
+	mw.matchers = append(mw.matchers, matcher)
+	return mw
+}
+
+// ServeHTTP calls the middleware unless the request is skipped.
+func (mw *Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, matcher := range mw.matchers {
+		if matcher(r) {
+			return
+		}
+	}
+	mw.handler.ServeHTTP(w, r)
+}
+
+// Skip returns a middleware handler function calling the middleware
+// unless the matcher returns true for the request.
+func Skip(middleware http.HandlerFunc, matcher func(r *http.Request) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !matcher(r) {
+			middleware(w, r)
+		}
+	}
+}
+
+// Except returns a middleware handler function calling the middleware
+// unless the path of the request is one of the patterns, as Middleware.Except.
+func Except(middleware http.HandlerFunc, patterns ...string) http.HandlerFunc {
+	return Skip(middleware, matchPaths(patterns))
+}
+
+func matchPaths(patterns []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		for _, pattern := range patterns {
+			if strings.HasSuffix(pattern, "/*") {
+				subtree := pattern[:len(pattern)-1]
+				if strings.HasPrefix(r.URL.Path, subtree) || r.URL.Path == subtree[:len(subtree)-1] {
+					return true
+				}
+			} else if r.URL.Path == pattern {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareExcept(t *testing.T) {
+	m := NewMux()
+	m.Use(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Middleware", "auth")
+	}).Except("/health", "/static/*")
+	m.Use(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Middleware", "log")
+	}).Skip(func(r *http.Request) bool {
+		return r.Method == "HEAD"
+	})
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	m.HandleFunc("/health", handler).GET().HEAD()
+	m.HandleFunc("/static", handler).GET()
+	m.HandleFunc("/static/:file", handler).GET()
+	m.HandleFunc("/healthz", handler).GET()
+	m.HandleFunc("/users", handler).GET().HEAD()
+	tests := []struct {
+		method, target string
+		middlewares    int
+	}{
+		{"GET", "/users", 2},
+		{"HEAD", "/users", 1},
+		{"GET", "/health", 1},
+		{"HEAD", "/health", 0},
+		{"GET", "/static/app.js", 1},
+		{"GET", "/static", 1},
+		{"GET", "/healthz", 2},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+		if n := len(w.Header()["X-Middleware"]); n != test.middlewares {
+			t.Error(test.method, test.target, w.Header()["X-Middleware"])
+		}
+	}
+}
+
+func TestSkip(t *testing.T) {
+	var calls int
+	middleware := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}
+	skip := Skip(middleware, func(r *http.Request) bool {
+		return r.Header.Get("X-Skip") != ""
+	})
+	except := Except(middleware, "/metrics")
+	r := httptest.NewRequest("GET", "/metrics", nil)
+	skip(nil, r)
+	except(nil, r)
+	r.Header.Set("X-Skip", "1")
+	skip(nil, r)
+	except(nil, httptest.NewRequest("GET", "/", nil))
+	if calls != 2 {
+		t.Error(calls)
+	}
+}
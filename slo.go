@@ -0,0 +1,230 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSLOWindow = time.Hour
+	// sloBuckets is the number of the buckets of a sliding window.
+	sloBuckets = 60
+	// sloShortBuckets is the number of the buckets of the short window,
+	// one twelfth of the window, such as 5 minutes of an hour.
+	sloShortBuckets = sloBuckets / 12
+)
+
+// Objective is the service level objective of a route.
+type Objective struct {
+	// Route is the route pattern, such as "/users/:id". An empty Route is
+	// the objective of the routes without their own.
+	Route string `json:"route"`
+	// Success is the target ratio of the good requests, such as 0.999.
+	Success float64 `json:"success"`
+	// Latency is the maximum duration of a good request. Zero means the
+	// duration is not considered. The requests replied with a 5xx status
+	// code are bad.
+	Latency time.Duration `json:"latency,omitempty"`
+}
+
+// SLOStatus is the status of an objective over the sliding windows.
+type SLOStatus struct {
+	Objective
+	// Total is the number of the requests in the window.
+	Total int64 `json:"total"`
+	// Good is the number of the good requests in the window.
+	Good int64 `json:"good"`
+	// SuccessRate is the ratio of the good requests in the window.
+	SuccessRate float64 `json:"successRate"`
+	// BurnRate is the rate the error budget is consumed in the window. One
+	// consumes the budget exactly by the end of the window.
+	BurnRate float64 `json:"burnRate"`
+	// ShortBurnRate is the burn rate in the last twelfth of the window,
+	// for detecting the fast burns.
+	ShortBurnRate float64 `json:"shortBurnRate"`
+	// Budget is the remaining ratio of the error budget in the window.
+	Budget float64 `json:"budget"`
+	// Met reports whether the objective is met in the window.
+	Met bool `json:"met"`
+}
+
+// SLO tracks the compliance of the routes with their objectives over a
+// sliding window. It is a Metrics consuming the "request.duration" timers
+// of a Mux, so it can be set alone or with other exporters:
+//
+//	slo := rum.NewSLO(time.Hour, rum.Objective{Success: 0.999, Latency: 100 * time.Millisecond})
+//	m.SetMetrics(rum.MultiMetrics(statsd, slo))
+//	m.Handle("/slo", slo).GET()
+type SLO struct {
+	window     time.Duration
+	bucket     time.Duration
+	objectives map[string]Objective
+	mu         sync.Mutex
+	routes     map[string]*sloWindow
+	now        func() time.Time
+}
+
+type sloBucket struct {
+	epoch int64
+	total int64
+	good  int64
+}
+
+type sloWindow struct {
+	buckets [sloBuckets]sloBucket
+}
+
+// NewSLO returns a new SLO tracking the objectives over the window. If the
+// window is zero, one hour is used.
+func NewSLO(window time.Duration, objectives ...Objective) *SLO {
+	if window <= 0 {
+		window = defaultSLOWindow
+	}
+	s := &SLO{
+		window:     window,
+		bucket:     window / sloBuckets,
+		objectives: make(map[string]Objective),
+		routes:     make(map[string]*sloWindow),
+		now:        time.Now,
+	}
+	if s.bucket <= 0 {
+		s.bucket = 1
+	}
+	for _, o := range objectives {
+		s.objectives[o.Route] = o
+	}
+	return s
+}
+
+// Count implements the Metrics interface. The counters are ignored.
+func (s *SLO) Count(name string, value int64, tags ...string) {}
+
+// Timing implements the Metrics interface, tracking the "request.duration"
+// timers tagged with the route and the status.
+func (s *SLO) Timing(name string, d time.Duration, tags ...string) {
+	if name != "request.duration" {
+		return
+	}
+	var route string
+	var status int
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, "route:") {
+			route = tag[len("route:"):]
+		} else if strings.HasPrefix(tag, "status:") {
+			status, _ = strconv.Atoi(tag[len("status:"):])
+		}
+	}
+	o, ok := s.objective(route)
+	if !ok {
+		return
+	}
+	good := status < 500 && (o.Latency <= 0 || d <= o.Latency)
+	epoch := s.now().UnixNano() / int64(s.bucket)
+	s.mu.Lock()
+	w, ok := s.routes[route]
+	if !ok {
+		w = &sloWindow{}
+		s.routes[route] = w
+	}
+	b := &w.buckets[epoch%sloBuckets]
+	if b.epoch != epoch {
+		*b = sloBucket{epoch: epoch}
+	}
+	b.total++
+	if good {
+		b.good++
+	}
+	s.mu.Unlock()
+}
+
+func (s *SLO) objective(route string) (Objective, bool) {
+	if o, ok := s.objectives[route]; ok {
+		return o, true
+	}
+	o, ok := s.objectives[""]
+	return o, ok
+}
+
+// Status returns the status of the objectives of the routes with requests
+// in the window, sorted by route.
+func (s *SLO) Status() []SLOStatus {
+	epoch := s.now().UnixNano() / int64(s.bucket)
+	s.mu.Lock()
+	statuses := make([]SLOStatus, 0, len(s.routes))
+	for route, w := range s.routes {
+		o, _ := s.objective(route)
+		o.Route = route
+		status := SLOStatus{Objective: o}
+		var shortTotal, shortGood int64
+		for _, b := range w.buckets {
+			if age := epoch - b.epoch; age >= 0 && age < sloBuckets {
+				status.Total += b.total
+				status.Good += b.good
+				if age < sloShortBuckets {
+					shortTotal += b.total
+					shortGood += b.good
+				}
+			}
+		}
+		if status.Total == 0 {
+			continue
+		}
+		status.SuccessRate = float64(status.Good) / float64(status.Total)
+		status.BurnRate = burnRate(status.Total, status.Good, o.Success)
+		status.ShortBurnRate = burnRate(shortTotal, shortGood, o.Success)
+		status.Budget = 1 - status.BurnRate
+		status.Met = status.SuccessRate >= o.Success
+		statuses = append(statuses, status)
+	}
+	s.mu.Unlock()
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Route < statuses[j].Route })
+	return statuses
+}
+
+// burnRate returns the ratio of the error rate to the error budget.
+func burnRate(total, good int64, success float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	errorRate := float64(total-good) / float64(total)
+	budget := 1 - success
+	if budget <= 0 {
+		if errorRate > 0 {
+			return float64(total - good)
+		}
+		return 0
+	}
+	return errorRate / budget
+}
+
+// Export exports the "slo.success_rate", "slo.burn_rate",
+// "slo.short_burn_rate" and "slo.budget" gauges of the routes to the
+// metrics implementing Gauger, to be called periodically.
+func (s *SLO) Export(metrics Metrics) {
+	g, ok := metrics.(Gauger)
+	if !ok {
+		return
+	}
+	for _, status := range s.Status() {
+		tag := "route:" + status.Route
+		g.Gauge("slo.success_rate", status.SuccessRate, tag)
+		g.Gauge("slo.burn_rate", status.BurnRate, tag)
+		g.Gauge("slo.short_burn_rate", status.ShortBurnRate, tag)
+		g.Gauge("slo.budget", status.Budget, tag)
+	}
+}
+
+// ServeHTTP replies to the request with the JSON summary of the status.
+func (s *SLO) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, http.StatusOK, map[string]interface{}{
+		"window":     s.window.String(),
+		"objectives": s.Status(),
+	})
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testGauges struct {
+	testMetrics
+	gauges map[string]float64
+}
+
+func (m *testGauges) Gauge(name string, value float64, tags ...string) {
+	m.gauges[name+" "+tags[0]] = value
+}
+
+func TestSLO(t *testing.T) {
+	now := time.Unix(3600, 0)
+	slo := NewSLO(time.Hour,
+		Objective{Success: 0.9},
+		Objective{Route: "/slow", Success: 0.5, Latency: 10 * time.Millisecond},
+	)
+	slo.now = func() time.Time { return now }
+	gauges := &testGauges{gauges: make(map[string]float64)}
+	m := NewMux()
+	m.SetMetrics(MultiMetrics(slo, gauges))
+	m.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		if m.Params(r)["id"] == "0" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}).GET()
+	m.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}).GET()
+	serve := func(path string) {
+		m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	for i := 0; i < 8; i++ {
+		serve("/users/1")
+	}
+	now = now.Add(50 * time.Minute)
+	serve("/users/0")
+	serve("/users/0")
+	serve("/missing")
+	slo.Timing("request.duration", 20*time.Millisecond, "route:/slow", "status:200")
+	slo.Timing("request.duration", time.Millisecond, "route:/slow", "status:200")
+	slo.Timing("request.count", time.Millisecond, "route:/slow", "status:200")
+	slo.Count("requests", 1)
+	if len(gauges.counts) != 11 {
+		t.Error(len(gauges.counts))
+	}
+	statuses := slo.Status()
+	if len(statuses) != 3 {
+		t.Fatal(statuses)
+	}
+	missing, slow, users := statuses[0], statuses[1], statuses[2]
+	if missing.Route != "/missing" || missing.Total != 1 || missing.Good != 1 || !missing.Met || missing.BurnRate != 0 {
+		t.Error(missing)
+	}
+	if slow.Total != 2 || slow.Good != 1 || slow.BurnRate != 1 || !slow.Met || slow.Latency != 10*time.Millisecond {
+		t.Error(slow)
+	}
+	if users.Route != "/users/:id" || users.Total != 10 || users.Good != 8 || users.Met ||
+		math.Abs(users.BurnRate-2) > 1e-9 || math.Abs(users.ShortBurnRate-10) > 1e-9 || math.Abs(users.Budget+1) > 1e-9 {
+		t.Error(users)
+	}
+	slo.Export(gauges)
+	if v := gauges.gauges["slo.burn_rate route:/users/:id"]; math.Abs(v-2) > 1e-9 {
+		t.Error(gauges.gauges)
+	}
+	slo.Export(&testMetrics{})
+
+	w := httptest.NewRecorder()
+	slo.ServeHTTP(w, httptest.NewRequest("GET", "/slo", nil))
+	var summary struct {
+		Window     string      `json:"window"`
+		Objectives []SLOStatus `json:"objectives"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &summary) != nil || summary.Window != "1h0m0s" || len(summary.Objectives) != 3 {
+		t.Error(w.Code, w.Body.String())
+	}
+
+	now = now.Add(55 * time.Minute)
+	if statuses := slo.Status(); len(statuses) != 3 || statuses[2].Total != 2 || statuses[2].ShortBurnRate != 0 {
+		t.Error(statuses)
+	}
+	now = now.Add(time.Hour)
+	if statuses := slo.Status(); len(statuses) != 0 {
+		t.Error(statuses)
+	}
+}
+
+func TestSLOWithoutDefault(t *testing.T) {
+	slo := NewSLO(0, Objective{Route: "/a", Success: 1})
+	slo.Timing("request.duration", time.Millisecond, "route:/b", "status:200")
+	slo.Timing("request.duration", time.Millisecond, "route:/a", "status:500")
+	slo.Timing("request.duration", time.Millisecond, "route:/a", "status:200")
+	statuses := slo.Status()
+	if len(statuses) != 1 || statuses[0].Route != "/a" || statuses[0].BurnRate != 1 || statuses[0].Met {
+		t.Error(statuses)
+	}
+	if slo.window != time.Hour {
+		t.Error(slo.window)
+	}
+}
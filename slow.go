@@ -0,0 +1,58 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// SlowClients configures the detection of the clients consuming the
+// responses slowly, which hold the connections and the buffered responses.
+type SlowClients struct {
+	// Stall is the duration of a write to the connection after which
+	// the client is slow. Zero disables the detection.
+	Stall time.Duration
+	// Abort limits each write to the Stall, so the connections of the slow
+	// clients fail with a write timeout and are closed.
+	Abort bool
+	// OnSlow is optionally called after the response to a slow client,
+	// with the total write stall of the response, so that the client can
+	// be deprioritized, for example by closing the connection.
+	OnSlow func(conn net.Conn, req *http.Request, stall time.Duration)
+}
+
+// SetSlowClients sets the detection of the slow clients. It should be
+// called before serving.
+func (m *Rum) SetSlowClients(s SlowClients) {
+	m.slowClients = s
+}
+
+// SlowClients returns the number of the responses to the slow clients.
+func (m *Rum) SlowClients() uint64 {
+	return atomic.LoadUint64(&m.slow)
+}
+
+// WriteStall returns the total duration of the writes to the connections
+// measured by the slow client detection.
+func (m *Rum) WriteStall() time.Duration {
+	return time.Duration(atomic.LoadInt64(&m.writeStall))
+}
+
+// slowClient records the write stall of the response and reports the slow client.
+func (m *Rum) slowClient(c *writeConn, req *http.Request) {
+	if c.stallLimit <= 0 {
+		return
+	}
+	atomic.AddInt64(&m.writeStall, int64(c.stall))
+	if !c.slow {
+		return
+	}
+	atomic.AddUint64(&m.slow, 1)
+	if m.slowClients.OnSlow != nil {
+		m.slowClients.OnSlow(c.Conn, req, c.stall)
+	}
+}
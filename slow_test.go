@@ -0,0 +1,100 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSlowClients(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	m.SetErrorLog(log.New(ioutil.Discard, "", 0))
+	var stalled int64
+	m.SetSlowClients(SlowClients{
+		Stall: time.Millisecond * 20,
+		Abort: true,
+		OnSlow: func(conn net.Conn, req *http.Request, stall time.Duration) {
+			atomic.StoreInt64(&stalled, int64(stall))
+		},
+	})
+	chunk := bytes.Repeat([]byte("a"), 1<<16)
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	})
+	m.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 1024; i++ {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	})
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	if m.SlowClients() != 0 {
+		t.Error(m.SlowClients())
+	}
+	conn, err := net.Dial("tcp", "127.0.0.1"+addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("GET /large HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	for i := 0; i < 100 && m.SlowClients() == 0; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	conn.Close()
+	if m.SlowClients() != 1 || m.WriteErrors() != 1 || atomic.LoadInt64(&stalled) < int64(time.Millisecond*20) {
+		t.Error(m.SlowClients(), m.WriteErrors(), time.Duration(atomic.LoadInt64(&stalled)))
+	}
+	if m.WriteStall() < time.Millisecond*20 {
+		t.Error(m.WriteStall())
+	}
+	m.Close()
+	<-done
+}
+
+func TestSlowWriteConn(t *testing.T) {
+	m := New()
+	m.SetSlowClients(SlowClients{Stall: time.Millisecond * 10})
+	client, server := net.Pipe()
+	c := m.trackConn(server).(*writeConn)
+	c.begin(time.Time{})
+	go func() {
+		b := make([]byte, 5)
+		time.Sleep(time.Millisecond * 20)
+		client.Read(b)
+		client.Read(b)
+	}()
+	if n, err := c.Write([]byte("Hello")); n != 5 || err != nil {
+		t.Error(n, err)
+	}
+	if !c.slow || c.stall < time.Millisecond*10 {
+		t.Error(c.slow, c.stall)
+	}
+	c.begin(time.Time{})
+	if n, err := c.Write([]byte("World")); n != 5 || err != nil || c.slow {
+		t.Error(n, err, c.slow)
+	}
+	c.begin(time.Now().Add(-time.Second))
+	if !c.deadline.IsZero() {
+		t.Error(c.deadline)
+	}
+	go client.Read(make([]byte, 5))
+	if n, err := c.Write([]byte("Hello")); n != 5 || err != nil {
+		t.Error(n, err)
+	}
+	client.Close()
+}
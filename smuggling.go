@@ -0,0 +1,109 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+)
+
+// ErrAmbiguousRequest is the error of reading a request whose framing is
+// ambiguous, such as both a Content-Length and a Transfer-Encoding, the
+// differing Content-Lengths or the obsolete line folding, which may be
+// exploited to smuggle requests. The request is replied with 400 Bad
+// Request and the connection is closed.
+var ErrAmbiguousRequest = errors.New("Ambiguous Request")
+
+// checkFastRequest validates the head of the request buffered by the
+// reader for the fast request parser, which only frames the bodies by the
+// Content-Length. It returns an error if the request is ambiguous, and
+// false if the request must be read by the net/http request parser: with
+// a Transfer-Encoding, or a head not fitting in the buffer.
+func checkFastRequest(reader *bufio.Reader) (bool, error) {
+	b, complete := peekHead(reader)
+	if !complete {
+		return false, nil
+	}
+	i := bytes.IndexByte(b, '\n')
+	if !validRequestLine(bytes.TrimSuffix(b[:i], []byte("\r"))) {
+		return false, ErrAmbiguousRequest
+	}
+	var contentLength []byte
+	var transferEncoding bool
+	for b = b[i+1:]; len(b) > 0; {
+		i := bytes.IndexByte(b, '\n')
+		line := b[:i]
+		b = b[i+1:]
+		if n := len(line); n > 0 && line[n-1] == '\r' {
+			line = line[:n-1]
+		}
+		if len(line) == 0 {
+			break
+		}
+		if line[0] == ' ' || line[0] == '\t' || bytes.IndexByte(line, '\r') >= 0 {
+			return false, ErrAmbiguousRequest
+		}
+		colon := bytes.IndexByte(line, ':')
+		if colon <= 0 || !validFieldName(line[:colon]) {
+			return false, ErrAmbiguousRequest
+		}
+		name, value := line[:colon], bytes.Trim(line[colon+1:], " \t")
+		switch {
+		case bytes.EqualFold(name, []byte("Content-Length")):
+			if !validContentLength(value) || contentLength != nil && !bytes.Equal(contentLength, value) {
+				return false, ErrAmbiguousRequest
+			}
+			contentLength = value
+		case bytes.EqualFold(name, []byte("Transfer-Encoding")):
+			transferEncoding = true
+		}
+	}
+	if transferEncoding {
+		if contentLength != nil {
+			return false, ErrAmbiguousRequest
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// validRequestLine reports whether the request line is a method, a target
+// and an HTTP/1 version separated by single spaces.
+func validRequestLine(line []byte) bool {
+	first := bytes.IndexByte(line, ' ')
+	last := bytes.LastIndexByte(line, ' ')
+	if first <= 0 || last <= first+1 || !validFieldName(line[:first]) {
+		return false
+	}
+	target := line[first+1 : last]
+	return bytes.IndexByte(target, ' ') < 0 && bytes.IndexByte(target, '\t') < 0 &&
+		bytes.HasPrefix(line[last+1:], []byte("HTTP/1."))
+}
+
+// validFieldName reports whether the name is a token (RFC 7230, section 3.2.6).
+func validFieldName(name []byte) bool {
+	for _, c := range name {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case bytes.IndexByte([]byte("!#$%&'*+-.^_`|~"), c) >= 0:
+		default:
+			return false
+		}
+	}
+	return len(name) > 0
+}
+
+// validContentLength reports whether the value is a decimal Content-Length.
+func validContentLength(value []byte) bool {
+	if len(value) == 0 || len(value) > 18 {
+		return false
+	}
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckFastRequest(t *testing.T) {
+	tests := []struct {
+		request string
+		fast    bool
+		err     error
+	}{
+		{"GET / HTTP/1.1\r\nHost: a\r\n\r\n", true, nil},
+		{"POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 5\r\nContent-Length: 5\r\n\r\nhello", true, nil},
+		{"POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n", false, nil},
+		{"POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 5\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n", false, ErrAmbiguousRequest},
+		{"POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 5\r\nContent-Length: 6\r\n\r\nhello", false, ErrAmbiguousRequest},
+		{"POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 0x5\r\n\r\nhello", false, ErrAmbiguousRequest},
+		{"POST / HTTP/1.1\r\nHost: a\r\nContent-Length: -1\r\n\r\n", false, ErrAmbiguousRequest},
+		{"GET / HTTP/1.1\r\nHost: a\r\nX-Folded: a\r\n b\r\n\r\n", false, ErrAmbiguousRequest},
+		{"POST / HTTP/1.1\r\nHost: a\r\nContent-Length : 5\r\n\r\nhello", false, ErrAmbiguousRequest},
+		{"GET / HTTP/1.1\r\nHost: a\r\nInvalid\r\n\r\n", false, ErrAmbiguousRequest},
+		{"GET  / HTTP/1.1\r\nHost: a\r\n\r\n", false, ErrAmbiguousRequest},
+		{"GET / FTP/1.1\r\nHost: a\r\n\r\n", false, ErrAmbiguousRequest},
+		{"GET / HTTP/1.1\r\nHost: a\r\nX-Long: " + strings.Repeat("x", 4096) + "\r\n\r\n", false, nil},
+	}
+	for _, test := range tests {
+		fast, err := checkFastRequest(bufio.NewReader(strings.NewReader(test.request)))
+		if fast != test.fast || err != test.err {
+			t.Errorf("%q %v %v", test.request, fast, err)
+		}
+	}
+}
+
+func TestSmugglingServe(t *testing.T) {
+	m := New()
+	m.SetFast(true)
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	})
+	addr := ":8080"
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	for _, test := range []struct {
+		request string
+		status  int
+		body    string
+	}{
+		{"POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\nConnection: close\r\n\r\n5\r\nhello\r\n0\r\n\r\n", http.StatusOK, "hello"},
+		{"POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 5\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\nGET /admin HTTP/1.1\r\n\r\n", http.StatusBadRequest, "400 Bad Request\n"},
+	} {
+		conn, err := net.Dial("tcp", "127.0.0.1"+addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write([]byte(test.request))
+		reader := bufio.NewReader(conn)
+		res, err := http.ReadResponse(reader, nil)
+		if err != nil {
+			t.Error(err)
+		} else {
+			body, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			if res.StatusCode != test.status || string(body) != test.body {
+				t.Error(res.Status, string(body))
+			}
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if _, err := http.ReadResponse(reader, nil); err == nil {
+			t.Error("the connection should be closed")
+		}
+		conn.Close()
+	}
+	m.Close()
+	<-done
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"mime"
+	"net/http"
+)
+
+// SetNoSniff disables the automatic Content-Type detection on the first
+// write, so that the responses without a Content-Type set by the handler
+// have no Content-Type header.
+func (m *Rum) SetNoSniff(noSniff bool) {
+	m.noSniff = noSniff
+}
+
+// SetSniffer sets the function detecting the Content-Type of the responses
+// from the data of the first write when the handler has not set it.
+// If the function returns an empty string, no Content-Type is set.
+// A nil function restores the default detection.
+func (m *Rum) SetSniffer(sniffer func(data []byte) string) {
+	m.sniffer = sniffer
+}
+
+// ContentTypeIs reports whether the Content-Type of the header has the media
+// type, ignoring the parameters and the case.
+func ContentTypeIs(header http.Header, mediaType string) bool {
+	values, ok := header["Content-Type"]
+	if !ok || len(values) == 0 {
+		return false
+	}
+	t, _, err := mime.ParseMediaType(values[0])
+	if err != nil {
+		return false
+	}
+	want, _, err := mime.ParseMediaType(mediaType)
+	return err == nil && t == want
+}
+
+type sniffResponseWriter struct {
+	forwarder
+	sniffer     func(data []byte) string
+	code        int
+	wroteHeader bool
+}
+
+func (w *sniffResponseWriter) WriteHeader(code int) {
+	if informational(code) {
+		w.ResponseWriter.WriteHeader(code)
+	} else if w.code == 0 {
+		w.code = code
+	}
+}
+
+// writeHeader detects the Content-Type from the data of the first write
+// and writes the header.
+func (w *sniffResponseWriter) writeHeader(data []byte) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	header := w.ResponseWriter.Header()
+	if len(header["Content-Type"]) == 0 {
+		if ctype := w.sniffer(data); ctype != "" {
+			header.Set("Content-Type", ctype)
+		} else {
+			header["Content-Type"] = nil
+		}
+	}
+	if w.code != 0 {
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+}
+
+func (w *sniffResponseWriter) Write(p []byte) (int, error) {
+	w.writeHeader(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// finish writes the header if the handler has not written the body.
+func (w *sniffResponseWriter) finish() {
+	w.writeHeader(nil)
+}
+
+// Flush implements the http.Flusher interface.
+func (w *sniffResponseWriter) Flush() {
+	w.writeHeader(nil)
+	w.forwarder.Flush()
+}
+
+// FlushError implements the error-returning Flush.
+func (w *sniffResponseWriter) FlushError() error {
+	w.writeHeader(nil)
+	return Flush(w.ResponseWriter)
+}
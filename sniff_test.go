@@ -0,0 +1,81 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestContentTypeIs(t *testing.T) {
+	header := http.Header{}
+	if ContentTypeIs(header, "text/plain") {
+		t.Error()
+	}
+	header.Set("Content-Type", "Application/JSON; charset=utf-8")
+	if !ContentTypeIs(header, "application/json") || ContentTypeIs(header, "text/plain") {
+		t.Error()
+	}
+	header.Set("Content-Type", ";")
+	if ContentTypeIs(header, "application/json") {
+		t.Error()
+	}
+}
+
+func TestSniff(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html></html>"))
+	})
+	m.HandleFunc("/typed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	})
+	m.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("<html></html>"))
+	})
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	contentType := func(path string) (string, int) {
+		resp, err := client.Get("http://" + addr + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.Header.Get("Content-Type"), resp.StatusCode
+	}
+	if ctype, _ := contentType("/"); ctype != "text/html; charset=utf-8" {
+		t.Error(ctype)
+	}
+	m.SetNoSniff(true)
+	if ctype, _ := contentType("/"); ctype != "" {
+		t.Error(ctype)
+	}
+	if ctype, _ := contentType("/typed"); ctype != "application/json" {
+		t.Error(ctype)
+	}
+	m.SetNoSniff(false)
+	m.SetSniffer(func(data []byte) string {
+		if len(data) > 0 && data[0] == '<' {
+			return "application/xml"
+		}
+		return ""
+	})
+	if ctype, code := contentType("/status"); ctype != "application/xml" || code != http.StatusCreated {
+		t.Error(ctype, code)
+	}
+	if ctype, _ := contentType("/typed"); ctype != "application/json" {
+		t.Error(ctype)
+	}
+	m.Close()
+	<-done
+}
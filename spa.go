@@ -0,0 +1,50 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package rum
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// SPA serves the files of the fsys under the prefix as StaticFS, and the
+// index file, such as "index.html", for the unknown paths without a file
+// extension, so the client-side routes of a single-page app are served by
+// the app. The paths of the groups of the Mux, such as an API group, and
+// the unknown files with an extension reply 404 Not Found. The index is
+// served with Cache-Control: no-cache, so a new deployment is picked up.
+//
+//	m.Group("/api", api)
+//	m.SPA("/", dist, "index.html")
+func (m *Mux) SPA(prefix string, fsys fs.FS, index string) (*Entry, error) {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	s, err := newStaticFS(fsys, Static{Index: index})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.files[index]; !ok {
+		return nil, &fs.PathError{Op: "open", Path: index, Err: fs.ErrNotExist}
+	}
+	s.prefix = m.replace(m.group + prefix)
+	s.fallback = index
+	s.exclude = m.inGroup
+	return m.HandlePrefix(prefix, s).GET().HEAD(), nil
+}
+
+// inGroup reports whether the path is under a group of the Mux.
+func (m *Mux) inGroup(path string) bool {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+	for group := range m.groups {
+		if path == group || strings.HasPrefix(path, strings.TrimSuffix(group, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
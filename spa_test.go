@@ -0,0 +1,59 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSPA(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":    {Data: []byte("<div id=app></div>")},
+		"assets/app.js": {Data: []byte("console.log(1)")},
+	}
+	m := NewMux()
+	m.Group("/api", func(m *Mux) {
+		m.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("users"))
+		}).GET()
+	})
+	if _, err := m.SPA("/", fsys, "index.html"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		target string
+		code   int
+		body   string
+	}{
+		{"/", http.StatusOK, "<div id=app></div>"},
+		{"/assets/app.js", http.StatusOK, "console.log(1)"},
+		{"/users/8/settings", http.StatusOK, "<div id=app></div>"},
+		{"/assets/missing.js", http.StatusNotFound, ""},
+		{"/api/users", http.StatusOK, "users"},
+		{"/api/missing", http.StatusNotFound, ""},
+		{"/api", http.StatusNotFound, ""},
+		{"/apiary", http.StatusOK, "<div id=app></div>"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", test.target, nil))
+		if w.Code != test.code || test.body != "" && w.Body.String() != test.body {
+			t.Error(test.target, w.Code, w.Body.String())
+		}
+	}
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/dashboard", nil))
+	if w.Header().Get("Cache-Control") != "no-cache" || w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Error(w.Header())
+	}
+	if _, err := NewMux().SPA("/app", fsys, "missing.html"); err == nil {
+		t.Error()
+	}
+}
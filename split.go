@@ -0,0 +1,82 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+)
+
+type splitter struct {
+	alternate http.Handler
+	percent   float64
+	key       func(r *http.Request) string
+}
+
+// alternates reports whether the request is served by the alternate handler.
+func (s *splitter) alternates(r *http.Request) bool {
+	if s.percent <= 0 {
+		return false
+	} else if s.percent >= 100 {
+		return true
+	}
+	var key string
+	if s.key != nil {
+		key = s.key(r)
+	}
+	var n uint64
+	if key == "" {
+		n = uint64(rand.Int63())
+	} else {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		n = mix64(h.Sum64())
+	}
+	return float64(n%10000) < s.percent*100
+}
+
+// SplitHandler returns a handler serving the percent of the requests with
+// the alternate handler and the others with the handler, for the canary
+// releases and the A/B tests. The requests with the same key, such as
+// HashCookie("session") or HashHeader("X-User"), are served by the same
+// handler. If the key function is nil or returns an empty string, the
+// requests are split at random.
+func SplitHandler(handler, alternate http.Handler, percent float64, key func(r *http.Request) string) http.Handler {
+	s := &splitter{alternate: alternate, percent: percent, key: key}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.alternates(r) {
+			alternate.ServeHTTP(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Split serves the percent of the requests of the entry with the alternate
+// handler, as SplitHandler does. It can be called again at runtime to change
+// the percent, and a zero percent serves all of the requests with the
+// registered handlers again.
+func (entry *Entry) Split(alternate http.Handler, percent float64, key func(r *http.Request) string) *Entry {
+	entry.split.Store(&splitter{alternate: alternate, percent: percent, key: key})
+	return entry
+}
+
+// splitHandler returns the alternate handler if the request is split to it.
+func (entry *Entry) splitHandler(r *http.Request) http.Handler {
+	if s, ok := entry.split.Load().(*splitter); ok && s.alternates(r) {
+		return s.alternate
+	}
+	return nil
+}
+
+// HashCookie returns a function returning the value of the named cookie.
+func HashCookie(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if c, err := r.Cookie(name); err == nil {
+			return c.Value
+		}
+		return ""
+	}
+}
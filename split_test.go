@@ -0,0 +1,79 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	m := NewMux()
+	hello := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		})
+	}
+	entry := m.Handle("/hello", hello("stable")).GET()
+	serve := func(user string) string {
+		req := httptest.NewRequest("GET", "/hello", nil)
+		if user != "" {
+			req.AddCookie(&http.Cookie{Name: "session", Value: user})
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+	entry.Split(hello("canary"), 20, HashCookie("session"))
+	canary := 0
+	for i := 0; i < 1000; i++ {
+		user := "user" + strconv.Itoa(i)
+		result := serve(user)
+		if serve(user) != result {
+			t.Error(user)
+		}
+		if result == "canary" {
+			canary++
+		}
+	}
+	if canary < 150 || canary > 250 {
+		t.Error(canary)
+	}
+	canary = 0
+	for i := 0; i < 1000; i++ {
+		if serve("") == "canary" {
+			canary++
+		}
+	}
+	if canary < 150 || canary > 250 {
+		t.Error(canary)
+	}
+	entry.Split(hello("canary"), 100, nil)
+	if result := serve(""); result != "canary" {
+		t.Error(result)
+	}
+	entry.Split(nil, 0, nil)
+	if result := serve("user1"); result != "stable" {
+		t.Error(result)
+	}
+}
+
+func TestSplitHandler(t *testing.T) {
+	handler := SplitHandler(http.NotFoundHandler(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("alternate"))
+	}), 50, HashHeader("X-User"))
+	counts := map[int]int{}
+	for i := 0; i < 100; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-User", strconv.Itoa(i))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		counts[w.Code]++
+	}
+	if counts[http.StatusOK] < 30 || counts[http.StatusNotFound] < 30 {
+		t.Error(counts)
+	}
+}
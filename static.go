@@ -0,0 +1,85 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	faviconMaxAge = 24 * time.Hour
+	robotsMaxAge  = time.Hour
+)
+
+// staticContent serves a content from memory.
+type staticContent struct {
+	name         string
+	content      []byte
+	contentType  string
+	etag         string
+	cacheControl string
+}
+
+// StaticContent returns a handler replying with the content from memory.
+// The Content-Type is set from the extension of the name, or sniffed from
+// the content. The strong ETag is computed once, and the Cache-Control
+// allows caching for the maxAge, or requires revalidation if zero.
+// The Range and the conditional requests are handled.
+func StaticContent(name string, content []byte, maxAge time.Duration) http.Handler {
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+	sum := sha256.Sum256(content)
+	cacheControl := "no-cache"
+	if maxAge > 0 {
+		cacheControl = "public, max-age=" + strconv.FormatInt(int64(maxAge/time.Second), 10)
+	}
+	return &staticContent{
+		name:         name,
+		content:      content,
+		contentType:  contentType,
+		etag:         `"` + hex.EncodeToString(sum[:16]) + `"`,
+		cacheControl: cacheControl,
+	}
+}
+
+func (s *staticContent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	header.Set("Content-Type", s.contentType)
+	header.Set("ETag", s.etag)
+	header.Set("Cache-Control", s.cacheControl)
+	http.ServeContent(w, r, s.name, time.Time{}, bytes.NewReader(s.content))
+}
+
+// Favicon registers the icon to be served from memory at /favicon.ico,
+// cached for a day.
+func (m *Mux) Favicon(icon []byte) *Entry {
+	return m.Handle("/favicon.ico", StaticContent("favicon.ico", icon, faviconMaxAge)).GET().HEAD()
+}
+
+// FaviconFile is like Favicon but reads the icon from the file once.
+func (m *Mux) FaviconFile(path string) (*Entry, error) {
+	icon, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return m.Handle("/favicon.ico", StaticContent(filepath.Base(path), icon, faviconMaxAge)).GET().HEAD(), nil
+}
+
+// Robots registers the content to be served from memory at /robots.txt,
+// cached for an hour.
+//
+//	m.Robots("User-agent: *\nDisallow: /admin/\n")
+func (m *Mux) Robots(content string) *Entry {
+	return m.Handle("/robots.txt", StaticContent("robots.txt", []byte(content), robotsMaxAge)).GET().HEAD()
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStaticContent(t *testing.T) {
+	h := StaticContent("app.js", []byte("console.log(1)"), 0)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/app.js", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "console.log(1)" || len(etag) != 34 ||
+		w.Header().Get("Cache-Control") != "no-cache" || w.Header().Get("Content-Type") == "" {
+		t.Error(w.Code, w.Body.String(), w.Header())
+	}
+	r := httptest.NewRequest("GET", "/app.js", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Error(w.Code)
+	}
+	r = httptest.NewRequest("GET", "/app.js", nil)
+	r.Header.Set("Range", "bytes=0-6")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "console" {
+		t.Error(w.Code, w.Body.String())
+	}
+}
+
+func TestFaviconRobots(t *testing.T) {
+	icon := []byte("\x00\x00\x01\x00icon")
+	m := NewMux()
+	m.Favicon(icon)
+	m.Robots("User-agent: *\nDisallow: /admin/\n")
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/favicon.ico", nil))
+	if w.Code != http.StatusOK || w.Body.String() != string(icon) ||
+		w.Header().Get("Cache-Control") != "public, max-age=86400" || w.Header().Get("Content-Type") != "image/vnd.microsoft.icon" && w.Header().Get("Content-Type") != "image/x-icon" {
+		t.Error(w.Code, w.Header())
+	}
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("HEAD", "/robots.txt", nil))
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("Content-Length") != "32" ||
+		w.Header().Get("Cache-Control") != "public, max-age=3600" || w.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Error(w.Code, w.Header())
+	}
+	dir, err := ioutil.TempDir("", "rum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "favicon.png")
+	ioutil.WriteFile(path, []byte("\x89PNG\r\n\x1a\npng"), 0644)
+	m = NewMux()
+	if _, err := m.FaviconFile(path); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/favicon.ico", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Error(w.Code, w.Header())
+	}
+	if _, err := m.FaviconFile(filepath.Join(dir, "missing.ico")); err == nil {
+		t.Error()
+	}
+}
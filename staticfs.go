@@ -0,0 +1,289 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package rum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// precompressed are the encodings of the precompressed siblings by preference.
+var precompressed = []struct {
+	encoding, ext string
+}{
+	{"br", ".br"},
+	{"zstd", ".zst"},
+	{"gzip", ".gz"},
+}
+
+// Static configures the serving of the files of StaticFS.
+type Static struct {
+	// Precompressed serves the .br, the .zst or the .gz sibling of a file,
+	// if any, to the clients accepting the encoding. The encoding of the
+	// highest quality in the Accept-Encoding is chosen, preferring br, then
+	// zstd, then gzip. Each sibling has its own ETag, and the responses of
+	// the files with siblings vary by Accept-Encoding.
+	Precompressed bool
+	// MaxAge is the max-age of the Cache-Control, or no-cache if zero.
+	MaxAge time.Duration
+	// Index is the file served for a directory, index.html if empty.
+	Index string
+	// Listing lists the directories without an index file.
+	Listing bool
+	// ListingTemplate optionally renders the listings, executed with a
+	// *DirListing. If nil, a default styled HTML page is rendered.
+	ListingTemplate *template.Template
+	// Hidden holds the path.Match patterns of the names of the files and
+	// the directories hidden from the listings and not served, such as ".*".
+	Hidden []string
+	// Access holds the access rules by directory, such as "private" or "."
+	// for the root. The rule of the deepest directory containing a path
+	// applies, and the request is rejected with 403 Forbidden if it returns false.
+	Access map[string]func(r *http.Request) bool
+}
+
+type staticFile struct {
+	modtime time.Time
+	size    int64
+	etag    string
+	// siblings holds the precompressed siblings by encoding.
+	siblings map[string]string
+}
+
+type staticFS struct {
+	fsys         fs.FS
+	prefix       string
+	options      Static
+	cacheControl string
+	files        map[string]*staticFile
+	dirs         map[string]bool
+	// fallback is served for the unknown paths not excluded, if not empty.
+	fallback string
+	exclude  func(path string) bool
+}
+
+// StaticFS serves the files of the fsys, such as an embed.FS, under the
+// prefix, for single-binary deployments. The files are walked and their
+// strong ETags are computed once at mount time, so the fsys should not
+// change afterwards. The Range and the conditional requests are handled.
+//
+//	//go:embed public
+//	var public embed.FS
+//
+//	sub, _ := fs.Sub(public, "public")
+//	m.StaticFS("/static/", sub, rum.Static{Precompressed: true, MaxAge: time.Hour})
+func (m *Mux) StaticFS(prefix string, fsys fs.FS, options Static) (*Entry, error) {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	s, err := newStaticFS(fsys, options)
+	if err != nil {
+		return nil, err
+	}
+	s.prefix = m.replace(m.group + prefix)
+	return m.HandlePrefix(prefix, s).GET().HEAD(), nil
+}
+
+func newStaticFS(fsys fs.FS, options Static) (*staticFS, error) {
+	if options.Index == "" {
+		options.Index = "index.html"
+	}
+	s := &staticFS{
+		fsys:         fsys,
+		options:      options,
+		cacheControl: "no-cache",
+		files:        make(map[string]*staticFile),
+		dirs:         make(map[string]bool),
+	}
+	if len(options.Access) > 0 {
+		access := make(map[string]func(r *http.Request) bool, len(options.Access))
+		for dir, rule := range options.Access {
+			access[cleanDir(dir)] = rule
+		}
+		s.options.Access = access
+	}
+	if options.MaxAge > 0 {
+		s.cacheControl = "public, max-age=" + strconv.FormatInt(int64(options.MaxAge/time.Second), 10)
+	}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			s.dirs[name] = true
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		etag, err := fileETag(fsys, name)
+		if err != nil {
+			return err
+		}
+		s.files[name] = &staticFile{modtime: info.ModTime(), size: info.Size(), etag: etag}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if options.Precompressed {
+		for name, file := range s.files {
+			for _, p := range precompressed {
+				if _, ok := s.files[name+p.ext]; ok {
+					if file.siblings == nil {
+						file.siblings = make(map[string]string)
+					}
+					file.siblings[p.encoding] = name + p.ext
+				}
+			}
+		}
+	}
+	return s, nil
+}
+
+func fileETag(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`, nil
+}
+
+// open returns the name of the file or the directory to serve for the
+// path, redirecting the directories without the trailing slash.
+func (s *staticFS) open(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(r.URL.Path, s.prefix)), "/")
+	if name == "" {
+		name = "."
+	}
+	if !s.dirs[name] {
+		return name, true
+	}
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		u := &url.URL{Path: r.URL.Path + "/", RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+		return "", false
+	}
+	return name, true
+}
+
+func (s *staticFS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.open(w, r)
+	if !ok {
+		return
+	}
+	if s.hidden(name) {
+		http.Error(w, "404 Not Found : "+r.URL.String(), http.StatusNotFound)
+		return
+	}
+	if !s.allowed(r, name) {
+		http.Error(w, "403 Forbidden : "+r.URL.String(), http.StatusForbidden)
+		return
+	}
+	if s.dirs[name] {
+		index := path.Join(name, s.options.Index)
+		if _, ok := s.files[index]; !ok && s.options.Listing {
+			s.list(w, r, name)
+			return
+		}
+		name = index
+	}
+	file, ok := s.files[name]
+	if !ok && s.fallback != "" && path.Ext(name) == "" && !s.exclude(r.URL.Path) {
+		name = s.fallback
+		file, ok = s.files[name]
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	if !ok {
+		http.Error(w, "404 Not Found : "+r.URL.String(), http.StatusNotFound)
+		return
+	}
+	s.serveFile(w, r, name, file)
+}
+
+func (s *staticFS) serveFile(w http.ResponseWriter, r *http.Request, name string, file *staticFile) {
+	header := w.Header()
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		header.Set("Content-Type", ctype)
+	}
+	if _, ok := header["Cache-Control"]; !ok {
+		header.Set("Cache-Control", s.cacheControl)
+	}
+	served, f := name, file
+	if len(file.siblings) > 0 {
+		header.Add("Vary", "Accept-Encoding")
+		if encoding := negotiateEncoding(r, file.siblings); encoding != "" {
+			header.Set("Content-Encoding", encoding)
+			served = file.siblings[encoding]
+			f = s.files[served]
+		}
+	}
+	header.Set("ETag", f.etag)
+	if etagMatch(r.Header.Get("If-None-Match"), f.etag) {
+		header.Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	rd, err := s.fsys.Open(served)
+	if err != nil {
+		http.Error(w, "500 Internal Server Error : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rd.Close()
+	if rs, ok := rd.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, name, f.modtime, rs)
+		return
+	}
+	ServeReader(w, r, name, f.modtime, rd, f.size)
+}
+
+// negotiateEncoding returns the encoding of the siblings of the highest
+// quality in the Accept-Encoding of the request, or the empty string for
+// the identity. The ties are broken by the preference of precompressed.
+func negotiateEncoding(r *http.Request, siblings map[string]string) string {
+	var best string
+	var bestQ float64
+	for _, p := range precompressed {
+		if _, ok := siblings[p.encoding]; !ok {
+			continue
+		}
+		if q := encodingQuality(r, p.encoding); q > bestQ {
+			best, bestQ = p.encoding, q
+		}
+	}
+	return best
+}
+
+// etagMatch reports whether the If-None-Match header matches the etag.
+func etagMatch(ifNoneMatch, etag string) bool {
+	for _, v := range strings.Split(ifNoneMatch, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == "*" || v == etag {
+			return true
+		}
+	}
+	return false
+}
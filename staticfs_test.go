@@ -0,0 +1,150 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestStaticFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":       {Data: []byte("<h1>home</h1>")},
+		"app.js":           {Data: []byte("console.log(1)")},
+		"app.js.gz":        {Data: []byte("gzipped")},
+		"app.js.br":        {Data: []byte("brotli")},
+		"docs/index.html":  {Data: []byte("<h1>docs</h1>")},
+		"docs/guide.txt":   {Data: []byte("guide")},
+		"images/empty.txt": {Data: []byte("")},
+	}
+	m := NewMux()
+	if _, err := m.StaticFS("/static", fsys, Static{Precompressed: true, MaxAge: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	m.Group("/api", func(m *Mux) {
+		m.StaticFS("/files/", fsys, Static{})
+	})
+	serve := func(target, acceptEncoding, ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", target, nil)
+		if acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		return w
+	}
+	w := serve("/static/app.js", "", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "console.log(1)" || len(etag) != 34 ||
+		w.Header().Get("Cache-Control") != "public, max-age=3600" || w.Header().Get("Vary") != "Accept-Encoding" ||
+		w.Header().Get("Content-Encoding") != "" {
+		t.Error(w.Code, w.Body.String(), w.Header())
+	}
+	if w := serve("/static/app.js", "", etag); w.Code != http.StatusNotModified {
+		t.Error(w.Code)
+	}
+	w = serve("/static/app.js", "gzip, deflate", "")
+	if w.Body.String() != "gzipped" || w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("ETag") == etag {
+		t.Error(w.Body.String(), w.Header())
+	}
+	if w := serve("/static/app.js", "gzip, br", ""); w.Body.String() != "brotli" || w.Header().Get("Content-Encoding") != "br" {
+		t.Error(w.Body.String(), w.Header())
+	}
+	if w := serve("/static/app.js", "br;q=0, gzip;q=0.5", ""); w.Body.String() != "gzipped" {
+		t.Error(w.Body.String())
+	}
+	if w := serve("/static/", "", ""); w.Code != http.StatusOK || w.Body.String() != "<h1>home</h1>" {
+		t.Error(w.Code, w.Body.String())
+	}
+	if w := serve("/static/docs", "", ""); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/static/docs/" {
+		t.Error(w.Code, w.Header())
+	}
+	if w := serve("/static/docs/", "", ""); w.Body.String() != "<h1>docs</h1>" {
+		t.Error(w.Body.String())
+	}
+	if w := serve("/static/images/", "", ""); w.Code != http.StatusNotFound {
+		t.Error(w.Code)
+	}
+	if w := serve("/static/missing.js", "", ""); w.Code != http.StatusNotFound {
+		t.Error(w.Code)
+	}
+	if w := serve("/static/../static/docs/guide.txt", "", ""); w.Body.String() != "guide" {
+		t.Error(w.Code, w.Body.String())
+	}
+	w = serve("/api/files/app.js", "gzip", "")
+	if w.Body.String() != "console.log(1)" || w.Header().Get("Cache-Control") != "no-cache" || w.Header().Get("Vary") != "" {
+		t.Error(w.Body.String(), w.Header())
+	}
+	if _, err := m.StaticFS("/missing/", os.DirFS("/missing/rum"), Static{}); err == nil {
+		t.Error()
+	}
+}
+
+func TestStaticFSPrecompressed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":     {Data: []byte("console.log(1)")},
+		"app.js.gz":  {Data: []byte("gzipped")},
+		"app.js.br":  {Data: []byte("brotli")},
+		"app.js.zst": {Data: []byte("zstandard")},
+		"lib.js":     {Data: []byte("lib")},
+		"lib.js.zst": {Data: []byte("zstandard")},
+	}
+	m := NewMux()
+	if _, err := m.StaticFS("/", fsys, Static{Precompressed: true}); err != nil {
+		t.Fatal(err)
+	}
+	serve := func(target, acceptEncoding, ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", target, nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		return w
+	}
+	etags := make(map[string]string)
+	for _, test := range []struct {
+		acceptEncoding, encoding, body string
+	}{
+		{"", "", "console.log(1)"},
+		{"zstd, gzip", "zstd", "zstandard"},
+		{"gzip, zstd, br", "br", "brotli"},
+		{"gzip;q=1, br;q=0.5, zstd;q=0.8", "gzip", "gzipped"},
+		{"*", "br", "brotli"},
+		{"*;q=0.1, gzip", "gzip", "gzipped"},
+		{"br;q=0, zstd;q=0, *", "gzip", "gzipped"},
+		{"identity", "", "console.log(1)"},
+	} {
+		w := serve("/app.js", test.acceptEncoding, "")
+		if w.Body.String() != test.body || w.Header().Get("Content-Encoding") != test.encoding ||
+			w.Header().Get("Vary") != "Accept-Encoding" || w.Header().Get("Content-Type") != "text/javascript; charset=utf-8" {
+			t.Error(test.acceptEncoding, w.Body.String(), w.Header())
+		}
+		etag := w.Header().Get("ETag")
+		if other, ok := etags[etag]; ok && other != test.encoding {
+			t.Error("the ETag of", test.encoding, "is the one of", other)
+		}
+		etags[etag] = test.encoding
+		if w := serve("/app.js", test.acceptEncoding, etag); w.Code != http.StatusNotModified || w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Error(test.acceptEncoding, w.Code, w.Header())
+		}
+	}
+	if w := serve("/lib.js", "gzip, br", ""); w.Body.String() != "lib" || w.Header().Get("Content-Encoding") != "" {
+		t.Error(w.Body.String(), w.Header())
+	}
+	if w := serve("/lib.js", "zstd", ""); w.Body.String() != "zstandard" || w.Header().Get("Content-Encoding") != "zstd" {
+		t.Error(w.Body.String(), w.Header())
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStatsdFlushInterval = time.Second
+	// defaultStatsdPacketSize fits the UDP payload in an ethernet MTU.
+	defaultStatsdPacketSize = 1432
+)
+
+var statsdTagReplacer = strings.NewReplacer(",", "_", "|", "_", "\n", "_")
+
+// StatsdOptions represents the options of a Statsd exporter.
+type StatsdOptions struct {
+	// Prefix is prepended to the metric names, such as "app.".
+	Prefix string
+	// Tags are added to all metrics.
+	Tags []string
+	// DogStatsD enables the DogStatsD tags. The plain statsd protocol has no
+	// tags, so the tags are dropped if false.
+	DogStatsD bool
+	// FlushInterval is the interval of sending the metrics. If zero, one second is used.
+	FlushInterval time.Duration
+	// MaxPacketSize is the max size of a UDP packet batching the metrics.
+	// If zero, 1432 bytes is used.
+	MaxPacketSize int
+	// ErrorLog logs the errors of sending. If nil, the standard logger is used.
+	ErrorLog *log.Logger
+}
+
+// Statsd is a Metrics pushing the counters, the gauges and the timers to
+// a statsd or DogStatsD server over UDP.
+type Statsd struct {
+	opts     StatsdOptions
+	conn     net.Conn
+	mu       sync.Mutex
+	counters map[string]int64
+	gauges   map[string]float64
+	buf      []byte
+	done     chan struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewStatsd returns a Statsd exporter sending to the addr, such as "127.0.0.1:8125".
+func NewStatsd(addr string, opts *StatsdOptions) (*Statsd, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Statsd{conn: conn, counters: make(map[string]int64), gauges: make(map[string]float64), done: make(chan struct{})}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.FlushInterval <= 0 {
+		s.opts.FlushInterval = defaultStatsdFlushInterval
+	}
+	if s.opts.MaxPacketSize <= 0 {
+		s.opts.MaxPacketSize = defaultStatsdPacketSize
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+// Count adds the value to the counter of the name. The counters are
+// aggregated until flushed.
+func (s *Statsd) Count(name string, value int64, tags ...string) {
+	key := s.key(name, tags)
+	s.mu.Lock()
+	if !s.closed {
+		s.counters[key] += value
+	}
+	s.mu.Unlock()
+}
+
+// Gauge sets the gauge of the name to the value. The last value is sent
+// when flushed.
+func (s *Statsd) Gauge(name string, value float64, tags ...string) {
+	key := s.key(name, tags)
+	s.mu.Lock()
+	if !s.closed {
+		s.gauges[key] = value
+	}
+	s.mu.Unlock()
+}
+
+// Timing records the duration in milliseconds to the timer of the name.
+func (s *Statsd) Timing(name string, d time.Duration, tags ...string) {
+	ms := strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+	s.mu.Lock()
+	if !s.closed {
+		s.append(s.line(s.key(name, tags), ms, "ms"))
+	}
+	s.mu.Unlock()
+}
+
+// Flush sends the pending metrics.
+func (s *Statsd) Flush() {
+	s.mu.Lock()
+	s.flush()
+	s.mu.Unlock()
+}
+
+// Close flushes the pending metrics and closes the connection.
+func (s *Statsd) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	s.mu.Unlock()
+	s.wg.Wait()
+	s.Flush()
+	return s.conn.Close()
+}
+
+func (s *Statsd) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Flush()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// key returns the prefixed name with the sorted tags, such as "app.requests|#a:1,b:2".
+func (s *Statsd) key(name string, tags []string) string {
+	name = s.opts.Prefix + name
+	if !s.opts.DogStatsD || len(tags)+len(s.opts.Tags) == 0 {
+		return name
+	}
+	all := make([]string, 0, len(tags)+len(s.opts.Tags))
+	all = append(all, s.opts.Tags...)
+	all = append(all, tags...)
+	for i := range all {
+		all[i] = statsdTagReplacer.Replace(all[i])
+	}
+	sort.Strings(all)
+	return name + "|#" + strings.Join(all, ",")
+}
+
+// line formats the metric of the key as "name:value|type|#tags".
+func (s *Statsd) line(key, value, typ string) string {
+	name, tags := key, ""
+	if i := strings.Index(key, "|#"); i >= 0 {
+		name, tags = key[:i], key[i:]
+	}
+	return name + ":" + value + "|" + typ + tags
+}
+
+// append batches the line, sending the packet before it overflows.
+func (s *Statsd) append(line string) {
+	if len(s.buf) > 0 && len(s.buf)+1+len(line) > s.opts.MaxPacketSize {
+		s.send()
+	}
+	if len(s.buf) > 0 {
+		s.buf = append(s.buf, '\n')
+	}
+	s.buf = append(s.buf, line...)
+}
+
+func (s *Statsd) flush() {
+	keys := make([]string, 0, len(s.counters))
+	for key := range s.counters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s.append(s.line(key, strconv.FormatInt(s.counters[key], 10), "c"))
+	}
+	if len(keys) > 0 {
+		s.counters = make(map[string]int64)
+	}
+	keys = keys[:0]
+	for key := range s.gauges {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s.append(s.line(key, strconv.FormatFloat(s.gauges[key], 'f', -1, 64), "g"))
+	}
+	if len(keys) > 0 {
+		s.gauges = make(map[string]float64)
+	}
+	if len(s.buf) > 0 {
+		s.send()
+	}
+}
+
+func (s *Statsd) send() {
+	_, err := s.conn.Write(s.buf)
+	s.buf = s.buf[:0]
+	if err != nil {
+		if s.opts.ErrorLog != nil {
+			s.opts.ErrorLog.Printf("rum: statsd: %v", err)
+		} else {
+			log.Printf("rum: statsd: %v", err)
+		}
+	}
+}
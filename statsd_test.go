@@ -0,0 +1,105 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func listenStatsd(t *testing.T) (*net.UDPConn, func() []string) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func() []string {
+		buf := make([]byte, 65536)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(string(buf[:n]), "\n")
+	}
+	return conn, read
+}
+
+func TestStatsd(t *testing.T) {
+	conn, read := listenStatsd(t)
+	defer conn.Close()
+	s, err := NewStatsd(conn.LocalAddr().String(), &StatsdOptions{
+		Prefix:        "app.",
+		Tags:          []string{"env:test"},
+		DogStatsD:     true,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Count("requests", 1, "route:/a")
+	s.Count("requests", 2, "route:/a")
+	s.Count("requests", 1, "route:/b,c")
+	s.Timing("request.duration", 1500*time.Microsecond, "route:/a")
+	s.Flush()
+	lines := read()
+	expect := []string{
+		"app.request.duration:1.5|ms|#env:test,route:/a",
+		"app.requests:3|c|#env:test,route:/a",
+		"app.requests:1|c|#env:test,route:/b_c",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expect, "\n") {
+		t.Error(lines)
+	}
+	s.Count("requests", 1)
+	if err := s.Close(); err != nil {
+		t.Error(err)
+	}
+	if lines := read(); len(lines) != 1 || lines[0] != "app.requests:1|c|#env:test" {
+		t.Error(lines)
+	}
+	s.Count("requests", 1)
+	if err := s.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStatsdBatching(t *testing.T) {
+	conn, read := listenStatsd(t)
+	defer conn.Close()
+	s, err := NewStatsd(conn.LocalAddr().String(), &StatsdOptions{MaxPacketSize: 30, FlushInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for i := 0; i < 3; i++ {
+		s.Timing("latency", time.Millisecond, "dropped:tag")
+	}
+	if lines := read(); len(lines) != 2 || lines[0] != "latency:1|ms" {
+		t.Error(lines)
+	}
+	if lines := read(); len(lines) != 1 || lines[0] != "latency:1|ms" {
+		t.Error(lines)
+	}
+	if _, err := NewStatsd("missing:port", nil); err == nil {
+		t.Error()
+	}
+}
+
+func TestStatsdGauge(t *testing.T) {
+	conn, read := listenStatsd(t)
+	defer conn.Close()
+	s, err := NewStatsd(conn.LocalAddr().String(), &StatsdOptions{DogStatsD: true, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Gauge("slo.burn_rate", 2, "route:/a")
+	s.Gauge("slo.burn_rate", 0.5, "route:/a")
+	s.Flush()
+	if lines := read(); len(lines) != 1 || lines[0] != "slo.burn_rate:0.5|g|#route:/a" {
+		t.Error(lines)
+	}
+}
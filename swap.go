@@ -0,0 +1,39 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+)
+
+type swappedHandler struct {
+	handler http.Handler
+}
+
+// SetHandler atomically replaces the handler and the per-method handlers
+// of the entry at runtime, without re-registering the pattern, so that the
+// feature toggles and the canary handlers can flip while serving. The
+// methods of the entry are kept. A nil handler restores the registered
+// handlers.
+func (entry *Entry) SetHandler(handler http.Handler) *Entry {
+	entry.swapped.Store(&swappedHandler{handler: handler})
+	return entry
+}
+
+func (entry *Entry) swappedHandler() http.Handler {
+	if s, ok := entry.swapped.Load().(*swappedHandler); ok {
+		return s.handler
+	}
+	return nil
+}
+
+// allows reports whether the entry serves the method.
+func (entry *Entry) allows(method string) bool {
+	for i, m := range methods {
+		if m == method {
+			return entry.handlers[i] != nil
+		}
+	}
+	return false
+}
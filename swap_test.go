@@ -0,0 +1,53 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestSetHandler(t *testing.T) {
+	m := NewMux()
+	m.Problems()
+	hello := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		})
+	}
+	entry := m.Handle("/hello", hello("stable")).GET().POST()
+	test := func(method, result string, status int) {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(method, "/hello", nil))
+		if w.Code != status || status == http.StatusOK && w.Body.String() != result {
+			t.Error(method, w.Code, w.Body.String())
+		}
+	}
+	test("GET", "stable", http.StatusOK)
+	entry.SetHandler(hello("canary"))
+	test("GET", "canary", http.StatusOK)
+	test("POST", "canary", http.StatusOK)
+	test("PUT", "", http.StatusMethodNotAllowed)
+	entry.SetHandler(nil)
+	test("POST", "stable", http.StatusOK)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			entry.SetHandler(hello("canary"))
+		}()
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, httptest.NewRequest("GET", "/hello", nil))
+			if body := w.Body.String(); body != "stable" && body != "canary" {
+				t.Error(body)
+			}
+		}()
+	}
+	wg.Wait()
+}
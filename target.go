@@ -0,0 +1,49 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"strings"
+)
+
+// asteriskForm reports whether the request target is the asterisk-form
+// "*", which applies to the server as a whole (RFC 7230, section 5.3.4).
+func asteriskForm(r *http.Request) bool {
+	return r.URL.Path == "*" && r.URL.Scheme == "" && r.URL.Host == ""
+}
+
+// serveAsterisk replies to an "OPTIONS *" request with the methods served
+// by the routes of the Mux, and to the other methods with a 400 status code.
+func (m *Mux) serveAsterisk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "OPTIONS" {
+		http.Error(w, "400 Bad Request : asterisk-form target of "+r.Method, http.StatusBadRequest)
+		return
+	}
+	served := make(map[string]bool)
+	for _, route := range m.Routes() {
+		for _, method := range route.Methods {
+			served[method] = true
+		}
+	}
+	allowed := []string{"OPTIONS"}
+	for _, method := range methods {
+		if method != "OPTIONS" && served[method] {
+			allowed = append(allowed, method)
+		}
+	}
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusOK)
+}
+
+// absoluteForm replaces the Host of a request of the absolute-form target,
+// such as "GET http://example.com/ HTTP/1.1", by the host of the target,
+// which overrides the Host header (RFC 7230, section 5.4), as the net/http
+// request parser does.
+func absoluteForm(r *http.Request) {
+	if r.URL.Host != "" {
+		r.Host = r.URL.Host
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAsteriskForm(t *testing.T) {
+	m := NewMux()
+	m.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {}).GET().HEAD()
+	m.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {}).POST()
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("OPTIONS", "*", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Allow") != "OPTIONS, GET, HEAD, POST" || w.Body.Len() != 0 {
+		t.Error(w.Code, w.Header(), w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "*", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Error(w.Code)
+	}
+}
+
+func TestAbsoluteForm(t *testing.T) {
+	m := New()
+	m.SetFast(true)
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + " " + r.URL.Path))
+	})
+	m.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + " " + r.URL.Path))
+	})
+	req, _, err := m.readFastRequest(bufio.NewReader(strings.NewReader("GET http://example.com/a HTTP/1.1\r\nHost: other\r\n\r\n")), m)
+	if err != nil || req.Host != "example.com" {
+		t.Error(req, err)
+	}
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "example.com /" {
+		t.Error(w.Code, w.Body.String())
+	}
+	addr := ":8080"
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	for _, test := range []struct {
+		request string
+		status  string
+		body    string
+	}{
+		{"GET http://example.com/a HTTP/1.1\r\nHost: other\r\nConnection: close\r\n\r\n", "200", "example.com /a"},
+		{"OPTIONS * HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n", "200", ""},
+	} {
+		conn, err := net.Dial("tcp", "127.0.0.1"+addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write([]byte(test.request))
+		res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Error(err)
+		} else {
+			var body strings.Builder
+			bufio.NewReader(res.Body).WriteTo(&body)
+			res.Body.Close()
+			if !strings.HasPrefix(res.Status, test.status) || body.String() != test.body {
+				t.Error(test.request, res.Status, body.String())
+			}
+		}
+		conn.Close()
+	}
+	m.Close()
+	<-done
+}
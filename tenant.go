@@ -0,0 +1,115 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Tenants is a registry of independent Mux instances keyed by tenant.
+// It is an http.Handler that dispatches the request to the Mux of its
+// tenant. Tenants can be added and removed at runtime; the registry is
+// copied on write so that serving never blocks. The tenants are case
+// insensitive, as the hosts are.
+type Tenants struct {
+	mu      sync.Mutex
+	key     func(r *http.Request) string
+	tenants atomic.Value
+	// NotFound optionally handles the requests of unknown tenants.
+	NotFound http.Handler
+}
+
+// NewTenants returns a new Tenants with the function returning the tenant
+// of a request, such as HostTenant or HeaderTenant("X-Tenant").
+func NewTenants(key func(r *http.Request) string) *Tenants {
+	t := &Tenants{key: key}
+	t.tenants.Store(map[string]*Mux{})
+	return t
+}
+
+// HostTenant returns the lowercase host of the request without the port.
+func HostTenant(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// HeaderTenant returns a function returning the value of the named header.
+func HeaderTenant(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+func (t *Tenants) load() map[string]*Mux {
+	return t.tenants.Load().(map[string]*Mux)
+}
+
+// Add adds or replaces the Mux of the tenant.
+func (t *Tenants) Add(tenant string, m *Mux) {
+	tenant = strings.ToLower(tenant)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	old := t.load()
+	tenants := make(map[string]*Mux, len(old)+1)
+	for k, v := range old {
+		tenants[k] = v
+	}
+	tenants[tenant] = m
+	t.tenants.Store(tenants)
+}
+
+// Remove removes the tenant.
+func (t *Tenants) Remove(tenant string) {
+	tenant = strings.ToLower(tenant)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	old := t.load()
+	if _, ok := old[tenant]; !ok {
+		return
+	}
+	tenants := make(map[string]*Mux, len(old))
+	for k, v := range old {
+		if k != tenant {
+			tenants[k] = v
+		}
+	}
+	t.tenants.Store(tenants)
+}
+
+// Get returns the Mux of the tenant, or nil.
+func (t *Tenants) Get(tenant string) *Mux {
+	return t.load()[strings.ToLower(tenant)]
+}
+
+// Tenants returns the sorted tenants, in lowercase.
+func (t *Tenants) Tenants() []string {
+	tenants := t.load()
+	names := make([]string, 0, len(tenants))
+	for name := range tenants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServeHTTP dispatches the request to the Mux of its tenant.
+func (t *Tenants) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m, ok := t.load()[strings.ToLower(t.key(r))]; ok {
+		m.ServeHTTP(w, r)
+		return
+	}
+	if t.NotFound != nil {
+		t.NotFound.ServeHTTP(w, r)
+		return
+	}
+	http.Error(w, "404 Not Found : "+r.URL.String(), http.StatusNotFound)
+}
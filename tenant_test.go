@@ -0,0 +1,72 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testTenantMux(name string) *Mux {
+	m := NewMux()
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	})
+	return m
+}
+
+func TestTenants(t *testing.T) {
+	tenants := NewTenants(HostTenant)
+	tenants.Add("a.example.com", testTenantMux("a"))
+	tenants.Add("B.Example.com", testTenantMux("b"))
+	if names := tenants.Tenants(); len(names) != 2 || names[0] != "a.example.com" || names[1] != "b.example.com" {
+		t.Error(names)
+	}
+	tests := []struct {
+		host   string
+		status int
+		body   string
+	}{
+		{"A.example.com:8080", http.StatusOK, "a"},
+		{"b.example.com", http.StatusOK, "b"},
+		{"c.example.com", http.StatusNotFound, "404 Not Found : /\n"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Host = test.host
+		w := httptest.NewRecorder()
+		tenants.ServeHTTP(w, r)
+		if w.Code != test.status || w.Body.String() != test.body {
+			t.Error(test.host, w.Code, w.Body.String())
+		}
+	}
+	tenants.Remove("A.example.com")
+	tenants.Remove("a.example.com")
+	if tenants.Get("a.example.com") != nil || tenants.Get("b.example.com") == nil {
+		t.Error()
+	}
+	tenants.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMisdirectedRequest)
+	})
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Host = "a.example.com"
+	w := httptest.NewRecorder()
+	tenants.ServeHTTP(w, r)
+	if w.Code != http.StatusMisdirectedRequest {
+		t.Error(w.Code)
+	}
+}
+
+func TestHeaderTenant(t *testing.T) {
+	tenants := NewTenants(HeaderTenant("X-Tenant"))
+	tenants.Add("acme", testTenantMux("acme"))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Tenant", "Acme")
+	w := httptest.NewRecorder()
+	tenants.ServeHTTP(w, r)
+	if w.Body.String() != "acme" {
+		t.Error(w.Body.String())
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net"
+	"time"
+)
+
+// SetReadTimeout sets the maximum duration for reading each request,
+// including the body, and for waiting for the next request on a
+// keep-alive connection. In the poll mode the read deadline is set once
+// the connection is readable, so an idle connection is closed by a timer
+// when no request arrives within the timeout.
+//
+// The request contexts have a deadline derived from the timeouts, so that
+// the downstream calls can honor the same budget. With a write timeout the
+// deadline is the write deadline of the response, otherwise it is the read
+// deadline of the request.
+func (m *Rum) SetReadTimeout(d time.Duration) {
+	m.readTimeout = d
+}
+
+// setReadDeadline sets the read deadline of the conn and returns it.
+func (m *Rum) setReadDeadline(conn net.Conn) time.Time {
+	if m.readTimeout <= 0 {
+		return time.Time{}
+	}
+	deadline := time.Now().Add(m.readTimeout)
+	conn.SetReadDeadline(deadline)
+	return deadline
+}
+
+// requestDeadline returns the deadline of the request context.
+func (m *Rum) requestDeadline(readDeadline time.Time) time.Time {
+	if m.writeTimeout > 0 {
+		return time.Now().Add(m.writeTimeout)
+	}
+	return readDeadline
+}
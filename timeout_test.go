@@ -0,0 +1,103 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRequestDeadline(t *testing.T) {
+	for _, mode := range []string{"normal", "fast", "poll"} {
+		testRequestDeadline(mode, false, t)
+		testRequestDeadline(mode, true, t)
+	}
+}
+
+func testRequestDeadline(mode string, write bool, t *testing.T) {
+	addr := ":8080"
+	m := New()
+	switch mode {
+	case "fast":
+		m.SetFast(true)
+	case "poll":
+		m.SetPoll(true)
+	}
+	m.SetReadTimeout(time.Second)
+	if write {
+		m.SetWriteTimeout(time.Second * 2)
+	}
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		if !ok {
+			t.Error(mode, "no deadline")
+		} else if d := time.Until(deadline); d <= 0 || d > time.Second {
+			t.Error(mode, d)
+		}
+		w.Write([]byte("Hello World"))
+	})
+	m.HandleFunc("/write", func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		if !ok {
+			t.Error(mode, "no deadline")
+		} else if d := time.Until(deadline); d <= time.Second || d > time.Second*2 {
+			t.Error(mode, d)
+		}
+		w.Write([]byte("Hello World"))
+	})
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	if write {
+		testHTTP("GET", "http://"+addr+"/write", http.StatusOK, "Hello World", t)
+	} else {
+		testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	}
+	m.Close()
+	<-done
+}
+
+func TestReadTimeout(t *testing.T) {
+	for _, mode := range []string{"normal", "fast", "poll"} {
+		testReadTimeout(mode, t)
+	}
+}
+
+func testReadTimeout(mode string, t *testing.T) {
+	addr := ":8080"
+	m := New()
+	switch mode {
+	case "fast":
+		m.SetFast(true)
+	case "poll":
+		m.SetPoll(true)
+	}
+	m.SetReadTimeout(time.Millisecond * 50)
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	})
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Error(mode, err)
+	}
+	conn.Close()
+	m.Close()
+	<-done
+}
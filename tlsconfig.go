@@ -0,0 +1,145 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"time"
+)
+
+// maxTicketKeys is the number of session ticket keys kept by the rotation,
+// so that the tickets issued with the previous keys can still be resumed.
+const maxTicketKeys = 3
+
+// ModernTLSConfig returns a tls.Config of the Mozilla "modern" profile,
+// which only supports TLS 1.3.
+func ModernTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS13,
+		NextProtos: []string{"http/1.1"},
+	}
+}
+
+// IntermediateTLSConfig returns a tls.Config of the Mozilla "intermediate"
+// profile, which supports TLS 1.2 with the ECDHE AEAD cipher suites and TLS 1.3.
+func IntermediateTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+		},
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+		NextProtos:       []string{"http/1.1"},
+	}
+}
+
+// tlsConfig returns a clone of the TLSConfig, or of the intermediate profile
+// if it is nil, with the http/1.1 protocol and TLS 1.2 as the minimum version,
+// selecting the added certificates by the SNI.
+func (m *Rum) tlsConfig() *tls.Config {
+	var config *tls.Config
+	if m.TLSConfig != nil {
+		config = m.TLSConfig.Clone()
+	} else {
+		config = IntermediateTLSConfig()
+	}
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+	if !strSliceContains(config.NextProtos, "http/1.1") {
+		config.NextProtos = append(config.NextProtos, "http/1.1")
+	}
+	config.GetCertificate = m.getCertificate(config.GetCertificate)
+	return config
+}
+
+// SetSessionTicketKeys sets the session ticket keys of the TLS listeners.
+// The first key encrypts the new tickets, all of the keys decrypt them.
+// It can be called while serving.
+func (m *Rum) SetSessionTicketKeys(keys [][32]byte) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.ticketKeys = keys
+	for _, config := range m.tlsConfigs {
+		config.SetSessionTicketKeys(keys)
+	}
+}
+
+// SetSessionTicketRotation enables the Server to rotate the session ticket
+// keys of the TLS listeners at the interval. A new random key is prepended
+// at each rotation and the last three keys are kept. Zero disables the rotation.
+func (m *Rum) SetSessionTicketRotation(interval time.Duration) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.rotationInterval = interval
+	if m.rotation != nil {
+		close(m.rotation)
+		m.rotation = nil
+	}
+	if interval > 0 && len(m.tlsConfigs) > 0 {
+		m.startRotation()
+	}
+}
+
+func (m *Rum) trackTLSConfig(config *tls.Config) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.tlsConfigs = append(m.tlsConfigs, config)
+	if len(m.ticketKeys) > 0 {
+		config.SetSessionTicketKeys(m.ticketKeys)
+	}
+	if m.rotationInterval > 0 && m.rotation == nil {
+		m.startRotation()
+	}
+	if m.certRefreshInterval > 0 && m.certRefresh == nil {
+		m.startCertRefresh()
+	}
+}
+
+// startRotation must be called with the lock held.
+func (m *Rum) startRotation() {
+	done := make(chan struct{})
+	m.rotation = done
+	m.rotateTicketKeys()
+	go func(interval time.Duration) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.mut.Lock()
+				select {
+				case <-done:
+				default:
+					m.rotateTicketKeys()
+				}
+				m.mut.Unlock()
+			case <-done:
+				return
+			}
+		}
+	}(m.rotationInterval)
+}
+
+// rotateTicketKeys must be called with the lock held.
+func (m *Rum) rotateTicketKeys() {
+	var key [32]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return
+	}
+	keys := append([][32]byte{key}, m.ticketKeys...)
+	if len(keys) > maxTicketKeys {
+		keys = keys[:maxTicketKeys]
+	}
+	m.ticketKeys = keys
+	for _, config := range m.tlsConfigs {
+		config.SetSessionTicketKeys(keys)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTLSConfig(t *testing.T) {
+	m := New()
+	config := m.tlsConfig()
+	if config.MinVersion != tls.VersionTLS12 || len(config.CipherSuites) == 0 || !strSliceContains(config.NextProtos, "http/1.1") {
+		t.Error(config)
+	}
+	m.TLSConfig = &tls.Config{NextProtos: []string{"h2"}}
+	config = m.tlsConfig()
+	if config.MinVersion != tls.VersionTLS12 || len(config.NextProtos) != 2 || len(m.TLSConfig.NextProtos) != 1 {
+		t.Error(config.NextProtos, m.TLSConfig.NextProtos)
+	}
+	if ModernTLSConfig().MinVersion != tls.VersionTLS13 {
+		t.Error()
+	}
+}
+
+func TestSessionTicketRotation(t *testing.T) {
+	cert, err := tls.X509KeyPair(testCertPEM, testKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ":8080"
+	m := New()
+	m.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	m.SetSessionTicketKeys([][32]byte{{1}})
+	m.SetSessionTicketRotation(time.Millisecond * 5)
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello World"))
+	})
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		m.Serve(ln)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 30)
+	testHTTPTLS("GET", "https://"+addr+"/", http.StatusOK, "Hello World", t)
+	m.mut.Lock()
+	keys := len(m.ticketKeys)
+	first := m.ticketKeys[0]
+	m.mut.Unlock()
+	if keys != maxTicketKeys || first == [32]byte{1} {
+		t.Error(keys)
+	}
+	m.SetSessionTicketRotation(0)
+	if m.rotation != nil {
+		t.Error()
+	}
+	m.SetSessionTicketRotation(time.Hour)
+	m.Close()
+	<-done
+	if m.rotation != nil {
+		t.Error()
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Transformer rewrites the response bodies in a streaming manner.
+type Transformer interface {
+	// Transform returns a writer rewriting the body written to it into dst,
+	// or nil to leave the response untouched. It may modify the header,
+	// which is not written yet. The writer is closed at the end of the
+	// response and must write any buffered data to dst.
+	Transform(r *http.Request, code int, header http.Header, dst io.Writer) io.WriteCloser
+}
+
+// TransformerFunc is an adapter to allow the use of ordinary functions as transformers.
+type TransformerFunc func(r *http.Request, code int, header http.Header, dst io.Writer) io.WriteCloser
+
+// Transform calls f(r, code, header, dst).
+func (f TransformerFunc) Transform(r *http.Request, code int, header http.Header, dst io.Writer) io.WriteCloser {
+	return f(r, code, header, dst)
+}
+
+// TransformHandler returns a handler that rewrites the response bodies of
+// the handler by the transformers, the first transformer seeing the body
+// written by the handler. The transformed responses have no Content-Length.
+//
+// The encoded responses are not transformed, so a compression handler
+// should wrap the TransformHandler to compress the transformed bodies.
+func TransformHandler(handler http.Handler, transformers ...Transformer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &transformResponseWriter{forwarder: forwarder{w}, r: r, transformers: transformers}
+		defer tw.close()
+		handler.ServeHTTP(expose(tw, w), r)
+	})
+}
+
+// ReplaceTransformer returns a Transformer replacing the first n
+// occurrences of old by new in the bodies of the responses whose content
+// type begins with the contentType, such as injecting a snippet before
+// "</head>" in the HTML pages or rewriting the links in the proxied
+// content. If n < 0, all of the occurrences are replaced.
+func ReplaceTransformer(contentType, old, new string, n int) Transformer {
+	return TransformerFunc(func(r *http.Request, code int, header http.Header, dst io.Writer) io.WriteCloser {
+		if old == "" || n == 0 || !strings.HasPrefix(header.Get("Content-Type"), contentType) {
+			return nil
+		}
+		return &replaceWriter{dst: dst, old: []byte(old), new: []byte(new), n: n}
+	})
+}
+
+type replaceWriter struct {
+	dst  io.Writer
+	old  []byte
+	new  []byte
+	n    int
+	buf  []byte
+	werr error
+}
+
+func (w *replaceWriter) Write(p []byte) (int, error) {
+	if w.werr != nil {
+		return 0, w.werr
+	}
+	w.buf = append(w.buf, p...)
+	buf := w.buf
+	for w.n != 0 {
+		i := bytes.Index(buf, w.old)
+		if i < 0 {
+			break
+		}
+		w.write(buf[:i])
+		w.write(w.new)
+		buf = buf[i+len(w.old):]
+		w.n--
+	}
+	// keeps the tail that may begin an occurrence spanning the writes.
+	if keep := len(w.old) - 1; w.n != 0 && len(buf) > keep {
+		w.write(buf[:len(buf)-keep])
+		buf = buf[len(buf)-keep:]
+	} else if w.n == 0 {
+		w.write(buf)
+		buf = buf[len(buf):]
+	}
+	w.buf = append(w.buf[:0], buf...)
+	if w.werr != nil {
+		return 0, w.werr
+	}
+	return len(p), nil
+}
+
+func (w *replaceWriter) write(p []byte) {
+	if w.werr == nil && len(p) > 0 {
+		_, w.werr = w.dst.Write(p)
+	}
+}
+
+func (w *replaceWriter) Close() error {
+	w.write(w.buf)
+	w.buf = nil
+	return w.werr
+}
+
+// transformResponseWriter sets up the transformers when the header is written.
+type transformResponseWriter struct {
+	forwarder
+	r            *http.Request
+	transformers []Transformer
+	w            io.Writer
+	closers      []io.WriteCloser
+	wroteHeader  bool
+}
+
+func (w *transformResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	if informational(code) {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+	w.w = w.ResponseWriter
+	header := w.Header()
+	if w.r.Method != "HEAD" && code != http.StatusNoContent && code != http.StatusNotModified && header.Get("Content-Encoding") == "" {
+		for i := len(w.transformers) - 1; i >= 0; i-- {
+			if wc := w.transformers[i].Transform(w.r, code, header, w.w); wc != nil {
+				w.w = wc
+				w.closers = append(w.closers, wc)
+			}
+		}
+		if len(w.closers) > 0 {
+			header.Del("Content-Length")
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *transformResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.w.Write(p)
+}
+
+// close closes the transformers from the outermost, flushing their
+// buffered data through the inner ones.
+func (w *transformResponseWriter) close() {
+	for i := len(w.closers) - 1; i >= 0; i-- {
+		w.closers[i].Close()
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestTransformHandler(t *testing.T) {
+	handler := TransformHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/html":
+			body := "<html><head></head><body><a href=\"http://backend/a\">a</a><a href=\"http://backend/b\">b</a></body></html>"
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			// writes byte by byte to split the occurrences across the writes.
+			for i := 0; i < len(body); i++ {
+				w.Write([]byte{body[i]})
+			}
+		case "/gzip":
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write([]byte("</head>"))
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"url":"http://backend/a"}`))
+		}
+	}),
+		ReplaceTransformer("text/html", "</head>", "<script>analytics()</script></head>", 1),
+		ReplaceTransformer("text/html", "http://backend/", "/", -1),
+	)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/html", nil))
+	if result := w.Body.String(); result != "<html><head><script>analytics()</script></head><body><a href=\"/a\">a</a><a href=\"/b\">b</a></body></html>" {
+		t.Error(result)
+	}
+	if w.Header().Get("Content-Length") != "" {
+		t.Error(w.Header())
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/gzip", nil))
+	if result := w.Body.String(); result != "</head>" {
+		t.Error(result)
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/json", nil))
+	if result := w.Body.String(); result != `{"url":"http://backend/a"}` {
+		t.Error(result)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package tus
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// FileStore is a Store keeping each upload in a data file of a directory,
+// named after the ID, with its Info in a .info JSON file. The offset of an
+// upload is the size of its data file, so the uploads survive the restarts.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a new FileStore of the directory, creating it if
+// it does not exist.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Path returns the path of the data file of the upload.
+func (s *FileStore) Path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+func (s *FileStore) infoPath(id string) string {
+	return filepath.Join(s.dir, id+".info")
+}
+
+// Create implements the Store interface.
+func (s *FileStore) Create(size int64, metadata map[string]string) (Info, error) {
+	info := Info{ID: NewID(), Size: size, Metadata: metadata}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return info, err
+	}
+	f, err := os.OpenFile(s.Path(info.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return info, err
+	}
+	f.Close()
+	if err := ioutil.WriteFile(s.infoPath(info.ID), data, 0644); err != nil {
+		os.Remove(s.Path(info.ID))
+		return info, err
+	}
+	return info, nil
+}
+
+// Info implements the Store interface.
+func (s *FileStore) Info(id string) (Info, error) {
+	var info Info
+	if !validID(id) {
+		return info, ErrNotFound
+	}
+	data, err := ioutil.ReadFile(s.infoPath(id))
+	if os.IsNotExist(err) {
+		return info, ErrNotFound
+	} else if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, err
+	}
+	stat, err := os.Stat(s.Path(id))
+	if os.IsNotExist(err) {
+		return info, ErrNotFound
+	} else if err != nil {
+		return info, err
+	}
+	info.Offset = stat.Size()
+	return info, nil
+}
+
+// Write implements the Store interface.
+func (s *FileStore) Write(id string, offset int64, r io.Reader) (int64, error) {
+	info, err := s.Info(id)
+	if err != nil {
+		return 0, err
+	}
+	if offset != info.Offset {
+		return 0, ErrOffsetMismatch
+	}
+	f, err := os.OpenFile(s.Path(id), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, io.LimitReader(r, info.Size-offset))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && offset+n == info.Size {
+		// The bytes beyond the size are not written.
+		var b [1]byte
+		if m, _ := r.Read(b[:]); m > 0 {
+			err = ErrTooLarge
+		}
+	}
+	return n, err
+}
+
+// Terminate implements the Store interface.
+func (s *FileStore) Terminate(id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	err := os.Remove(s.infoPath(id))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	return os.Remove(s.Path(id))
+}
+
+// validID reports whether the id can not escape the directory.
+func validID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
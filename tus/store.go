@@ -0,0 +1,164 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package tus
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sync"
+)
+
+var (
+	// ErrNotFound is returned by a Store for an unknown upload.
+	ErrNotFound = errors.New("Upload Not Found")
+	// ErrOffsetMismatch is returned by a Store if the offset of a write is
+	// not the offset of the upload.
+	ErrOffsetMismatch = errors.New("Upload Offset Mismatch")
+	// ErrTooLarge is returned by a Store if a write exceeds the size of the upload.
+	ErrTooLarge = errors.New("Upload Too Large")
+)
+
+// Info is the state of an upload.
+type Info struct {
+	// ID is the identifier of the upload in the URL.
+	ID string `json:"id"`
+	// Size is the total size in bytes of the upload.
+	Size int64 `json:"size"`
+	// Offset is the number of the bytes received.
+	Offset int64 `json:"offset"`
+	// Metadata is the decoded Upload-Metadata of the creation.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Complete reports whether all the bytes of the upload are received.
+func (info Info) Complete() bool {
+	return info.Offset >= info.Size
+}
+
+// Store is the storage backend of the uploads. The writes of an upload are
+// serialized by the Handler.
+type Store interface {
+	// Create creates an upload of the size and the metadata, and returns
+	// its Info with a new ID.
+	Create(size int64, metadata map[string]string) (Info, error)
+	// Info returns the Info of the upload, or ErrNotFound.
+	Info(id string) (Info, error)
+	// Write appends the bytes of the reader to the upload at the offset,
+	// which must be the offset of the upload, and returns the number of the
+	// bytes written, which are kept even on error.
+	Write(id string, offset int64, r io.Reader) (int64, error)
+	// Terminate removes the upload.
+	Terminate(id string) error
+}
+
+// NewID returns a new random ID of an upload.
+func NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// MemoryStore is a Store keeping the uploads in memory, for the tests and
+// the small uploads.
+type MemoryStore struct {
+	mu      sync.Mutex
+	uploads map[string]*memoryUpload
+}
+
+type memoryUpload struct {
+	info Info
+	data []byte
+}
+
+// NewMemoryStore returns a new MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{uploads: make(map[string]*memoryUpload)}
+}
+
+// Create implements the Store interface.
+func (s *MemoryStore) Create(size int64, metadata map[string]string) (Info, error) {
+	info := Info{ID: NewID(), Size: size, Metadata: metadata}
+	s.mu.Lock()
+	s.uploads[info.ID] = &memoryUpload{info: info}
+	s.mu.Unlock()
+	return info, nil
+}
+
+// Info implements the Store interface.
+func (s *MemoryStore) Info(id string) (Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[id]
+	if !ok {
+		return Info{}, ErrNotFound
+	}
+	return u.info, nil
+}
+
+// Write implements the Store interface.
+func (s *MemoryStore) Write(id string, offset int64, r io.Reader) (int64, error) {
+	info, err := s.Info(id)
+	if err != nil {
+		return 0, err
+	}
+	if offset != info.Offset {
+		return 0, ErrOffsetMismatch
+	}
+	buf := make([]byte, 32<<10)
+	var written int64
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			tooLarge := false
+			if rest := info.Size - info.Offset - written; int64(n) > rest {
+				// The bytes beyond the size are not written.
+				n, tooLarge = int(rest), true
+			}
+			s.mu.Lock()
+			u, ok := s.uploads[id]
+			if !ok {
+				s.mu.Unlock()
+				return written, ErrNotFound
+			}
+			u.data = append(u.data, buf[:n]...)
+			u.info.Offset += int64(n)
+			s.mu.Unlock()
+			written += int64(n)
+			if tooLarge {
+				return written, ErrTooLarge
+			}
+		}
+		if err == io.EOF {
+			return written, nil
+		} else if err != nil {
+			return written, err
+		}
+	}
+}
+
+// Terminate implements the Store interface.
+func (s *MemoryStore) Terminate(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.uploads[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.uploads, id)
+	return nil
+}
+
+// Bytes returns the bytes received of the upload.
+func (s *MemoryStore) Bytes(id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), u.data...), nil
+}
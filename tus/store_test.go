@@ -0,0 +1,64 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package tus
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func testStore(t *testing.T, s Store) {
+	info, err := s.Create(11, map[string]string{"filename": "hello.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.Write(info.ID, 0, strings.NewReader("Hello ")); err != nil || n != 6 {
+		t.Error(n, err)
+	}
+	if _, err := s.Write(info.ID, 0, strings.NewReader("World")); err != ErrOffsetMismatch {
+		t.Error(err)
+	}
+	if n, err := s.Write(info.ID, 6, strings.NewReader("World!")); err != ErrTooLarge || n != 5 {
+		t.Error(n, err)
+	}
+	if info, err = s.Info(info.ID); err != nil || info.Offset != 11 || !info.Complete() || info.Metadata["filename"] != "hello.txt" {
+		t.Error(info, err)
+	}
+	if err := s.Terminate(info.ID); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Info(info.ID); err != ErrNotFound {
+		t.Error(err)
+	}
+	if err := s.Terminate(info.ID); err != ErrNotFound {
+		t.Error(err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+	if _, err := s.Info("../tus"); err != ErrNotFound {
+		t.Error(err)
+	}
+	info, _ := s.Create(5, nil)
+	s.Write(info.ID, 0, strings.NewReader("Hello"))
+	if data, err := ioutil.ReadFile(s.Path(info.ID)); err != nil || string(data) != "Hello" {
+		t.Error(string(data), err)
+	}
+}
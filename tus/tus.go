@@ -0,0 +1,300 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+// Package tus implements the server side of the tus resumable upload
+// protocol 1.0.0 with the creation and the termination extensions, on the
+// routes of a rum.Mux group.
+//
+//	h := tus.New(tus.NewMemoryStore(), &tus.Options{MaxSize: 1 << 30})
+//	m.Group("/files", h.Mount)
+//
+// A client creates an upload by a POST to /files/ with an Upload-Length
+// header, then sends the bytes by PATCH requests to the returned Location
+// from the Upload-Offset, resuming after a failure from the offset
+// returned by a HEAD request.
+package tus
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/hslam/rum"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// Version is the version of the tus protocol.
+	Version = "1.0.0"
+	// Extensions is the supported extensions of the tus protocol.
+	Extensions = "creation,termination"
+	// ContentType is the Content-Type of the PATCH requests.
+	ContentType = "application/offset+octet-stream"
+)
+
+// Options configures a Handler.
+type Options struct {
+	// MaxSize is the maximum size in bytes of an upload. Zero means no limit.
+	MaxSize int64
+	// OnComplete is called with the Info of an upload when all its bytes
+	// are received.
+	OnComplete func(info Info)
+	// ErrorLog logs the errors of the Store, replied with 500 Internal
+	// Server Error. If nil, the standard logger is used.
+	ErrorLog *log.Logger
+}
+
+// Handler serves the tus protocol with a Store.
+type Handler struct {
+	store   Store
+	options Options
+	locks   sync.Map
+}
+
+// New returns a new Handler of the store. The options may be nil.
+func New(store Store, options *Options) *Handler {
+	h := &Handler{store: store}
+	if options != nil {
+		h.options = *options
+	}
+	return h
+}
+
+// Mount registers the routes of the protocol to the Mux, typically a group:
+// POST and OPTIONS on "/", HEAD, PATCH, DELETE and OPTIONS on "/:id".
+func (h *Handler) Mount(m *rum.Mux) {
+	id := func(r *http.Request) string {
+		return m.Params(r)["id"]
+	}
+	m.HandleFunc("/", h.capabilities).OPTIONS()
+	m.HandleFunc("/", h.create).POST()
+	m.HandleFunc("/:id", h.capabilities).OPTIONS()
+	m.HandleFunc("/:id", func(w http.ResponseWriter, r *http.Request) {
+		h.head(w, r, id(r))
+	}).HEAD()
+	m.HandleFunc("/:id", func(w http.ResponseWriter, r *http.Request) {
+		h.patch(w, r, id(r))
+	}).PATCH()
+	m.HandleFunc("/:id", func(w http.ResponseWriter, r *http.Request) {
+		h.terminate(w, r, id(r))
+	}).DELETE()
+}
+
+// capabilities replies with the capabilities of the server.
+func (h *Handler) capabilities(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	header.Set("Tus-Resumable", Version)
+	header.Set("Tus-Version", Version)
+	header.Set("Tus-Extension", Extensions)
+	if h.options.MaxSize > 0 {
+		header.Set("Tus-Max-Size", strconv.FormatInt(h.options.MaxSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resumable checks the Tus-Resumable header of the request, replying 412
+// Precondition Failed if the version is not supported.
+func (h *Handler) resumable(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Tus-Resumable", Version)
+	if r.Header.Get("Tus-Resumable") != Version {
+		w.Header().Set("Tus-Version", Version)
+		http.Error(w, "412 Precondition Failed", http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	if !h.resumable(w, r) {
+		return
+	}
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		http.Error(w, "400 Bad Request : invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+	if h.options.MaxSize > 0 && size > h.options.MaxSize {
+		http.Error(w, "413 Request Entity Too Large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	metadata, err := parseMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, "400 Bad Request : invalid Upload-Metadata", http.StatusBadRequest)
+		return
+	}
+	info, err := h.store.Create(size, metadata)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+info.ID)
+	w.Header().Set("Upload-Offset", "0")
+	w.WriteHeader(http.StatusCreated)
+	if size == 0 && h.options.OnComplete != nil {
+		h.options.OnComplete(info)
+	}
+}
+
+func (h *Handler) head(w http.ResponseWriter, r *http.Request, id string) {
+	if !h.resumable(w, r) {
+		return
+	}
+	info, err := h.store.Info(id)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	header := w.Header()
+	header.Set("Cache-Control", "no-store")
+	header.Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	header.Set("Upload-Length", strconv.FormatInt(info.Size, 10))
+	if len(info.Metadata) > 0 {
+		header.Set("Upload-Metadata", formatMetadata(info.Metadata))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request, id string) {
+	if !h.resumable(w, r) {
+		return
+	}
+	if r.Header.Get("Content-Type") != ContentType {
+		http.Error(w, "415 Unsupported Media Type", http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "400 Bad Request : invalid Upload-Offset", http.StatusBadRequest)
+		return
+	}
+	unlock, err := h.lock(id)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	defer unlock()
+	n, err := h.store.Write(id, offset, r.Body)
+	if err != nil && n == 0 {
+		if info, infoErr := h.store.Info(id); infoErr != nil || info.Complete() {
+			h.locks.Delete(id)
+		}
+		h.error(w, r, err)
+		return
+	}
+	info, infoErr := h.store.Info(id)
+	if infoErr != nil {
+		h.error(w, r, infoErr)
+		return
+	}
+	if info.Complete() {
+		h.locks.Delete(id)
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	if err != nil {
+		// The bytes written are kept, and the client resumes from the offset.
+		h.error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	if info.Complete() && n > 0 && h.options.OnComplete != nil {
+		h.options.OnComplete(info)
+	}
+}
+
+func (h *Handler) terminate(w http.ResponseWriter, r *http.Request, id string) {
+	if !h.resumable(w, r) {
+		return
+	}
+	unlock, err := h.lock(id)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	defer unlock()
+	err = h.store.Terminate(id)
+	h.locks.Delete(id)
+	if err != nil {
+		h.error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lock serializes the writes of the upload. The id is validated and the
+// upload must exist, so that the locks are only kept for the uploads in
+// progress, and deleted on completion or termination.
+func (h *Handler) lock(id string) (func(), error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	if _, err := h.store.Info(id); err != nil {
+		return nil, err
+	}
+	v, _ := h.locks.LoadOrStore(id, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return func() {
+		mu.Unlock()
+	}, nil
+}
+
+// error replies with the status code of the error. The unexpected errors of
+// the Store are logged, and not sent to the client.
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "404 Not Found", http.StatusNotFound)
+	case errors.Is(err, ErrOffsetMismatch):
+		http.Error(w, "409 Conflict", http.StatusConflict)
+	case errors.Is(err, ErrTooLarge), errors.Is(err, rum.ErrBodyTooLarge):
+		http.Error(w, "413 Request Entity Too Large", http.StatusRequestEntityTooLarge)
+	default:
+		if h.options.ErrorLog != nil {
+			h.options.ErrorLog.Printf("rum: tus %s %s: %v", r.Method, r.URL.Path, err)
+		} else {
+			log.Printf("rum: tus %s %s: %v", r.Method, r.URL.Path, err)
+		}
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// parseMetadata parses the Upload-Metadata header of the comma separated
+// keys and base64 encoded values.
+func parseMetadata(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, errors.New("invalid pair")
+		}
+		var value []byte
+		if len(fields) == 2 {
+			var err error
+			if value, err = base64.StdEncoding.DecodeString(fields[1]); err != nil {
+				return nil, err
+			}
+		}
+		metadata[fields[0]] = string(value)
+	}
+	return metadata, nil
+}
+
+// formatMetadata formats the Upload-Metadata header.
+func formatMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		if value == "" {
+			pairs = append(pairs, key)
+		} else {
+			pairs = append(pairs, key+" "+base64.StdEncoding.EncodeToString([]byte(value)))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package tus
+
+import (
+	"bytes"
+	"errors"
+	"github.com/hslam/rum"
+	"github.com/hslam/rum/rumtest"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func request(t *testing.T, c *rumtest.Client, method, url string, header map[string]string, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, c.URL+url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Tus-Resumable", Version)
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res
+}
+
+func TestHandler(t *testing.T) {
+	store := NewMemoryStore()
+	var completed []Info
+	h := New(store, &Options{MaxSize: 100, OnComplete: func(info Info) {
+		completed = append(completed, info)
+	}})
+	m := rum.NewMux()
+	m.Group("/files", h.Mount)
+	c := rumtest.NewClient(m)
+	defer c.Close()
+
+	res := request(t, c, "OPTIONS", "/files/", nil, "")
+	if res.StatusCode != http.StatusNoContent || res.Header.Get("Tus-Extension") != Extensions || res.Header.Get("Tus-Max-Size") != "100" {
+		t.Error(res.StatusCode, res.Header)
+	}
+	if res = request(t, c, "POST", "/files/", map[string]string{"Upload-Length": "101"}, ""); res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Error(res.StatusCode)
+	}
+	res = request(t, c, "POST", "/files/", map[string]string{
+		"Upload-Length":   "11",
+		"Upload-Metadata": "filename aGVsbG8udHh0,private",
+	}, "")
+	if res.StatusCode != http.StatusCreated {
+		t.Fatal(res.StatusCode)
+	}
+	location := res.Header.Get("Location")
+	if !strings.HasPrefix(location, "/files/") {
+		t.Fatal(location)
+	}
+	patch := func(offset, body string) *http.Response {
+		return request(t, c, "PATCH", location, map[string]string{"Content-Type": ContentType, "Upload-Offset": offset}, body)
+	}
+	if res = patch("0", "Hello "); res.StatusCode != http.StatusNoContent || res.Header.Get("Upload-Offset") != "6" {
+		t.Error(res.StatusCode, res.Header)
+	}
+	if res = patch("0", "World"); res.StatusCode != http.StatusConflict {
+		t.Error(res.StatusCode)
+	}
+	res = request(t, c, "HEAD", location, nil, "")
+	if res.StatusCode != http.StatusOK || res.Header.Get("Upload-Offset") != "6" || res.Header.Get("Upload-Length") != "11" ||
+		res.Header.Get("Upload-Metadata") != "filename aGVsbG8udHh0,private" || res.Header.Get("Cache-Control") != "no-store" {
+		t.Error(res.StatusCode, res.Header)
+	}
+	if res = patch("6", "World"); res.StatusCode != http.StatusNoContent || res.Header.Get("Upload-Offset") != "11" {
+		t.Error(res.StatusCode, res.Header)
+	}
+	if len(completed) != 1 || completed[0].Metadata["filename"] != "hello.txt" {
+		t.Error(completed)
+	}
+	if res = patch("11", "!"); res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Error(res.StatusCode)
+	}
+	if testLocks(h) != 0 {
+		t.Error(testLocks(h))
+	}
+	id := location[len("/files/"):]
+	if data, err := store.Bytes(id); err != nil || string(data) != "Hello World" {
+		t.Error(string(data), err)
+	}
+	if res = request(t, c, "DELETE", location, nil, ""); res.StatusCode != http.StatusNoContent {
+		t.Error(res.StatusCode)
+	}
+	if res = request(t, c, "HEAD", location, nil, ""); res.StatusCode != http.StatusNotFound {
+		t.Error(res.StatusCode)
+	}
+}
+
+func TestHandlerPreconditions(t *testing.T) {
+	h := New(NewMemoryStore(), nil)
+	m := rum.NewMux()
+	m.Group("/files", h.Mount)
+	c := rumtest.NewClient(m)
+	defer c.Close()
+	res := request(t, c, "POST", "/files/", map[string]string{"Tus-Resumable": "0.2.2", "Upload-Length": "1"}, "")
+	if res.StatusCode != http.StatusPreconditionFailed || res.Header.Get("Tus-Version") != Version {
+		t.Error(res.StatusCode, res.Header)
+	}
+	if res = request(t, c, "POST", "/files/", map[string]string{"Upload-Length": "-1"}, ""); res.StatusCode != http.StatusBadRequest {
+		t.Error(res.StatusCode)
+	}
+	if res = request(t, c, "POST", "/files/", map[string]string{"Upload-Length": "1", "Upload-Metadata": "a !!!"}, ""); res.StatusCode != http.StatusBadRequest {
+		t.Error(res.StatusCode)
+	}
+	res = request(t, c, "POST", "/files/", map[string]string{"Upload-Length": "1"}, "")
+	location := res.Header.Get("Location")
+	if res = request(t, c, "PATCH", location, map[string]string{"Upload-Offset": "0"}, "a"); res.StatusCode != http.StatusUnsupportedMediaType {
+		t.Error(res.StatusCode)
+	}
+	if res = request(t, c, "PATCH", location, map[string]string{"Content-Type": ContentType, "Upload-Offset": "0"}, "ab"); res.StatusCode != http.StatusRequestEntityTooLarge || res.Header.Get("Upload-Offset") != "1" {
+		t.Error(res.StatusCode, res.Header)
+	}
+	if res = request(t, c, "PATCH", "/files/unknown", map[string]string{"Content-Type": ContentType, "Upload-Offset": "0"}, "a"); res.StatusCode != http.StatusNotFound {
+		t.Error(res.StatusCode)
+	}
+	if res = request(t, c, "DELETE", "/files/unknown", nil, ""); res.StatusCode != http.StatusNotFound {
+		t.Error(res.StatusCode)
+	}
+	if testLocks(h) != 0 {
+		t.Error(testLocks(h))
+	}
+}
+
+func testLocks(h *Handler) (n int) {
+	h.locks.Range(func(key, value interface{}) bool {
+		n++
+		return true
+	})
+	return
+}
+
+type failingStore struct {
+	*MemoryStore
+}
+
+func (s failingStore) Write(id string, offset int64, r io.Reader) (int64, error) {
+	return 0, errors.New("open /var/uploads/" + id + ": no space left on device")
+}
+
+func TestHandlerStoreError(t *testing.T) {
+	var logs bytes.Buffer
+	h := New(failingStore{NewMemoryStore()}, &Options{ErrorLog: log.New(&logs, "", 0)})
+	m := rum.NewMux()
+	m.Group("/files", h.Mount)
+	c := rumtest.NewClient(m)
+	defer c.Close()
+	res := request(t, c, "POST", "/files/", map[string]string{"Upload-Length": "1"}, "")
+	location := res.Header.Get("Location")
+	req, _ := http.NewRequest("PATCH", c.URL+location, strings.NewReader("a"))
+	req.Header.Set("Tus-Resumable", Version)
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("Upload-Offset", "0")
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusInternalServerError || strings.Contains(string(body), "/var/uploads") {
+		t.Error(res.StatusCode, string(body))
+	}
+	if !strings.Contains(logs.String(), "no space left on device") {
+		t.Error(logs.String())
+	}
+}
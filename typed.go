@@ -0,0 +1,205 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package rum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// Ctx is the context of a typed handler.
+type Ctx struct {
+	context.Context
+	// Request is the HTTP request.
+	Request *http.Request
+	// ResponseWriter sets the headers of the response, whose body is
+	// encoded from the result of the handler.
+	ResponseWriter http.ResponseWriter
+}
+
+// StatusCoder is implemented by the results of the typed handlers
+// replied with a status code other than 200 OK.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Typed returns an error-returning handler calling the typed handler. The
+// request struct is bound from the JSON body, then from the fields tagged
+// with `param:"name"`, `query:"name"` and `header:"name"` of the path
+// params of the Mux, the query and the header. The result is encoded as
+// JSON, or replied with 204 No Content if it is nil. A binding error is
+// an *HTTPError of 400 Bad Request, and the errors are converted by the
+// ErrorHandler of the entry or the Mux.
+func Typed[Req, Resp any](m *Mux, handler func(ctx Ctx, req Req) (Resp, error)) HandlerE {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		var req Req
+		if err := bind(m, r, &req); err != nil {
+			return &HTTPError{Code: http.StatusBadRequest, Err: err}
+		}
+		resp, err := handler(Ctx{Context: r.Context(), Request: r, ResponseWriter: w}, req)
+		if err != nil {
+			return err
+		}
+		return encode(w, resp)
+	}
+}
+
+// GET registers the typed handler of the GET requests with the pattern to the Mux.
+func GET[Req, Resp any](m *Mux, pattern string, handler func(ctx Ctx, req Req) (Resp, error)) *Entry {
+	return m.HandleFuncE(pattern, Typed(m, handler)).GET()
+}
+
+// POST registers the typed handler of the POST requests with the pattern to the Mux.
+func POST[Req, Resp any](m *Mux, pattern string, handler func(ctx Ctx, req Req) (Resp, error)) *Entry {
+	return m.HandleFuncE(pattern, Typed(m, handler)).POST()
+}
+
+// PUT registers the typed handler of the PUT requests with the pattern to the Mux.
+func PUT[Req, Resp any](m *Mux, pattern string, handler func(ctx Ctx, req Req) (Resp, error)) *Entry {
+	return m.HandleFuncE(pattern, Typed(m, handler)).PUT()
+}
+
+// PATCH registers the typed handler of the PATCH requests with the pattern to the Mux.
+func PATCH[Req, Resp any](m *Mux, pattern string, handler func(ctx Ctx, req Req) (Resp, error)) *Entry {
+	return m.HandleFuncE(pattern, Typed(m, handler)).PATCH()
+}
+
+// DELETE registers the typed handler of the DELETE requests with the pattern to the Mux.
+func DELETE[Req, Resp any](m *Mux, pattern string, handler func(ctx Ctx, req Req) (Resp, error)) *Entry {
+	return m.HandleFuncE(pattern, Typed(m, handler)).DELETE()
+}
+
+func bind(m *Mux, r *http.Request, v interface{}) error {
+	if r.Body != nil && r.Body != http.NoBody && r.Method != "GET" && r.Method != "HEAD" {
+		if ct := r.Header.Get("Content-Type"); ct != "" {
+			if t, _, err := mime.ParseMediaType(ct); err != nil || t != "application/json" {
+				return fmt.Errorf("unsupported content type %q", ct)
+			}
+		}
+		if err := json.NewDecoder(r.Body).Decode(v); err != nil && err != io.EOF {
+			return err
+		}
+	}
+	rv := reflect.ValueOf(v).Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	var params map[string]string
+	query := r.URL.Query()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		var values []string
+		var name string
+		if name = field.Tag.Get("param"); name != "" {
+			if params == nil {
+				params = m.Params(r)
+			}
+			if value, ok := params[name]; ok {
+				values = []string{value}
+			}
+		} else if name = field.Tag.Get("query"); name != "" {
+			values = query[name]
+		} else if name = field.Tag.Get("header"); name != "" {
+			values = r.Header.Values(name)
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if err := setField(rv.Field(i), values); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setField(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return setValue(v, values[0])
+}
+
+func setValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Ptr:
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(p)
+	default:
+		return errors.New("unsupported type " + v.Type().String())
+	}
+	return nil
+}
+
+func encode(w http.ResponseWriter, resp interface{}) error {
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	if rv := reflect.ValueOf(resp); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	code := http.StatusOK
+	if s, ok := resp.(StatusCoder); ok {
+		code = s.StatusCode()
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_, err = w.Write(body)
+	return err
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package rum
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type typedUserReq struct {
+	ID    int      `param:"id"`
+	Tags  []string `query:"tag"`
+	Token string   `header:"X-Token"`
+	Name  string   `json:"name"`
+}
+
+type typedUserResp struct {
+	ID    int      `json:"id"`
+	Tags  []string `json:"tags"`
+	Token string   `json:"token"`
+	Name  string   `json:"name"`
+}
+
+type typedCreated struct {
+	ID int `json:"id"`
+}
+
+func (typedCreated) StatusCode() int {
+	return http.StatusCreated
+}
+
+func TestTyped(t *testing.T) {
+	m := NewMux()
+	GET(m, "/users/:id", func(ctx Ctx, req typedUserReq) (typedUserResp, error) {
+		return typedUserResp{ID: req.ID, Tags: req.Tags, Token: req.Token}, nil
+	})
+	PUT(m, "/users/:id", func(ctx Ctx, req typedUserReq) (typedUserResp, error) {
+		return typedUserResp{ID: req.ID, Name: req.Name}, nil
+	})
+	POST(m, "/users", func(ctx Ctx, req typedUserReq) (typedCreated, error) {
+		return typedCreated{ID: 1}, nil
+	})
+	DELETE(m, "/users/:id", func(ctx Ctx, req typedUserReq) (*typedUserResp, error) {
+		if req.ID == 0 {
+			return nil, errors.New("zero")
+		}
+		return nil, nil
+	})
+	PATCH(m, "/users/:id", func(ctx Ctx, req typedUserReq) (*typedUserResp, error) {
+		return nil, NewHTTPError(http.StatusConflict, "")
+	})
+	tests := []struct {
+		method, target, body, contentType string
+		code                              int
+		resp                              string
+	}{
+		{"GET", "/users/8?tag=a&tag=b", "", "", http.StatusOK, `{"id":8,"tags":["a","b"],"token":"t","name":""}`},
+		{"GET", "/users/x", "", "", http.StatusBadRequest, ""},
+		{"PUT", "/users/8", `{"name":"foo"}`, "application/json", http.StatusOK, `{"id":8,"tags":null,"token":"","name":"foo"}`},
+		{"PUT", "/users/8", `{"name":`, "application/json", http.StatusBadRequest, ""},
+		{"PUT", "/users/8", `name=foo`, "application/x-www-form-urlencoded", http.StatusBadRequest, ""},
+		{"POST", "/users", "", "", http.StatusCreated, `{"id":1}`},
+		{"DELETE", "/users/8", "", "", http.StatusNoContent, ""},
+		{"DELETE", "/users/0", "", "", http.StatusInternalServerError, ""},
+		{"PATCH", "/users/8", "", "", http.StatusConflict, ""},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+		if test.contentType != "" {
+			r.Header.Set("Content-Type", test.contentType)
+		}
+		r.Header.Set("X-Token", "t")
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Error(test.method, test.target, w.Code)
+		}
+		if test.resp != "" {
+			if w.Body.String() != test.resp {
+				t.Error(test.method, test.target, w.Body.String())
+			}
+			if w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+				t.Error(w.Header().Get("Content-Type"))
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// upgradeType returns the protocol of the Upgrade header if the Connection
+// header requests an upgrade.
+func upgradeType(header http.Header) string {
+	for _, v := range header["Connection"] {
+		for _, k := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(k), "upgrade") {
+				return header.Get("Upgrade")
+			}
+		}
+	}
+	return ""
+}
+
+// switchProtocols hijacks the connection of the client and copies the data
+// between the client and the upstream in both directions, for the upgraded
+// connections such as the WebSocket connections.
+func (p *Proxy) switchProtocols(w http.ResponseWriter, r *http.Request, res *http.Response) {
+	backConn, ok := res.Body.(io.ReadWriteCloser)
+	if !ok {
+		res.Body.Close()
+		p.fail(w, r, http.ErrNotSupported)
+		return
+	}
+	defer backConn.Close()
+	if upgrade := upgradeType(res.Header); !strings.EqualFold(upgrade, upgradeType(r.Header)) {
+		p.fail(w, r, http.ErrNotSupported)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		p.fail(w, r, http.ErrNotSupported)
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	// The read and write timeouts of the Server do not apply to the
+	// upgraded connection, which is closed by the IdleTimeout instead.
+	conn.SetDeadline(time.Time{})
+	writeResponseHeader(rw.Writer, http.StatusSwitchingProtocols, res.Header)
+	if err := rw.Flush(); err != nil {
+		return
+	}
+	idle := newIdleCloser(p.IdleTimeout, conn, backConn)
+	defer idle.stop()
+	done := make(chan struct{}, 2)
+	go func() {
+		idle.copy(backConn, rw.Reader)
+		done <- struct{}{}
+	}()
+	go func() {
+		idle.copy(conn, backConn)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// idleCloser closes the connections when no data is copied for the timeout.
+type idleCloser struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+func newIdleCloser(timeout time.Duration, closers ...io.Closer) *idleCloser {
+	c := &idleCloser{timeout: timeout}
+	if timeout > 0 {
+		c.timer = time.AfterFunc(timeout, func() {
+			for _, closer := range closers {
+				closer.Close()
+			}
+		})
+	}
+	return c
+}
+
+func (c *idleCloser) touch() {
+	if c.timer == nil {
+		return
+	}
+	c.mu.Lock()
+	c.timer.Reset(c.timeout)
+	c.mu.Unlock()
+}
+
+func (c *idleCloser) stop() {
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+}
+
+// copy copies from src to dst with a pooled buffer until an error occurs.
+func (c *idleCloser) copy(dst io.Writer, src io.Reader) {
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+	for {
+		n, err := src.Read(*buf)
+		if n > 0 {
+			c.touch()
+			if _, werr := dst.Write((*buf)[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
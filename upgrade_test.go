@@ -0,0 +1,120 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testEchoUpstream(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if upgradeType(r.Header) != "echo" {
+			w.Write([]byte("not upgraded"))
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		rw.Flush()
+		io.Copy(conn, rw)
+	}))
+}
+
+func TestProxyUpgrade(t *testing.T) {
+	upstream := testEchoUpstream(t)
+	defer upstream.Close()
+	p, _ := NewProxy(upstream.URL)
+	p.IdleTimeout = time.Millisecond * 100
+	addr := ":8080"
+	m := New()
+	m.Handler = p
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /echo HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n"))
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Upgrade") != "echo" {
+		t.Fatal(resp.StatusCode, resp.Header)
+	}
+	for _, msg := range []string{"Hello", "World"} {
+		conn.Write([]byte(msg))
+		buf := make([]byte, len(msg))
+		if _, err := io.ReadFull(reader, buf); err != nil || string(buf) != msg {
+			t.Error(string(buf), err)
+		}
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	start := time.Now()
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Error(err)
+	}
+	if d := time.Since(start); d < time.Millisecond*50 {
+		t.Error(d)
+	}
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "not upgraded", t)
+	m.Close()
+	<-done
+}
+
+func TestProxyUpgradeTimeouts(t *testing.T) {
+	upstream := testEchoUpstream(t)
+	defer upstream.Close()
+	p, _ := NewProxy(upstream.URL)
+	addr := ":8080"
+	m := New()
+	m.SetReadTimeout(time.Millisecond * 50)
+	m.SetWriteTimeout(time.Millisecond * 50)
+	m.Handler = p
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /echo HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n"))
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatal(resp.StatusCode)
+	}
+	time.Sleep(time.Millisecond * 100)
+	conn.Write([]byte("Hello"))
+	buf := make([]byte, 5)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadFull(reader, buf); err != nil || string(buf) != "Hello" {
+		t.Error(string(buf), err)
+	}
+	m.Close()
+	<-done
+}
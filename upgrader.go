@@ -0,0 +1,132 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Upgrader serves a connection switched to a custom protocol, such as a
+// tunnel. The conn is hijacked from the Server and the reader holds the
+// data buffered after the request. The conn is closed when it returns. In
+// the poll mode it runs on its own goroutine, off the poll workers.
+//
+// The upgraders are called before the Handler, so neither the routes nor
+// the middleware, such as the authentication, apply to the requests they
+// serve. An upgrader authenticates the request itself.
+type Upgrader func(conn net.Conn, reader *bufio.Reader, req *http.Request)
+
+type upgraders struct {
+	protocols   map[string]Upgrader
+	authorities map[string]Upgrader
+}
+
+// SetUpgrader registers the upgrader of the protocol requested by the
+// Upgrade header. The Server replies 101 Switching Protocols before calling
+// the upgrader, bypassing the Handler. A nil upgrader removes the protocol.
+func (m *Rum) SetUpgrader(protocol string, upgrader Upgrader) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	old := m.loadUpgraders()
+	u := &upgraders{protocols: make(map[string]Upgrader), authorities: old.authorities}
+	for k, v := range old.protocols {
+		u.protocols[k] = v
+	}
+	protocol = strings.ToLower(protocol)
+	if upgrader != nil {
+		u.protocols[protocol] = upgrader
+	} else {
+		u.protocols = withoutUpgrader(u.protocols, protocol)
+	}
+	m.upgraders.Store(u)
+}
+
+// SetConnectUpgrader registers the upgrader of the CONNECT requests to the
+// authority, such as "example.com:443", or to any authority if it is "*".
+// The Server replies 200 Connection Established before calling the upgrader,
+// bypassing the Handler. A nil upgrader removes the authority.
+func (m *Rum) SetConnectUpgrader(authority string, upgrader Upgrader) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	old := m.loadUpgraders()
+	u := &upgraders{protocols: old.protocols, authorities: make(map[string]Upgrader)}
+	for k, v := range old.authorities {
+		u.authorities[k] = v
+	}
+	authority = strings.ToLower(authority)
+	if upgrader != nil {
+		u.authorities[authority] = upgrader
+	} else {
+		u.authorities = withoutUpgrader(u.authorities, authority)
+	}
+	m.upgraders.Store(u)
+}
+
+func withoutUpgrader(upgraders map[string]Upgrader, key string) map[string]Upgrader {
+	without := make(map[string]Upgrader, len(upgraders))
+	for k, v := range upgraders {
+		if k != key {
+			without[k] = v
+		}
+	}
+	return without
+}
+
+func (m *Rum) loadUpgraders() *upgraders {
+	if u, ok := m.upgraders.Load().(*upgraders); ok {
+		return u
+	}
+	return &upgraders{}
+}
+
+// upgrader returns the upgrader of the request and its status line.
+func (m *Rum) upgrader(req *http.Request) (Upgrader, string) {
+	u, ok := m.upgraders.Load().(*upgraders)
+	if !ok {
+		return nil, ""
+	}
+	if req.Method == "CONNECT" {
+		if upgrader := u.authorities[strings.ToLower(req.Host)]; upgrader != nil {
+			return upgrader, "HTTP/1.1 200 Connection Established\r\n\r\n"
+		} else if upgrader := u.authorities["*"]; upgrader != nil {
+			return upgrader, "HTTP/1.1 200 Connection Established\r\n\r\n"
+		}
+		return nil, ""
+	}
+	if len(u.protocols) == 0 {
+		return nil, ""
+	}
+	for _, protocol := range strings.Split(upgradeType(req.Header), ",") {
+		protocol = strings.TrimSpace(protocol)
+		if upgrader := u.protocols[strings.ToLower(protocol)]; upgrader != nil {
+			return upgrader, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: " + protocol + "\r\nConnection: Upgrade\r\n\r\n"
+		}
+	}
+	return nil, ""
+}
+
+// serveUpgrade switches the connection and serves it by the upgrader. In
+// the poll mode the upgrader runs on its own goroutine, so the poll worker
+// goes on serving the other conns.
+func (m *Rum) serveUpgrade(upgrader Upgrader, status string, req *http.Request, conn net.Conn, rw *bufio.ReadWriter) {
+	conn.SetDeadline(time.Time{})
+	rw.Writer.WriteString(status)
+	if err := rw.Writer.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+	if m.poll {
+		go func() {
+			defer conn.Close()
+			upgrader(conn, rw.Reader, req)
+		}()
+		return
+	}
+	defer conn.Close()
+	upgrader(conn, rw.Reader, req)
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testUpgrader(request, status, greeting string, t *testing.T) {
+	conn, err := net.Dial("tcp", "127.0.0.1:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// the greeting is sent along with the request to be buffered by the Server.
+	conn.Write([]byte(request + greeting))
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != status {
+		t.Error(resp.Status)
+	}
+	buf := make([]byte, len(greeting))
+	if _, err := io.ReadFull(reader, buf); err != nil || string(buf) != greeting {
+		t.Error(err, string(buf))
+	}
+	conn.Write([]byte("ping"))
+	buf = make([]byte, 4)
+	if _, err := io.ReadFull(reader, buf); err != nil || string(buf) != "ping" {
+		t.Error(err, string(buf))
+	}
+}
+
+func TestUpgrader(t *testing.T) {
+	for _, mode := range []struct{ fast, poll bool }{{false, false}, {true, false}, {false, true}, {true, true}} {
+		addr := ":8080"
+		m := New()
+		m.SetFast(mode.fast)
+		m.SetPoll(mode.poll)
+		echo := func(conn net.Conn, reader *bufio.Reader, req *http.Request) {
+			io.Copy(conn, reader)
+		}
+		m.SetUpgrader("Echo", echo)
+		m.SetUpgrader("removed", echo)
+		m.SetUpgrader("removed", nil)
+		m.SetConnectUpgrader("*", echo)
+		m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("Hello World"))
+		})
+		done := make(chan struct{})
+		go func() {
+			m.Run(addr)
+			close(done)
+		}()
+		time.Sleep(time.Millisecond * 10)
+		testUpgrader("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: other, echo\r\n\r\n", "101 Switching Protocols", "hello", t)
+		testUpgrader("CONNECT example.com:443 HTTP/1.1\r\nHost: example.com:443\r\n\r\n", "200 Connection Established", "hello", t)
+		testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+		conn, err := net.Dial("tcp", "127.0.0.1"+addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade, close\r\nUpgrade: removed\r\n\r\n"))
+		if resp, err := http.ReadResponse(bufio.NewReader(conn), nil); err != nil {
+			t.Error(err)
+		} else if resp.StatusCode != http.StatusOK {
+			t.Error(resp.Status)
+		}
+		conn.Close()
+		m.Close()
+		<-done
+	}
+}
+
+func TestServeUpgradeHijacked(t *testing.T) {
+	m := New()
+	m.SetPoll(true)
+	upgraded := make(chan struct{})
+	release := make(chan struct{})
+	m.SetConnectUpgrader("*", func(conn net.Conn, reader *bufio.Reader, req *http.Request) {
+		close(upgraded)
+		<-release
+	})
+	client, server := net.Pipe()
+	defer client.Close()
+	go io.Copy(ioutil.Discard, client)
+	req := httptest.NewRequest("CONNECT", "example.com:443", nil)
+	rw := bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server))
+	if hijacked := m.serveRequest(m, req, server, rw, time.Time{}, time.Time{}); !hijacked {
+		t.Error("should be hijacked")
+	}
+	<-upgraded
+	close(release)
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithValue injects the static value of the key into the contexts of the
+// requests served by the entry, such as the roles required by the route, a
+// feature flag or the configuration of a shared handler, instead of a global
+// map keyed by the path. The value is seen by the middleware and the handler
+// with r.Context().Value(key). A key set again replaces its value. The key
+// must be comparable, as for context.WithValue. It should be called before
+// serving.
+func (entry *Entry) WithValue(key, value interface{}) *Entry {
+	entry.values = append(entry.values, entryValue{key: key, value: value})
+	return entry
+}
+
+type entryValue struct {
+	key   interface{}
+	value interface{}
+}
+
+// valuesContext carries the values of an entry in one context, instead of
+// one context per value.
+type valuesContext struct {
+	context.Context
+	values []entryValue
+}
+
+// Value returns the last value of the key set by the entry, or else the
+// value of the parent context.
+func (c *valuesContext) Value(key interface{}) interface{} {
+	for i := len(c.values) - 1; i >= 0; i-- {
+		if c.values[i].key == key {
+			return c.values[i].value
+		}
+	}
+	return c.Context.Value(key)
+}
+
+// withValues returns the request with the values of the entry in its context.
+func (entry *Entry) withValues(r *http.Request) *http.Request {
+	return r.WithContext(&valuesContext{Context: r.Context(), values: entry.values})
+}
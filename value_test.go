@@ -0,0 +1,40 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testValueKey struct{}
+
+func TestWithValue(t *testing.T) {
+	m := New()
+	m.Use(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/admin" && r.Context().Value("role") != "admin" {
+			t.Error(r.Context().Value("role"))
+		}
+	})
+	m.HandleFunc("/admin", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Context().Value("role").(string) + " " + r.Context().Value(testValueKey{}).(string)))
+	}).WithValue("role", "user").WithValue(testValueKey{}, "beta").WithValue("role", "admin").All()
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Context().Value("role"); v != nil {
+			t.Error(v)
+		}
+		w.Write([]byte("Hello World"))
+	}).All()
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/admin", nil))
+	if w.Body.String() != "admin beta" {
+		t.Error(w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK {
+		t.Error(w.Code)
+	}
+}
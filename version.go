@@ -0,0 +1,119 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultVersionHeader is the default header negotiating the API version.
+const DefaultVersionHeader = "X-Api-Version"
+
+// VersionContextKey is a context key. The associated value will be of type string.
+var VersionContextKey = &contextKey{"version"}
+
+// Version registers a group of the API version to the Mux. The routes of
+// the version are matched by the path prefix "/"+version, or by the
+// negotiated version of the requests without the prefix.
+//
+// The version is negotiated by the version parameter of the Accept header,
+// such as "application/vnd.api+json;version=2", by the version header, or
+// by the default version, in that order. A negotiated version "2" also
+// matches the version "v2". The negotiated requests are served with the
+// version prefixed to the URL path.
+func (m *Mux) Version(version string, f func(m *Mux)) {
+	m.Group("/"+version, f)
+	m.mut.Lock()
+	m.versions = append(m.versions, version)
+	m.mut.Unlock()
+}
+
+// DefaultVersion sets the version of the requests without a negotiated version.
+func (m *Mux) DefaultVersion(version string) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.defaultVersion = version
+}
+
+// VersionHeader sets the header negotiating the API version.
+// If empty, the DefaultVersionHeader is used.
+func (m *Mux) VersionHeader(header string) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.versionHeader = header
+}
+
+// APIVersion returns the negotiated API version of the request.
+func APIVersion(r *http.Request) string {
+	if version, ok := r.Context().Value(VersionContextKey).(string); ok {
+		return version
+	}
+	return ""
+}
+
+// negotiateVersion returns the registered version negotiated by the request.
+func (m *Mux) negotiateVersion(r *http.Request) string {
+	if v := acceptVersion(r.Header.Get("Accept")); v != "" {
+		if version := m.matchVersion(v); version != "" {
+			return version
+		}
+	}
+	header := m.versionHeader
+	if header == "" {
+		header = DefaultVersionHeader
+	}
+	if v := r.Header.Get(header); v != "" {
+		if version := m.matchVersion(v); version != "" {
+			return version
+		}
+	}
+	return m.matchVersion(m.defaultVersion)
+}
+
+func (m *Mux) matchVersion(v string) string {
+	if v == "" {
+		return ""
+	}
+	for _, version := range m.versions {
+		if version == v || version == "v"+v {
+			return version
+		}
+	}
+	return ""
+}
+
+// acceptVersion returns the version parameter of the Accept header.
+func acceptVersion(accept string) string {
+	if !strings.Contains(accept, "version=") {
+		return ""
+	}
+	for _, part := range strings.Split(accept, ",") {
+		if _, params, err := mime.ParseMediaType(part); err == nil && params["version"] != "" {
+			return params["version"]
+		}
+	}
+	return ""
+}
+
+// searchVersion searches the entry of the negotiated version, and returns
+// the request with the version prefixed to the URL path.
+func (m *Mux) searchVersion(path string, w http.ResponseWriter, r *http.Request) (*Entry, *http.Request) {
+	version := m.negotiateVersion(r)
+	if version == "" {
+		return nil, r
+	}
+	entry := m.searchEntry(m.replace("/"+version+path), w, r)
+	if entry == nil {
+		return nil, r
+	}
+	r = r.WithContext(context.WithValue(r.Context(), VersionContextKey, version))
+	u := *r.URL
+	u.Path = "/" + version + u.Path
+	u.RawPath = ""
+	r.URL = &u
+	return entry, r
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	m := NewMux()
+	for _, version := range []string{"v1", "v2"} {
+		version := version
+		m.Version(version, func(m *Mux) {
+			m.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(version + " " + APIVersion(r) + " " + m.Params(r)["id"]))
+			}).GET()
+		})
+	}
+	m.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}).GET()
+	test := func(header, value, path, result string, status int) {
+		req := httptest.NewRequest("GET", path, nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Error(path, header, value, w.Code)
+		} else if status == http.StatusOK && w.Body.String() != result {
+			t.Error(path, header, value, w.Body.String())
+		}
+	}
+	test("", "", "/v1/users/1", "v1  1", http.StatusOK)
+	test("", "", "/v2/users/2", "v2  2", http.StatusOK)
+	test("", "", "/users/1", "", http.StatusNotFound)
+	test("Accept", "application/vnd.api+json;version=2", "/users/3", "v2 v2 3", http.StatusOK)
+	test("Accept", "text/html, application/vnd.api+json; version=v1", "/users/4", "v1 v1 4", http.StatusOK)
+	test("Accept", "application/vnd.api+json;version=3", "/users/5", "", http.StatusNotFound)
+	test(DefaultVersionHeader, "1", "/users/6", "v1 v1 6", http.StatusOK)
+	test("", "", "/health", "ok", http.StatusOK)
+	m.DefaultVersion("v2")
+	test("", "", "/users/7", "v2 v2 7", http.StatusOK)
+	m.VersionHeader("X-Version")
+	test("X-Version", "v1", "/users/8", "v1 v1 8", http.StatusOK)
+	test(DefaultVersionHeader, "1", "/users/9", "v2 v2 9", http.StatusOK)
+}
@@ -0,0 +1,248 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSignatureMismatch is the error of a webhook whose signature does not
+// match the body.
+var ErrSignatureMismatch = errors.New("Signature Mismatch")
+
+// ErrSignatureExpired is the error of a webhook whose timestamp is outside
+// of the tolerance, or whose signature has been seen within it.
+var ErrSignatureExpired = errors.New("Signature Expired")
+
+// ErrTimestampUnsigned is the error of a WebhookSignature with a
+// TimestampHeader but no Payload signing the timestamp.
+var ErrTimestampUnsigned = errors.New("Timestamp Unsigned")
+
+// RawBodyContextKey is a context key. The associated value is the []byte
+// raw body of the request verified by the WebhookSignature.
+var RawBodyContextKey = &contextKey{"raw-body"}
+
+// RawBody returns the raw body of the request verified by the
+// WebhookSignature, or nil.
+func RawBody(r *http.Request) []byte {
+	body, _ := r.Context().Value(RawBodyContextKey).([]byte)
+	return body
+}
+
+// WebhookSignature verifies the HMAC signatures of the raw bodies of the
+// webhooks. The body is buffered within the MaxBodySize, and restored for
+// the handler, which can also get it by RawBody. The requests with a body
+// too large are replied with 413 Request Entity Too Large, and those with
+// a missing, mismatching or replayed signature with 401 Unauthorized.
+type WebhookSignature struct {
+	// Secrets are the HMAC secrets. A signature made by any of them is
+	// valid, so that the secrets can be rotated.
+	Secrets [][]byte
+	// Header is the header carrying the signature.
+	Header string
+	// Parse returns the timestamp and the hex encoded signatures of the
+	// value of the Header. If nil, the value without the Prefix is the
+	// signature.
+	Parse func(value string) (timestamp string, signatures []string)
+	// Prefix is the prefix of the signature, such as "sha256=".
+	Prefix string
+	// TimestampHeader is the header carrying the Unix timestamp of the
+	// webhook, if not parsed from the Header. It requires a Payload signing
+	// the timestamp, and the webhooks without it are rejected.
+	TimestampHeader string
+	// Payload returns the signed payload of the timestamp and the body.
+	// If nil, the body is signed.
+	Payload func(timestamp string, body []byte) []byte
+	// Hash returns the hash of the HMAC. If nil, SHA-256 is used.
+	Hash func() hash.Hash
+	// Tolerance is the replay window of the timestamps. The webhooks with a
+	// timestamp are rejected outside of the window, or if their signature
+	// has been seen within it. Zero means five minutes.
+	Tolerance time.Duration
+	// MaxBodySize is the maximum size in bytes of the body. Zero means one
+	// megabyte.
+	MaxBodySize int64
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+	now  func() time.Time
+}
+
+// GitHubWebhook returns the WebhookSignature of the X-Hub-Signature-256
+// header of the GitHub webhooks.
+func GitHubWebhook(secret string) *WebhookSignature {
+	return &WebhookSignature{
+		Secrets: [][]byte{[]byte(secret)},
+		Header:  "X-Hub-Signature-256",
+		Prefix:  "sha256=",
+	}
+}
+
+// StripeWebhook returns the WebhookSignature of the Stripe-Signature header
+// of the Stripe webhooks, signing the timestamp and the body.
+func StripeWebhook(secret string) *WebhookSignature {
+	return &WebhookSignature{
+		Secrets: [][]byte{[]byte(secret)},
+		Header:  "Stripe-Signature",
+		Parse: func(value string) (timestamp string, signatures []string) {
+			for _, field := range strings.Split(value, ",") {
+				k, v := field, ""
+				if i := strings.IndexByte(field, '='); i >= 0 {
+					k, v = strings.TrimSpace(field[:i]), strings.TrimSpace(field[i+1:])
+				}
+				switch k {
+				case "t":
+					timestamp = v
+				case "v1":
+					signatures = append(signatures, v)
+				}
+			}
+			return
+		},
+		Payload: func(timestamp string, body []byte) []byte {
+			return append([]byte(timestamp+"."), body...)
+		},
+	}
+}
+
+// Handler returns a handler verifying the signature of the webhooks before
+// calling the handler. It panics with ErrTimestampUnsigned if the timestamp
+// of the TimestampHeader is not signed.
+func (s *WebhookSignature) Handler(h http.Handler) http.Handler {
+	if s.TimestampHeader != "" && s.Payload == nil {
+		panic(ErrTimestampUnsigned)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		maxBodySize := s.MaxBodySize
+		if maxBodySize <= 0 {
+			maxBodySize = 1 << 20
+		}
+		if r.ContentLength > maxBodySize {
+			http.Error(w, "413 Request Entity Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+		if err != nil {
+			http.Error(w, "400 Bad Request", http.StatusBadRequest)
+			return
+		} else if int64(len(body)) > maxBodySize {
+			http.Error(w, "413 Request Entity Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err := s.Verify(r, body); err != nil {
+			http.Error(w, "401 Unauthorized : "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), RawBodyContextKey, body))
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		r.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Verify verifies the signature of the request over the body.
+func (s *WebhookSignature) Verify(r *http.Request, body []byte) error {
+	value := r.Header.Get(s.Header)
+	var timestamp string
+	var signatures []string
+	if s.Parse != nil {
+		timestamp, signatures = s.Parse(value)
+	} else if strings.HasPrefix(value, s.Prefix) {
+		signatures = []string{value[len(s.Prefix):]}
+	}
+	if s.TimestampHeader != "" {
+		if s.Payload == nil {
+			return ErrTimestampUnsigned
+		}
+		if timestamp = r.Header.Get(s.TimestampHeader); timestamp == "" {
+			return ErrSignatureExpired
+		}
+	}
+	if len(signatures) == 0 {
+		return ErrSignatureMismatch
+	}
+	payload := body
+	if s.Payload != nil {
+		payload = s.Payload(timestamp, body)
+	}
+	hashFunc := s.Hash
+	if hashFunc == nil {
+		hashFunc = sha256.New
+	}
+	var matched []byte
+	for _, secret := range s.Secrets {
+		mac := hmac.New(hashFunc, secret)
+		mac.Write(payload)
+		expected := mac.Sum(nil)
+		for _, signature := range signatures {
+			if decoded, err := hex.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+				matched = expected
+			}
+		}
+	}
+	if matched == nil {
+		return ErrSignatureMismatch
+	}
+	if timestamp == "" {
+		return nil
+	}
+	return s.replay(timestamp, string(matched))
+}
+
+// replay checks the timestamp against the tolerance, and records the
+// decoded signature to reject its replay within the tolerance, whatever
+// the letter case of its hex encoding.
+func (s *WebhookSignature) replay(timestamp, signature string) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrSignatureExpired
+	}
+	tolerance := s.Tolerance
+	if tolerance <= 0 {
+		tolerance = time.Minute * 5
+	}
+	now := time.Now()
+	if s.now != nil {
+		now = s.now()
+	}
+	at := time.Unix(unix, 0)
+	if at.Before(now.Add(-tolerance)) || at.After(now.Add(tolerance)) {
+		return ErrSignatureExpired
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen == nil {
+		s.seen = make(map[string]time.Time)
+	}
+	if expires, ok := s.seen[signature]; ok && now.Before(expires) {
+		return ErrSignatureExpired
+	}
+	if len(s.seen) >= maxBuckets {
+		seen := make(map[string]time.Time)
+		for k, expires := range s.seen {
+			if now.Before(expires) {
+				seen[k] = expires
+			}
+		}
+		s.seen = seen
+	}
+	s.seen[signature] = at.Add(tolerance)
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGitHubWebhook(t *testing.T) {
+	s := GitHubWebhook("old")
+	s.Secrets = append(s.Secrets, []byte("new"))
+	s.MaxBodySize = 16
+	h := s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(RawBody(r)) != string(body) {
+			t.Error(string(RawBody(r)))
+		}
+		w.Write(body)
+	}))
+	tests := []struct {
+		body      string
+		signature string
+		status    int
+	}{
+		{`{"ok":true}`, "sha256=" + testSign("old", `{"ok":true}`), http.StatusOK},
+		{`{"ok":true}`, "sha256=" + testSign("new", `{"ok":true}`), http.StatusOK},
+		{`{"ok":true}`, "sha256=" + testSign("other", `{"ok":true}`), http.StatusUnauthorized},
+		{`{"ok":false}`, "sha256=" + testSign("old", `{"ok":true}`), http.StatusUnauthorized},
+		{`{"ok":true}`, testSign("old", `{"ok":true}`), http.StatusUnauthorized},
+		{`{"ok":true}`, "", http.StatusUnauthorized},
+		{strings.Repeat("x", 17), "sha256=" + testSign("old", strings.Repeat("x", 17)), http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(test.body))
+		if test.signature != "" {
+			r.Header.Set("X-Hub-Signature-256", test.signature)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Error(test.body, test.signature, w.Code)
+		} else if w.Code == http.StatusOK && w.Body.String() != test.body {
+			t.Error(w.Body.String())
+		}
+	}
+	r := httptest.NewRequest("POST", "/", strings.NewReader(strings.Repeat("x", 17)))
+	r.ContentLength = -1
+	r.Header.Set("X-Hub-Signature-256", "sha256="+testSign("old", strings.Repeat("x", 17)))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Error(w.Code)
+	}
+}
+
+func TestStripeWebhook(t *testing.T) {
+	s := StripeWebhook("whsec")
+	now := time.Unix(1600000000, 0)
+	s.now = func() time.Time { return now }
+	h := s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	body := `{"id":"evt_1"}`
+	header := func(timestamp int64, secret string) string {
+		t := strconv.FormatInt(timestamp, 10)
+		return "t=" + t + ",v1=" + testSign("other", t+"."+body) + ",v1=" + testSign(secret, t+"."+body)
+	}
+	tests := []struct {
+		signature string
+		status    int
+	}{
+		{header(now.Unix(), "whsec"), http.StatusOK},
+		{header(now.Unix(), "whsec"), http.StatusUnauthorized},
+		{"t=" + strconv.FormatInt(now.Unix(), 10) + ",v1=" + strings.ToUpper(testSign("whsec", strconv.FormatInt(now.Unix(), 10)+"."+body)), http.StatusUnauthorized},
+		{header(now.Unix()-60, "whsec"), http.StatusOK},
+		{header(now.Unix()-600, "whsec"), http.StatusUnauthorized},
+		{header(now.Unix()+600, "whsec"), http.StatusUnauthorized},
+		{header(now.Unix()-30, "wrong"), http.StatusUnauthorized},
+		{"t=abc,v1=" + testSign("whsec", "abc."+body), http.StatusUnauthorized},
+	}
+	for i, test := range tests {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		r.Header.Set("Stripe-Signature", test.signature)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Error(i, w.Code, w.Body.String())
+		}
+	}
+	now = now.Add(time.Minute * 6)
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	r.Header.Set("Stripe-Signature", header(now.Unix(), "whsec"))
+	if err := s.Verify(r, []byte(body)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWebhookTimestampHeader(t *testing.T) {
+	s := &WebhookSignature{
+		Secrets:         [][]byte{[]byte("secret")},
+		Header:          "X-Signature",
+		TimestampHeader: "X-Timestamp",
+		Payload: func(timestamp string, body []byte) []byte {
+			return append([]byte(timestamp+":"), body...)
+		},
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("X-Timestamp", timestamp)
+	r.Header.Set("X-Signature", testSign("secret", timestamp+":body"))
+	if err := s.Verify(r, []byte("body")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Verify(r, []byte("body")); err != ErrSignatureExpired {
+		t.Error(err)
+	}
+	r.Header.Del("X-Timestamp")
+	r.Header.Set("X-Signature", testSign("secret", ":body"))
+	if err := s.Verify(r, []byte("body")); err != ErrSignatureExpired {
+		t.Error(err)
+	}
+	s.Payload = nil
+	if err := s.Verify(r, []byte("body")); err != ErrTimestampUnsigned {
+		t.Error(err)
+	}
+	defer func() {
+		if e := recover(); e != ErrTimestampUnsigned {
+			t.Error(e)
+		}
+	}()
+	s.Handler(http.NotFoundHandler())
+}
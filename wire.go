@@ -0,0 +1,352 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// The responses, the interim responses, the switched protocols and the
+// other responses written to the connection by the Server itself use the
+// status lines and the header bytes precomputed below, instead of formatting
+// them on every request.
+
+var (
+	crlf       = []byte("\r\n")
+	colonSpace = []byte(": ")
+	lastChunk  = []byte("0\r\n\r\n")
+)
+
+// ErrStatusCode is returned by RegisterStatus for a code out of [100, 599].
+var ErrStatusCode = errors.New("Invalid Status Code")
+
+// wireTables holds the status lines, the registered status texts and the
+// registered header keys. The tables are replaced on registration and never
+// modified, so the responses read them without locking.
+type wireTables struct {
+	lines [600][]byte
+	texts [600]string
+	keys  map[string]string
+}
+
+var (
+	wireMu sync.Mutex
+	wire   atomic.Value
+)
+
+// defaultWire holds the status lines of the known status codes.
+var defaultWire = func() *wireTables {
+	t := &wireTables{keys: map[string]string{}}
+	for code := range t.lines {
+		if text := http.StatusText(code); text != "" {
+			t.lines[code] = []byte("HTTP/1.1 " + strconv.Itoa(code) + " " + text + "\r\n")
+		}
+	}
+	return t
+}()
+
+func loadWire() *wireTables {
+	if t, ok := wire.Load().(*wireTables); ok {
+		return t
+	}
+	return defaultWire
+}
+
+// copyWire returns a copy of the tables to register into.
+func copyWire() *wireTables {
+	old := loadWire()
+	t := &wireTables{lines: old.lines, texts: old.texts, keys: make(map[string]string, len(old.keys))}
+	for k, v := range old.keys {
+		t.keys[k] = v
+	}
+	return t
+}
+
+// RegisterStatus registers the text of a status code, such as a code of
+// the API missing from net/http or a reason phrase differing from the
+// standard one. The status line is precomputed for the responses of the
+// Server, and the text is returned by StatusText for the default error
+// replies. It is safe to call while serving.
+func RegisterStatus(code int, text string) error {
+	if code < 100 || code >= len(wireTables{}.lines) {
+		return ErrStatusCode
+	}
+	wireMu.Lock()
+	defer wireMu.Unlock()
+	t := copyWire()
+	t.texts[code] = text
+	t.lines[code] = []byte("HTTP/1.1 " + strconv.Itoa(code) + " " + text + "\r\n")
+	wire.Store(t)
+	return nil
+}
+
+// StatusText returns the text of the status code registered by
+// RegisterStatus, or else http.StatusText.
+func StatusText(code int) string {
+	t := loadWire()
+	if code >= 0 && code < len(t.texts) && t.texts[code] != "" {
+		return t.texts[code]
+	}
+	return http.StatusText(code)
+}
+
+// RegisterHeaderKeys registers the forms of the header keys written by the
+// Server, such as "WWW-Authenticate" or "X-API-Key" instead of the canonical
+// "Www-Authenticate" and "X-Api-Key", for the clients sensitive to the case.
+// The handlers keep setting the canonical keys. It is safe to call while
+// serving.
+func RegisterHeaderKeys(keys ...string) {
+	wireMu.Lock()
+	defer wireMu.Unlock()
+	t := copyWire()
+	for _, key := range keys {
+		t.keys[http.CanonicalHeaderKey(key)] = key
+	}
+	wire.Store(t)
+}
+
+// headerValueReplacer sanitizes the newlines in the header values, as http.Header.Write does.
+var headerValueReplacer = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+// statusLine returns the status line of the code.
+func statusLine(code int) []byte {
+	if t := loadWire(); code >= 0 && code < len(t.lines) && t.lines[code] != nil {
+		return t.lines[code]
+	}
+	return []byte("HTTP/1.1 " + strconv.Itoa(code) + " status code " + strconv.Itoa(code) + "\r\n")
+}
+
+// writeResponseHeader writes the status line and the header of the
+// response to w, without sorting the keys nor allocating for the values
+// free of newlines.
+func writeResponseHeader(w *bufio.Writer, code int, header http.Header) {
+	w.Write(statusLine(code))
+	keys := loadWire().keys
+	for key, values := range header {
+		if key == "" {
+			continue
+		}
+		if k, ok := keys[key]; ok {
+			key = k
+		}
+		for _, v := range values {
+			if strings.ContainsAny(v, "\r\n") {
+				v = headerValueReplacer.Replace(v)
+			}
+			w.WriteString(key)
+			w.Write(colonSpace)
+			w.WriteString(strings.TrimSpace(v))
+			w.Write(crlf)
+		}
+	}
+	w.Write(crlf)
+}
+
+// wireResponse is the http.ResponseWriter of the Server. The body is
+// buffered up to the size, to reply with a Content-Length, and streamed with
+// the chunked transfer encoding beyond, unless the handler sets the
+// Content-Length. The header is written when the response is sent, so the
+// Server can still mark the connection closed after the handler returns.
+type wireResponse struct {
+	req         *http.Request
+	conn        net.Conn
+	rw          *bufio.ReadWriter
+	header      http.Header
+	size        int
+	code        int
+	buf         []byte
+	length      int64
+	written     int64
+	wroteHeader bool
+	sent        bool
+	noBody      bool
+	chunked     bool
+	closing     bool
+	hijacked    bool
+	scratch     [20]byte
+}
+
+var wireResponsePool = sync.Pool{New: func() interface{} {
+	return &wireResponse{}
+}}
+
+func newWireResponse(req *http.Request, conn net.Conn, rw *bufio.ReadWriter, size int) *wireResponse {
+	w := wireResponsePool.Get().(*wireResponse)
+	w.req, w.conn, w.rw = req, conn, rw
+	w.header = make(http.Header)
+	w.size = size
+	w.length = -1
+	return w
+}
+
+// freeWireResponse puts the response back to the pool, unless the
+// connection has been hijacked by the handler.
+func freeWireResponse(w *wireResponse) {
+	if w.hijacked {
+		return
+	}
+	*w = wireResponse{buf: w.buf[:0]}
+	wireResponsePool.Put(w)
+}
+
+// Header implements the http.ResponseWriter interface.
+func (w *wireResponse) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (w *wireResponse) WriteHeader(code int) {
+	if w.hijacked || w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.code = code
+	w.noBody = !bodyAllowedForStatus(code)
+}
+
+// Write implements the http.ResponseWriter interface.
+func (w *wireResponse) Write(p []byte) (int, error) {
+	if w.hijacked {
+		return 0, http.ErrHijacked
+	}
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if w.noBody {
+		return 0, http.ErrBodyNotAllowed
+	}
+	if !w.sent {
+		if len(w.buf)+len(p) <= w.size {
+			w.buf = append(w.buf, p...)
+			return len(p), nil
+		}
+		if err := w.send(false); err != nil {
+			return 0, err
+		}
+	}
+	if w.length >= 0 && w.written+int64(len(p)) > w.length {
+		return 0, http.ErrContentLength
+	}
+	if err := w.writeBody(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// send writes the header and the buffered body. The final response has a
+// Content-Length, the streamed one is chunked unless the handler sets the
+// Content-Length, and the one the handler sets chunked stays chunked.
+func (w *wireResponse) send(final bool) error {
+	w.sent = true
+	header := w.header
+	_, haveType := header["Content-Type"]
+	if !haveType && len(w.buf) > 0 && header.Get("Content-Encoding") == "" {
+		header["Content-Type"] = []string{http.DetectContentType(w.buf)}
+	}
+	head := w.req.Method == http.MethodHead
+	if cl := header.Get("Content-Length"); cl != "" {
+		if n, err := strconv.ParseInt(cl, 10, 64); err == nil && n >= 0 {
+			w.length = n
+		}
+	}
+	switch {
+	case w.noBody:
+	case header.Get("Transfer-Encoding") == "chunked":
+		w.chunked, w.length = !head, -1
+	case final && (!head || len(w.buf) > 0):
+		w.length = int64(len(w.buf))
+		header["Content-Length"] = []string{strconv.Itoa(len(w.buf))}
+	case final || w.length >= 0:
+	case head:
+	case w.req.ProtoAtLeast(1, 1):
+		w.chunked = true
+		header["Transfer-Encoding"] = []string{"chunked"}
+	default:
+		w.closing = true
+	}
+	if w.req.Close || w.closing {
+		header["Connection"] = []string{"close"}
+	} else if !w.req.ProtoAtLeast(1, 1) {
+		header["Connection"] = []string{"keep-alive"}
+	}
+	w.closing = header.Get("Connection") == "close"
+	writeResponseHeader(w.rw.Writer, w.code, header)
+	if len(w.buf) > 0 {
+		buf := w.buf
+		w.buf = w.buf[:0]
+		return w.writeBody(buf)
+	}
+	return nil
+}
+
+func (w *wireResponse) writeBody(p []byte) error {
+	w.written += int64(len(p))
+	if w.req.Method == http.MethodHead {
+		return nil
+	}
+	if w.chunked {
+		w.rw.Writer.Write(strconv.AppendInt(w.scratch[:0], int64(len(p)), 16))
+		w.rw.Writer.Write(crlf)
+		w.rw.Writer.Write(p)
+		_, err := w.rw.Writer.Write(crlf)
+		return err
+	}
+	_, err := w.rw.Writer.Write(p)
+	return err
+}
+
+// Flush implements the http.Flusher interface, streaming the response.
+func (w *wireResponse) Flush() {
+	if w.hijacked {
+		return
+	}
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.sent {
+		w.send(false)
+	}
+	w.rw.Writer.Flush()
+}
+
+// Hijack implements the http.Hijacker interface.
+func (w *wireResponse) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.hijacked {
+		return nil, nil, http.ErrHijacked
+	}
+	w.hijacked = true
+	return w.conn, w.rw, nil
+}
+
+// finish sends the response which the handler has not flushed, ends the
+// chunked body, and flushes the connection. The connection is closed by the
+// Server after the response if the request or the response asks for it, or
+// if the body is shorter than its Content-Length.
+func (w *wireResponse) finish() error {
+	if w.hijacked {
+		return nil
+	}
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.sent {
+		w.send(true)
+	} else if w.length >= 0 && w.written < w.length && w.req.Method != http.MethodHead {
+		w.closing = true
+	}
+	if w.chunked {
+		w.rw.Writer.Write(lastChunk)
+	}
+	return w.rw.Writer.Flush()
+}
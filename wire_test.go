@@ -0,0 +1,229 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/hslam/response"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteResponseHeader(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writeResponseHeader(w, http.StatusEarlyHints, http.Header{"Link": {"</style.css>; rel=preload"}})
+	w.Flush()
+	if buf.String() != "HTTP/1.1 103 Early Hints\r\nLink: </style.css>; rel=preload\r\n\r\n" {
+		t.Errorf("%q", buf.String())
+	}
+	buf.Reset()
+	writeResponseHeader(w, 599, http.Header{"X-Injected": {"a\r\nSet-Cookie: b"}})
+	w.Flush()
+	if buf.String() != "HTTP/1.1 599 status code 599\r\nX-Injected: a Set-Cookie: b\r\n\r\n" {
+		t.Errorf("%q", buf.String())
+	}
+	if string(statusLine(http.StatusOK)) != "HTTP/1.1 200 OK\r\n" {
+		t.Error(string(statusLine(http.StatusOK)))
+	}
+}
+
+func TestRegisterStatus(t *testing.T) {
+	defer wire.Store(defaultWire)
+	if err := RegisterStatus(600, "Invalid"); err != ErrStatusCode {
+		t.Error(err)
+	}
+	if err := RegisterStatus(599, "Network Connect Timeout Error"); err != nil {
+		t.Error(err)
+	}
+	if text := StatusText(599); text != "Network Connect Timeout Error" {
+		t.Error(text)
+	}
+	if text := StatusText(http.StatusLocked); text != "Locked" {
+		t.Error(text)
+	}
+	if msg := NewHTTPError(599, "").Error(); msg != "599 Network Connect Timeout Error" {
+		t.Error(msg)
+	}
+	RegisterHeaderKeys("X-API-Key")
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	header := http.Header{}
+	header.Set("X-API-Key", "secret")
+	writeResponseHeader(w, 599, header)
+	w.Flush()
+	if buf.String() != "HTTP/1.1 599 Network Connect Timeout Error\r\nX-API-Key: secret\r\n\r\n" {
+		t.Errorf("%q", buf.String())
+	}
+	RegisterStatus(http.StatusLocked, "Resource Locked")
+	buf.Reset()
+	rw := bufio.NewReadWriter(bufio.NewReader(&buf), w)
+	res := newWireResponse(httptest.NewRequest("GET", "/", nil), nil, rw, 64)
+	res.Header().Set("X-API-Key", "secret")
+	res.WriteHeader(http.StatusLocked)
+	res.finish()
+	if !strings.HasPrefix(buf.String(), "HTTP/1.1 423 Resource Locked\r\n") || !strings.Contains(buf.String(), "\r\nX-API-Key: secret\r\n") {
+		t.Errorf("%q", buf.String())
+	}
+	if defaultWire.texts[http.StatusLocked] != "" || string(statusLine(http.StatusLocked)) != "HTTP/1.1 423 Resource Locked\r\n" {
+		t.Error(defaultWire.texts[http.StatusLocked])
+	}
+}
+
+func TestRegisterStatusServing(t *testing.T) {
+	defer wire.Store(defaultWire)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			RegisterStatus(599, "Network Connect Timeout Error")
+			RegisterHeaderKeys("X-API-Key")
+		}
+	}()
+	w := bufio.NewWriter(ioutil.Discard)
+	header := http.Header{"X-Api-Key": {"secret"}}
+	for i := 0; i < 100; i++ {
+		writeResponseHeader(w, 599, header)
+		StatusText(599)
+	}
+	<-done
+}
+
+// serveWire replies to the request with the handler through a wireResponse
+// and reads the response back.
+func serveWire(t *testing.T, req *http.Request, size int, handler http.HandlerFunc) (*http.Response, string, *wireResponse) {
+	var buf bytes.Buffer
+	rw := bufio.NewReadWriter(bufio.NewReader(&buf), bufio.NewWriter(&buf))
+	w := newWireResponse(req, nil, rw, size)
+	handler(w, req)
+	if err := w.finish(); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(&buf), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body), w
+}
+
+func TestWireResponse(t *testing.T) {
+	hello := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello"))
+		w.Write([]byte(" World"))
+	}
+	resp, body, _ := serveWire(t, httptest.NewRequest("GET", "/", nil), 64, hello)
+	if body != "Hello World" || resp.ContentLength != 11 || len(resp.TransferEncoding) > 0 {
+		t.Error(body, resp.ContentLength, resp.TransferEncoding)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Error(ct)
+	}
+	resp, body, _ = serveWire(t, httptest.NewRequest("GET", "/", nil), 4, hello)
+	if body != "Hello World" || len(resp.TransferEncoding) == 0 || resp.TransferEncoding[0] != "chunked" {
+		t.Error(body, resp.TransferEncoding)
+	}
+	resp, body, _ = serveWire(t, httptest.NewRequest("GET", "/", nil), 4, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "11")
+		hello(w, r)
+	})
+	if body != "Hello World" || resp.ContentLength != 11 || len(resp.TransferEncoding) > 0 {
+		t.Error(body, resp.ContentLength, resp.TransferEncoding)
+	}
+	resp, body, _ = serveWire(t, httptest.NewRequest("HEAD", "/", nil), 64, hello)
+	if body != "" || resp.ContentLength != 11 {
+		t.Error(body, resp.ContentLength)
+	}
+	resp, body, _ = serveWire(t, httptest.NewRequest("GET", "/", nil), 64, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+		if _, err := w.Write([]byte("Hello")); err != http.ErrBodyNotAllowed {
+			t.Error(err)
+		}
+	})
+	if resp.StatusCode != http.StatusNoContent || body != "" || resp.Header.Get("Content-Length") != "" {
+		t.Error(resp.StatusCode, body, resp.Header)
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.ProtoMinor = 0
+	resp, body, w := serveWire(t, req, 4, hello)
+	if body != "Hello World" || !w.closing || !resp.Close {
+		t.Error(body, w.closing, resp.Close)
+	}
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Close = true
+	resp, _, w = serveWire(t, req, 64, hello)
+	if !w.closing || !resp.Close {
+		t.Error(w.closing, resp.Close)
+	}
+	_, _, w = serveWire(t, httptest.NewRequest("GET", "/", nil), 64, hello)
+	if w.closing {
+		t.Error(w.closing)
+	}
+}
+
+var benchmarkHeader = http.Header{
+	"Content-Type": {"text/plain; charset=utf-8"},
+	"Server":       {"rum"},
+	"Link":         {"</style.css>; rel=preload; as=style"},
+}
+
+func BenchmarkWriteResponseHeader(b *testing.B) {
+	w := bufio.NewWriter(ioutil.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		writeResponseHeader(w, http.StatusOK, benchmarkHeader)
+	}
+}
+
+func BenchmarkWriteResponseHeaderFmt(b *testing.B) {
+	w := bufio.NewWriter(ioutil.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		fmt.Fprintf(w, "HTTP/1.1 %d %s\r\n", http.StatusOK, http.StatusText(http.StatusOK))
+		benchmarkHeader.Write(w)
+		w.WriteString("\r\n")
+	}
+}
+
+// benchmarkResponse replies with a small body, as the regular responses do.
+func benchmarkResponse(w http.ResponseWriter) {
+	header := w.Header()
+	for key, values := range benchmarkHeader {
+		header[key] = values
+	}
+	w.Write([]byte("Hello World"))
+}
+
+func BenchmarkWireResponse(b *testing.B) {
+	req := httptest.NewRequest("GET", "/", nil)
+	rw := bufio.NewReadWriter(bufio.NewReader(strings.NewReader("")), bufio.NewWriter(ioutil.Discard))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w := newWireResponse(req, nil, rw, DefaultResponseBuffer)
+		benchmarkResponse(w)
+		w.finish()
+		freeWireResponse(w)
+	}
+}
+
+// BenchmarkResponse is the previous hot path through github.com/hslam/response.
+func BenchmarkResponse(b *testing.B) {
+	req := httptest.NewRequest("GET", "/", nil)
+	rw := bufio.NewReadWriter(bufio.NewReader(strings.NewReader("")), bufio.NewWriter(ioutil.Discard))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w := response.NewResponseSize(req, nil, rw, DefaultResponseBuffer)
+		benchmarkResponse(w)
+		w.FinishRequest()
+		response.FreeResponse(w)
+	}
+}
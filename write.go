@@ -0,0 +1,230 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// SetWriteTimeout sets the maximum duration before timing out writes of
+// each response, and enables the Server to detect the partial writes and
+// the broken connections, which are counted, logged to the error log and
+// returned by Flush.
+func (m *Rum) SetWriteTimeout(d time.Duration) {
+	m.writeTimeout = d
+}
+
+// SetErrorLog sets the logger for the errors of the connections.
+// If nil, the errors are logged to the standard logger.
+func (m *Rum) SetErrorLog(logger *log.Logger) {
+	m.errorLog = logger
+}
+
+// WriteErrors returns the number of responses failed to be written.
+func (m *Rum) WriteErrors() uint64 {
+	return atomic.LoadUint64(&m.writeErrors)
+}
+
+func (m *Rum) logf(format string, args ...interface{}) {
+	if m.errorLog != nil {
+		m.errorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// Flush sends any buffered data of the response to the client and returns
+// the error of the connection, such as a write timeout or a broken pipe.
+// It returns http.ErrNotSupported if the response writer can not flush.
+func Flush(w http.ResponseWriter) error {
+	if f, ok := w.(interface{ FlushError() error }); ok {
+		return f.FlushError()
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+		return nil
+	}
+	return http.ErrNotSupported
+}
+
+// forwarder forwards the optional interfaces of the wrapped response
+// writer. The response writers embed it and override the methods that need
+// their own bookkeeping, and are exposed to the handlers by expose.
+type forwarder struct {
+	http.ResponseWriter
+}
+
+// Flush implements the http.Flusher interface.
+func (w forwarder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// FlushError implements the error-returning Flush.
+func (w forwarder) FlushError() error {
+	return Flush(w.ResponseWriter)
+}
+
+// Push implements the http.Pusher interface.
+func (w forwarder) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Hijack implements the http.Hijacker interface.
+func (w forwarder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// forwarding is a response writer embedding the forwarder.
+type forwarding interface {
+	flushWriter
+	http.Hijacker
+	http.Pusher
+}
+
+type flushWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	FlushError() error
+}
+
+// expose returns the response writer w wrapping the response writer
+// wrapped, which implements http.Hijacker and http.Pusher only if the
+// wrapped one does, so that the handlers detecting them are not misled.
+func expose(w forwarding, wrapped http.ResponseWriter) http.ResponseWriter {
+	_, hijacker := wrapped.(http.Hijacker)
+	_, pusher := wrapped.(http.Pusher)
+	switch {
+	case hijacker && pusher:
+		return w
+	case hijacker:
+		return struct {
+			flushWriter
+			http.Hijacker
+		}{w, w}
+	case pusher:
+		return struct {
+			flushWriter
+			http.Pusher
+		}{w, w}
+	}
+	return struct{ flushWriter }{w}
+}
+
+// writeConn records the write errors and the write stalls of a connection.
+type writeConn struct {
+	net.Conn
+	err     error
+	partial bool
+	written int64
+	// stallLimit is the write stall of a slow client, and abort limits the writes to it.
+	stallLimit time.Duration
+	abort      bool
+	timeout    bool
+	deadline   time.Time
+	stall      time.Duration
+	slow       bool
+}
+
+// trackConn wraps the conn to record the write errors when the write timeout
+// or the slow client detection is set.
+func (m *Rum) trackConn(conn net.Conn) net.Conn {
+	if m.writeTimeout <= 0 && m.slowClients.Stall <= 0 {
+		return conn
+	}
+	return &writeConn{Conn: conn, stallLimit: m.slowClients.Stall, abort: m.slowClients.Abort, timeout: m.writeTimeout > 0}
+}
+
+// begin starts the response with the write deadline of the request. Without
+// the write timeout the response has no deadline, and the slow client
+// detection alone limits the writes.
+func (c *writeConn) begin(deadline time.Time) {
+	c.written = 0
+	c.stall = 0
+	c.slow = false
+	if !c.timeout {
+		deadline = time.Time{}
+	}
+	c.deadline = deadline
+	c.Conn.SetWriteDeadline(deadline)
+}
+
+func (c *writeConn) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	if c.stallLimit <= 0 {
+		return c.write(p)
+	}
+	if c.abort {
+		deadline := time.Now().Add(c.stallLimit)
+		if !c.deadline.IsZero() && c.deadline.Before(deadline) {
+			deadline = c.deadline
+		}
+		c.Conn.SetWriteDeadline(deadline)
+	}
+	start := time.Now()
+	n, err := c.write(p)
+	stall := time.Since(start)
+	c.stall += stall
+	if stall >= c.stallLimit {
+		c.slow = true
+	}
+	return n, err
+}
+
+func (c *writeConn) write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written += int64(n)
+	if err != nil {
+		c.err = err
+		c.partial = n > 0 || c.written > 0
+	}
+	return n, err
+}
+
+// writeError counts and logs the write error of the response, and closes the connection.
+func (m *Rum) writeError(c *writeConn, req *http.Request) {
+	if c.err == nil {
+		return
+	}
+	atomic.AddUint64(&m.writeErrors, 1)
+	if c.partial {
+		m.logf("rum: partial write of %s %s after %d bytes: %v", req.Method, req.RequestURI, c.written, c.err)
+	} else {
+		m.logf("rum: write of %s %s: %v", req.Method, req.RequestURI, c.err)
+	}
+	c.Conn.Close()
+}
+
+type flushResponseWriter struct {
+	forwarder
+	conn    *writeConn
+	flusher interface{}
+}
+
+// FlushError flushes the buffered data and returns the error of the connection.
+func (w *flushResponseWriter) FlushError() error {
+	if f, ok := w.flusher.(http.Flusher); ok {
+		f.Flush()
+	}
+	return w.conn.err
+}
+
+// Flush implements the http.Flusher interface.
+func (w *flushResponseWriter) Flush() {
+	w.FlushError()
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package rum
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTimeout(t *testing.T) {
+	addr := ":8080"
+	m := New()
+	m.SetWriteTimeout(time.Second)
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello "))
+		if err := Flush(w); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte("World"))
+	})
+	done := make(chan struct{})
+	go func() {
+		m.Run(addr)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 10)
+	testHTTP("GET", "http://"+addr+"/", http.StatusOK, "Hello World", t)
+	if m.WriteErrors() != 0 {
+		t.Error(m.WriteErrors())
+	}
+	m.Close()
+	<-done
+}
+
+func TestWriteConn(t *testing.T) {
+	m := New()
+	if conn, _ := net.Pipe(); m.trackConn(conn) != conn {
+		t.Error("should not track the conn without the write timeout")
+	}
+	m.SetWriteTimeout(time.Second)
+	buf := bytes.NewBuffer(nil)
+	m.SetErrorLog(log.New(buf, "", 0))
+	client, server := net.Pipe()
+	c := m.trackConn(server).(*writeConn)
+	c.begin(time.Now().Add(m.writeTimeout))
+	go func() {
+		b := make([]byte, 5)
+		client.Read(b)
+		client.Close()
+	}()
+	if _, err := c.Write([]byte("Hello World")); err == nil {
+		t.Error("should be a partial write")
+	}
+	if !c.partial {
+		t.Error("should be a partial write")
+	}
+	if _, err := c.Write([]byte("Hello World")); err != c.err {
+		t.Error(err)
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	w := &flushResponseWriter{forwarder: forwarder{httptest.NewRecorder()}, conn: c}
+	if err := Flush(w); err != c.err {
+		t.Error(err)
+	}
+	m.writeError(c, req)
+	if m.WriteErrors() != 1 {
+		t.Error(m.WriteErrors())
+	}
+	if !strings.Contains(buf.String(), "partial write of GET /") {
+		t.Error(buf.String())
+	}
+	if err := Flush(struct{ http.ResponseWriter }{}); err != http.ErrNotSupported {
+		t.Error(err)
+	}
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (w hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+func TestExpose(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := forwarder{rec}
+	w.Flush()
+	if !rec.Flushed {
+		t.Error("not flushed")
+	}
+	if err := w.Push("/style.css", nil); err != http.ErrNotSupported {
+		t.Error(err)
+	}
+	for _, w := range []forwarding{
+		&accessResponseWriter{}, &bodyLimitResponseWriter{}, &breakerResponseWriter{},
+		&compressResponseWriter{}, &debugResponseWriter{}, &grpcWebResponseWriter{},
+		&hintsResponseWriter{}, &bufferResponseWriter{}, &sniffResponseWriter{},
+		&transformResponseWriter{}, &flushResponseWriter{},
+	} {
+		exposed := expose(w, rec)
+		_, hijacker := exposed.(http.Hijacker)
+		_, pusher := exposed.(http.Pusher)
+		if _, flusher := exposed.(http.Flusher); !flusher || hijacker || pusher {
+			t.Errorf("%T exposes %v %v %v", w, flusher, hijacker, pusher)
+		}
+	}
+	hw := &hintsResponseWriter{forwarder: forwarder{hijackRecorder{rec}}}
+	inner := expose(hw, hw.ResponseWriter)
+	exposed := expose(&sniffResponseWriter{forwarder: forwarder{inner}}, inner)
+	if _, ok := exposed.(http.Pusher); ok {
+		t.Error("should not be a Pusher")
+	}
+	if h, ok := exposed.(http.Hijacker); !ok {
+		t.Error("should be a Hijacker")
+	} else if h.Hijack(); !hw.hijacked {
+		t.Error("not hijacked")
+	}
+}
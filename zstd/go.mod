@@ -0,0 +1,22 @@
+module github.com/hslam/rum/zstd
+
+go 1.22
+
+require (
+	github.com/hslam/rum v0.0.0-00010101000000-000000000000
+	github.com/klauspost/compress v1.18.0
+)
+
+require (
+	github.com/hslam/buffer v0.0.0-20230217202846-e7b1b6ebf283 // indirect
+	github.com/hslam/mmap v1.0.0 // indirect
+	github.com/hslam/netpoll v0.0.4-0.20230514092318-c286d2b379aa // indirect
+	github.com/hslam/request v0.0.3-0.20210611154049-b6a2b5ff3af6 // indirect
+	github.com/hslam/response v0.0.2-0.20210701170805-d45009729528 // indirect
+	github.com/hslam/reuse v0.0.0-20230219162114-9a3f8d1f9550 // indirect
+	github.com/hslam/scheduler v0.0.0-20211028175315-641598104976 // indirect
+	github.com/hslam/sendfile v1.0.1 // indirect
+	github.com/hslam/splice v1.0.3 // indirect
+)
+
+replace github.com/hslam/rum => ../
@@ -0,0 +1,46 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+// Package zstd provides the Zstandard content coding of the rum
+// Compression, based on the github.com/klauspost/compress/zstd package.
+// It is a separate module, so that only the servers using it depend on
+// the compression library.
+//
+//	c := &rum.Compression{Encoders: []*rum.Encoder{zstd.NewEncoder(zstd.DefaultLevel), rum.GzipEncoder(gzip.DefaultCompression)}}
+//	m.Handler = c.Handler(mux)
+package zstd
+
+import (
+	"github.com/hslam/rum"
+	"github.com/klauspost/compress/zstd"
+	"io"
+)
+
+const (
+	// BestSpeed is the fastest compression level.
+	BestSpeed = 1
+	// DefaultLevel is the default compression level of Zstandard.
+	DefaultLevel = 3
+	// BestCompression is the best compression level.
+	BestCompression = 22
+)
+
+// WindowSize is the maximum window size of the encoders, which the
+// decoders of the content coding are required to support by RFC 8878.
+const WindowSize = 8 << 20
+
+// NewEncoder returns an Encoder of zstd at the level, from BestSpeed to
+// BestCompression. The level is rounded to the closest level of the
+// encoder, and the pooled encoders compress each response on a single
+// goroutine.
+func NewEncoder(level int) *rum.Encoder {
+	return &rum.Encoder{Name: "zstd", Level: level, New: newWriter}
+}
+
+func newWriter(w io.Writer, level int) (rum.CompressWriter, error) {
+	return zstd.NewWriter(w,
+		zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)),
+		zstd.WithEncoderConcurrency(1),
+		zstd.WithWindowSize(WindowSize),
+	)
+}
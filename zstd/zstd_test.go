@@ -0,0 +1,50 @@
+// Copyright (c) 2020 Meng Huang (mhboy@outlook.com)
+// This package is licensed under a MIT license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"compress/gzip"
+	"github.com/hslam/rum"
+	"github.com/klauspost/compress/zstd"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+	text := strings.Repeat("Hello World ", 200)
+	mux := rum.NewMux()
+	mux.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(text))
+	})
+	for _, level := range []int{BestSpeed, DefaultLevel, 7, BestCompression} {
+		c := &rum.Compression{Encoders: []*rum.Encoder{NewEncoder(level), rum.GzipEncoder(gzip.DefaultCompression)}}
+		handler := c.Handler(mux)
+		for i := 0; i < 2; i++ {
+			r := httptest.NewRequest("GET", "/text", nil)
+			r.Header.Set("Accept-Encoding", "gzip, zstd")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if encoding := w.Header().Get("Content-Encoding"); encoding != "zstd" {
+				t.Fatal(level, encoding)
+			}
+			if w.Body.Len() >= len(text) {
+				t.Error(level, w.Body.Len())
+			}
+			d, err := zstd.NewReader(w.Body, zstd.WithDecoderMaxWindow(WindowSize))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := ioutil.ReadAll(d)
+			d.Close()
+			if err != nil {
+				t.Error(level, err)
+			} else if string(body) != text {
+				t.Error(level, len(body))
+			}
+		}
+	}
+}